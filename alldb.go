@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// clusterFlags are the global flags not passed on to the per-database runs
+// of -all-databases.
+var clusterFlags = map[string]bool{
+	"db": true, "W": true, "service": true,
+	"all-databases": true, "include-databases": true, "exclude-databases": true,
+	"manifest": true,
+}
+
+// matchAny reports whether name matches one of the comma separated glob
+// patterns.
+func matchAny(patterns, name string) bool {
+	for _, p := range strings.Split(patterns, ",") {
+		if ok, _ := path.Match(strings.TrimSpace(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// listDatabases returns the databases of the cluster accepting connections,
+// except templates, filtered by the include and exclude patterns.
+func (a *app) listDatabases(include, exclude string) ([]string, error) {
+	dsn := a.connStr
+	if a.service["dbname"] == "" && os.Getenv("PGDATABASE") == "" {
+		var err error
+		if dsn, err = withParams(dsn, map[string]string{"dbname": "postgres"}); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := a.open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var all []string
+	if _, err := conn.NewSession(nil).SelectBySql(`SELECT datname FROM pg_database
+WHERE datallowconn AND NOT datistemplate ORDER BY datname`).Load(&all); err != nil {
+		return nil, fmt.Errorf("select databases: %v", err)
+	}
+	var names []string
+	for _, name := range all {
+		if matchAny(include, name) && (exclude == "" || !matchAny(exclude, name)) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// runAllDatabases runs the command once per database of the cluster, each
+// in its own process so a failing database does not stop the others. {db}
+// in the command arguments is replaced by the database name, e.g. for -o.
+// It prints a summary and exits with the highest exit code of the runs.
+func (a *app) runAllDatabases(service, include, exclude string) {
+	names, err := a.listDatabases(include, exclude)
+	if err != nil {
+		a.log.WithError(err).Fatal("list databases")
+	}
+	if len(names) == 0 {
+		a.log.Fatal("no database matches")
+	}
+
+	var global []string
+	flag.Visit(func(f *flag.Flag) {
+		if !clusterFlags[f.Name] {
+			global = append(global, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	if service != "" {
+		global = append(global, "-service="+service)
+	}
+	env := os.Environ()
+	if a.password != "" {
+		env = append(env, "PGPASSWORD="+a.password)
+	}
+
+	type result struct {
+		db      string
+		err     error
+		elapsed time.Duration
+	}
+	var results []result
+	code := exitOK
+	for _, name := range names {
+		dsn, err := setParam(a.connStr, "dbname", name)
+		if err != nil {
+			a.log.WithError(err).Fatal("connection string")
+		}
+		args := append([]string{"-db=" + dsn}, global...)
+		// Each run writes a manifest of its own if the path has {db}.
+		if path := flag.Lookup("manifest").Value.String(); strings.Contains(path, "{db}") {
+			args = append(args, "-manifest="+strings.Replace(path, "{db}", name, -1))
+		}
+		for _, arg := range flag.Args() {
+			args = append(args, strings.Replace(arg, "{db}", name, -1))
+		}
+
+		fmt.Printf("== %s ==\n", name)
+		cmd := exec.Command(os.Args[0], args...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr, cmd.Env = os.Stdin, os.Stdout, os.Stderr, env
+		start := time.Now()
+		done := a.manifest.phase("database")
+		err = cmd.Run()
+		done()
+		results = append(results, result{name, err, time.Since(start)})
+		if e, ok := err.(*exec.ExitError); ok && e.ExitCode() > code {
+			code = e.ExitCode()
+		} else if err != nil && !ok {
+			code = exitFailure
+		}
+	}
+
+	fmt.Println("== summary ==")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATABASE\tSTATUS\tTIME")
+	for _, r := range results {
+		status := "ok"
+		if r.err != nil {
+			status = r.err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.db, status, r.elapsed.Round(time.Millisecond))
+	}
+	tw.Flush()
+	if code != exitOK {
+		a.log.Exit(code)
+	}
+}
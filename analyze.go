@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// analyses are the checks of `analyze <name>` which look at the data, not
+// only the structure.
+var analyses = map[string]func(a *app, args []string){
+	"arrays":        (*app).analyzeArrays,
+	"columns":       (*app).analyzeColumns,
+	"copies":        (*app).analyzeCopies,
+	"enums":         (*app).analyzeEnums,
+	"fk-actions":    (*app).analyzeFKActions,
+	"functions":     (*app).analyzeFunctions,
+	"index-types":   (*app).analyzeIndexTypes,
+	"indexes":       (*app).analyzeIndexes,
+	"json":          (*app).analyzeJSON,
+	"key-types":     (*app).analyzeKeyTypes,
+	"keys":          (*app).analyzeKeys,
+	"large-objects": (*app).analyzeLargeObjects,
+	"matviews":      (*app).analyzeMatviews,
+	"partitions":    (*app).analyzePartitions,
+	"sequences":     (*app).analyzeSequences,
+	"soft-delete":   (*app).analyzeSoftDelete,
+	"toast":         (*app).analyzeToast,
+	"triggers":      (*app).analyzeTriggers,
+	"types":         (*app).analyzeTypes,
+}
+
+func (a *app) runAnalyze(args []string) {
+	if len(args) == 0 || analyses[args[0]] == nil {
+		names := make([]string, 0, len(analyses))
+		for name := range analyses {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		a.log.Fatalf("analyze requires an analysis: %s", strings.Join(names, ", "))
+	}
+	analyses[args[0]](a, args[1:])
+}
+
+// loadLive inspects the -db database and keeps the connection open for
+// querying the data. The caller closes the connection.
+func (a *app) loadLive() (*inspect.Database, *dbr.Connection) {
+	conn, err := a.connect()
+	if err != nil {
+		a.log.WithError(err).Fatal("connect")
+	}
+	db, err := a.inspect(conn.NewSession(nil))
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	return db, conn
+}
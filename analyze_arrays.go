@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// arrayStats describe the sampled values of an array column.
+type arrayStats struct {
+	N        int64   `db:"n"`
+	AvgLen   float64 `db:"avg_len"`
+	Median   float64 `db:"median"`
+	P95      float64 `db:"p95"`
+	MaxLen   int64   `db:"max_len"`
+	Elements int64   `db:"elements"`
+	Distinct int64   `db:"distinct_elements"`
+}
+
+// arrayAdvice suggests a normalization from the statistics of an array
+// column, or returns an empty string.
+func arrayAdvice(st *arrayStats) string {
+	switch {
+	case st.P95 > 100:
+		return "long arrays, a child table can be indexed and updated per element"
+	case st.Distinct > 0 && st.Distinct <= 20 && st.Elements >= 10*st.Distinct:
+		return "few distinct elements, consider an enum or a lookup table"
+	case st.Elements > 0 && st.Distinct == st.Elements && st.MaxLen > 1:
+		return "elements never repeat, possibly references to normalize into a join table"
+	}
+	return ""
+}
+
+// analyzeArrays samples array columns and reports their lengths and the
+// cardinality of their elements.
+func (a *app) analyzeArrays(args []string) {
+	fs := flag.NewFlagSet("analyze arrays", flag.ContinueOnError)
+	sample := fs.Int("sample", 10000, "Rows to sample per column.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Column\tType\tSampled\tAvg len\tMedian\tP95\tMax\tDistinct elements\tAdvice")
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				if c.DataType != "ARRAY" {
+					continue
+				}
+				col := inspect.QuoteIdent(c.Name)
+				query := fmt.Sprintf(`WITH s AS (SELECT %[1]s AS v FROM %[2]s WHERE %[1]s IS NOT NULL LIMIT %[3]d)
+SELECT count(*) AS n, coalesce(avg(cardinality(v)), 0)::float8 AS avg_len,
+	coalesce(percentile_cont(0.5) WITHIN GROUP (ORDER BY cardinality(v)), 0) AS median,
+	coalesce(percentile_cont(0.95) WITHIN GROUP (ORDER BY cardinality(v)), 0) AS p95,
+	coalesce(max(cardinality(v)), 0) AS max_len,
+	(SELECT count(e) FROM s, unnest(s.v) e) AS elements,
+	(SELECT count(DISTINCT e) FROM s, unnest(s.v) e) AS distinct_elements
+FROM s`, col, tableRef(t), *sample)
+				st := &arrayStats{}
+				if err := sess.SelectBySql(query).LoadOne(st); err != nil {
+					a.log.WithError(err).Fatalf("sample %s.%s", qualifiedName(t), c.Name)
+				}
+				if st.N == 0 {
+					continue
+				}
+				advice := arrayAdvice(st)
+				if advice == "" {
+					advice = "ok"
+				}
+				fmt.Fprintf(tw, "%s.%s\t%s\t%d\t%.1f\t%.0f\t%.0f\t%d\t%d of %d\t%s\n", qualifiedName(t), c.Name,
+					c.UDTName[1:]+"[]", st.N, st.AvgLen, st.Median, st.P95, st.MaxLen, st.Distinct, st.Elements, advice)
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// nextvalPattern matches the sequence of a serial column's default, which
+// differs for every table.
+var nextvalPattern = regexp.MustCompile(`nextval\('[^']*'::regclass\)`)
+
+// columnShape is how a column is declared, comparable across tables.
+type columnShape struct {
+	typ      string
+	nullable bool
+	def      string
+}
+
+func (s columnShape) String() string {
+	null := "NOT NULL"
+	if s.nullable {
+		null = "NULL"
+	}
+	def := s.def
+	if def == "" {
+		def = "no default"
+	}
+	return fmt.Sprintf("%s %s, %s", s.typ, null, def)
+}
+
+// analyzeColumns groups the columns of the same name across tables, e.g.
+// every created_at, and reports the names whose type, nullability or
+// default disagree between tables, with how many tables declare each.
+func (a *app) analyzeColumns(args []string) {
+	fs := flag.NewFlagSet("analyze columns", flag.ContinueOnError)
+	minTables := fs.Int("min-tables", 2, "Only compare columns found in at least as many tables.")
+	ignore := fs.String("ignore", "id", "Comma separated column names not to compare.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	ignored := make(map[string]bool)
+	for _, n := range strings.Split(*ignore, ",") {
+		ignored[strings.TrimSpace(n)] = true
+	}
+
+	shapes := make(map[string]map[columnShape][]string)
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" || t.PartitionOf != "" {
+				continue
+			}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				if ignored[c.Name] {
+					continue
+				}
+				def := nextvalPattern.ReplaceAllString(c.Default, "nextval(...)")
+				switch {
+				case c.Identity != "":
+					def = "identity"
+				case c.Generated != "":
+					def = "generated"
+				}
+				shape := columnShape{c.TypeName(), c.Nullable, def}
+				if shapes[c.Name] == nil {
+					shapes[c.Name] = make(map[columnShape][]string)
+				}
+				shapes[c.Name][shape] = append(shapes[c.Name][shape], qualifiedName(t))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(shapes))
+	for name, variants := range shapes {
+		tables := 0
+		for _, ts := range variants {
+			tables += len(ts)
+		}
+		if len(variants) > 1 && tables >= *minTables {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Column\tDeclared as\tTables\tExamples")
+	for _, name := range names {
+		variants := make([]columnShape, 0, len(shapes[name]))
+		for v := range shapes[name] {
+			variants = append(variants, v)
+		}
+		sort.Slice(variants, func(i, j int) bool {
+			ni, nj := len(shapes[name][variants[i]]), len(shapes[name][variants[j]])
+			if ni != nj {
+				return ni > nj
+			}
+			return variants[i].String() < variants[j].String()
+		})
+		for i, v := range variants {
+			tables := shapes[name][v]
+			examples := tables
+			if len(examples) > 3 {
+				examples = append(examples[:3:3], "...")
+			}
+			label := ""
+			if i == 0 {
+				label = name
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", label, v, len(tables), strings.Join(examples, ", "))
+		}
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(tw, "no inconsistent columns")
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
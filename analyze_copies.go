@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datainq/pq-inspector/diff"
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// columnSignature is the names and types of the columns of t, in order.
+func columnSignature(t *inspect.Table) string {
+	parts := make([]string, len(t.Columns))
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		parts[i] = c.Name + " " + c.TypeName()
+		if !c.Nullable {
+			parts[i] += " not null"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reference picks the copy whose signature most copies share, the first
+// of them on a tie.
+func reference(copies []*inspect.Table) *inspect.Table {
+	count := map[string]int{}
+	for _, t := range copies {
+		count[columnSignature(t)]++
+	}
+	ref := copies[0]
+	for _, t := range copies[1:] {
+		if count[columnSignature(t)] > count[columnSignature(ref)] {
+			ref = t
+		}
+	}
+	return ref
+}
+
+// requalify returns a copy of t whose column defaults refer to schema
+// instead of the schema of t, since defaults name sequences by their
+// schema, e.g. nextval('t1.orders_id_seq').
+func requalify(t *inspect.Table, schema string) *inspect.Table {
+	c := *t
+	c.Columns = make([]inspect.Column, len(t.Columns))
+	for i, col := range t.Columns {
+		col.Default = strings.Replace(col.Default, t.Schema+".", schema+".", -1)
+		c.Columns[i] = col
+	}
+	return &c
+}
+
+// divergence lists how copy differs from ref, ignoring attributes which
+// naturally differ between schemas.
+func divergence(ref, copy *inspect.Table) []string {
+	var out []string
+	opts := diff.Options{IgnoreAttributes: []string{"owner", "comment", "tablespace"}}
+	for _, ch := range diff.CompareTables(ref, requalify(copy, ref.Schema), opts) {
+		out = append(out, ch.String())
+	}
+	return out
+}
+
+// analyzeCopies finds tables repeated across schemas, as in schema per
+// tenant designs, and reports how the copies diverge from the most common
+// structure. Differently named tables with the same columns are listed as
+// well.
+func (a *app) analyzeCopies(args []string) {
+	fs := flag.NewFlagSet("analyze copies", flag.ContinueOnError)
+	minCopies := fs.Int("min-copies", 2, "Only report tables found in at least this many schemas.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+
+	byName := map[string][]*inspect.Table{}
+	var names []string
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			if byName[t.Name] == nil {
+				names = append(names, t.Name)
+			}
+			byName[t.Name] = append(byName[t.Name], t)
+		}
+	}
+	sort.Strings(names)
+
+	diverged := 0
+	bySignature := map[string][]*inspect.Table{}
+	for _, name := range names {
+		copies := byName[name]
+		if len(copies) < *minCopies {
+			for _, t := range copies {
+				sig := columnSignature(t)
+				bySignature[sig] = append(bySignature[sig], t)
+			}
+			continue
+		}
+		ref := reference(copies)
+		var lines []string
+		for _, t := range copies {
+			if t == ref {
+				continue
+			}
+			for _, d := range divergence(ref, t) {
+				lines = append(lines, fmt.Sprintf("  %s: %s", t.Schema, d))
+			}
+		}
+		fmt.Printf("%s: %d copies, reference %s", name, len(copies), qualifiedName(ref))
+		if len(lines) == 0 {
+			fmt.Println(", identical")
+			continue
+		}
+		diverged++
+		fmt.Println()
+		for _, l := range lines {
+			fmt.Println(l)
+		}
+	}
+
+	var sigs []string
+	for sig, tables := range bySignature {
+		schemas := map[string]bool{}
+		for _, t := range tables {
+			schemas[t.Schema] = true
+		}
+		if len(schemas) >= *minCopies && len(tables[0].Columns) > 0 {
+			sigs = append(sigs, sig)
+		}
+	}
+	sort.Strings(sigs)
+	for _, sig := range sigs {
+		var qn []string
+		for _, t := range bySignature[sig] {
+			qn = append(qn, qualifiedName(t))
+		}
+		fmt.Printf("same columns under different names: %s\n", strings.Join(qn, ", "))
+	}
+	if diverged > 0 {
+		a.log.Warnf("%d tables diverge between schemas", diverged)
+		a.log.Exit(exitFindings)
+	}
+}
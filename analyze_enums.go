@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// analyzeEnums counts how often each label of the enum types is used by
+// the columns of that type and marks unused labels and types.
+func (a *app) analyzeEnums(args []string) {
+	fs := flag.NewFlagSet("analyze enums", flag.ContinueOnError)
+	maxRows := fs.Int64("max-rows", 10000000, "Skip tables with more estimated rows, counting labels scans the table.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Enum\tLabel\tRows\tShare\tNote")
+	for _, s := range db.Schemas {
+		for _, e := range s.Enums {
+			counts := make(map[string]int64, len(e.Labels))
+			var columns, skipped int
+			var total int64
+			for _, ts := range db.Schemas {
+				for _, t := range ts.Tables {
+					if t.Type != "BASE TABLE" {
+						continue
+					}
+					for _, c := range t.Columns {
+						if c.UDTSchema != s.Name || c.UDTName != e.Name {
+							continue
+						}
+						columns++
+						if t.Stats.RowEstimate > *maxRows {
+							skipped++
+							a.log.Warnf("skipping %s.%s, ~%d rows", qualifiedName(t), c.Name, t.Stats.RowEstimate)
+							continue
+						}
+						var rows []struct {
+							Label string `db:"label"`
+							N     int64  `db:"n"`
+						}
+						col := inspect.QuoteIdent(c.Name)
+						query := fmt.Sprintf("SELECT %[1]s::text AS label, count(*) AS n FROM %[2]s WHERE %[1]s IS NOT NULL GROUP BY 1",
+							col, tableRef(t))
+						if _, err := sess.SelectBySql(query).Load(&rows); err != nil {
+							a.log.WithError(err).Fatalf("count labels of %s.%s", qualifiedName(t), c.Name)
+						}
+						for _, r := range rows {
+							counts[r.Label] += r.N
+							total += r.N
+						}
+					}
+				}
+			}
+			writeEnumUsage(tw, s.Name+"."+e.Name, e, counts, total, columns, skipped)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
+
+func writeEnumUsage(tw *tabwriter.Writer, name string, e inspect.Enum, counts map[string]int64, total int64, columns, skipped int) {
+	if columns == 0 {
+		fmt.Fprintf(tw, "%s\t-\t-\t-\tno column uses the type\n", name)
+		return
+	}
+	for _, l := range e.Labels {
+		n := counts[l]
+		share, note := "-", ""
+		if total > 0 {
+			share = fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+		}
+		if n == 0 && skipped == 0 {
+			note = "unused"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", name, l, n, share, note)
+	}
+}
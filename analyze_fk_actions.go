@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// cascadeChain is the longest path of ON DELETE CASCADE foreign keys
+// starting at a table, and the number of tables a delete can reach.
+type cascadeChain struct {
+	path    []*inspect.Table
+	reached int
+}
+
+// cascadeChains follows the ON DELETE CASCADE foreign keys pointing at t
+// and the tables they belong to, returning the longest path of deletes and
+// every table a delete of t removes rows from. Cycles are followed once.
+func cascadeChains(db *inspect.Database, t *inspect.Table, onPath map[*inspect.Table]bool, reached map[*inspect.Table]bool) []*inspect.Table {
+	onPath[t] = true
+	defer delete(onPath, t)
+	longest := []*inspect.Table{t}
+	for _, r := range t.ReferencedBy {
+		if r.FK.OnDelete != "CASCADE" {
+			continue
+		}
+		s := db.Schema(r.Schema)
+		if s == nil {
+			continue
+		}
+		child := s.Table(r.Table)
+		if child == nil || onPath[child] {
+			continue
+		}
+		reached[child] = true
+		if p := cascadeChains(db, child, onPath, reached); len(p)+1 > len(longest) {
+			longest = append([]*inspect.Table{t}, p...)
+		}
+	}
+	return longest
+}
+
+// analyzeFKActions groups the foreign keys by their referential actions,
+// shows the ON DELETE CASCADE chains longer than -max-hops and flags NO
+// ACTION and RESTRICT keys pointing at tables rows are often deleted from,
+// to make the blast radius of a delete visible.
+func (a *app) analyzeFKActions(args []string) {
+	fs := flag.NewFlagSet("analyze fk-actions", flag.ContinueOnError)
+	maxHops := fs.Int("max-hops", 2, "Report ON DELETE CASCADE chains with more foreign keys.")
+	minDeletes := fs.Int64("min-deletes", 1000, "Flag blocking keys pointing at tables with at least as many deleted rows.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var deletes []struct {
+		Schema  string `db:"schema"`
+		Table   string `db:"table"`
+		Deletes int64  `db:"deletes"`
+	}
+	if _, err := sess.SelectBySql(`SELECT schemaname AS schema, relname AS table, n_tup_del AS deletes
+FROM pg_stat_user_tables`).Load(&deletes); err != nil {
+		a.log.WithError(err).Fatal("select table statistics")
+	}
+	deleted := make(map[string]int64, len(deletes))
+	for _, d := range deletes {
+		deleted[d.Schema+"."+d.Table] = d.Deletes
+	}
+
+	actions := make(map[string][]string)
+	type blocking struct {
+		fk      string
+		parent  string
+		action  string
+		deletes int64
+	}
+	var blocked []blocking
+	var chains []cascadeChain
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			for _, fk := range t.FKs {
+				key := fk.OnDelete + "\t" + fk.OnUpdate
+				actions[key] = append(actions[key], qualifiedName(t)+"."+fk.Name)
+				if fk.OnDelete != "NO ACTION" && fk.OnDelete != "RESTRICT" {
+					continue
+				}
+				if n := deleted[fk.RefSchema+"."+fk.RefTable]; n >= *minDeletes {
+					blocked = append(blocked, blocking{qualifiedName(t) + "." + fk.Name,
+						fk.RefSchema + "." + fk.RefTable, fk.OnDelete, n})
+				}
+			}
+			reached := make(map[*inspect.Table]bool)
+			if path := cascadeChains(db, t, make(map[*inspect.Table]bool), reached); len(path)-1 > *maxHops {
+				chains = append(chains, cascadeChain{path, len(reached)})
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "On delete\tOn update\tForeign keys")
+	keys := make([]string, 0, len(actions))
+	for k := range actions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%d\n", k, len(actions[k]))
+	}
+	if len(keys) == 0 {
+		fmt.Fprintln(tw, "no foreign keys")
+	}
+
+	sort.SliceStable(chains, func(i, j int) bool { return len(chains[i].path) > len(chains[j].path) })
+	if len(chains) > 0 {
+		fmt.Fprintf(tw, "\nDeleting from\tHops\tTables reached\tLongest ON DELETE CASCADE chain\n")
+	}
+	for _, c := range chains {
+		names := make([]string, len(c.path))
+		for i, t := range c.path {
+			names[i] = qualifiedName(t)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", names[0], len(c.path)-1, c.reached, strings.Join(names, " -> "))
+	}
+
+	sort.SliceStable(blocked, func(i, j int) bool { return blocked[i].deletes > blocked[j].deletes })
+	if len(blocked) > 0 {
+		fmt.Fprintf(tw, "\nForeign key\tOn delete\tReferenced table\tRows deleted\n")
+	}
+	for _, b := range blocked {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", b.fk, b.action, b.parent, b.deletes)
+	}
+	if len(blocked) > 0 {
+		fmt.Fprintln(tw, "\nEvery delete from these tables checks the referencing rows and fails while any exist;")
+		fmt.Fprintln(tw, "index the key columns and decide whether CASCADE or SET NULL is meant.")
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
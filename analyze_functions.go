@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	// callPattern finds function calls in a function body.
+	callPattern = regexp.MustCompile(`(?i)\b([a-z_][a-z0-9_$]*)\s*\(`)
+	// writePattern finds statements and calls changing the database.
+	writePattern = regexp.MustCompile(`(?i)\b(insert\s+into|update\s+\S+\s+set|delete\s+from|merge\s+into|truncate|` +
+		`create|alter|drop|grant|revoke|copy|lock\s+table|nextval|setval|execute)\b`)
+	// stableKeywords are SQL keywords reading the transaction time or
+	// session state without parentheses.
+	stableKeywords = regexp.MustCompile(`(?i)\b(current_timestamp|current_date|current_time|localtime|localtimestamp|current_user|session_user|current_schema)\b`)
+)
+
+// volatilityRank orders volatilities from the most to the least strict.
+var volatilityRank = map[string]int{"immutable": 0, "stable": 1, "volatile": 2}
+
+// bodyEffects looks at the source of a SQL or PL/pgSQL function for
+// statements writing to the database and for calls of functions which are
+// volatile or stable whatever their arguments. volatility maps function
+// names to the least volatile of their overloads. The result is the
+// volatility the body needs at least and the calls making it so.
+func bodyEffects(body string, volatility map[string]string) (string, []string) {
+	if m := writePattern.FindString(body); m != "" {
+		return "volatile", []string{strings.ToLower(strings.Fields(m)[0])}
+	}
+	need := "immutable"
+	var calls []string
+	seen := make(map[string]bool)
+	for _, m := range callPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.ToLower(m[1])
+		v, ok := volatility[name]
+		if !ok || v == "immutable" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if volatilityRank[v] > volatilityRank[need] {
+			need, calls = v, nil
+		}
+		if v == need {
+			calls = append(calls, name+"()")
+		}
+	}
+	if need == "immutable" {
+		if m := stableKeywords.FindString(body); m != "" {
+			need, calls = "stable", []string{strings.ToLower(m)}
+		}
+	}
+	return need, calls
+}
+
+type functionSource struct {
+	Schema    string `db:"schema"`
+	Signature string `db:"signature"`
+	Source    string `db:"source"`
+}
+
+type functionUse struct {
+	Schema    string `db:"schema"`
+	Signature string `db:"signature"`
+	UsedBy    string `db:"used_by"`
+}
+
+// analyzeFunctions audits the volatility and parallel safety labels of
+// functions: functions behind indexes and generated columns which are no
+// longer IMMUTABLE or call functions which are not, functions left at the
+// default PARALLEL UNSAFE, and VOLATILE functions which only read and could
+// be STABLE. Wrong labels make the planner skip index scans, constant
+// folding and parallel plans, or corrupt indexes.
+func (a *app) analyzeFunctions(args []string) {
+	fs := flag.NewFlagSet("analyze functions", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var volatilities []struct {
+		Name       string `db:"name"`
+		Volatility string `db:"volatility"`
+	}
+	if _, err := sess.SelectBySql(`SELECT proname AS name,
+	CASE min(provolatile::text) WHEN 'i' THEN 'immutable' WHEN 's' THEN 'stable' ELSE 'volatile' END AS volatility
+FROM pg_proc GROUP BY proname`).Load(&volatilities); err != nil {
+		a.log.WithError(err).Fatal("select function volatility")
+	}
+	volatility := make(map[string]string, len(volatilities))
+	for _, v := range volatilities {
+		volatility[v.Name] = v.Volatility
+	}
+
+	var sources []functionSource
+	if _, err := sess.SelectBySql(`SELECT n.nspname AS schema,
+	p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')' AS signature, p.prosrc AS source
+FROM pg_proc p
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+	JOIN pg_language l ON l.oid = p.prolang
+WHERE l.lanname IN ('sql', 'plpgsql') AND n.nspname NOT IN ('pg_catalog', 'information_schema')`).Load(&sources); err != nil {
+		a.log.WithError(err).Fatal("select function sources")
+	}
+	source := make(map[string]string, len(sources))
+	for _, v := range sources {
+		source[v.Schema+"."+v.Signature] = v.Source
+	}
+
+	var uses []functionUse
+	if _, err := sess.SelectBySql(`SELECT DISTINCT n.nspname AS schema,
+	p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')' AS signature,
+	(pg_identify_object(d.classid, d.objid, d.objsubid)).type || ' ' ||
+		(pg_identify_object(d.classid, d.objid, d.objsubid)).identity AS used_by
+FROM pg_depend d
+	JOIN pg_proc p ON p.oid = d.refobjid
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+	LEFT JOIN pg_class i ON d.classid = 'pg_class'::regclass AND i.oid = d.objid
+	LEFT JOIN pg_attrdef ad ON d.classid = 'pg_attrdef'::regclass AND ad.oid = d.objid
+	LEFT JOIN pg_attribute a ON a.attrelid = ad.adrelid AND a.attnum = ad.adnum
+WHERE d.refclassid = 'pg_proc'::regclass AND (i.relkind = 'i' OR a.attgenerated = 's')`).Load(&uses); err != nil {
+		a.log.WithError(err).Fatal("select function uses")
+	}
+	usedBy := make(map[string][]string)
+	for _, v := range uses {
+		key := v.Schema + "." + v.Signature
+		usedBy[key] = append(usedBy[key], v.UsedBy)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Function\tVolatility\tParallel\tIssue")
+	flagged := 0
+	for _, s := range db.Schemas {
+		for i := range s.Functions {
+			f := &s.Functions[i]
+			if f.Result == "" || f.Result == "trigger" || f.Result == "event_trigger" {
+				continue
+			}
+			key := s.Name + "." + f.Signature()
+			body, known := source[key]
+			need, calls := "volatile", []string(nil)
+			if known {
+				need, calls = bodyEffects(body, volatility)
+			}
+
+			var issues []string
+			if users := usedBy[key]; len(users) > 0 {
+				sort.Strings(users)
+				switch {
+				case f.Volatility != "immutable":
+					issues = append(issues, fmt.Sprintf("%s but used by %s, results of rows written earlier may differ",
+						strings.ToUpper(f.Volatility), strings.Join(users, ", ")))
+				case known && need != "immutable":
+					issues = append(issues, fmt.Sprintf("IMMUTABLE but calls %s, %s may return wrong rows",
+						strings.Join(calls, ", "), strings.Join(users, ", ")))
+				}
+			}
+			if f.Volatility == "volatile" && known && need != "volatile" {
+				issues = append(issues, "VOLATILE but only reads, mark it STABLE so the planner can use indexes")
+			}
+			if f.Parallel == "unsafe" && (!known || need != "volatile") {
+				issues = append(issues, "PARALLEL UNSAFE by default, label it PARALLEL SAFE or RESTRICTED if it is")
+			}
+			for _, issue := range issues {
+				flagged++
+				fmt.Fprintf(tw, "%s.%s\t%s\t%s\t%s\n", s.Name, f.Signature(), f.Volatility, f.Parallel, issue)
+			}
+		}
+	}
+	if flagged == 0 {
+		fmt.Fprintln(tw, "no issues")
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
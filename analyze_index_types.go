@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// brinTypes are the column types whose values usually follow insertion
+// order in append-only tables.
+var brinTypes = map[string]bool{
+	"timestamptz": true, "timestamp": true, "date": true, "int4": true, "int8": true, "numeric": true,
+}
+
+// gistTypes are the column types best indexed with GiST, by UDT name.
+var gistTypes = map[string]bool{
+	"int4range": true, "int8range": true, "numrange": true, "tsrange": true, "tstzrange": true, "daterange": true,
+	"int4multirange": true, "int8multirange": true, "nummultirange": true, "tsmultirange": true,
+	"tstzmultirange": true, "datemultirange": true, "geometry": true, "geography": true, "box": true,
+	"circle": true, "polygon": true, "point": true,
+}
+
+// indexedWith reports whether an index of one of the methods covers the
+// column, directly or in an expression.
+func indexedWith(t *inspect.Table, column string, methods ...string) bool {
+	word := regexp.MustCompile(`\b` + regexp.QuoteMeta(column) + `\b`)
+	for _, idx := range t.Indexes {
+		ok := false
+		for _, m := range methods {
+			ok = ok || idx.Method == m
+		}
+		if !ok {
+			continue
+		}
+		for _, c := range idx.Columns {
+			if c == column || word.MatchString(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type tableWrites struct {
+	Inserts int64 `db:"inserts"`
+	Changes int64 `db:"changes"`
+}
+
+type columnCorrelation struct {
+	Column      string          `db:"column_name"`
+	Correlation dbr.NullFloat64 `db:"correlation"`
+}
+
+type indexHint struct {
+	table   *inspect.Table
+	column  string
+	suggest string
+	reason  string
+}
+
+// brinHints suggests replacing single-column btree indexes of large
+// append-only tables by BRIN when the column follows the physical order.
+// A table is append-only when updates and deletes are at most 1% of its
+// inserts.
+func (a *app) brinHints(sess *dbr.Session, t *inspect.Table, minCorrelation float64) []indexHint {
+	var w tableWrites
+	err := sess.SelectBySql(`SELECT n_tup_ins AS inserts, n_tup_upd + n_tup_del AS changes
+FROM pg_stat_user_tables WHERE schemaname = ? AND relname = ?`, t.Schema, t.Name).LoadOne(&w)
+	if err != nil && err != dbr.ErrNotFound {
+		a.log.WithError(err).Fatalf("read statistics of %s", qualifiedName(t))
+	}
+	if w.Inserts == 0 || w.Changes*100 > w.Inserts {
+		return nil
+	}
+
+	var stats []columnCorrelation
+	if _, err := sess.SelectBySql(`SELECT attname AS column_name, correlation FROM pg_stats
+WHERE schemaname = ? AND tablename = ?`, t.Schema, t.Name).Load(&stats); err != nil {
+		a.log.WithError(err).Fatalf("read statistics of %s", qualifiedName(t))
+	}
+	correlation := make(map[string]float64, len(stats))
+	for _, s := range stats {
+		if s.Correlation.Valid {
+			correlation[s.Column] = s.Correlation.Float64
+		}
+	}
+
+	var hints []indexHint
+	for _, idx := range t.Indexes {
+		if idx.Method != "btree" || idx.Unique || idx.Primary || idx.Predicate != "" || len(idx.Columns) != 1 {
+			continue
+		}
+		c := t.Column(idx.Columns[0])
+		if c == nil || !brinTypes[c.UDTName] {
+			continue
+		}
+		corr, ok := correlation[c.Name]
+		if !ok || math.Abs(corr) < minCorrelation {
+			continue
+		}
+		var size int64
+		if err := sess.SelectBySql(`SELECT pg_relation_size(to_regclass(?))`,
+			inspect.QualifiedName(t.Schema, idx.Name)).LoadOne(&size); err != nil {
+			a.log.WithError(err).Fatalf("read size of %s", idx.Name)
+		}
+		hints = append(hints, indexHint{t, c.Name,
+			fmt.Sprintf("CREATE INDEX CONCURRENTLY ON %s USING brin (%s); DROP INDEX CONCURRENTLY %s;",
+				tableRef(t), inspect.QuoteIdent(c.Name), inspect.QualifiedName(t.Schema, idx.Name)),
+			fmt.Sprintf("append-only, correlation %.2f, btree %s is %s; BRIN suits range scans, not point lookups",
+				corr, idx.Name, humanBytes(size))})
+	}
+	return hints
+}
+
+// analyzeIndexTypes suggests index methods better suited to the columns
+// than what they have: BRIN for ordered columns of large append-only
+// tables, GIN for jsonb and tsvector columns and GiST for ranges and
+// geometries without such an index.
+func (a *app) analyzeIndexTypes(args []string) {
+	fs := flag.NewFlagSet("analyze index-types", flag.ContinueOnError)
+	minRows := fs.Int64("min-rows", 10000, "Ignore tables with fewer estimated rows for GIN and GiST hints.")
+	brinRows := fs.Int64("brin-min-rows", 1000000, "Ignore tables with fewer estimated rows for BRIN hints.")
+	minCorrelation := fs.Float64("min-correlation", 0.9, "Correlation of column and physical order needed for BRIN.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var hints []indexHint
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" || t.Stats.RowEstimate < *minRows {
+				continue
+			}
+			if t.Stats.RowEstimate >= *brinRows {
+				hints = append(hints, a.brinHints(sess, t, *minCorrelation)...)
+			}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				col := inspect.QuoteIdent(c.Name)
+				switch {
+				case c.UDTName == "jsonb" && !indexedWith(t, c.Name, "gin"):
+					hints = append(hints, indexHint{t, c.Name,
+						fmt.Sprintf("CREATE INDEX CONCURRENTLY ON %s USING gin (%s jsonb_path_ops);", tableRef(t), col),
+						"jsonb without a GIN index, containment (@>) and path queries scan the table"})
+				case c.UDTName == "tsvector" && !indexedWith(t, c.Name, "gin", "gist"):
+					hints = append(hints, indexHint{t, c.Name,
+						fmt.Sprintf("CREATE INDEX CONCURRENTLY ON %s USING gin (%s);", tableRef(t), col),
+						"tsvector without a GIN index, full text matches (@@) scan the table"})
+				case gistTypes[c.UDTName] && !indexedWith(t, c.Name, "gist", "spgist"):
+					hints = append(hints, indexHint{t, c.Name,
+						fmt.Sprintf("CREATE INDEX CONCURRENTLY ON %s USING gist (%s);", tableRef(t), col),
+						c.UDTName + " without a GiST index, overlap and containment queries scan the table"})
+				}
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Column\tSuggestion\tReason")
+	for _, h := range hints {
+		fmt.Fprintf(tw, "%s.%s\t%s\t%s\n", qualifiedName(h.table), h.column, h.suggest, h.reason)
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+var (
+	// stmtTable matches table references with an optional alias.
+	stmtTable = regexp.MustCompile(`(?i)\b(?:from|join|update)\s+((?:"?\w+"?\.)?"?\w+"?)(?:\s+(?:as\s+)?(\w+))?`)
+	// stmtFilter matches the column on the left of a comparison.
+	stmtFilter = regexp.MustCompile(`(?i)(?:\b(\w+)\.)?"?(\w+)"?\s*(?:=|<>|!=|<=|>=|<|>|\bin\b|\bnot\s+in\b|\blike\b|\bilike\b|\bis\b|\bbetween\b|= any)`)
+	// stmtJoin matches the qualified column on the right of an equality.
+	stmtJoin = regexp.MustCompile(`=\s*(\w+)\.(\w+)`)
+	// stmtOrder matches the ORDER BY list.
+	stmtOrder = regexp.MustCompile(`(?is)\border\s+by\s+(.+?)(?:\blimit\b|\boffset\b|\bfor\b|\)|$)`)
+	// stmtSet matches the SET list of an UPDATE, which is not a filter.
+	stmtSet = regexp.MustCompile(`(?is)\bset\b.*?(\bwhere\b|$)`)
+	// stmtNotAlias are keywords which can follow a table name.
+	stmtNotAlias = map[string]bool{"where": true, "join": true, "on": true, "left": true, "right": true, "inner": true,
+		"outer": true, "full": true, "cross": true, "natural": true, "group": true, "order": true, "limit": true,
+		"offset": true, "set": true, "using": true, "for": true, "union": true, "returning": true, "having": true,
+		"window": true, "lateral": true}
+)
+
+// columnUse is a column filtered, joined or sorted on by a statement.
+type columnUse struct {
+	table  *inspect.Table
+	column string
+}
+
+// statementColumns finds the columns a statement filters, joins or sorts
+// on. It is a heuristic over the normalized text from pg_stat_statements,
+// not a SQL parser: unqualified columns are attributed to the only table
+// of the statement having them.
+func statementColumns(db *inspect.Database, query string) []columnUse {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(query)), "insert") {
+		return nil
+	}
+	aliases := make(map[string]*inspect.Table)
+	var tables []*inspect.Table
+	for _, m := range stmtTable.FindAllStringSubmatch(query, -1) {
+		name := strings.Replace(m[1], `"`, "", -1)
+		t := findTable(db, name)
+		if t == nil {
+			continue
+		}
+		tables = append(tables, t)
+		aliases[t.Name] = t
+		if alias := strings.ToLower(m[2]); alias != "" && !stmtNotAlias[alias] {
+			aliases[alias] = t
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	seen := make(map[columnUse]bool)
+	var uses []columnUse
+	add := func(qualifier, column string) {
+		var t *inspect.Table
+		if qualifier != "" {
+			t = aliases[strings.ToLower(qualifier)]
+		} else {
+			for _, cand := range tables {
+				if cand.Column(column) != nil {
+					if t != nil && t != cand {
+						return // ambiguous
+					}
+					t = cand
+				}
+			}
+		}
+		if t == nil || t.Column(column) == nil {
+			return
+		}
+		u := columnUse{t, column}
+		if !seen[u] {
+			seen[u] = true
+			uses = append(uses, u)
+		}
+	}
+
+	filtered := stmtSet.ReplaceAllString(query, "$1")
+	for _, m := range stmtFilter.FindAllStringSubmatch(filtered, -1) {
+		add(m[1], m[2])
+	}
+	for _, m := range stmtJoin.FindAllStringSubmatch(filtered, -1) {
+		add(m[1], m[2])
+	}
+	for _, m := range stmtOrder.FindAllStringSubmatch(filtered, -1) {
+		for _, item := range strings.Split(m[1], ",") {
+			f := strings.Fields(item)
+			if len(f) == 0 {
+				continue
+			}
+			ref := strings.Replace(f[0], `"`, "", -1)
+			if i := strings.Index(ref, "."); i >= 0 {
+				add(ref[:i], ref[i+1:])
+			} else {
+				add("", ref)
+			}
+		}
+	}
+	return uses
+}
+
+// leadingIndex reports whether an index of t starts with column.
+func leadingIndex(t *inspect.Table, column string) bool {
+	for _, idx := range t.Indexes {
+		if len(idx.Columns) > 0 && idx.Columns[0] == column {
+			return true
+		}
+	}
+	return false
+}
+
+type statement struct {
+	Query string  `db:"query"`
+	Calls int64   `db:"calls"`
+	Time  float64 `db:"total_time"`
+}
+
+type tableActivity struct {
+	Reads  int64 `db:"reads"`
+	Writes int64 `db:"writes"`
+}
+
+// topStatements reads the statements taking the most time. The time column
+// was renamed in PostgreSQL 13.
+func topStatements(sess *dbr.Session, limit int) ([]statement, error) {
+	var n int
+	err := sess.SelectBySql(`SELECT count(*) FROM pg_extension WHERE extname = 'pg_stat_statements'`).LoadOne(&n)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("pg_stat_statements is not installed")
+	}
+	timeColumn := "total_time"
+	var renamed int
+	err = sess.SelectBySql(`SELECT count(*) FROM pg_attribute
+WHERE attrelid = 'pg_stat_statements'::regclass AND attname = 'total_exec_time'`).LoadOne(&renamed)
+	if err != nil {
+		return nil, err
+	}
+	if renamed > 0 {
+		timeColumn = "total_exec_time"
+	}
+	var stmts []statement
+	_, err = sess.SelectBySql(fmt.Sprintf(`SELECT query, calls, %s AS total_time
+FROM pg_stat_statements
+WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+ORDER BY %s DESC LIMIT %d`, timeColumn, timeColumn, limit)).Load(&stmts)
+	return stmts, err
+}
+
+// analyzeIndexes suggests indexes for columns the most expensive statements
+// filter, join or sort on which no index starts with.
+func (a *app) analyzeIndexes(args []string) {
+	fs := flag.NewFlagSet("analyze indexes", flag.ContinueOnError)
+	top := fs.Int("top", 100, "Number of statements by total time to look at.")
+	minRows := fs.Int64("min-rows", 10000, "Ignore tables with fewer estimated rows, scanning them is cheap.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+	stmts, err := topStatements(sess, *top)
+	if err != nil {
+		a.log.WithError(err).Fatal("read pg_stat_statements")
+	}
+
+	type candidate struct {
+		columnUse
+		calls int64
+		time  float64
+	}
+	byUse := make(map[columnUse]*candidate)
+	for _, st := range stmts {
+		for _, u := range statementColumns(db, st.Query) {
+			if u.table.Stats.RowEstimate < *minRows || leadingIndex(u.table, u.column) {
+				continue
+			}
+			c := byUse[u]
+			if c == nil {
+				c = &candidate{columnUse: u}
+				byUse[u] = c
+			}
+			c.calls += st.Calls
+			c.time += st.Time
+		}
+	}
+	var candidates []*candidate
+	for _, c := range byUse {
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].time > candidates[j].time })
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Suggested index\tCalls\tTime (ms)\tNote")
+	for _, c := range candidates {
+		var act tableActivity
+		err := sess.SelectBySql(`SELECT COALESCE(seq_scan, 0) + COALESCE(idx_scan, 0) AS reads,
+	n_tup_ins + n_tup_upd + n_tup_del AS writes
+FROM pg_stat_user_tables WHERE schemaname = ? AND relname = ?`, c.table.Schema, c.table.Name).LoadOne(&act)
+		if err != nil && err != dbr.ErrNotFound {
+			a.log.WithError(err).Fatalf("read statistics of %s", qualifiedName(c.table))
+		}
+		note := ""
+		if act.Writes > act.Reads {
+			note = fmt.Sprintf("write-heavy (%d writes, %d scans), another index slows writes", act.Writes, act.Reads)
+		}
+		fmt.Fprintf(tw, "CREATE INDEX CONCURRENTLY ON %s (%s);\t%d\t%.0f\t%s\n", tableRef(c.table), inspect.QuoteIdent(c.column),
+			c.calls, c.time, note)
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// jsonShape accumulates the types of the values found at one path of
+// sampled JSON documents, and the shapes of their members and elements.
+type jsonShape struct {
+	seen    int
+	types   map[string]int
+	objects int // values which were objects, the base of member frequencies
+	members map[string]*jsonShape
+	items   *jsonShape
+}
+
+func newJSONShape() *jsonShape {
+	return &jsonShape{types: map[string]int{}, members: map[string]*jsonShape{}}
+}
+
+// jsonType names the JSON Schema type of a value decoded with UseNumber.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func (s *jsonShape) add(v interface{}) {
+	s.seen++
+	s.types[jsonType(v)]++
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s.objects++
+		for k, m := range v {
+			if s.members[k] == nil {
+				s.members[k] = newJSONShape()
+			}
+			s.members[k].add(m)
+		}
+	case []interface{}:
+		if s.items == nil {
+			s.items = newJSONShape()
+		}
+		for _, e := range v {
+			s.items.add(e)
+		}
+	}
+}
+
+// typeList lists the types seen, most frequent first, with their share.
+func (s *jsonShape) typeList() string {
+	names := s.typeNames()
+	sort.SliceStable(names, func(i, j int) bool { return s.types[names[i]] > s.types[names[j]] })
+	if len(names) == 1 {
+		return names[0]
+	}
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = fmt.Sprintf("%s %d%%", n, 100*s.types[n]/s.seen)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (s *jsonShape) typeNames() []string {
+	names := make([]string, 0, len(s.types))
+	for n := range s.types {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeRows writes a row per path below s, with the share of the parent
+// objects that have the member.
+func (s *jsonShape) writeRows(w io.Writer, path string) {
+	keys := make([]string, 0, len(s.members))
+	for k := range s.members {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		m := s.members[k]
+		fmt.Fprintf(w, "  %s.%s\t%s\t%d%%\n", path, k, m.typeList(), 100*m.seen/s.objects)
+		m.writeRows(w, path+"."+k)
+	}
+	if s.items != nil && s.items.seen > 0 {
+		fmt.Fprintf(w, "  %s[]\t%s\t\n", path, s.items.typeList())
+		s.items.writeRows(w, path+"[]")
+	}
+}
+
+// schema returns a JSON Schema accepting the sampled values. Members found
+// in every object are required.
+func (s *jsonShape) schema() map[string]interface{} {
+	sch := map[string]interface{}{}
+	types := s.typeNames()
+	if s.types["integer"] > 0 && s.types["number"] > 0 {
+		types = without(types, "integer")
+	}
+	if len(types) == 1 {
+		sch["type"] = types[0]
+	} else if len(types) > 1 {
+		sch["type"] = types
+	}
+	if s.objects > 0 {
+		props := map[string]interface{}{}
+		var required []string
+		for k, m := range s.members {
+			props[k] = m.schema()
+			if m.seen == s.objects {
+				required = append(required, k)
+			}
+		}
+		sch["properties"] = props
+		if len(required) > 0 {
+			sort.Strings(required)
+			sch["required"] = required
+		}
+	}
+	if s.items != nil && s.items.seen > 0 {
+		sch["items"] = s.items.schema()
+	}
+	return sch
+}
+
+func without(list []string, s string) []string {
+	var out []string
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// analyzeJSON samples json and jsonb columns and infers the keys, their
+// types and frequency, as a report or as JSON Schemas.
+func (a *app) analyzeJSON(args []string) {
+	fs := flag.NewFlagSet("analyze json", flag.ContinueOnError)
+	sample := fs.Int("sample", 1000, "Values to sample per column.")
+	only := fs.String("column", "", "Only profile this column, as schema.table.column.")
+	format := fs.String("format", "text", "Output format: text or jsonschema.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+	if *format != "text" && *format != "jsonschema" {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	type profile struct {
+		name  string
+		shape *jsonShape
+	}
+	var profiles []profile
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				name := qualifiedName(t) + "." + c.Name
+				if (c.UDTName != "json" && c.UDTName != "jsonb") || (*only != "" && *only != name) {
+					continue
+				}
+				var values []string
+				query := fmt.Sprintf("SELECT %[1]s::text FROM %[2]s WHERE %[1]s IS NOT NULL LIMIT %[3]d",
+					inspect.QuoteIdent(c.Name), tableRef(t), *sample)
+				if _, err := sess.SelectBySql(query).Load(&values); err != nil {
+					a.log.WithError(err).Fatalf("sample %s", name)
+				}
+				shape := newJSONShape()
+				for _, v := range values {
+					dec := json.NewDecoder(strings.NewReader(v))
+					dec.UseNumber()
+					var doc interface{}
+					if err := dec.Decode(&doc); err != nil {
+						a.log.WithError(err).Warnf("parse value of %s", name)
+						continue
+					}
+					shape.add(doc)
+				}
+				profiles = append(profiles, profile{name, shape})
+			}
+		}
+	}
+	if *only != "" && len(profiles) == 0 {
+		a.log.Fatalf("no json column %s", *only)
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if *format == "jsonschema" {
+		schemas := map[string]interface{}{}
+		for _, p := range profiles {
+			sch := p.shape.schema()
+			sch["$schema"] = "http://json-schema.org/draft-07/schema#"
+			sch["title"] = p.name
+			schemas[p.name] = sch
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(schemas)
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "Path\tTypes\tPresent")
+		for _, p := range profiles {
+			fmt.Fprintf(tw, "%s\t%s\t%d sampled\n", p.name, p.shape.typeList(), p.shape.seen)
+			p.shape.writeRows(tw, "$")
+		}
+		err = tw.Flush()
+	}
+	if err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// keyKind classifies the primary key of t: none, composite, uuid, bigint,
+// integer, smallint or natural for any other single column.
+func keyKind(t *inspect.Table) string {
+	switch len(t.PK.Columns) {
+	case 0:
+		return "none"
+	case 1:
+	default:
+		return "composite"
+	}
+	c := t.Column(t.PK.Columns[0])
+	if c == nil {
+		return "natural"
+	}
+	switch c.UDTName {
+	case "uuid":
+		return "uuid"
+	case "int8":
+		return "bigint"
+	case "int4":
+		return "integer"
+	case "int2":
+		return "smallint"
+	}
+	return "natural"
+}
+
+// intRank orders the integer types by width.
+var intRank = map[string]int{"int2": 1, "int4": 2, "int8": 3}
+
+// keyMismatch describes how the type of a foreign key column differs from
+// the column it references, or is empty if they agree.
+func keyMismatch(from, to *inspect.Column) string {
+	if from.UDTName == to.UDTName {
+		return ""
+	}
+	switch {
+	case intRank[from.UDTName] > 0 && intRank[to.UDTName] > intRank[from.UDTName]:
+		return fmt.Sprintf("%s references %s, inserts fail once the referenced values outgrow it", from.UDTName, to.UDTName)
+	case intRank[from.UDTName] > 0 && intRank[to.UDTName] > 0:
+		return fmt.Sprintf("%s references %s, wider than needed", from.UDTName, to.UDTName)
+	}
+	return fmt.Sprintf("%s references %s, joins cast one side and may not use its index", from.UDTName, to.UDTName)
+}
+
+// analyzeKeyTypes reports the distribution of primary key types across the
+// tables and the foreign keys whose columns differ in type from the keys
+// they reference, which makes joins cast and may break index use.
+func (a *app) analyzeKeyTypes(args []string) {
+	fs := flag.NewFlagSet("analyze key-types", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+
+	kinds := make(map[string][]string)
+	tables := 0
+	type mismatch struct {
+		fk, column, ref, issue string
+	}
+	var mismatches []mismatch
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" || t.PartitionOf != "" {
+				continue
+			}
+			tables++
+			k := keyKind(t)
+			kinds[k] = append(kinds[k], qualifiedName(t))
+			for _, fk := range t.FKs {
+				ps := db.Schema(fk.RefSchema)
+				if ps == nil {
+					continue
+				}
+				parent := ps.Table(fk.RefTable)
+				if parent == nil {
+					continue
+				}
+				for i, name := range fk.Columns {
+					if i >= len(fk.RefColumns) {
+						break
+					}
+					from, to := t.Column(name), parent.Column(fk.RefColumns[i])
+					if from == nil || to == nil {
+						continue
+					}
+					if issue := keyMismatch(from, to); issue != "" {
+						mismatches = append(mismatches, mismatch{qualifiedName(t) + "." + fk.Name,
+							qualifiedName(t) + "." + from.Name, qualifiedName(parent) + "." + to.Name, issue})
+					}
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(kinds))
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(kinds[names[i]]) != len(kinds[names[j]]) {
+			return len(kinds[names[i]]) > len(kinds[names[j]])
+		}
+		return names[i] < names[j]
+	})
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Primary key\tTables\tShare\tExamples")
+	for _, k := range names {
+		examples := kinds[k]
+		if len(examples) > 3 {
+			examples = append(examples[:3:3], "...")
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.0f%%\t%s\n", k, len(kinds[k]), 100*float64(len(kinds[k]))/float64(tables),
+			strings.Join(examples, ", "))
+	}
+	if tables == 0 {
+		fmt.Fprintln(tw, "no tables")
+	}
+
+	if len(mismatches) > 0 {
+		fmt.Fprintln(tw, "\nForeign key\tColumn\tReferences\tIssue")
+	}
+	for _, m := range mismatches {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.fk, m.column, m.ref, m.issue)
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
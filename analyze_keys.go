@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// naturalKeyName matches names of columns which usually identify a row.
+var naturalKeyName = regexp.MustCompile(`(?i)^(email|e_mail|slug|username|user_name|login|handle|sku|isbn|ean|code)$|_(code|number|slug|email)$`)
+
+// uniqueness tells how a column is kept unique: exactly, ignoring case or
+// not at all. Partial unique indexes do not count. Index expressions quote
+// column names as SQL does, e.g. lower("Email").
+func uniqueness(t *inspect.Table, column string) (exact, lower bool) {
+	exact = t.IsUnique(column)
+	quoted := inspect.QuoteIdent(column)
+	for _, idx := range t.Indexes {
+		if !idx.Unique || idx.Predicate != "" || len(idx.Columns) != 1 {
+			continue
+		}
+		switch idx.Columns[0] {
+		case column:
+			exact = true
+		case "lower(" + quoted + ")", "lower((" + quoted + ")::text)":
+			exact, lower = true, true
+		}
+	}
+	return exact, lower
+}
+
+// analyzeKeys checks columns named like natural keys for duplicates and for
+// unique indexes enforcing them.
+func (a *app) analyzeKeys(args []string) {
+	fs := flag.NewFlagSet("analyze keys", flag.ContinueOnError)
+	maxRows := fs.Int64("max-rows", 10000000, "Skip tables with more estimated rows, counting duplicates scans the table.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Column\tUnique\tDuplicates\tCase-insensitive duplicates\tAdvice")
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				if !naturalKeyName.MatchString(c.Name) {
+					continue
+				}
+				name := qualifiedName(t) + "." + c.Name
+				if t.Stats.RowEstimate > *maxRows {
+					fmt.Fprintf(tw, "%s\t-\t-\t-\tskipped, ~%d rows\n", name, t.Stats.RowEstimate)
+					continue
+				}
+				exact, lower := uniqueness(t, c.Name)
+				text := textTypes[c.UDTName]
+
+				var dups, ciDups int64
+				query := "SELECT count(*) FROM (SELECT 1 FROM %s WHERE %s IS NOT NULL GROUP BY %s HAVING count(*) > 1) d"
+				col := inspect.QuoteIdent(c.Name)
+				if !exact {
+					if err := sess.SelectBySql(fmt.Sprintf(query, tableRef(t), col, col)).LoadOne(&dups); err != nil {
+						a.log.WithError(err).Fatalf("count duplicates of %s", name)
+					}
+				}
+				if text && !lower {
+					lowered := "lower(" + col + ")"
+					if err := sess.SelectBySql(fmt.Sprintf(query, tableRef(t), col, lowered)).LoadOne(&ciDups); err != nil {
+						a.log.WithError(err).Fatalf("count duplicates of %s", name)
+					}
+				}
+
+				var advice []string
+				switch {
+				case !exact && dups > 0:
+					advice = append(advice, "looks like a key but has duplicates")
+				case !exact:
+					advice = append(advice, "add a unique index")
+				}
+				if ciDups > dups {
+					advice = append(advice, "values differ only in case")
+				} else if text && !lower && strings.Contains(strings.ToLower(c.Name), "email") {
+					advice = append(advice, "consider a unique index on lower("+c.Name+")")
+				}
+				if len(advice) == 0 {
+					advice = append(advice, "ok")
+				}
+				unique := "no"
+				if exact {
+					unique = "yes"
+				}
+				if lower {
+					unique = "yes, ignoring case"
+				}
+				ciCount := "-"
+				if text && !lower {
+					ciCount = fmt.Sprint(ciDups)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", name, unique, dups, ciCount, strings.Join(advice, "; "))
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func TestUniqueness(t *testing.T) {
+	tests := []struct {
+		name         string
+		column       string
+		index        inspect.Index
+		exact, lower bool
+	}{
+		{"none", "email", inspect.Index{Columns: []string{"email"}}, false, false},
+		{"unique", "email", inspect.Index{Columns: []string{"email"}, Unique: true}, true, false},
+		{"partial", "email", inspect.Index{Columns: []string{"email"}, Unique: true, Predicate: "deleted_at IS NULL"}, false, false},
+		{"lower", "email", inspect.Index{Columns: []string{"lower(email)"}, Unique: true}, true, true},
+		{"lower of varchar", "email", inspect.Index{Columns: []string{"lower((email)::text)"}, Unique: true}, true, true},
+		{"lower of quoted", "Email", inspect.Index{Columns: []string{`lower("Email")`}, Unique: true}, true, true},
+		{"lower of quoted varchar", "Email", inspect.Index{Columns: []string{`lower(("Email")::text)`}, Unique: true}, true, true},
+		{"lower of unquoted", "Email", inspect.Index{Columns: []string{"lower(Email)"}, Unique: true}, false, false},
+	}
+	for _, tt := range tests {
+		tbl := &inspect.Table{Schema: "public", Name: "users", Type: "BASE TABLE",
+			Columns: []inspect.Column{{Name: tt.column, UDTName: "text", Position: 1}},
+			Indexes: []inspect.Index{tt.index}}
+		if exact, lower := uniqueness(tbl, tt.column); exact != tt.exact || lower != tt.lower {
+			t.Errorf("%s: uniqueness = %t, %t, want %t, %t", tt.name, exact, lower, tt.exact, tt.lower)
+		}
+	}
+}
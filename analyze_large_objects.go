@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// largeObjectColumn is a column which may hold large object OIDs.
+type largeObjectColumn struct {
+	table  *inspect.Table
+	column *inspect.Column
+	// Sampled is the number of non-null values read and Found the number
+	// of those naming an existing large object.
+	Sampled int64 `db:"sampled"`
+	Found   int64 `db:"found"`
+}
+
+// unlinksLargeObjects reports whether a trigger of t removes the large
+// objects of deleted or updated rows, as lo_manage of the lo extension does.
+func unlinksLargeObjects(t *inspect.Table) bool {
+	for _, tg := range t.Triggers {
+		if strings.Contains(tg.Function, "lo_manage") || strings.Contains(tg.Definition, "lo_unlink") {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeLargeObjects reports whether the database stores large objects,
+// how much space they take and which oid and lo columns point at them.
+// Large objects are left behind by pg_dump --table, logical replication
+// and most migration tools, so they should usually move to bytea or
+// external storage.
+func (a *app) analyzeLargeObjects(args []string) {
+	fs := flag.NewFlagSet("analyze large-objects", flag.ContinueOnError)
+	sample := fs.Int("sample", 10000, "Values to sample per column.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var count, size int64
+	if err := sess.SelectBySql(`SELECT count(*) FROM pg_largeobject_metadata`).LoadOne(&count); err != nil {
+		a.log.WithError(err).Fatal("count large objects")
+	}
+	if err := sess.SelectBySql(`SELECT pg_total_relation_size('pg_catalog.pg_largeobject')`).LoadOne(&size); err != nil {
+		a.log.WithError(err).Fatal("read size of large objects")
+	}
+
+	var columns []*largeObjectColumn
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				if c.UDTName != "oid" && c.UDTName != "lo" {
+					continue
+				}
+				lc := &largeObjectColumn{table: t, column: c}
+				if count > 0 {
+					query := fmt.Sprintf(`WITH s AS (SELECT %s::oid AS v FROM %s WHERE %[1]s IS NOT NULL LIMIT %[3]d)
+SELECT count(*) AS sampled,
+	count(*) FILTER (WHERE EXISTS (SELECT 1 FROM pg_largeobject_metadata m WHERE m.oid = s.v)) AS found
+FROM s`, inspect.QuoteIdent(c.Name), tableRef(t), *sample)
+					if err := sess.SelectBySql(query).LoadOne(lc); err != nil {
+						a.log.WithError(err).Fatalf("sample %s.%s", qualifiedName(t), c.Name)
+					}
+				}
+				columns = append(columns, lc)
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "large objects\t%d\n", count)
+	fmt.Fprintf(tw, "size\t%s\n", humanBytes(size))
+	if count == 0 {
+		fmt.Fprintln(tw, "pg_largeobject is not in use")
+	}
+	if len(columns) > 0 {
+		fmt.Fprintln(tw, "\nColumn\tType\tSampled\tLarge objects\tNote")
+	}
+	for _, lc := range columns {
+		var notes []string
+		switch {
+		case count == 0:
+			notes = append(notes, "no large objects")
+		case lc.Found == 0:
+			notes = append(notes, "values are not large objects")
+		default:
+			notes = append(notes, "migrate to bytea or external storage")
+			if !unlinksLargeObjects(lc.table) {
+				notes = append(notes, "no lo_manage trigger, deleted rows leak their objects")
+			}
+		}
+		fmt.Fprintf(tw, "%s.%s\t%s\t%d\t%d\t%s\n", qualifiedName(lc.table), lc.column.Name, lc.column.UDTName,
+			lc.Sampled, lc.Found, strings.Join(notes, "; "))
+	}
+	if count > 0 {
+		fmt.Fprintln(tw, "\nLarge objects are not copied by pg_dump --table, logical replication or most migration tools;")
+		fmt.Fprintln(tw, "move them to bytea columns or external storage before migrating.")
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// refreshColumns are the names of columns, conventionally set to now() by
+// the view query, holding when a materialized view was last refreshed.
+var refreshColumns = []string{"refreshed_at", "last_refreshed_at", "last_refresh", "refreshed"}
+
+// refreshColumn returns the timestamp column of t recording its refresh.
+func refreshColumn(t *inspect.Table) *inspect.Column {
+	for _, name := range refreshColumns {
+		if c := t.Column(name); c != nil && strings.HasPrefix(c.UDTName, "timestamp") {
+			return c
+		}
+	}
+	return nil
+}
+
+// lastRefresh returns when the materialized view t was last refreshed and
+// how that is known: from its refresh column or from the successful runs
+// of pg_cron jobs refreshing it. The time is invalid if neither is there.
+func lastRefresh(sess *dbr.Session, t *inspect.Table, cron bool) (dbr.NullTime, string, error) {
+	var at dbr.NullTime
+	if c := refreshColumn(t); c != nil && !t.Unpopulated {
+		query := fmt.Sprintf("SELECT max(%s) FROM %s", inspect.QuoteIdent(c.Name), tableRef(t))
+		err := sess.SelectBySql(query).LoadOne(&at)
+		return at, c.Name, err
+	}
+	if cron {
+		err := sess.SelectBySql(`SELECT max(end_time) FROM cron.job_run_details
+WHERE status = 'succeeded' AND command ILIKE '%refresh materialized view%' AND strpos(command, ?) > 0`,
+			t.Name).LoadOne(&at)
+		return at, "pg_cron", err
+	}
+	return at, "", nil
+}
+
+// analyzeMatviews reports the size, state and indexes of every
+// materialized view and, where it can be told, when it was last refreshed.
+func (a *app) analyzeMatviews(args []string) {
+	fs := flag.NewFlagSet("analyze matviews", flag.ContinueOnError)
+	stale := fs.Duration("stale", 24*time.Hour, "Report views not refreshed for longer as stale.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var cron bool
+	if err := sess.SelectBySql(`SELECT to_regclass('cron.job_run_details') IS NOT NULL`).LoadOne(&cron); err != nil {
+		a.log.WithError(err).Fatal("look for pg_cron")
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Materialized view\tSize\tRows\tPopulated\tIndexes\tConcurrently\tLast refresh\tNote")
+	var views int
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "MATERIALIZED VIEW" {
+				continue
+			}
+			views++
+			at, source, err := lastRefresh(sess, t, cron)
+			if err != nil {
+				a.log.WithError(err).Fatalf("read last refresh of %s", qualifiedName(t))
+			}
+			var notes []string
+			refreshed := "unknown"
+			if at.Valid {
+				age := time.Since(at.Time)
+				refreshed = fmt.Sprintf("%s ago (%s)", humanDuration(age), source)
+				if age > *stale {
+					notes = append(notes, "stale")
+				}
+			}
+			if t.Unpopulated {
+				notes = append(notes, "never refreshed, queries fail")
+			}
+			if !t.CanRefreshConcurrently() {
+				notes = append(notes, "REFRESH blocks readers, add a unique index")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%t\t%d\t%t\t%s\t%s\n", qualifiedName(t), humanBytes(t.Stats.SizeBytes),
+				t.Stats.RowEstimate, !t.Unpopulated, len(t.Indexes), t.CanRefreshConcurrently(), refreshed,
+				strings.Join(notes, "; "))
+		}
+	}
+	if views == 0 {
+		fmt.Fprintln(tw, "no materialized views")
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
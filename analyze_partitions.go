@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// columnStats are the planner statistics of a column from pg_stats.
+type columnStats struct {
+	Column      string  `db:"attname"`
+	NDistinct   float64 `db:"n_distinct"`
+	Correlation float64 `db:"correlation"`
+	NullFrac    float64 `db:"null_frac"`
+}
+
+// tenantColumn matches columns which usually split data by customer.
+var tenantColumn = regexp.MustCompile(`^(tenant|org|organization|account|customer|company|workspace)_id$`)
+
+var timeTypes = map[string]bool{"date": true, "timestamp": true, "timestamptz": true}
+
+// partitionAdvice suggests a partitioning method and key for t from its
+// column statistics: range by month over a time column which grows with the
+// physical order of rows, else list or hash over a tenant column. The
+// method is empty if no column fits.
+func partitionAdvice(t *inspect.Table, stats []columnStats) (method, column, reason string) {
+	best := 0.0
+	for _, st := range stats {
+		c := t.Column(st.Column)
+		if c == nil || !timeTypes[c.UDTName] || st.NullFrac > 0 {
+			continue
+		}
+		if corr := math.Abs(st.Correlation); corr > 0.9 && corr > best {
+			best = corr
+			method, column = "RANGE", c.Name
+			reason = fmt.Sprintf("%s is never null and follows the row order (correlation %.2f)", c.Name, st.Correlation)
+		}
+	}
+	if method != "" {
+		return method, column, reason
+	}
+	for _, st := range stats {
+		if !tenantColumn.MatchString(st.Column) || st.NullFrac > 0 {
+			continue
+		}
+		distinct := st.NDistinct
+		if distinct < 0 {
+			distinct = -distinct * float64(t.Stats.RowEstimate)
+		}
+		switch {
+		case distinct >= 2 && distinct <= 20:
+			return "LIST", st.Column, fmt.Sprintf("%s has %.0f distinct values", st.Column, distinct)
+		case distinct > 20:
+			return "HASH", st.Column, fmt.Sprintf("%s has ~%.0f distinct values", st.Column, distinct)
+		}
+	}
+	return "", "", ""
+}
+
+// writePartitionDDL writes an example migration to a partitioned copy of t.
+func writePartitionDDL(w *bufio.Writer, t *inspect.Table, method, column string, now time.Time) {
+	partition := func(suffix string) string {
+		return inspect.QualifiedName(t.Schema, t.Name+suffix)
+	}
+	parent := partition("_partitioned")
+	fmt.Fprintf(w, "CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS) PARTITION BY %s (%s);\n",
+		parent, tableRef(t), method, inspect.QuoteIdent(column))
+	switch method {
+	case "RANGE":
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 2; i++ {
+			from, to := month.AddDate(0, i, 0), month.AddDate(0, i+1, 0)
+			fmt.Fprintf(w, "CREATE TABLE %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s');\n",
+				partition(from.Format("_2006_01")), parent, from.Format("2006-01-02"), to.Format("2006-01-02"))
+		}
+		fmt.Fprintf(w, "CREATE TABLE %s PARTITION OF %s DEFAULT;\n", partition("_default"), parent)
+	case "LIST":
+		fmt.Fprintf(w, "-- one partition per value, e.g.\nCREATE TABLE %s PARTITION OF %s FOR VALUES IN (1);\n",
+			partition("_1"), parent)
+		fmt.Fprintf(w, "CREATE TABLE %s PARTITION OF %s DEFAULT;\n", partition("_default"), parent)
+	case "HASH":
+		for i := 0; i < 8; i++ {
+			fmt.Fprintf(w, "CREATE TABLE %s PARTITION OF %s FOR VALUES WITH (MODULUS 8, REMAINDER %d);\n",
+				partition(fmt.Sprintf("_p%d", i)), parent, i)
+		}
+	}
+	if len(t.PK.Columns) > 0 && !containsString(t.PK.Columns, column) {
+		fmt.Fprintf(w, "-- The primary key (%s) has to include %s.\n", sqlIdents(t.PK.Columns), inspect.QuoteIdent(column))
+	}
+}
+
+func loadColumnStats(sess *dbr.Session, t *inspect.Table) ([]columnStats, error) {
+	var stats []columnStats
+	_, err := sess.SelectBySql(`SELECT attname, n_distinct, COALESCE(correlation, 0) AS correlation, null_frac
+FROM pg_stats WHERE schemaname = ? AND tablename = ?`, t.Schema, t.Name).Load(&stats)
+	return stats, err
+}
+
+// analyzePartitions flags large tables without partitioning and suggests a
+// partition key from their column statistics, with example DDL.
+func (a *app) analyzePartitions(args []string) {
+	fs := flag.NewFlagSet("analyze partitions", flag.ContinueOnError)
+	minBytes := fs.Int64("min-bytes", 50<<30, "Flag tables larger than this, including indexes and TOAST.")
+	minRows := fs.Int64("min-rows", 100000000, "Flag tables with more estimated rows.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" || t.PartitionKey != "" || t.PartitionOf != "" {
+				continue
+			}
+			if t.Stats.SizeBytes < *minBytes && t.Stats.RowEstimate < *minRows {
+				continue
+			}
+			fmt.Fprintf(w, "-- %s: %s, ~%d rows, not partitioned\n", qualifiedName(t), humanBytes(t.Stats.SizeBytes),
+				t.Stats.RowEstimate)
+			stats, err := loadColumnStats(sess, t)
+			if err != nil {
+				a.log.WithError(err).Fatalf("read statistics of %s", qualifiedName(t))
+			}
+			method, column, reason := partitionAdvice(t, stats)
+			switch {
+			case len(stats) == 0:
+				w.WriteString("-- no column statistics, run ANALYZE first\n\n")
+				continue
+			case method == "":
+				w.WriteString("-- no time or tenant column suits as partition key\n\n")
+				continue
+			}
+			fmt.Fprintf(w, "-- suggested PARTITION BY %s (%s): %s\n", method, column, reason)
+			writePartitionDDL(w, t, method, column, time.Now())
+			w.WriteString("\n")
+		}
+	}
+	if err := w.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// columnMax is the largest value of the integer column types.
+var columnMax = map[string]int64{
+	"int2": math.MaxInt16,
+	"int4": math.MaxInt32,
+	"int8": math.MaxInt64,
+}
+
+// ownerColumn resolves the schema.table.column a sequence is owned by.
+func ownerColumn(db *inspect.Database, ownedBy string) (*inspect.Table, *inspect.Column) {
+	parts := strings.Split(ownedBy, ".")
+	if len(parts) != 3 {
+		return nil, nil
+	}
+	t := findTable(db, parts[0]+"."+parts[1])
+	if t == nil {
+		return nil, nil
+	}
+	return t, t.Column(parts[2])
+}
+
+// insertRate estimates values used per second from the inserts counted
+// since the statistics were last reset.
+func insertRate(sess *dbr.Session, t *inspect.Table) (float64, error) {
+	var r struct {
+		Inserts int64   `db:"inserts"`
+		Seconds float64 `db:"seconds"`
+	}
+	err := sess.SelectBySql(`SELECT s.n_tup_ins AS inserts,
+	extract(epoch FROM now() - COALESCE(d.stats_reset, pg_postmaster_start_time())) AS seconds
+FROM pg_stat_user_tables s, pg_stat_database d
+WHERE d.datname = current_database() AND s.schemaname = ? AND s.relname = ?`, t.Schema, t.Name).LoadOne(&r)
+	if err == dbr.ErrNotFound || r.Seconds <= 0 {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return float64(r.Inserts) / r.Seconds, nil
+}
+
+// analyzeSequences compares the sequences of serial and identity columns
+// with the largest value of the column and forecasts when they run out.
+// It exits with 1 if a smallint or integer column is past -threshold.
+func (a *app) analyzeSequences(args []string) {
+	fs := flag.NewFlagSet("analyze sequences", flag.ContinueOnError)
+	threshold := fs.Float64("threshold", 0.5, "Flag smallint and integer columns whose sequence used more than this fraction.")
+	since := fs.String("since", "", "Snapshot to measure growth from instead of insert statistics.")
+	a.parseFlags(fs, args)
+
+	var old *inspect.Database
+	if *since != "" {
+		var err error
+		if old, err = readSnapshot(*since); err != nil {
+			a.log.WithError(err).Fatal("read snapshot")
+		}
+	}
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Sequence\tColumn\tType\tLast value\tUsed\tExhausted in\tStatus")
+	flagged := 0
+	for _, s := range db.Schemas {
+		for _, seq := range s.Sequences {
+			t, c := ownerColumn(db, seq.OwnedBy)
+			if c == nil || columnMax[c.UDTName] == 0 || seq.Increment <= 0 {
+				continue
+			}
+			limit := columnMax[c.UDTName]
+			if seq.MaxValue < limit {
+				limit = seq.MaxValue
+			}
+			used := float64(seq.LastValue) / float64(limit)
+
+			// Values per second, from the snapshot if it has the sequence.
+			rate := 0.0
+			if old != nil {
+				if prev := old.Schema(s.Name); prev != nil {
+					for _, o := range prev.Sequences {
+						if o.Name == seq.Name && !old.InspectedAt.IsZero() {
+							rate = float64(seq.LastValue-o.LastValue) / db.InspectedAt.Sub(old.InspectedAt).Seconds()
+						}
+					}
+				}
+			} else {
+				r, err := insertRate(sess, t)
+				if err != nil {
+					a.log.WithError(err).Fatalf("read insert statistics of %s", qualifiedName(t))
+				}
+				rate = r * float64(seq.Increment)
+			}
+			eta := "-"
+			if rate > 0 {
+				eta = "over 100 years"
+				if secs := float64(limit-seq.LastValue) / rate; secs < 100*365*24*3600 {
+					eta = humanDuration(time.Duration(secs * float64(time.Second)))
+				}
+			}
+
+			status := "ok"
+			if c.UDTName != "int8" && used >= *threshold {
+				status = "migrate to bigint"
+				flagged++
+			}
+			fmt.Fprintf(tw, "%s.%s\t%s\t%s\t%d\t%.1f%%\t%s\t%s\n", s.Name, seq.Name, seq.OwnedBy, c.TypeName(),
+				seq.LastValue, used*100, eta, status)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if flagged > 0 {
+		a.log.Errorf("%d sequences past %.0f%% of their column type", flagged, *threshold*100)
+		a.log.Exit(exitFindings)
+	}
+}
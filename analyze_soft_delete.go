@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// softDeleteColumn returns the column of t marking rows as deleted: a
+// timestamp, date or boolean column with one of names.
+func softDeleteColumn(t *inspect.Table, names []string) *inspect.Column {
+	for _, name := range names {
+		c := t.Column(name)
+		if c == nil {
+			continue
+		}
+		switch c.UDTName {
+		case "timestamptz", "timestamp", "date", "bool":
+			return c
+		}
+	}
+	return nil
+}
+
+// excludesDeleted reports whether the predicate of a partial index
+// mentions the soft delete column, as in WHERE deleted_at IS NULL.
+func excludesDeleted(idx inspect.Index, column string) bool {
+	if idx.Predicate == "" {
+		return false
+	}
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(column) + `\b`).MatchString(idx.Predicate)
+}
+
+// analyzeSoftDelete lists the tables marking rows deleted instead of
+// deleting them, whether their indexes skip the deleted rows and which
+// foreign keys link a soft deleting table with a hard deleting one.
+func (a *app) analyzeSoftDelete(args []string) {
+	fs := flag.NewFlagSet("analyze soft-delete", flag.ContinueOnError)
+	columns := fs.String("columns", "deleted_at,deleted_on,is_deleted,deleted,archived_at",
+		"Comma separated names of soft delete columns, the first found in a table is used.")
+	a.parseFlags(fs, args)
+	names := strings.Split(*columns, ",")
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var stats []struct {
+		Schema   string  `db:"schema"`
+		Table    string  `db:"table"`
+		Column   string  `db:"column"`
+		NullFrac float64 `db:"null_frac"`
+	}
+	if _, err := sess.SelectBySql(`SELECT schemaname AS schema, tablename AS table, attname AS column, null_frac
+FROM pg_stats WHERE attname = ANY(string_to_array(?, ','))`, *columns).Load(&stats); err != nil {
+		a.log.WithError(err).Fatal("select column statistics")
+	}
+	nullFrac := make(map[string]float64, len(stats))
+	for _, s := range stats {
+		nullFrac[s.Schema+"."+s.Table+"."+s.Column] = s.NullFrac
+	}
+
+	soft := make(map[*inspect.Table]bool)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Table\tColumn\tDeleted\tIndexes\tExcluding deleted\tNote")
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" || t.PartitionOf != "" {
+				continue
+			}
+			c := softDeleteColumn(t, names)
+			if c == nil {
+				continue
+			}
+			soft[t] = true
+			deleted := "-"
+			if f, ok := nullFrac[t.Schema+"."+t.Name+"."+c.Name]; ok && c.UDTName != "bool" {
+				deleted = fmt.Sprintf("%.0f%%", 100*(1-f))
+			}
+			partial := 0
+			var notes []string
+			for _, idx := range t.Indexes {
+				if excludesDeleted(idx, c.Name) {
+					partial++
+				} else if idx.Unique && !idx.Primary {
+					notes = append(notes, fmt.Sprintf("unique index %s counts deleted rows", idx.Name))
+				}
+			}
+			if partial == 0 && len(t.Indexes) > 0 {
+				notes = append(notes, "no index skips deleted rows")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n", qualifiedName(t), c.Name, deleted, len(t.Indexes), partial,
+				strings.Join(notes, "; "))
+		}
+	}
+	if len(soft) == 0 {
+		fmt.Fprintln(tw, "no soft deleting tables")
+	}
+
+	type crossing struct {
+		fk, child, parent, note string
+	}
+	var crossings []crossing
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" || t.PartitionOf != "" {
+				continue
+			}
+			for _, fk := range t.FKs {
+				ps := db.Schema(fk.RefSchema)
+				if ps == nil {
+					continue
+				}
+				parent := ps.Table(fk.RefTable)
+				if parent == nil || parent == t {
+					continue
+				}
+				var note string
+				switch {
+				case soft[parent] && !soft[t]:
+					note = "soft deleting the parent leaves live rows pointing at it"
+				case soft[t] && !soft[parent]:
+					note = fmt.Sprintf("the parent is deleted for good, ON DELETE %s applies to soft deleted rows too", fk.OnDelete)
+				default:
+					continue
+				}
+				crossings = append(crossings, crossing{qualifiedName(t) + "." + fk.Name, qualifiedName(t), qualifiedName(parent), note})
+			}
+		}
+	}
+	sort.SliceStable(crossings, func(i, j int) bool { return crossings[i].fk < crossings[j].fk })
+	if len(crossings) > 0 {
+		fmt.Fprintln(tw, "\nForeign key\tReferencing\tReferenced\tNote")
+	}
+	for _, c := range crossings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.fk, c.child, c.parent, c.note)
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// toastThreshold approximates the stored size above which a value is moved
+// out of line into the TOAST table.
+const toastThreshold = 2000
+
+// toastStats describe the sampled stored sizes of a column.
+type toastStats struct {
+	column     *inspect.Column
+	N          int64 `db:"n"`
+	Large      int64 `db:"large"`
+	LargeBytes int64 `db:"large_bytes"`
+	MaxSize    int64 `db:"max_size"`
+}
+
+// analyzeToast lists the tables with the largest TOAST relations and
+// estimates from a sample which columns account for their volume.
+func (a *app) analyzeToast(args []string) {
+	fs := flag.NewFlagSet("analyze toast", flag.ContinueOnError)
+	sample := fs.Int("sample", 10000, "Rows to sample per table.")
+	top := fs.Int("top", 20, "Number of tables with the largest TOAST relations to analyze.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var tables []*inspect.Table
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type == "BASE TABLE" && t.Stats.ToastBytes > 0 {
+				tables = append(tables, t)
+			}
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].Stats.ToastBytes > tables[j].Stats.ToastBytes })
+	if len(tables) > *top {
+		tables = tables[:*top]
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Table\tTOAST size\tColumn\tStorage\tCompression\tSampled\tOut of line\tMax size\tShare")
+	for _, t := range tables {
+		var stats []*toastStats
+		var total int64
+		for i := range t.Columns {
+			c := &t.Columns[i]
+			if c.Storage != "extended" && c.Storage != "external" {
+				continue
+			}
+			query := fmt.Sprintf(`WITH s AS (SELECT pg_column_size(%s) AS size FROM %s LIMIT %d)
+SELECT count(size) AS n, count(*) FILTER (WHERE size > %d) AS large,
+	COALESCE(sum(size) FILTER (WHERE size > %[4]d), 0) AS large_bytes, COALESCE(max(size), 0) AS max_size
+FROM s`, inspect.QuoteIdent(c.Name), tableRef(t), *sample, toastThreshold)
+			st := &toastStats{column: c}
+			if err := sess.SelectBySql(query).LoadOne(st); err != nil {
+				a.log.WithError(err).Fatalf("sample %s.%s", qualifiedName(t), c.Name)
+			}
+			stats = append(stats, st)
+			total += st.LargeBytes
+		}
+		sort.SliceStable(stats, func(i, j int) bool { return stats[i].LargeBytes > stats[j].LargeBytes })
+
+		fmt.Fprintf(tw, "%s\t%s\t\t\t\t\t\t\t\n", qualifiedName(t), humanBytes(t.Stats.ToastBytes))
+		for _, st := range stats {
+			if st.Large == 0 {
+				continue
+			}
+			compression := st.column.Compression
+			if compression == "" {
+				compression = "default"
+			}
+			fmt.Fprintf(tw, "\t\t%s\t%s\t%s\t%d\t%d\t%s\t%.0f%%\n", st.column.Name, st.column.Storage, compression,
+				st.N, st.Large, humanBytes(st.MaxSize), 100*float64(st.LargeBytes)/float64(total))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
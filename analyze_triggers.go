@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// triggerFunction is a trigger function with its execution statistics,
+// which are collected only with track_functions = pl or all.
+type triggerFunction struct {
+	Function  string  `db:"function"` // schema.name, as in Trigger.Function
+	Language  string  `db:"language"`
+	Lines     int     `db:"lines"`
+	Calls     int64   `db:"calls"`
+	TotalTime float64 `db:"total_time"` // milliseconds
+	tables    []string
+}
+
+// rowWrites are the rows a table had inserted, updated and deleted
+// since the statistics were reset.
+type rowWrites struct {
+	Schema  string `db:"schema"`
+	Table   string `db:"table"`
+	Inserts int64  `db:"inserts"`
+	Updates int64  `db:"updates"`
+	Deletes int64  `db:"deletes"`
+}
+
+// rowTriggerCalls estimates how often the enabled row level triggers of t
+// fired, from the rows written per event.
+func rowTriggerCalls(t *inspect.Table, w rowWrites) int64 {
+	var n int64
+	for _, tg := range t.Triggers {
+		if tg.Level != "ROW" || !tg.Enabled {
+			continue
+		}
+		for _, e := range tg.Events {
+			switch e {
+			case "INSERT":
+				n += w.Inserts
+			case "UPDATE":
+				n += w.Updates
+			case "DELETE":
+				n += w.Deletes
+			}
+		}
+	}
+	return n
+}
+
+type triggerTable struct {
+	table            *inspect.Table
+	row, statement   int
+	languages        []string
+	writes, rowCalls int64
+}
+
+// analyzeTriggers summarizes the triggers of every table: how many fire per
+// row and per statement, the languages of their functions and how often
+// they ran. Tables with many row level triggers multiply the work of every
+// write and are flagged.
+func (a *app) analyzeTriggers(args []string) {
+	fs := flag.NewFlagSet("analyze triggers", flag.ContinueOnError)
+	maxRow := fs.Int("max-row-triggers", 3, "Flag tables with more enabled row level triggers.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var functions []*triggerFunction
+	if _, err := sess.SelectBySql(`SELECT n.nspname || '.' || p.proname AS function, l.lanname AS language,
+	array_length(regexp_split_to_array(p.prosrc, E'\n'), 1) AS lines,
+	COALESCE(s.calls, 0) AS calls, COALESCE(s.total_time, 0) AS total_time
+FROM pg_proc p
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+	JOIN pg_language l ON l.oid = p.prolang
+	LEFT JOIN pg_stat_user_functions s ON s.funcid = p.oid
+WHERE p.prorettype = 'trigger'::regtype`).Load(&functions); err != nil {
+		a.log.WithError(err).Fatal("select trigger functions")
+	}
+	byFunction := make(map[string]*triggerFunction, len(functions))
+	for _, f := range functions {
+		byFunction[f.Function] = f
+	}
+
+	var writes []rowWrites
+	if _, err := sess.SelectBySql(`SELECT schemaname AS schema, relname AS table,
+	n_tup_ins AS inserts, n_tup_upd AS updates, n_tup_del AS deletes
+FROM pg_stat_user_tables`).Load(&writes); err != nil {
+		a.log.WithError(err).Fatal("select table statistics")
+	}
+	byTable := make(map[string]rowWrites, len(writes))
+	for _, w := range writes {
+		byTable[w.Schema+"."+w.Table] = w
+	}
+
+	var tables []*triggerTable
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if len(t.Triggers) == 0 {
+				continue
+			}
+			tt := &triggerTable{table: t}
+			languages := make(map[string]bool)
+			for _, tg := range t.Triggers {
+				if !tg.Enabled {
+					continue
+				}
+				if tg.Level == "ROW" {
+					tt.row++
+				} else {
+					tt.statement++
+				}
+				if f := byFunction[tg.Function]; f != nil {
+					languages[f.Language] = true
+					f.tables = append(f.tables, qualifiedName(t))
+				}
+			}
+			for l := range languages {
+				tt.languages = append(tt.languages, l)
+			}
+			sort.Strings(tt.languages)
+			w := byTable[t.Schema+"."+t.Name]
+			tt.writes = w.Inserts + w.Updates + w.Deletes
+			tt.rowCalls = rowTriggerCalls(t, w)
+			tables = append(tables, tt)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		if tables[i].row != tables[j].row {
+			return tables[i].row > tables[j].row
+		}
+		return tables[i].rowCalls > tables[j].rowCalls
+	})
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Table\tRow triggers\tStatement triggers\tLanguages\tRows written\tRow trigger calls\tNote")
+	for _, tt := range tables {
+		note := ""
+		if tt.row > *maxRow {
+			note = fmt.Sprintf("write amplification hotspot, %d row triggers run for every row written", tt.row)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%d\t%d\t%s\n", qualifiedName(tt.table), tt.row, tt.statement,
+			strings.Join(tt.languages, ", "), tt.writes, tt.rowCalls, note)
+	}
+	if len(tables) == 0 {
+		fmt.Fprintln(tw, "no triggers")
+	}
+
+	var used []*triggerFunction
+	for _, f := range functions {
+		if len(f.tables) > 0 {
+			used = append(used, f)
+		}
+	}
+	sort.SliceStable(used, func(i, j int) bool { return used[i].TotalTime > used[j].TotalTime })
+	if len(used) > 0 {
+		fmt.Fprintln(tw, "\nFunction\tLanguage\tLines\tCalls\tTotal time\tTables")
+	}
+	for _, f := range used {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.0f ms\t%s\n", f.Function, f.Language, f.Lines, f.Calls, f.TotalTime,
+			strings.Join(f.tables, ", "))
+	}
+	if len(used) > 0 {
+		fmt.Fprintln(tw, "\nCalls and times are collected with track_functions = pl or all.")
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
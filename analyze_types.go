@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// typeAdvice is a tighter type for a column and the bytes it saves per row.
+type typeAdvice struct {
+	table    *inspect.Table
+	column   *inspect.Column
+	suggest  string
+	perRow   int64
+	evidence string
+}
+
+const uuidPattern = `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+
+// adviseText checks whether a text column holds only UUIDs or integers.
+func adviseText(sess *dbr.Session, t *inspect.Table, c *inspect.Column, sample int) (*typeAdvice, error) {
+	var r struct {
+		N       int64 `db:"n"`
+		UUID    bool  `db:"uuid"`
+		Integer bool  `db:"integer"`
+		Length  int64 `db:"length"`
+	}
+	query := fmt.Sprintf(`SELECT count(v) AS n, coalesce(bool_and(v ~ '%s'), false) AS uuid,
+	coalesce(bool_and(v ~ '^-?[0-9]{1,18}$'), false) AS integer, coalesce(avg(octet_length(v)), 0)::bigint AS length
+FROM (SELECT %s::text AS v FROM %s LIMIT %d) s`, uuidPattern, inspect.QuoteIdent(c.Name), tableRef(t), sample)
+	if err := sess.SelectBySql(query).LoadOne(&r); err != nil {
+		return nil, err
+	}
+	switch {
+	case r.N == 0:
+		return nil, nil
+	case r.UUID:
+		// A short text value has a 1 byte header, a uuid is 16 bytes.
+		return &typeAdvice{t, c, "uuid", r.Length + 1 - 16, fmt.Sprintf("%d sampled values are UUIDs", r.N)}, nil
+	case r.Integer:
+		return &typeAdvice{t, c, "bigint", r.Length + 1 - 8, fmt.Sprintf("%d sampled values are integers", r.N)}, nil
+	}
+	return nil, nil
+}
+
+// adviseBigint checks whether a bigint column fits into integer.
+func adviseBigint(sess *dbr.Session, t *inspect.Table, c *inspect.Column, sample int) (*typeAdvice, error) {
+	var r struct {
+		N   int64 `db:"n"`
+		Min int64 `db:"min"`
+		Max int64 `db:"max"`
+	}
+	query := fmt.Sprintf(`SELECT count(v) AS n, coalesce(min(v), 0) AS min, coalesce(max(v), 0) AS max
+FROM (SELECT %s AS v FROM %s LIMIT %d) s`, inspect.QuoteIdent(c.Name), tableRef(t), sample)
+	if err := sess.SelectBySql(query).LoadOne(&r); err != nil {
+		return nil, err
+	}
+	// Serial keys grow; only suggest int4 when far from its limit.
+	limit := int64(1<<31 - 1)
+	if c.DefaultKind() == inspect.DefaultSequence {
+		limit /= 10
+	}
+	if r.N == 0 || r.Min < -limit || r.Max > limit {
+		return nil, nil
+	}
+	return &typeAdvice{t, c, "integer", 4, fmt.Sprintf("sampled values between %d and %d", r.Min, r.Max)}, nil
+}
+
+// adviseNumeric finds the precision and scale an unconstrained numeric
+// column actually uses.
+func adviseNumeric(sess *dbr.Session, t *inspect.Table, c *inspect.Column, sample int) (*typeAdvice, error) {
+	var r struct {
+		N      int64 `db:"n"`
+		Scale  int   `db:"scale"`
+		Digits int   `db:"digits"`
+	}
+	query := fmt.Sprintf(`SELECT count(v) AS n, coalesce(max(scale(v)), 0) AS scale,
+	coalesce(max(length(trunc(abs(v))::text)), 0) AS digits
+FROM (SELECT %s AS v FROM %s LIMIT %d) s`, inspect.QuoteIdent(c.Name), tableRef(t), sample)
+	if err := sess.SelectBySql(query).LoadOne(&r); err != nil {
+		return nil, err
+	}
+	if r.N == 0 {
+		return nil, nil
+	}
+	if r.Scale == 0 && r.Digits <= 18 {
+		return &typeAdvice{t, c, "bigint", 0, fmt.Sprintf("sampled values are integers of up to %d digits", r.Digits)}, nil
+	}
+	return &typeAdvice{t, c, fmt.Sprintf("numeric(%d,%d)", r.Digits+r.Scale, r.Scale), 0,
+		fmt.Sprintf("sampled values have up to %d digits and %d decimals", r.Digits, r.Scale)}, nil
+}
+
+// analyzeTypes samples columns and suggests tighter types with the storage
+// they would save.
+func (a *app) analyzeTypes(args []string) {
+	fs := flag.NewFlagSet("analyze types", flag.ContinueOnError)
+	sample := fs.Int("sample", 10000, "Rows to sample per table.")
+	a.parseFlags(fs, args)
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	var advice []*typeAdvice
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				var advise func(*dbr.Session, *inspect.Table, *inspect.Column, int) (*typeAdvice, error)
+				switch {
+				case c.UDTName == "text" || c.UDTName == "varchar":
+					advise = adviseText
+				case c.UDTName == "int8":
+					advise = adviseBigint
+				case c.UDTName == "numeric" && c.Precision == 0:
+					advise = adviseNumeric
+				default:
+					continue
+				}
+				ad, err := advise(sess, t, c, *sample)
+				if err != nil {
+					a.log.WithError(err).Fatalf("sample %s.%s", qualifiedName(t), c.Name)
+				}
+				if ad != nil {
+					advice = append(advice, ad)
+				}
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Column\tType\tSuggested\tEst. saving\tEvidence")
+	for _, ad := range advice {
+		saving := "-"
+		if ad.perRow > 0 {
+			saving = humanBytes(ad.perRow * ad.table.Stats.RowEstimate)
+		}
+		fmt.Fprintf(tw, "%s.%s\t%s\t%s\t%s\t%s\n", qualifiedName(ad.table), ad.column.Name, ad.column.TypeName(),
+			ad.suggest, saving, ad.evidence)
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
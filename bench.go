@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// benchPrefix names the schemas of the synthetic catalog.
+const benchPrefix = "pgi_bench_"
+
+// syntheticCatalog returns DDL creating schemas with tables of the given
+// number of columns. Every table has a primary key, a foreign key to the
+// previous table of its schema with an index on it, a unique column and a
+// comment, so all loader queries return rows.
+func syntheticCatalog(schemas, tables, columns int) string {
+	var b strings.Builder
+	for s := 0; s < schemas; s++ {
+		schema := fmt.Sprintf("%s%d", benchPrefix, s)
+		fmt.Fprintf(&b, "CREATE SCHEMA %s;\n", schema)
+		for t := 0; t < tables; t++ {
+			fmt.Fprintf(&b, "CREATE TABLE %s.t%d (\n    id bigint PRIMARY KEY", schema, t)
+			if t > 0 {
+				fmt.Fprintf(&b, ",\n    parent_id bigint REFERENCES %s.t%d (id)", schema, t-1)
+			}
+			for c := 0; c < columns; c++ {
+				switch c % 3 {
+				case 0:
+					fmt.Fprintf(&b, ",\n    c%d text", c)
+				case 1:
+					fmt.Fprintf(&b, ",\n    c%d integer NOT NULL DEFAULT 0", c)
+				default:
+					fmt.Fprintf(&b, ",\n    c%d timestamptz", c)
+				}
+			}
+			b.WriteString(",\n    code text UNIQUE\n);\n")
+			if t > 0 {
+				fmt.Fprintf(&b, "CREATE INDEX ON %s.t%d (parent_id);\n", schema, t)
+			}
+			fmt.Fprintf(&b, "COMMENT ON TABLE %s.t%d IS 'synthetic table %d';\n", schema, t, t)
+		}
+	}
+	return b.String()
+}
+
+// runBench creates a synthetic catalog, inspects it -runs times and reports
+// the queries and wall time of every run. It exits with 1 when a run goes
+// over -max-queries or -max-time.
+func (a *app) runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	schemaCount := fs.Int("schema-count", 2, "Number of synthetic schemas.")
+	tables := fs.Int("tables", 100, "Tables per synthetic schema.")
+	columns := fs.Int("columns", 10, "Columns per synthetic table, besides the keys.")
+	runs := fs.Int("runs", 3, "Number of inspections to time.")
+	maxQueries := fs.Int("max-queries", 0, "Fail if a run executes more queries, 0 for no limit.")
+	maxTime := fs.Duration("max-time", 0, "Fail if a run takes longer, 0 for no limit.")
+	keep := fs.Bool("keep", false, "Keep the synthetic schemas instead of dropping them.")
+	printSQL := fs.Bool("sql", false, "Only print the synthetic catalog DDL.")
+	a.parseFlags(fs, args)
+
+	ddl := syntheticCatalog(*schemaCount, *tables, *columns)
+	if *printSQL {
+		fmt.Print(ddl)
+		return
+	}
+	var schemas []string
+	for s := 0; s < *schemaCount; s++ {
+		schemas = append(schemas, fmt.Sprintf("%s%d", benchPrefix, s))
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		a.log.WithError(err).Fatal("connect")
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(ddl); err != nil {
+		a.log.WithError(err).Fatal("create synthetic catalog")
+	}
+	drop := func() {
+		if *keep {
+			return
+		}
+		if _, err := conn.Exec("DROP SCHEMA " + strings.Join(schemas, ", ") + " CASCADE"); err != nil {
+			a.log.WithError(err).Error("drop synthetic catalog")
+		}
+	}
+
+	failed := false
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tTABLES\tQUERIES\tQUERY TIME\tWALL TIME")
+	for i := 1; i <= *runs; i++ {
+		n0, d0 := a.queries.Totals()
+		start := time.Now()
+		db, err := inspect.Load(conn.NewSession(nil), schemas)
+		if err != nil {
+			drop()
+			a.log.WithError(err).Fatal("inspect database")
+		}
+		wall := time.Since(start)
+		n1, d1 := a.queries.Totals()
+		count := 0
+		for _, s := range db.Schemas {
+			count += len(s.Tables)
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\n", i, count, n1-n0, (d1 - d0).Round(time.Millisecond), wall.Round(time.Millisecond))
+		if (*maxQueries > 0 && n1-n0 > *maxQueries) || (*maxTime > 0 && wall > *maxTime) {
+			failed = true
+		}
+	}
+	tw.Flush()
+	drop()
+	if failed {
+		a.log.Error("inspection over budget")
+		a.log.Exit(exitFindings)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gocraft/dbr"
+)
+
+// clusterSettings are the server settings reported by `cluster`.
+var clusterSettings = []string{
+	"server_version", "data_directory", "max_connections", "shared_buffers", "effective_cache_size",
+	"work_mem", "maintenance_work_mem", "wal_level", "max_wal_size", "archive_mode",
+	"max_wal_senders", "max_replication_slots", "hot_standby", "shared_preload_libraries",
+	"data_checksums", "timezone",
+}
+
+type clusterSetting struct {
+	Name    string `db:"name" json:"name"`
+	Setting string `db:"setting" json:"setting"`
+}
+
+type clusterExtension struct {
+	Name    string `db:"name" json:"name"`
+	Version string `db:"version" json:"version"`
+}
+
+type clusterDatabase struct {
+	Name       string             `db:"name" json:"name"`
+	Owner      string             `db:"owner" json:"owner"`
+	Encoding   string             `db:"encoding" json:"encoding"`
+	Collate    string             `db:"collate" json:"collate"`
+	Tablespace string             `db:"tablespace" json:"tablespace"`
+	SizeBytes  dbr.NullInt64      `db:"size_bytes" json:"size_bytes"` // null without CONNECT privilege
+	Extensions []clusterExtension `json:"extensions"`
+	Error      string             `json:"error,omitempty"` // why extensions are missing
+}
+
+type clusterTablespace struct {
+	Name      string        `db:"name" json:"name"`
+	Owner     string        `db:"owner" json:"owner"`
+	Location  string        `db:"location" json:"location"` // empty for the built-in ones
+	SizeBytes dbr.NullInt64 `db:"size_bytes" json:"size_bytes"`
+}
+
+type replicationSlot struct {
+	Name          string         `db:"name" json:"name"`
+	Type          string         `db:"type" json:"type"`
+	Plugin        dbr.NullString `db:"plugin" json:"plugin"`
+	Database      dbr.NullString `db:"database" json:"database"`
+	Active        bool           `db:"active" json:"active"`
+	RetainedBytes dbr.NullInt64  `db:"retained_bytes" json:"retained_bytes"` // WAL kept for the slot
+}
+
+// clusterReport is the server-level metadata shared by all databases.
+type clusterReport struct {
+	Settings         []clusterSetting    `json:"settings"`
+	Databases        []clusterDatabase   `json:"databases"`
+	Tablespaces      []clusterTablespace `json:"tablespaces"`
+	ReplicationSlots []replicationSlot   `json:"replication_slots"`
+}
+
+func loadCluster(sess *dbr.Session) (*clusterReport, error) {
+	r := &clusterReport{}
+	if _, err := sess.SelectBySql(`SELECT name, current_setting(name) AS setting
+FROM pg_settings WHERE name IN ?`, clusterSettings).Load(&r.Settings); err != nil {
+		return nil, fmt.Errorf("select settings: %v", err)
+	}
+	order := make(map[string]int, len(clusterSettings))
+	for i, name := range clusterSettings {
+		order[name] = i
+	}
+	sort.Slice(r.Settings, func(i, j int) bool { return order[r.Settings[i].Name] < order[r.Settings[j].Name] })
+
+	if _, err := sess.SelectBySql(`SELECT d.datname AS name, pg_get_userbyid(d.datdba) AS owner,
+	pg_encoding_to_char(d.encoding) AS encoding, d.datcollate AS "collate", t.spcname AS tablespace,
+	CASE WHEN has_database_privilege(d.oid, 'CONNECT') THEN pg_database_size(d.oid) END AS size_bytes
+FROM pg_database d JOIN pg_tablespace t ON t.oid = d.dattablespace
+WHERE d.datallowconn AND NOT d.datistemplate
+ORDER BY d.datname`).Load(&r.Databases); err != nil {
+		return nil, fmt.Errorf("select databases: %v", err)
+	}
+
+	if _, err := sess.SelectBySql(`SELECT spcname AS name, pg_get_userbyid(spcowner) AS owner,
+	pg_tablespace_location(oid) AS location,
+	CASE WHEN has_tablespace_privilege(oid, 'CREATE') OR spcname = 'pg_default'
+		THEN pg_tablespace_size(oid) END AS size_bytes
+FROM pg_tablespace ORDER BY spcname`).Load(&r.Tablespaces); err != nil {
+		return nil, fmt.Errorf("select tablespaces: %v", err)
+	}
+
+	var err error
+	if r.ReplicationSlots, err = loadReplicationSlots(sess); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func loadReplicationSlots(sess *dbr.Session) ([]replicationSlot, error) {
+	var slots []replicationSlot
+	if _, err := sess.SelectBySql(`SELECT slot_name AS name, slot_type AS type, plugin, database, active,
+	pg_wal_lsn_diff(CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END,
+		restart_lsn)::bigint AS retained_bytes
+FROM pg_replication_slots ORDER BY slot_name`).Load(&slots); err != nil {
+		return nil, fmt.Errorf("select replication slots: %v", err)
+	}
+	return slots, nil
+}
+
+// loadExtensions connects to every database and lists its extensions.
+// Databases the user cannot connect to get an error instead.
+func (a *app) loadExtensions(r *clusterReport) {
+	for i := range r.Databases {
+		d := &r.Databases[i]
+		d.Extensions = []clusterExtension{}
+		err := func() error {
+			dsn, err := setParam(a.connStr, "dbname", d.Name)
+			if err != nil {
+				return err
+			}
+			conn, err := a.open(dsn)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			_, err = conn.NewSession(nil).SelectBySql(`SELECT extname AS name, extversion AS version
+FROM pg_extension ORDER BY extname`).Load(&d.Extensions)
+			return err
+		}()
+		if err != nil {
+			d.Error = err.Error()
+			a.log.WithError(err).Warnf("list extensions of %s", d.Name)
+		}
+	}
+}
+
+func nullBytes(n dbr.NullInt64) string {
+	if !n.Valid {
+		return "-"
+	}
+	return humanBytes(n.Int64)
+}
+
+func writeClusterText(w io.Writer, r *clusterReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "== settings ==")
+	for _, s := range r.Settings {
+		fmt.Fprintf(tw, "%s\t%s\n", s.Name, s.Setting)
+	}
+	fmt.Fprintln(tw, "\n== databases ==")
+	fmt.Fprintln(tw, "DATABASE\tOWNER\tENCODING\tCOLLATE\tTABLESPACE\tSIZE\tEXTENSIONS")
+	for _, d := range r.Databases {
+		exts := make([]string, len(d.Extensions))
+		for i, e := range d.Extensions {
+			exts[i] = e.Name + " " + e.Version
+		}
+		if d.Error != "" {
+			exts = []string{"? (cannot connect)"}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.Name, d.Owner, d.Encoding, d.Collate, d.Tablespace,
+			nullBytes(d.SizeBytes), strings.Join(exts, ", "))
+	}
+	fmt.Fprintln(tw, "\n== tablespaces ==")
+	fmt.Fprintln(tw, "TABLESPACE\tOWNER\tLOCATION\tSIZE")
+	for _, t := range r.Tablespaces {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Name, t.Owner, t.Location, nullBytes(t.SizeBytes))
+	}
+	fmt.Fprintln(tw, "\n== replication slots ==")
+	if len(r.ReplicationSlots) == 0 {
+		fmt.Fprintln(tw, "none")
+	} else {
+		fmt.Fprintln(tw, "SLOT\tTYPE\tPLUGIN\tDATABASE\tACTIVE\tRETAINED WAL")
+	}
+	for _, s := range r.ReplicationSlots {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\n", s.Name, s.Type, s.Plugin.String, s.Database.String, s.Active,
+			nullBytes(s.RetainedBytes))
+	}
+	return tw.Flush()
+}
+
+// runCluster reports server-level metadata: settings of interest, the
+// databases with their sizes and extensions, tablespaces and replication
+// slots.
+func (a *app) runCluster(args []string) {
+	fs := flag.NewFlagSet("cluster", flag.ContinueOnError)
+	format := fs.String("format", "text", "Output format: text or json.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+	if *format != "text" && *format != "json" {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		a.log.WithError(err).Fatal("connect")
+	}
+	r, err := loadCluster(conn.NewSession(nil))
+	conn.Close()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect cluster")
+	}
+	a.loadExtensions(r)
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		err = writeClusterText(w, r)
+	}
+	if err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
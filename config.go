@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/datainq/pq-inspector/lint"
+)
+
+// Config is the content of the file passed with -config.
+type Config struct {
+	// Databases is the registry of named targets served by `serve`.
+	Databases []DatabaseConfig `json:"databases"`
+	// Webhooks are notified when drift or watch detect changes.
+	Webhooks []WebhookConfig `json:"webhooks"`
+	// Diff holds objects and attributes excluded from drift and watch.
+	Diff DiffConfig `json:"diff"`
+	// Lint extends the built-in lint rules.
+	Lint LintConfig `json:"lint"`
+	// Connection tunes the connection pool and the session settings.
+	Connection ConnectionConfig `json:"connection"`
+	// TenantColumn holds the tenant of rows in tables shared by tenants,
+	// the default of `security tenancy -column`.
+	TenantColumn string `json:"tenant_column"`
+	// Teams own the tables matching their patterns, reported by `teams`.
+	Teams []TeamConfig `json:"teams"`
+	// History records inspections in a SQLite file, see `history`.
+	History HistoryConfig `json:"history"`
+	// Telemetry exports traces and metrics of inspections, see -telemetry.
+	Telemetry TelemetryConfig `json:"telemetry"`
+}
+
+type DatabaseConfig struct {
+	Name    string   `json:"name"`
+	DSN     string   `json:"dsn"`
+	Schemas []string `json:"schemas"`
+}
+
+type WebhookConfig struct {
+	URL    string `json:"url"`
+	Format string `json:"format"` // json (default) or slack
+}
+
+type DiffConfig struct {
+	IgnoreObjects    []string `json:"ignore_objects"`    // e.g. "public.django_migrations", "*.tmp_*"
+	IgnoreAttributes []string `json:"ignore_attributes"` // owner, comment, tablespace, default, nullable, constraints, migrations
+}
+
+type TeamConfig struct {
+	Team  string   `json:"team"`
+	Match []string `json:"match"` // path.Match patterns of schema.table, e.g. "billing.*"
+}
+
+type HistoryConfig struct {
+	Path   string `json:"path"`    // SQLite file, the default of -history
+	Keep   int    `json:"keep"`    // inspections kept per database, all if 0
+	MaxAge string `json:"max_age"` // e.g. "2160h", older inspections are deleted
+}
+
+// maxAge parses MaxAge, 0 if empty.
+func (c HistoryConfig) maxAge() (time.Duration, error) {
+	if c.MaxAge == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.MaxAge)
+	if err != nil {
+		return 0, fmt.Errorf("parse history max_age: %v", err)
+	}
+	return d, nil
+}
+
+type TelemetryConfig struct {
+	Exporter string `json:"exporter"` // otlp or stdout, off if empty; the default of -telemetry
+	Endpoint string `json:"endpoint"` // OTLP/HTTP base URL, e.g. http://localhost:4318
+}
+
+type LintConfig struct {
+	Plugins []string `json:"plugins"` // Go plugins exporting lint.PluginSymbol
+	lint.Options
+}
+
+func loadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	seen := make(map[string]bool, len(cfg.Databases))
+	for _, d := range cfg.Databases {
+		if d.Name == "" {
+			return nil, fmt.Errorf("%s: database without a name", path)
+		}
+		if seen[d.Name] {
+			return nil, fmt.Errorf("%s: duplicate database %q", path, d.Name)
+		}
+		seen[d.Name] = true
+	}
+	return cfg, nil
+}
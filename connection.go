@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// ConnectionConfig tunes the connection pool and the session settings of
+// every database the app connects to. Settings given in a connection string
+// or the -service take precedence over these.
+type ConnectionConfig struct {
+	MaxOpenConns    int    `json:"max_open_conns"`
+	MaxIdleConns    int    `json:"max_idle_conns"`
+	ConnMaxLifetime string `json:"conn_max_lifetime"` // e.g. "30m"
+	ApplicationName string `json:"application_name"`
+	SearchPath      string `json:"search_path"`
+	WorkMem         string `json:"work_mem"` // e.g. "64MB"
+	// Pooler avoids session state for transaction-pooled PgBouncer
+	// endpoints: search_path and work_mem are not set, and parameterized
+	// queries are sent in a single round trip.
+	Pooler bool `json:"pooler"`
+}
+
+// defaultApplicationName identifies the tool in pg_stat_activity.
+const defaultApplicationName = "pg-inspector"
+
+// connectionFlags registers the flags overriding the connection config.
+// apply copies the flags that were set on the command line into c.
+func connectionFlags(fs *flag.FlagSet) (apply func(c *ConnectionConfig)) {
+	maxOpen := fs.Int("max-open-conns", 0, "Maximum number of open connections per database, unlimited if 0.")
+	maxIdle := fs.Int("max-idle-conns", 0, "Maximum number of idle connections per database, the driver default if 0.")
+	lifetime := fs.Duration("conn-max-lifetime", 0, "Close connections older than this, never if 0.")
+	appName := fs.String("application-name", defaultApplicationName, "application_name of the sessions.")
+	searchPath := fs.String("search-path", "", "search_path of the sessions, the server default if empty.")
+	workMem := fs.String("work-mem", "", "work_mem of the sessions, e.g. 64MB, the server default if empty.")
+	pooler := fs.Bool("pooler", false, "Avoid session-level settings, for transaction-pooled PgBouncer endpoints.")
+	return func(c *ConnectionConfig) {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "max-open-conns":
+				c.MaxOpenConns = *maxOpen
+			case "max-idle-conns":
+				c.MaxIdleConns = *maxIdle
+			case "conn-max-lifetime":
+				c.ConnMaxLifetime = lifetime.String()
+			case "application-name":
+				c.ApplicationName = *appName
+			case "search-path":
+				c.SearchPath = *searchPath
+			case "work-mem":
+				c.WorkMem = *workMem
+			case "pooler":
+				c.Pooler = *pooler
+			}
+		})
+		if c.ApplicationName == "" {
+			c.ApplicationName = *appName
+		}
+	}
+}
+
+// params returns the session settings as connection parameters. A pooler
+// keeps server connections between clients and rejects settings other than
+// application_name, so with Pooler the others are left out.
+func (c ConnectionConfig) params() map[string]string {
+	p := make(map[string]string)
+	if c.ApplicationName != "" {
+		p["application_name"] = c.ApplicationName
+	}
+	if c.Pooler {
+		p["binary_parameters"] = "yes"
+		return p
+	}
+	if c.SearchPath != "" {
+		p["search_path"] = c.SearchPath
+	}
+	if c.WorkMem != "" {
+		p["work_mem"] = c.WorkMem
+	}
+	return p
+}
+
+var dsnKey = regexp.MustCompile(`(?:^|\s)([a-z_]+)\s*=`)
+
+// withParams adds params missing from dsn, which is either a postgres://
+// URL or a list of key=value pairs, and returns it as key=value pairs.
+// lib/pq sends parameters it does not know itself to the server as session
+// settings.
+func withParams(dsn string, params map[string]string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return "", fmt.Errorf("parse connection URL: %v", err)
+		}
+	}
+
+	set := make(map[string]bool)
+	for _, m := range dsnKey.FindAllStringSubmatch(dsn, -1) {
+		set[m[1]] = true
+	}
+	var keys []string
+	for k := range params {
+		if !set[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		dsn += " " + param(k, params[k])
+	}
+	return strings.TrimSpace(dsn), nil
+}
+
+// setParam returns dsn as key=value pairs with key set to value, replacing
+// the value dsn has.
+func setParam(dsn, key, value string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return "", fmt.Errorf("parse connection URL: %v", err)
+		}
+	}
+	// lib/pq takes the last of repeated keys.
+	return strings.TrimSpace(dsn + " " + param(key, value)), nil
+}
+
+func param(key, value string) string {
+	return fmt.Sprintf("%s='%s'", key, strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value))
+}
+
+// open connects to dsn with the connection config applied. Its errors
+// never contain the password.
+func (a *app) open(dsn string) (*dbr.Connection, error) {
+	defer a.manifest.phase("connect")()
+	conn, err := a.dial(dsn)
+	return conn, redactError(err, dsn, a.password, a.service["password"])
+}
+
+func (a *app) dial(dsn string) (*dbr.Connection, error) {
+	c := a.cfg.Connection
+	if a.password != "" {
+		var err error
+		if dsn, err = withParams(dsn, map[string]string{"password": a.password}); err != nil {
+			return nil, err
+		}
+	}
+	dsn, err := withParams(dsn, a.service)
+	if err != nil {
+		return nil, err
+	}
+	if c.Pooler && (c.SearchPath != "" || c.WorkMem != "") {
+		a.log.Warn("search_path and work_mem are not set in pooler mode")
+	}
+	dsn, err = withParams(dsn, c.params())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dbr.Open("postgres", dsn, a.queries)
+	if err != nil {
+		return nil, err
+	}
+	a.manifest.connected(conn, dsn)
+	conn.SetMaxOpenConns(c.MaxOpenConns)
+	if c.MaxIdleConns > 0 {
+		conn.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime != "" {
+		d, err := time.ParseDuration(c.ConnMaxLifetime)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("parse conn_max_lifetime: %v", err)
+		}
+		conn.SetConnMaxLifetime(d)
+	}
+	return conn, nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func sqlLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// sqlIdents returns names as a comma separated list of identifiers.
+func sqlIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = inspect.QuoteIdent(n)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// sqlType is the declared type of a column as written in CREATE TABLE.
+func sqlType(c *inspect.Column) string {
+	switch {
+	case c.DataType == "ARRAY" && c.UDTSchema != "" && c.UDTSchema != "pg_catalog":
+		return inspect.QualifiedName(c.UDTSchema, strings.TrimPrefix(c.UDTName, "_")) + "[]"
+	case c.DataType == "ARRAY":
+		return strings.TrimPrefix(c.UDTName, "_") + "[]"
+	case c.DataType == "USER-DEFINED" && c.Spatial != nil && c.Spatial.Modifier() != "":
+		return inspect.QualifiedName(c.UDTSchema, c.UDTName) + "(" + c.Spatial.Modifier() + ")"
+	case c.DataType == "USER-DEFINED":
+		return inspect.QualifiedName(c.UDTSchema, c.UDTName)
+	case c.MaxLength > 0:
+		return fmt.Sprintf("%s(%d)", c.DataType, c.MaxLength)
+	case c.UDTName == "numeric" && c.Precision > 0:
+		return fmt.Sprintf("numeric(%d,%d)", c.Precision, c.Scale)
+	}
+	return c.DataType
+}
+
+// sequenceMax is the MAXVALUE of ascending sequences of each type when none
+// is given; descending ones end at -1.
+var sequenceMax = map[string]int64{"smallint": math.MaxInt16, "integer": math.MaxInt32, "bigint": math.MaxInt64}
+
+// checkExpr is the expression of a CHECK constraint definition, e.g.
+// (price > 0) of CHECK ((price > 0)) NOT VALID.
+func checkExpr(definition string) string {
+	definition = strings.TrimSuffix(definition, " NOT VALID")
+	return strings.TrimSuffix(strings.TrimPrefix(definition, "CHECK ("), ")")
+}
+
+// writeDDL writes a schema.sql with schemas, enums, sequences, tables,
+// constraints and indexes, leaving out owners, grants and anything else tied
+// to a server. Sequences come before the tables whose defaults use them and
+// are tied to their columns after, partitions follow their parents, and
+// foreign keys are added last so tables can be created in any order.
+func writeDDL(w io.Writer, db *inspect.Database) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "-- Generated by pg-inspector from database %s.\n", db.Name)
+
+	for _, s := range db.Schemas {
+		if s.Name != "public" {
+			fmt.Fprintf(bw, "\nCREATE SCHEMA IF NOT EXISTS %s;\n", inspect.QuoteIdent(s.Name))
+		}
+		for _, e := range s.Enums {
+			labels := make([]string, len(e.Labels))
+			for i, l := range e.Labels {
+				labels[i] = sqlLiteral(l)
+			}
+			fmt.Fprintf(bw, "\nCREATE TYPE %s AS ENUM (%s);\n", inspect.QualifiedName(s.Name, e.Name),
+				strings.Join(labels, ", "))
+		}
+		for _, seq := range s.Sequences {
+			// Identity columns create their sequences.
+			if _, c := ownerColumn(db, seq.OwnedBy); c != nil && c.Identity != "" {
+				continue
+			}
+			fmt.Fprintf(bw, "\nCREATE SEQUENCE %s AS %s", inspect.QualifiedName(s.Name, seq.Name), seq.DataType)
+			if seq.Increment != 0 && seq.Increment != 1 {
+				fmt.Fprintf(bw, " INCREMENT BY %d", seq.Increment)
+			}
+			defaultMax := sequenceMax[seq.DataType]
+			if seq.Increment < 0 {
+				defaultMax = -1
+			}
+			if seq.MaxValue != 0 && seq.MaxValue != defaultMax {
+				fmt.Fprintf(bw, " MAXVALUE %d", seq.MaxValue)
+			}
+			bw.WriteString(";\n")
+		}
+	}
+
+	for _, t := range tablesByPartitionDepth(db) {
+		writeCreateTable(bw, t)
+	}
+
+	for _, s := range db.Schemas {
+		for _, seq := range s.Sequences {
+			t, c := ownerColumn(db, seq.OwnedBy)
+			if c == nil || c.Identity != "" {
+				continue
+			}
+			fmt.Fprintf(bw, "\nALTER SEQUENCE %s OWNED BY %s.%s;\n", inspect.QualifiedName(s.Name, seq.Name),
+				inspect.QualifiedName(t.Schema, t.Name), inspect.QuoteIdent(c.Name))
+		}
+	}
+
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			// Partitions get the foreign keys of their parents.
+			if t.Type != "BASE TABLE" || t.PartitionOf != "" {
+				continue
+			}
+			for _, fk := range t.FKs {
+				if fk.Definition != "" {
+					fmt.Fprintf(bw, "\nALTER TABLE %s ADD CONSTRAINT %s %s;\n", inspect.QualifiedName(t.Schema, t.Name),
+						inspect.QuoteIdent(fk.Name), fk.Definition)
+					continue
+				}
+				fmt.Fprintf(bw, "\nALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+					inspect.QualifiedName(t.Schema, t.Name), inspect.QuoteIdent(fk.Name), sqlIdents(fk.Columns),
+					inspect.QualifiedName(fk.RefSchema, fk.RefTable), sqlIdents(fk.RefColumns))
+				if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+					fmt.Fprintf(bw, " ON UPDATE %s", fk.OnUpdate)
+				}
+				if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+					fmt.Fprintf(bw, " ON DELETE %s", fk.OnDelete)
+				}
+				bw.WriteString(";\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// tablesByPartitionDepth returns the base tables of db with tables that are
+// not partitions first, then partitions of those, and so on. Partitions of
+// tables outside db come last.
+func tablesByPartitionDepth(db *inspect.Database) []*inspect.Table {
+	var tables, partitions []*inspect.Table
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			switch {
+			case t.Type != "BASE TABLE":
+			case t.PartitionOf == "":
+				tables = append(tables, t)
+			default:
+				partitions = append(partitions, t)
+			}
+		}
+	}
+	created := make(map[string]bool)
+	for _, t := range tables {
+		created[t.Schema+"."+t.Name] = true
+	}
+	for len(partitions) > 0 {
+		var next []*inspect.Table
+		for _, t := range partitions {
+			if created[t.PartitionOf] {
+				tables = append(tables, t)
+				created[t.Schema+"."+t.Name] = true
+			} else {
+				next = append(next, t)
+			}
+		}
+		if len(next) == len(partitions) {
+			return append(tables, next...)
+		}
+		partitions = next
+	}
+	return tables
+}
+
+// writeCreateTable writes the CREATE TABLE statement of t and its indexes.
+// Partitions get their columns, keys, constraints and indexes from the
+// parent and are written with PARTITION OF alone.
+func writeCreateTable(w *bufio.Writer, t *inspect.Table) {
+	if t.PartitionOf != "" {
+		parent := strings.SplitN(t.PartitionOf, ".", 2)
+		fmt.Fprintf(w, "\nCREATE TABLE %s PARTITION OF %s %s", inspect.QualifiedName(t.Schema, t.Name),
+			inspect.QualifiedName(parent[0], parent[1]), t.PartitionBound)
+		if t.PartitionKey != "" {
+			fmt.Fprintf(w, " PARTITION BY %s", t.PartitionKey)
+		}
+		w.WriteString(";\n")
+		return
+	}
+	var lines []string
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		line := inspect.QuoteIdent(c.Name) + " " + sqlType(c)
+		if !c.Nullable {
+			line += " NOT NULL"
+		}
+		switch {
+		case c.Identity != "":
+			line += " GENERATED " + c.Identity + " AS IDENTITY"
+		case c.Generated != "":
+			line += " GENERATED ALWAYS AS (" + c.Generated + ") STORED"
+		case c.Default != "":
+			line += " DEFAULT " + c.Default
+		}
+		lines = append(lines, line)
+	}
+	if len(t.PK.Columns) > 0 {
+		lines = append(lines, fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", inspect.QuoteIdent(t.PK.Name), sqlIdents(t.PK.Columns)))
+	}
+	constraints := make(map[string]bool)
+	for _, c := range t.Constraints {
+		constraints[c.Name] = true
+		lines = append(lines, fmt.Sprintf("CONSTRAINT %s %s", inspect.QuoteIdent(c.Name), c.Definition))
+	}
+	fmt.Fprintf(w, "\nCREATE TABLE %s (\n    %s\n)", inspect.QualifiedName(t.Schema, t.Name),
+		strings.Join(lines, ",\n    "))
+	if t.PartitionKey != "" {
+		fmt.Fprintf(w, " PARTITION BY %s", t.PartitionKey)
+	}
+	w.WriteString(";\n")
+
+	for _, idx := range t.Indexes {
+		// Indexes of primary keys, unique and exclusion constraints come
+		// with them.
+		if idx.Primary || constraints[idx.Name] || idx.Constraint != nil && constraints[idx.Constraint.Name] {
+			continue
+		}
+		fmt.Fprintf(w, "%s;\n", idx.Definition)
+	}
+}
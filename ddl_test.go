@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func TestWriteDDLSequences(t *testing.T) {
+	orders := &inspect.Table{Schema: "public", Name: "orders", Type: "BASE TABLE",
+		Columns: []inspect.Column{
+			{Name: "id", DataType: "bigint", UDTName: "int8", Position: 1, Default: "nextval('orders_id_seq'::regclass)"},
+			{Name: "number", DataType: "bigint", UDTName: "int8", Position: 2, Default: "nextval('order_numbers'::regclass)"},
+		},
+		PK: inspect.PrimaryKey{Name: "orders_pkey", Columns: []string{"id"}},
+	}
+	users := &inspect.Table{Schema: "public", Name: "users", Type: "BASE TABLE",
+		Columns: []inspect.Column{{Name: "id", DataType: "bigint", UDTName: "int8", Position: 1, Identity: "BY DEFAULT"}},
+	}
+	db := &inspect.Database{Name: "app", Schemas: []*inspect.Schema{{Name: "public",
+		Tables: []*inspect.Table{orders, users},
+		Sequences: []inspect.Sequence{
+			{Name: "order_numbers", DataType: "bigint", Increment: 10, MaxValue: 999999},
+			{Name: "orders_id_seq", DataType: "bigint", Increment: 1, MaxValue: math.MaxInt64, OwnedBy: "public.orders.id"},
+			{Name: "users_id_seq", DataType: "bigint", Increment: 1, OwnedBy: "public.users.id"},
+		},
+	}}}
+	var b bytes.Buffer
+	if err := writeDDL(&b, db); err != nil {
+		t.Fatal(err)
+	}
+	ddl := b.String()
+	order := []string{
+		"CREATE SEQUENCE public.order_numbers AS bigint INCREMENT BY 10 MAXVALUE 999999;",
+		"CREATE SEQUENCE public.orders_id_seq AS bigint;",
+		"CREATE TABLE public.orders (",
+		"ALTER SEQUENCE public.orders_id_seq OWNED BY public.orders.id;",
+	}
+	assertInOrder(t, ddl, order)
+	if strings.Contains(ddl, "users_id_seq") {
+		t.Errorf("DDL creates the sequence of an identity column:\n%s", ddl)
+	}
+}
+
+func TestWriteDDLPartitions(t *testing.T) {
+	column := []inspect.Column{
+		{Name: "region", DataType: "text", UDTName: "text", Position: 1},
+		{Name: "created_at", DataType: "timestamp with time zone", UDTName: "timestamptz", Position: 2},
+	}
+	db := &inspect.Database{Name: "app", Schemas: []*inspect.Schema{
+		{Name: "archive", Tables: []*inspect.Table{
+			{Schema: "archive", Name: "events_2023", Type: "BASE TABLE", Columns: column,
+				PartitionOf: "public.events_eu", PartitionBound: "FOR VALUES FROM ('2023-01-01') TO ('2024-01-01')"},
+		}},
+		{Name: "public", Tables: []*inspect.Table{
+			{Schema: "public", Name: "events", Type: "BASE TABLE", Columns: column, PartitionKey: "LIST (region)"},
+			{Schema: "public", Name: "events_eu", Type: "BASE TABLE", Columns: column,
+				PartitionOf: "public.events", PartitionBound: "FOR VALUES IN ('eu')", PartitionKey: "RANGE (created_at)"},
+		}},
+	}}
+	var b bytes.Buffer
+	if err := writeDDL(&b, db); err != nil {
+		t.Fatal(err)
+	}
+	assertInOrder(t, b.String(), []string{
+		"CREATE TABLE public.events (\n    region text NOT NULL,\n    created_at timestamp with time zone NOT NULL\n) PARTITION BY LIST (region);",
+		"CREATE TABLE public.events_eu PARTITION OF public.events FOR VALUES IN ('eu') PARTITION BY RANGE (created_at);",
+		"CREATE TABLE archive.events_2023 PARTITION OF public.events_eu FOR VALUES FROM ('2023-01-01') TO ('2024-01-01');",
+	})
+}
+
+func TestWriteDDLConstraints(t *testing.T) {
+	bookings := &inspect.Table{Schema: "public", Name: "bookings", Type: "BASE TABLE",
+		Columns: []inspect.Column{
+			{Name: "room_id", DataType: "bigint", UDTName: "int8", Position: 1},
+			{Name: "during", DataType: "tstzrange", UDTName: "tstzrange", Position: 2},
+			{Name: "tags", DataType: "ARRAY", UDTName: "_tag", UDTSchema: "rooms", Position: 3, Nullable: true},
+			{Name: "notes", DataType: "ARRAY", UDTName: "_text", UDTSchema: "pg_catalog", Position: 4, Nullable: true},
+		},
+		Constraints: []inspect.Constraint{{Name: "bookings_no_overlap", Type: "EXCLUDE", Columns: []string{"room_id", "during"},
+			Definition: "EXCLUDE USING gist (room_id WITH =, during WITH &&)"}},
+		Indexes: []inspect.Index{{Name: "bookings_no_overlap", Method: "gist", Columns: []string{"room_id", "during"},
+			Definition: "CREATE INDEX bookings_no_overlap ON public.bookings USING gist (room_id, during)"}},
+		FKs: []inspect.ForeignKey{
+			{Name: "bookings_room_id_fkey", Columns: []string{"room_id"}, RefSchema: "rooms", RefTable: "rooms",
+				RefColumns: []string{"id"}, OnUpdate: "NO ACTION", OnDelete: "NO ACTION",
+				Definition: "FOREIGN KEY (room_id) REFERENCES rooms.rooms(id) MATCH FULL DEFERRABLE INITIALLY DEFERRED NOT VALID"},
+			{Name: "bookings_legacy_fkey", Columns: []string{"room_id"}, RefSchema: "rooms", RefTable: "rooms",
+				RefColumns: []string{"id"}, OnUpdate: "NO ACTION", OnDelete: "CASCADE"},
+		},
+	}
+	db := &inspect.Database{Name: "app", Schemas: []*inspect.Schema{{Name: "public", Tables: []*inspect.Table{bookings}}}}
+	var b bytes.Buffer
+	if err := writeDDL(&b, db); err != nil {
+		t.Fatal(err)
+	}
+	ddl := b.String()
+	assertInOrder(t, ddl, []string{
+		"    tags rooms.tag[],\n    notes text[],\n",
+		"    CONSTRAINT bookings_no_overlap EXCLUDE USING gist (room_id WITH =, during WITH &&)\n);",
+		"ALTER TABLE public.bookings ADD CONSTRAINT bookings_room_id_fkey FOREIGN KEY (room_id) REFERENCES rooms.rooms(id) " +
+			"MATCH FULL DEFERRABLE INITIALLY DEFERRED NOT VALID;",
+		"ALTER TABLE public.bookings ADD CONSTRAINT bookings_legacy_fkey FOREIGN KEY (room_id) REFERENCES rooms.rooms (id) " +
+			"ON DELETE CASCADE;",
+	})
+	if strings.Contains(ddl, "CREATE INDEX bookings_no_overlap") {
+		t.Errorf("DDL creates the index of an exclusion constraint:\n%s", ddl)
+	}
+}
+
+// assertInOrder fails unless s contains parts in the order given.
+func assertInOrder(t *testing.T, s string, parts []string) {
+	t.Helper()
+	at := 0
+	for _, p := range parts {
+		i := strings.Index(s[at:], p)
+		if i < 0 {
+			t.Fatalf("missing %q after offset %d in:\n%s", p, at, s)
+		}
+		at += i + len(p)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// findTable looks up a table by "schema.table", a name without a schema
+// refers to the public schema.
+func findTable(db *inspect.Database, name string) *inspect.Table {
+	schema, table := "public", name
+	if i := strings.Index(name, "."); i >= 0 {
+		schema, table = name[:i], name[i+1:]
+	}
+	s := db.Schema(schema)
+	if s == nil {
+		return nil
+	}
+	return s.Table(table)
+}
+
+func fkDefinition(fk inspect.ForeignKey) string {
+	s := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", sqlIdents(fk.Columns),
+		inspect.QualifiedName(fk.RefSchema, fk.RefTable), sqlIdents(fk.RefColumns))
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		s += " ON UPDATE " + fk.OnUpdate
+	}
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		s += " ON DELETE " + fk.OnDelete
+	}
+	return s
+}
+
+// writeDescription prints t in the spirit of psql's \d+.
+func writeDescription(w io.Writer, t *inspect.Table) error {
+	kind := strings.TrimPrefix(t.Type, "BASE ")
+	fmt.Fprintf(w, "%s %q\n", strings.Title(strings.ToLower(kind)), qualifiedName(t))
+	fmt.Fprintf(w, "Owner: %s", t.Owner)
+	if t.Tablespace != "" {
+		fmt.Fprintf(w, ", tablespace: %s", t.Tablespace)
+	}
+	fmt.Fprintf(w, ", size: %s, ~%d rows\n", humanBytes(t.Stats.SizeBytes), t.Stats.RowEstimate)
+	if t.Toast != "" {
+		fmt.Fprintf(w, "TOAST: %s, %s\n", t.Toast, humanBytes(t.Stats.ToastBytes))
+	}
+	if t.Comment != "" {
+		fmt.Fprintf(w, "Comment: %s\n", t.Comment)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Column\tType\tNullable\tDefault\tStorage\tCompression\tComment")
+	fmt.Fprintln(tw, "------\t----\t--------\t-------\t-------\t-----------\t-------")
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		nullable := ""
+		if !c.Nullable {
+			nullable = "not null"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Name, c.TypeName(), nullable, c.Default, c.Storage,
+			c.Compression, c.Comment)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	if len(t.Indexes) > 0 {
+		fmt.Fprintln(w, "Indexes:")
+		for _, ix := range t.Indexes {
+			fmt.Fprintf(w, "    %s\n", ix.Definition)
+		}
+	}
+	var checks []inspect.Constraint
+	for _, c := range t.Constraints {
+		if c.Type == "CHECK" {
+			checks = append(checks, c)
+		}
+	}
+	if len(checks) > 0 {
+		fmt.Fprintln(w, "Check constraints:")
+		for _, c := range checks {
+			fmt.Fprintf(w, "    %q %s\n", c.Name, c.Definition)
+		}
+	}
+	if len(t.FKs) > 0 {
+		fmt.Fprintln(w, "Foreign-key constraints:")
+		for _, fk := range t.FKs {
+			fmt.Fprintf(w, "    %q %s\n", fk.Name, fkDefinition(fk))
+		}
+	}
+	if len(t.ReferencedBy) > 0 {
+		fmt.Fprintln(w, "Referenced by:")
+		for _, r := range t.ReferencedBy {
+			fmt.Fprintf(w, "    TABLE \"%s.%s\" CONSTRAINT %q %s\n", r.Schema, r.Table, r.FK.Name, fkDefinition(r.FK))
+		}
+	}
+	if len(t.Triggers) > 0 {
+		fmt.Fprintln(w, "Triggers:")
+		for _, tg := range t.Triggers {
+			disabled := ""
+			if !tg.Enabled {
+				disabled = " (disabled)"
+			}
+			fmt.Fprintf(w, "    %s%s\n", tg.Definition, disabled)
+		}
+	}
+	if len(t.Rules) > 0 {
+		fmt.Fprintln(w, "Rules:")
+		for _, r := range t.Rules {
+			disabled := ""
+			if !r.Enabled {
+				disabled = " (disabled)"
+			}
+			fmt.Fprintf(w, "    %s%s\n", r.Definition, disabled)
+		}
+	}
+	if t.RowSecurity {
+		forced := ""
+		if t.ForceRowSecurity {
+			forced = ", forced"
+		}
+		fmt.Fprintf(w, "Policies (row security enabled%s):\n", forced)
+		if len(t.Policies) == 0 {
+			fmt.Fprintln(w, "    (none)")
+		}
+		for _, p := range t.Policies {
+			kind := ""
+			if !p.Permissive {
+				kind = " AS RESTRICTIVE"
+			}
+			fmt.Fprintf(w, "    POLICY %q%s FOR %s TO %s", p.Name, kind, p.Command, strings.Join(p.Roles, ", "))
+			if p.Using != "" {
+				fmt.Fprintf(w, " USING (%s)", p.Using)
+			}
+			if p.Check != "" {
+				fmt.Fprintf(w, " WITH CHECK (%s)", p.Check)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+func (a *app) runDescribe(args []string) {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		a.log.Fatal("usage: describe schema.table")
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	t := findTable(db, fs.Arg(0))
+	if t == nil {
+		a.log.Fatalf("table %s not found", fs.Arg(0))
+	}
+	if err := writeDescription(os.Stdout, t); err != nil {
+		a.log.WithError(err).Fatal("describe")
+	}
+}
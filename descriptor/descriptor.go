@@ -0,0 +1,138 @@
+// Package descriptor defines the schema descriptor, a documented
+// interchange format for inspected databases. Its JSON Schema is in
+// schema.json next to this file; YAML and JSON encodings share it.
+package descriptor
+
+import "github.com/datainq/pq-inspector/inspect"
+
+// Version is the version of the descriptor format.
+const Version = 1
+
+type Descriptor struct {
+	Version  int      `json:"version" yaml:"version"`
+	Database string   `json:"database" yaml:"database"`
+	Schemas  []Schema `json:"schemas" yaml:"schemas"`
+}
+
+type Schema struct {
+	Name   string  `json:"name" yaml:"name"`
+	Tables []Table `json:"tables" yaml:"tables"`
+}
+
+type Table struct {
+	Name    string `json:"name" yaml:"name"`
+	Kind    string `json:"kind" yaml:"kind"` // table, view, materialized_view, foreign_table or temporary
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	// Annotations are the @key=value words of the comment.
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Columns     []Column          `json:"columns" yaml:"columns"`
+	PrimaryKey  []string          `json:"primary_key,omitempty" yaml:"primary_key,omitempty"`
+	Relations   []Relation        `json:"relations,omitempty" yaml:"relations,omitempty"`
+	Indexes     []Index           `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+}
+
+type Column struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type" yaml:"type"` // e.g. int8, varchar(20), numeric(10,2)
+	Nullable bool   `json:"nullable" yaml:"nullable"`
+	Default  string `json:"default,omitempty" yaml:"default,omitempty"`
+	// DefaultKind classifies the default, see inspect.Column.DefaultKind.
+	DefaultKind string `json:"default_kind,omitempty" yaml:"default_kind,omitempty"`
+	Comment     string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	// Annotations are the @key=value words of the comment.
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	// ReadOnly is set on generated and GENERATED ALWAYS identity columns,
+	// which clients cannot write.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	// Values are the values a check constraint allows, an enum.
+	Values []string `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// Relation is a foreign key from Columns of the table to Columns of the
+// referenced table.
+type Relation struct {
+	Name       string    `json:"name" yaml:"name"`
+	Columns    []string  `json:"columns" yaml:"columns"`
+	References Reference `json:"references" yaml:"references"`
+	OnUpdate   string    `json:"on_update" yaml:"on_update"`
+	OnDelete   string    `json:"on_delete" yaml:"on_delete"`
+}
+
+type Reference struct {
+	Schema  string   `json:"schema" yaml:"schema"`
+	Table   string   `json:"table" yaml:"table"`
+	Columns []string `json:"columns" yaml:"columns"`
+}
+
+type Index struct {
+	Name      string   `json:"name" yaml:"name"`
+	Method    string   `json:"method" yaml:"method"`
+	Columns   []string `json:"columns" yaml:"columns"`
+	Unique    bool     `json:"unique" yaml:"unique"`
+	Predicate string   `json:"predicate,omitempty" yaml:"predicate,omitempty"`
+}
+
+var tableKinds = map[string]string{
+	"BASE TABLE":        "table",
+	"VIEW":              "view",
+	"MATERIALIZED VIEW": "materialized_view",
+	"FOREIGN TABLE":     "foreign_table",
+	"LOCAL TEMPORARY":   "temporary",
+}
+
+// FromDatabase describes an inspected database.
+func FromDatabase(db *inspect.Database) *Descriptor {
+	d := &Descriptor{Version: Version, Database: db.Name, Schemas: []Schema{}}
+	for _, s := range db.Schemas {
+		ds := Schema{Name: s.Name, Tables: []Table{}}
+		for _, t := range s.Tables {
+			ds.Tables = append(ds.Tables, fromTable(t))
+		}
+		d.Schemas = append(d.Schemas, ds)
+	}
+	return d
+}
+
+func fromTable(t *inspect.Table) Table {
+	dt := Table{
+		Name:        t.Name,
+		Kind:        tableKinds[t.Type],
+		Comment:     t.Comment,
+		Annotations: t.Annotations,
+		Columns:     []Column{},
+		PrimaryKey:  t.PK.Columns,
+	}
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		dt.Columns = append(dt.Columns, Column{
+			Name:        c.Name,
+			Type:        c.TypeName(),
+			Nullable:    c.Nullable,
+			Default:     c.Default,
+			DefaultKind: c.DefaultKind(),
+			Comment:     c.Comment,
+			Annotations: c.Annotations,
+			ReadOnly:    c.ReadOnly(),
+			Values:      c.AllowedValues,
+		})
+	}
+	for _, fk := range t.FKs {
+		dt.Relations = append(dt.Relations, Relation{
+			Name:       fk.Name,
+			Columns:    fk.Columns,
+			References: Reference{Schema: fk.RefSchema, Table: fk.RefTable, Columns: fk.RefColumns},
+			OnUpdate:   fk.OnUpdate,
+			OnDelete:   fk.OnDelete,
+		})
+	}
+	for _, idx := range t.Indexes {
+		dt.Indexes = append(dt.Indexes, Index{
+			Name:      idx.Name,
+			Method:    idx.Method,
+			Columns:   idx.Columns,
+			Unique:    idx.Unique,
+			Predicate: idx.Predicate,
+		})
+	}
+	return dt
+}
@@ -0,0 +1,256 @@
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violation is a difference between a desired descriptor and a database.
+type Violation struct {
+	Rule   string `json:"rule" yaml:"rule"`     // missing, unexpected or mismatch
+	Object string `json:"object" yaml:"object"` // schema, table, column, primary_key, relation or index
+	Name   string `json:"name" yaml:"name"`     // qualified name of the object
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s %s %s", v.Rule, v.Object, v.Name)
+	if v.Detail != "" {
+		s += ": " + v.Detail
+	}
+	return s
+}
+
+// VerifyOptions select the objects a database may have beyond those of
+// the desired descriptor.
+type VerifyOptions struct {
+	ExtraSchemas   bool
+	ExtraTables    bool
+	ExtraColumns   bool
+	ExtraRelations bool
+	ExtraIndexes   bool
+}
+
+// Verify checks that got, usually FromDatabase of a live database,
+// conforms to want: every schema, table, column, relation and index of
+// want exists with the same definition, and got has no other objects
+// unless the options permit them. Attributes left empty in want, such as
+// a column type or an index name, match anything.
+func Verify(want, got *Descriptor, opts VerifyOptions) []Violation {
+	var out []Violation
+	for _, ws := range want.Schemas {
+		gs := got.schema(ws.Name)
+		if gs == nil {
+			out = append(out, Violation{Rule: "missing", Object: "schema", Name: ws.Name})
+			continue
+		}
+		for _, wt := range ws.Tables {
+			gt := gs.table(wt.Name)
+			if gt == nil {
+				out = append(out, Violation{Rule: "missing", Object: "table", Name: ws.Name + "." + wt.Name, Detail: wt.Kind})
+				continue
+			}
+			out = append(out, verifyTable(ws.Name+"."+wt.Name, &wt, gt, opts)...)
+		}
+		if !opts.ExtraTables {
+			for _, gt := range gs.Tables {
+				if ws.table(gt.Name) == nil {
+					out = append(out, Violation{Rule: "unexpected", Object: "table", Name: gs.Name + "." + gt.Name, Detail: gt.Kind})
+				}
+			}
+		}
+	}
+	if !opts.ExtraSchemas {
+		for _, gs := range got.Schemas {
+			if want.schema(gs.Name) == nil {
+				out = append(out, Violation{Rule: "unexpected", Object: "schema", Name: gs.Name})
+			}
+		}
+	}
+	return out
+}
+
+func verifyTable(name string, want, got *Table, opts VerifyOptions) []Violation {
+	var out []Violation
+	mismatch := func(object, name, format string, args ...interface{}) {
+		out = append(out, Violation{Rule: "mismatch", Object: object, Name: name, Detail: fmt.Sprintf(format, args...)})
+	}
+	if want.Kind != "" && want.Kind != got.Kind {
+		mismatch("table", name, "kind %s, want %s", got.Kind, want.Kind)
+	}
+	if len(want.PrimaryKey) > 0 && !sameStrings(want.PrimaryKey, got.PrimaryKey) {
+		mismatch("primary_key", name, "(%s), want (%s)", strings.Join(got.PrimaryKey, ", "), strings.Join(want.PrimaryKey, ", "))
+	}
+
+	for _, wc := range want.Columns {
+		cname := name + "." + wc.Name
+		gc := got.column(wc.Name)
+		if gc == nil {
+			out = append(out, Violation{Rule: "missing", Object: "column", Name: cname, Detail: wc.Type})
+			continue
+		}
+		if wc.Type != "" && wc.Type != gc.Type {
+			mismatch("column", cname, "type %s, want %s", gc.Type, wc.Type)
+		}
+		if wc.Nullable != gc.Nullable {
+			mismatch("column", cname, "nullable %t, want %t", gc.Nullable, wc.Nullable)
+		}
+		if wc.Default != "" && wc.Default != gc.Default {
+			mismatch("column", cname, "default %q, want %q", gc.Default, wc.Default)
+		}
+		if len(wc.Values) > 0 && !sameStrings(wc.Values, gc.Values) {
+			mismatch("column", cname, "values (%s), want (%s)", strings.Join(gc.Values, ", "), strings.Join(wc.Values, ", "))
+		}
+	}
+	if !opts.ExtraColumns {
+		for _, gc := range got.Columns {
+			if want.column(gc.Name) == nil {
+				out = append(out, Violation{Rule: "unexpected", Object: "column", Name: name + "." + gc.Name, Detail: gc.Type})
+			}
+		}
+	}
+
+	matched := make(map[int]bool)
+	for _, wr := range want.Relations {
+		i := got.relation(wr)
+		if i < 0 {
+			out = append(out, Violation{Rule: "missing", Object: "relation", Name: name + "." + relationName(wr), Detail: wr.describe()})
+			continue
+		}
+		matched[i] = true
+		gr := got.Relations[i]
+		if wr.describe() != gr.describe() {
+			mismatch("relation", name+"."+relationName(wr), "%s, want %s", gr.describe(), wr.describe())
+		}
+		if wr.OnUpdate != "" && wr.OnUpdate != gr.OnUpdate {
+			mismatch("relation", name+"."+relationName(wr), "on update %s, want %s", gr.OnUpdate, wr.OnUpdate)
+		}
+		if wr.OnDelete != "" && wr.OnDelete != gr.OnDelete {
+			mismatch("relation", name+"."+relationName(wr), "on delete %s, want %s", gr.OnDelete, wr.OnDelete)
+		}
+	}
+	if !opts.ExtraRelations {
+		for i, gr := range got.Relations {
+			if !matched[i] {
+				out = append(out, Violation{Rule: "unexpected", Object: "relation", Name: name + "." + gr.Name, Detail: gr.describe()})
+			}
+		}
+	}
+
+	matched = make(map[int]bool)
+	for _, wi := range want.Indexes {
+		i := got.index(wi)
+		if i < 0 {
+			out = append(out, Violation{Rule: "missing", Object: "index", Name: name + "." + indexName(wi), Detail: wi.describe()})
+			continue
+		}
+		matched[i] = true
+		gi := got.Indexes[i]
+		if !sameStrings(wi.Columns, gi.Columns) || wi.Unique != gi.Unique ||
+			wi.Method != "" && wi.Method != gi.Method || wi.Predicate != "" && wi.Predicate != gi.Predicate {
+			mismatch("index", name+"."+indexName(wi), "%s, want %s", gi.describe(), wi.describe())
+		}
+	}
+	if !opts.ExtraIndexes {
+		for i, gi := range got.Indexes {
+			if !matched[i] {
+				out = append(out, Violation{Rule: "unexpected", Object: "index", Name: name + "." + gi.Name, Detail: gi.describe()})
+			}
+		}
+	}
+	return out
+}
+
+func (d *Descriptor) schema(name string) *Schema {
+	for i := range d.Schemas {
+		if d.Schemas[i].Name == name {
+			return &d.Schemas[i]
+		}
+	}
+	return nil
+}
+
+func (s *Schema) table(name string) *Table {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+func (t *Table) column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// relation returns the index of the relation matching r by name or, if r
+// has none, by columns, or -1.
+func (t *Table) relation(r Relation) int {
+	for i, gr := range t.Relations {
+		if r.Name != "" && gr.Name == r.Name || r.Name == "" && sameStrings(gr.Columns, r.Columns) {
+			return i
+		}
+	}
+	return -1
+}
+
+// index returns the index of the index matching idx by name or, if idx
+// has none, by columns, or -1.
+func (t *Table) index(idx Index) int {
+	for i, gi := range t.Indexes {
+		if idx.Name != "" && gi.Name == idx.Name || idx.Name == "" && sameStrings(gi.Columns, idx.Columns) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r Relation) describe() string {
+	return fmt.Sprintf("(%s) -> %s.%s(%s)", strings.Join(r.Columns, ", "), r.References.Schema, r.References.Table,
+		strings.Join(r.References.Columns, ", "))
+}
+
+func (idx Index) describe() string {
+	s := "(" + strings.Join(idx.Columns, ", ") + ")"
+	if idx.Method != "" {
+		s = idx.Method + " " + s
+	}
+	if idx.Unique {
+		s = "unique " + s
+	}
+	if idx.Predicate != "" {
+		s += " WHERE " + idx.Predicate
+	}
+	return s
+}
+
+func relationName(r Relation) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return "(" + strings.Join(r.Columns, ", ") + ")"
+}
+
+func indexName(idx Index) string {
+	if idx.Name != "" {
+		return idx.Name
+	}
+	return "(" + strings.Join(idx.Columns, ", ") + ")"
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
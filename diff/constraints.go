@@ -0,0 +1,139 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// tableConstraint is a primary key, unique or check constraint, foreign key
+// or index flattened for comparison.
+type tableConstraint struct {
+	kind string
+	name string
+	def  string // the definition without names of the table or constraint
+	// unique is set for keys, indexes and exclusion constraints which
+	// reject duplicate or overlapping values.
+	unique bool
+}
+
+func tableConstraints(t *inspect.Table) []tableConstraint {
+	var cs []tableConstraint
+	if len(t.PK.Columns) > 0 {
+		cs = append(cs, tableConstraint{"primary key", t.PK.Name, "(" + strings.Join(t.PK.Columns, ", ") + ")", true})
+	}
+	constraints := make(map[string]bool)
+	for _, c := range t.Constraints {
+		constraints[c.Name] = true
+		cs = append(cs, tableConstraint{strings.ToLower(c.Type) + " constraint", c.Name, c.Definition,
+			c.Type == "UNIQUE" || c.Type == "EXCLUDE"})
+	}
+	for _, fk := range t.FKs {
+		// Foreign keys within the schema of the table are compared by table
+		// name only, so tables of different schemas compare alike.
+		ref := fk.RefTable
+		if fk.RefSchema != t.Schema {
+			ref = fk.RefSchema + "." + ref
+		}
+		def := fmt.Sprintf("(%s) REFERENCES %s(%s)", strings.Join(fk.Columns, ", "), ref, strings.Join(fk.RefColumns, ", "))
+		if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+			def += " ON UPDATE " + fk.OnUpdate
+		}
+		if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+			def += " ON DELETE " + fk.OnDelete
+		}
+		cs = append(cs, tableConstraint{"foreign key", fk.Name, def, false})
+	}
+	for _, idx := range t.Indexes {
+		// Indexes of keys and unique constraints change with them.
+		if idx.Primary || constraints[idx.Name] {
+			continue
+		}
+		def := fmt.Sprintf("USING %s (%s)", idx.Method, strings.Join(idx.Columns, ", "))
+		if idx.Unique {
+			def = "UNIQUE " + def
+		}
+		if idx.Predicate != "" {
+			def += " WHERE " + idx.Predicate
+		}
+		cs = append(cs, tableConstraint{"index", idx.Name, def, idx.Unique})
+	}
+	return cs
+}
+
+// dropBreaks reports whether dropping c can break clients relying on it:
+// keys, unique constraints and indexes and foreign keys.
+func (c tableConstraint) dropBreaks() bool {
+	return c.unique || c.kind == "foreign key"
+}
+
+// addBreaks reports whether adding c to table, as it was before, may fail
+// on or reject existing rows. NOT VALID constraints skip the existing rows.
+func (c tableConstraint) addBreaks(table *inspect.Table) bool {
+	return (c.unique || c.kind != "index") && !strings.HasSuffix(c.def, " NOT VALID") && populated(table)
+}
+
+// compareConstraints returns the changes to the keys, constraints and
+// indexes of table from into those of to. Constraints are matched by name;
+// a dropped and an added one of the same definition are a rename.
+func compareConstraints(from, to *inspect.Table, opts Options) []Change {
+	if !opts.compares("constraints") {
+		return nil
+	}
+	name, oldName := to.Schema+"."+to.Name, from.Schema+"."+from.Name
+	old := make(map[string]tableConstraint)
+	for _, c := range tableConstraints(from) {
+		old[c.kind+"\x00"+c.name] = c
+	}
+	var changes []Change
+	var added []tableConstraint
+	seen := make(map[string]bool)
+	for _, c := range tableConstraints(to) {
+		if opts.ignored(name + "." + c.name) {
+			continue
+		}
+		id := c.kind + "\x00" + c.name
+		seen[id] = true
+		prev, ok := old[id]
+		switch {
+		case !ok:
+			added = append(added, c)
+		case prev.def != c.def:
+			changes = append(changes, Change{Kind: Modified, Object: c.kind, Name: name + "." + c.name,
+				Detail: fmt.Sprintf("%s -> %s", prev.def, c.def), Breaking: prev.dropBreaks() || c.addBreaks(from)})
+		}
+	}
+	var removed []tableConstraint
+	for _, c := range tableConstraints(from) {
+		if !seen[c.kind+"\x00"+c.name] && !opts.ignored(oldName+"."+c.name) {
+			removed = append(removed, c)
+		}
+	}
+
+	renamedFrom := make(map[int]bool)
+	renamedTo := make(map[int]bool)
+	for i, r := range removed {
+		for j, a := range added {
+			if !renamedTo[j] && r.kind == a.kind && r.def == a.def {
+				renamedFrom[i], renamedTo[j] = true, true
+				changes = append(changes, Change{Kind: Renamed, Object: a.kind, Name: name + "." + a.name,
+					From: oldName + "." + r.name, Detail: a.def, Confidence: 1})
+				break
+			}
+		}
+	}
+	for i, c := range removed {
+		if !renamedFrom[i] {
+			changes = append(changes, Change{Kind: Removed, Object: c.kind, Name: oldName + "." + c.name,
+				Detail: c.def, Breaking: c.dropBreaks()})
+		}
+	}
+	for j, c := range added {
+		if !renamedTo[j] {
+			changes = append(changes, Change{Kind: Added, Object: c.kind, Name: name + "." + c.name,
+				Detail: c.def, Breaking: c.addBreaks(from)})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,329 @@
+// Package diff compares two inspected databases.
+package diff
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+type Kind string
+
+const (
+	Added    Kind = "added"
+	Removed  Kind = "removed"
+	Modified Kind = "modified"
+	Renamed  Kind = "renamed"
+)
+
+// Change is a single difference between two databases.
+type Change struct {
+	Kind   Kind   `json:"kind"`
+	Object string `json:"object"` // schema, table, column, operator, aggregate, ... or migrations
+	Name   string `json:"name"`   // qualified name of the object
+	Detail string `json:"detail,omitempty"`
+
+	// From is the old qualified name of a renamed object and Confidence
+	// how likely the rename is, from 0 to 1.
+	From       string  `json:"from,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+
+	// Breaking is set for changes that can break existing clients or
+	// data: dropped or renamed objects, narrowed types, dropped keys,
+	// unique constraints and foreign keys and new NOT NULL columns, keys
+	// and constraints on populated tables.
+	Breaking bool `json:"breaking"`
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Kind, c.Object, c.Name)
+	if c.Kind == Renamed {
+		s = fmt.Sprintf("%s %s %s -> %s (confidence %.2f)", c.Kind, c.Object, c.From, c.Name, c.Confidence)
+	}
+	if c.Detail != "" {
+		s += ": " + c.Detail
+	}
+	if c.Breaking {
+		s += " [breaking]"
+	}
+	return s
+}
+
+type Options struct {
+	// DetectRenames reports a dropped and an added object that look alike
+	// as a rename.
+	DetectRenames bool
+	// IgnoreObjects are path.Match patterns of qualified names left out of
+	// the comparison, e.g. "public.django_migrations" or "*.tmp_*".
+	IgnoreObjects []string
+	// IgnoreAttributes are attributes which are not compared, any of:
+	// owner, comment, tablespace, default, nullable, constraints (keys,
+	// constraints and indexes), migrations.
+	IgnoreAttributes []string
+}
+
+func (o Options) ignored(name string) bool {
+	for _, p := range o.IgnoreObjects {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Options) compares(attr string) bool {
+	for _, a := range o.IgnoreAttributes {
+		if a == attr {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare returns the changes needed to turn from into to.
+func Compare(from, to *inspect.Database, opts Options) []Change {
+	var changes []Change
+	for _, s := range from.Schemas {
+		if to.Schema(s.Name) == nil && !opts.ignored(s.Name) {
+			changes = append(changes, Change{Kind: Removed, Object: "schema", Name: s.Name, Breaking: true})
+		}
+	}
+	for _, s := range to.Schemas {
+		if opts.ignored(s.Name) {
+			continue
+		}
+		old := from.Schema(s.Name)
+		if old == nil {
+			changes = append(changes, Change{Kind: Added, Object: "schema", Name: s.Name})
+			old = &inspect.Schema{Name: s.Name, Owner: s.Owner}
+		}
+		if old.Owner != s.Owner && opts.compares("owner") {
+			changes = append(changes, Change{Kind: Modified, Object: "schema", Name: s.Name,
+				Detail: fmt.Sprintf("owner %s -> %s", old.Owner, s.Owner)})
+		}
+		changes = append(changes, compareSchema(old, s, opts)...)
+		changes = append(changes, compareObjects(old, s, opts)...)
+	}
+	if opts.compares("migrations") {
+		changes = append(changes, compareMigrations(from.Migrations, to.Migrations)...)
+	}
+	return changes
+}
+
+// CompareSchemas returns the changes needed to turn the tables of schema
+// from into those of to, which usually has another name.
+func CompareSchemas(from, to *inspect.Schema, opts Options) []Change {
+	return compareSchema(from, to, opts)
+}
+
+func compareSchema(from, to *inspect.Schema, opts Options) []Change {
+	var removed, added []*inspect.Table
+	for _, t := range from.Tables {
+		if to.Table(t.Name) == nil && !opts.ignored(from.Name+"."+t.Name) {
+			removed = append(removed, t)
+		}
+	}
+	for _, t := range to.Tables {
+		if from.Table(t.Name) == nil && !opts.ignored(to.Name+"."+t.Name) {
+			added = append(added, t)
+		}
+	}
+	var renames []rename
+	if opts.DetectRenames {
+		renames = matchRenames(len(removed), len(added), func(i, j int) float64 {
+			return tableSimilarity(removed[i], added[j])
+		})
+	}
+
+	var changes []Change
+	renamedFrom := make(map[int]bool, len(renames))
+	renamedTo := make(map[int]bool, len(renames))
+	for _, r := range renames {
+		renamedFrom[r.from], renamedTo[r.to] = true, true
+		changes = append(changes, Change{Kind: Renamed, Object: "table",
+			Name: to.Name + "." + added[r.to].Name, From: from.Name + "." + removed[r.from].Name,
+			Confidence: r.score, Breaking: true})
+		changes = append(changes, compareTable(removed[r.from], added[r.to], opts)...)
+	}
+	for i, t := range removed {
+		if !renamedFrom[i] {
+			changes = append(changes, Change{Kind: Removed, Object: "table", Name: from.Name + "." + t.Name, Breaking: true})
+		}
+	}
+	for i, t := range added {
+		if !renamedTo[i] {
+			changes = append(changes, Change{Kind: Added, Object: "table", Name: to.Name + "." + t.Name})
+		}
+	}
+	for _, t := range to.Tables {
+		if old := from.Table(t.Name); old != nil && !opts.ignored(to.Name+"."+t.Name) {
+			changes = append(changes, compareTable(old, t, opts)...)
+		}
+	}
+	return changes
+}
+
+// CompareTables returns the changes needed to turn table from into to,
+// which may be in different schemas or have different names.
+func CompareTables(from, to *inspect.Table, opts Options) []Change {
+	return compareTable(from, to, opts)
+}
+
+func compareTable(from, to *inspect.Table, opts Options) []Change {
+	name := to.Schema + "." + to.Name
+	var changes []Change
+	if d := tableDetail(from, to, opts); d.text != "" {
+		changes = append(changes, Change{Kind: Modified, Object: "table", Name: name, Detail: d.text,
+			Breaking: d.breaking})
+	}
+
+	var removed, added []*inspect.Column
+	for i, c := range from.Columns {
+		if to.Column(c.Name) == nil && !opts.ignored(name+"."+c.Name) {
+			removed = append(removed, &from.Columns[i])
+		}
+	}
+	for i, c := range to.Columns {
+		if from.Column(c.Name) == nil && !opts.ignored(name+"."+c.Name) {
+			added = append(added, &to.Columns[i])
+		}
+	}
+	var renames []rename
+	if opts.DetectRenames {
+		renames = matchRenames(len(removed), len(added), func(i, j int) float64 {
+			return columnSimilarity(removed[i], added[j])
+		})
+	}
+	renamedFrom := make(map[int]bool, len(renames))
+	renamedTo := make(map[int]bool, len(renames))
+	for _, r := range renames {
+		renamedFrom[r.from], renamedTo[r.to] = true, true
+		changes = append(changes, Change{Kind: Renamed, Object: "column",
+			Name: name + "." + added[r.to].Name, From: name + "." + removed[r.from].Name,
+			Detail: columnDetail(removed[r.from], added[r.to], from, opts).text, Confidence: r.score,
+			Breaking: true})
+	}
+	for i, c := range removed {
+		if !renamedFrom[i] {
+			changes = append(changes, Change{Kind: Removed, Object: "column", Name: name + "." + c.Name,
+				Breaking: true})
+		}
+	}
+	for i, c := range added {
+		if !renamedTo[i] {
+			changes = append(changes, Change{Kind: Added, Object: "column", Name: name + "." + c.Name,
+				Detail: c.TypeName(), Breaking: !c.Nullable && c.Default == "" && c.Identity == "" && c.Generated == "" && populated(from)})
+		}
+	}
+	for _, c := range to.Columns {
+		old := from.Column(c.Name)
+		if old == nil || opts.ignored(name+"."+c.Name) {
+			continue
+		}
+		if d := columnDetail(old, &c, from, opts); d.text != "" {
+			changes = append(changes, Change{Kind: Modified, Object: "column", Name: name + "." + c.Name,
+				Detail: d.text, Breaking: d.breaking})
+		}
+	}
+	return append(changes, compareConstraints(from, to, opts)...)
+}
+
+// details joins descriptions of modified attributes and tracks whether any
+// of the modifications is breaking.
+type details struct {
+	text     string
+	breaking bool
+}
+
+func (d *details) add(breaking bool, format string, args ...interface{}) {
+	if d.text != "" {
+		d.text += ", "
+	}
+	d.text += fmt.Sprintf(format, args...)
+	d.breaking = d.breaking || breaking
+}
+
+func tableDetail(from, to *inspect.Table, opts Options) details {
+	var d details
+	if from.Type != to.Type {
+		d.add(true, "type %s -> %s", from.Type, to.Type)
+	}
+	if from.PartitionKey != to.PartitionKey {
+		d.add(false, "partitioning %q -> %q", from.PartitionKey, to.PartitionKey)
+	}
+	if from.Owner != to.Owner && opts.compares("owner") {
+		d.add(false, "owner %s -> %s", from.Owner, to.Owner)
+	}
+	if from.Tablespace != to.Tablespace && opts.compares("tablespace") {
+		d.add(false, "tablespace %q -> %q", from.Tablespace, to.Tablespace)
+	}
+	if from.Comment != to.Comment && opts.compares("comment") {
+		d.add(false, "comment %q -> %q", from.Comment, to.Comment)
+	}
+	return d
+}
+
+// columnDetail describes how column from changed into to; table is the
+// column's table before the change.
+func columnDetail(from, to *inspect.Column, table *inspect.Table, opts Options) details {
+	var d details
+	if ft, tt := from.TypeName(), to.TypeName(); ft != tt {
+		d.add(narrows(from, to), "type %s -> %s", ft, tt)
+	}
+	if from.Nullable != to.Nullable && opts.compares("nullable") {
+		d.add(!to.Nullable && populated(table), "nullable %t -> %t", from.Nullable, to.Nullable)
+	}
+	if from.Default != to.Default && opts.compares("default") {
+		d.add(false, "default %q -> %q", from.Default, to.Default)
+	}
+	if from.Comment != to.Comment && opts.compares("comment") {
+		d.add(false, "comment %q -> %q", from.Comment, to.Comment)
+	}
+	return d
+}
+
+// populated reports whether the table may have rows before the change.
+// An estimate of 0 is no proof of an empty table: tables never analyzed
+// have it before PostgreSQL 14, and so do snapshots and golden files
+// without sizes. Only an inspected table with no rows estimated and no
+// pages of its own counts as empty.
+func populated(t *inspect.Table) bool {
+	return t.Stats.RowEstimate != 0 || t.Stats.SizeBytes == 0 || t.Stats.HeapBytes != 0
+}
+
+// Summary is a one line description of changes, e.g. "2 added, 1 removed".
+func Summary(changes []Change) string {
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	counts := make(map[Kind]int)
+	for _, c := range changes {
+		counts[c.Kind]++
+	}
+	var s string
+	for _, k := range []Kind{Added, Removed, Renamed, Modified} {
+		if counts[k] == 0 {
+			continue
+		}
+		if s != "" {
+			s += ", "
+		}
+		s += fmt.Sprintf("%d %s", counts[k], k)
+	}
+	if n := len(Breaking(changes)); n > 0 {
+		s += fmt.Sprintf(" (%d breaking)", n)
+	}
+	return s
+}
+
+// Breaking returns the breaking changes.
+func Breaking(changes []Change) []Change {
+	var breaking []Change
+	for _, c := range changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
@@ -0,0 +1,288 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func testTable(rows int64, modify func(t *inspect.Table)) *inspect.Table {
+	t := &inspect.Table{
+		Schema: "public",
+		Name:   "orders",
+		Type:   "BASE TABLE",
+		Columns: []inspect.Column{
+			{Name: "id", UDTName: "int8", Position: 1},
+			{Name: "customer_id", UDTName: "int8", Position: 2, Nullable: true},
+			{Name: "total", UDTName: "numeric", Position: 3, Nullable: true},
+		},
+		PK: inspect.PrimaryKey{Name: "orders_pkey", Columns: []string{"id"}},
+		Indexes: []inspect.Index{
+			{Name: "orders_pkey", Method: "btree", Columns: []string{"id"}, Unique: true, Primary: true},
+		},
+	}
+	// An inspected table; one without rows has only its primary key's pages.
+	t.Stats = inspect.TableStats{RowEstimate: rows, SizeBytes: 8192}
+	if rows > 0 {
+		t.Stats.SizeBytes += rows * 100
+		t.Stats.HeapBytes = rows * 100
+	}
+	if modify != nil {
+		modify(t)
+	}
+	return t
+}
+
+func customerFK(t *inspect.Table) {
+	t.FKs = append(t.FKs, inspect.ForeignKey{Name: "orders_customer_id_fkey", Columns: []string{"customer_id"},
+		RefSchema: "public", RefTable: "customers", RefColumns: []string{"id"}, OnUpdate: "NO ACTION", OnDelete: "NO ACTION"})
+}
+
+func uniqueTotal(t *inspect.Table) {
+	t.Constraints = append(t.Constraints, inspect.Constraint{Name: "orders_total_key", Type: "UNIQUE",
+		Columns: []string{"total"}, Definition: "UNIQUE (total)"})
+	t.Indexes = append(t.Indexes, inspect.Index{Name: "orders_total_key", Method: "btree",
+		Columns: []string{"total"}, Unique: true})
+}
+
+func positiveTotal(t *inspect.Table) {
+	t.Constraints = append(t.Constraints, inspect.Constraint{Name: "orders_total_check", Type: "CHECK",
+		Columns: []string{"total"}, Definition: "CHECK (total > 0)"})
+}
+
+func customerIndex(name string, unique bool) func(t *inspect.Table) {
+	return func(t *inspect.Table) {
+		t.Indexes = append(t.Indexes, inspect.Index{Name: name, Method: "btree",
+			Columns: []string{"customer_id"}, Unique: unique})
+	}
+}
+
+func TestCompareTableConstraints(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to *inspect.Table
+		want     []Change
+	}{
+		{
+			name: "index added",
+			from: testTable(100, nil),
+			to:   testTable(100, customerIndex("orders_customer_id_idx", false)),
+			want: []Change{{Kind: Added, Object: "index", Name: "public.orders.orders_customer_id_idx",
+				Detail: "USING btree (customer_id)"}},
+		},
+		{
+			name: "index dropped",
+			from: testTable(100, customerIndex("orders_customer_id_idx", false)),
+			to:   testTable(100, nil),
+			want: []Change{{Kind: Removed, Object: "index", Name: "public.orders.orders_customer_id_idx",
+				Detail: "USING btree (customer_id)"}},
+		},
+		{
+			name: "unique index added to populated table",
+			from: testTable(100, nil),
+			to:   testTable(100, customerIndex("orders_customer_id_idx", true)),
+			want: []Change{{Kind: Added, Object: "index", Name: "public.orders.orders_customer_id_idx",
+				Detail: "UNIQUE USING btree (customer_id)", Breaking: true}},
+		},
+		{
+			name: "unique index dropped",
+			from: testTable(0, customerIndex("orders_customer_id_idx", true)),
+			to:   testTable(0, nil),
+			want: []Change{{Kind: Removed, Object: "index", Name: "public.orders.orders_customer_id_idx",
+				Detail: "UNIQUE USING btree (customer_id)", Breaking: true}},
+		},
+		{
+			name: "index renamed",
+			from: testTable(100, customerIndex("orders_customer_idx", false)),
+			to:   testTable(100, customerIndex("orders_customer_id_idx", false)),
+			want: []Change{{Kind: Renamed, Object: "index", Name: "public.orders.orders_customer_id_idx",
+				From: "public.orders.orders_customer_idx", Detail: "USING btree (customer_id)", Confidence: 1}},
+		},
+		{
+			name: "primary key dropped",
+			from: testTable(0, nil),
+			to: testTable(0, func(t *inspect.Table) {
+				t.PK = inspect.PrimaryKey{}
+				t.Indexes = nil
+			}),
+			want: []Change{{Kind: Removed, Object: "primary key", Name: "public.orders.orders_pkey",
+				Detail: "(id)", Breaking: true}},
+		},
+		{
+			name: "primary key columns changed",
+			from: testTable(0, nil),
+			to:   testTable(0, func(t *inspect.Table) { t.PK.Columns = []string{"id", "customer_id"} }),
+			want: []Change{{Kind: Modified, Object: "primary key", Name: "public.orders.orders_pkey",
+				Detail: "(id) -> (id, customer_id)", Breaking: true}},
+		},
+		{
+			name: "unique constraint dropped",
+			from: testTable(0, uniqueTotal),
+			to:   testTable(0, nil),
+			want: []Change{{Kind: Removed, Object: "unique constraint", Name: "public.orders.orders_total_key",
+				Detail: "UNIQUE (total)", Breaking: true}},
+		},
+		{
+			name: "unique constraint added to populated table",
+			from: testTable(100, nil),
+			to:   testTable(100, uniqueTotal),
+			want: []Change{{Kind: Added, Object: "unique constraint", Name: "public.orders.orders_total_key",
+				Detail: "UNIQUE (total)", Breaking: true}},
+		},
+		{
+			name: "check constraint added to empty table",
+			from: testTable(0, nil),
+			to:   testTable(0, positiveTotal),
+			want: []Change{{Kind: Added, Object: "check constraint", Name: "public.orders.orders_total_check",
+				Detail: "CHECK (total > 0)"}},
+		},
+		{
+			name: "check constraint added to populated table",
+			from: testTable(100, nil),
+			to:   testTable(100, positiveTotal),
+			want: []Change{{Kind: Added, Object: "check constraint", Name: "public.orders.orders_total_check",
+				Detail: "CHECK (total > 0)", Breaking: true}},
+		},
+		{
+			name: "check constraint added NOT VALID",
+			from: testTable(100, nil),
+			to: testTable(100, func(t *inspect.Table) {
+				positiveTotal(t)
+				t.Constraints[0].Definition += " NOT VALID"
+			}),
+			want: []Change{{Kind: Added, Object: "check constraint", Name: "public.orders.orders_total_check",
+				Detail: "CHECK (total > 0) NOT VALID"}},
+		},
+		{
+			name: "check constraint dropped",
+			from: testTable(100, positiveTotal),
+			to:   testTable(100, nil),
+			want: []Change{{Kind: Removed, Object: "check constraint", Name: "public.orders.orders_total_check",
+				Detail: "CHECK (total > 0)"}},
+		},
+		{
+			name: "foreign key dropped",
+			from: testTable(0, customerFK),
+			to:   testTable(0, nil),
+			want: []Change{{Kind: Removed, Object: "foreign key", Name: "public.orders.orders_customer_id_fkey",
+				Detail: "(customer_id) REFERENCES customers(id)", Breaking: true}},
+		},
+		{
+			name: "foreign key added to populated table",
+			from: testTable(100, nil),
+			to:   testTable(100, customerFK),
+			want: []Change{{Kind: Added, Object: "foreign key", Name: "public.orders.orders_customer_id_fkey",
+				Detail: "(customer_id) REFERENCES customers(id)", Breaking: true}},
+		},
+		{
+			name: "foreign key action changed",
+			from: testTable(100, customerFK),
+			to:   testTable(100, func(t *inspect.Table) { customerFK(t); t.FKs[0].OnDelete = "CASCADE" }),
+			want: []Change{{Kind: Modified, Object: "foreign key", Name: "public.orders.orders_customer_id_fkey",
+				Detail:   "(customer_id) REFERENCES customers(id) -> (customer_id) REFERENCES customers(id) ON DELETE CASCADE",
+				Breaking: true}},
+		},
+		{
+			name: "same constraints in another schema",
+			from: testTable(100, func(t *inspect.Table) { customerFK(t); uniqueTotal(t) }),
+			to: testTable(100, func(t *inspect.Table) {
+				customerFK(t)
+				uniqueTotal(t)
+				t.Schema, t.FKs[0].RefSchema = "tenant1", "tenant1"
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareTables(tt.from, tt.to, Options{})
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d changes %v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompareTableIgnoreConstraints(t *testing.T) {
+	from := testTable(100, nil)
+	to := testTable(100, func(t *inspect.Table) { customerFK(t); positiveTotal(t) })
+	if got := CompareTables(from, to, Options{IgnoreAttributes: []string{"constraints"}}); len(got) != 0 {
+		t.Errorf("got %v, want no changes", got)
+	}
+}
+
+func TestCompareColumnsBreaking(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     int64
+		modify   func(t *inspect.Table)
+		breaking bool
+	}{
+		{"nullable column added", 100, func(t *inspect.Table) {
+			t.Columns = append(t.Columns, inspect.Column{Name: "note", UDTName: "text", Position: 4, Nullable: true})
+		}, false},
+		{"NOT NULL column added to populated table", 100, func(t *inspect.Table) {
+			t.Columns = append(t.Columns, inspect.Column{Name: "note", UDTName: "text", Position: 4})
+		}, true},
+		{"NOT NULL column added to empty table", 0, func(t *inspect.Table) {
+			t.Columns = append(t.Columns, inspect.Column{Name: "note", UDTName: "text", Position: 4})
+		}, false},
+		{"NOT NULL column with default added", 100, func(t *inspect.Table) {
+			t.Columns = append(t.Columns, inspect.Column{Name: "note", UDTName: "text", Position: 4, Default: "''::text"})
+		}, false},
+		{"column dropped", 0, func(t *inspect.Table) { t.Columns = t.Columns[:2] }, true},
+		{"type widened", 100, func(t *inspect.Table) { t.Columns[1].UDTName = "numeric" }, false},
+		{"type narrowed", 100, func(t *inspect.Table) { t.Columns[1].UDTName = "int4" }, true},
+		{"made NOT NULL", 100, func(t *inspect.Table) { t.Columns[1].Nullable = false }, true},
+		{"made nullable", 100, func(t *inspect.Table) { t.Columns[0].Nullable = true }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := CompareTables(testTable(tt.rows, nil), testTable(tt.rows, tt.modify), Options{})
+			if len(changes) != 1 {
+				t.Fatalf("got changes %v, want one", changes)
+			}
+			if changes[0].Breaking != tt.breaking {
+				t.Errorf("%v: breaking = %t, want %t", changes[0], changes[0].Breaking, tt.breaking)
+			}
+		})
+	}
+}
+
+func TestBreakingByOldStats(t *testing.T) {
+	// The new schema was just migrated, e.g. into a scratch database, so
+	// its tables are empty; the old one decides.
+	notNull := func(t *inspect.Table) { t.Columns[1].Nullable = false }
+	changes := CompareTables(testTable(100, nil), testTable(0, notNull), Options{})
+	if len(changes) != 1 || !changes[0].Breaking {
+		t.Errorf("got %v, want a breaking change", changes)
+	}
+	changes = CompareTables(testTable(0, nil), testTable(100, notNull), Options{})
+	if len(changes) != 1 || changes[0].Breaking {
+		t.Errorf("got %v, want a change which is not breaking", changes)
+	}
+}
+
+func TestPopulated(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats inspect.TableStats
+		want  bool
+	}{
+		{"rows", inspect.TableStats{RowEstimate: 100, SizeBytes: 16384, HeapBytes: 8192}, true},
+		{"empty", inspect.TableStats{SizeBytes: 8192}, false},
+		{"never analyzed", inspect.TableStats{RowEstimate: -1, SizeBytes: 16384, HeapBytes: 8192}, true},
+		{"never analyzed before PostgreSQL 14", inspect.TableStats{SizeBytes: 16384, HeapBytes: 8192}, true},
+		{"rows deleted", inspect.TableStats{SizeBytes: 16384, HeapBytes: 8192}, true},
+		{"no statistics", inspect.TableStats{}, true},
+	}
+	for _, tt := range tests {
+		if got := populated(&inspect.Table{Stats: tt.stats}); got != tt.want {
+			t.Errorf("%s: populated = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// Conflict is an object both sides of a three-way comparison changed in
+// ways that cannot both be applied.
+type Conflict struct {
+	Name   string   `json:"name"`
+	Ours   []Change `json:"ours"`
+	Theirs []Change `json:"theirs"`
+}
+
+func (c Conflict) String() string {
+	s := c.Name
+	for _, ch := range c.Ours {
+		s += "\n  ours:   " + ch.String()
+	}
+	for _, ch := range c.Theirs {
+		s += "\n  theirs: " + ch.String()
+	}
+	return s
+}
+
+// Merged is the result of a three-way comparison.
+type Merged struct {
+	Ours      []Change   `json:"ours"`      // made by ours only
+	Theirs    []Change   `json:"theirs"`    // made by theirs only
+	Both      []Change   `json:"both"`      // made alike by both
+	Conflicts []Conflict `json:"conflicts"` // made differently by both
+}
+
+// changeKeys returns the qualified names a change touches: its name and,
+// for a rename, the old name.
+func changeKeys(c Change) []string {
+	if c.Kind == Renamed {
+		return []string{c.Name, c.From}
+	}
+	return []string{c.Name}
+}
+
+// dropped returns the old name of a removed or renamed object, whose
+// contents the other side must leave alone.
+func dropped(c Change) string {
+	switch c.Kind {
+	case Removed:
+		return c.Name
+	case Renamed:
+		return c.From
+	}
+	return ""
+}
+
+// Merge compares ours and theirs, two databases derived from base such as
+// the databases of two branches and the snapshot they forked from. Changes
+// to the same object conflict unless both sides end up with the same
+// object, and so do changes to the contents of an object the other side
+// removed or renamed, e.g. a column added to a dropped table.
+func Merge(base, ours, theirs *inspect.Database, opts Options) Merged {
+	a, b := Compare(base, ours, opts), Compare(base, theirs, opts)
+	direct := opts
+	direct.DetectRenames = false
+	differ := make(map[string]bool)
+	for _, c := range Compare(ours, theirs, direct) {
+		differ[c.Name] = true
+	}
+
+	inB := make(map[string][]int)
+	for j, c := range b {
+		for _, k := range changeKeys(c) {
+			inB[k] = append(inB[k], j)
+		}
+	}
+	var m Merged
+	conflicts := make(map[string]int)
+	conflictA, conflictB, matchedA, matchedB := make(map[int]bool), make(map[int]bool), make(map[int]bool), make(map[int]bool)
+	conflict := func(name string, i, j int) {
+		n, ok := conflicts[name]
+		if !ok {
+			n = len(m.Conflicts)
+			conflicts[name] = n
+			m.Conflicts = append(m.Conflicts, Conflict{Name: name})
+		}
+		if !conflictA[i] {
+			conflictA[i] = true
+			m.Conflicts[n].Ours = append(m.Conflicts[n].Ours, a[i])
+		}
+		if !conflictB[j] {
+			conflictB[j] = true
+			m.Conflicts[n].Theirs = append(m.Conflicts[n].Theirs, b[j])
+		}
+	}
+
+	for i, c := range a {
+		for _, k := range changeKeys(c) {
+			for _, j := range inB[k] {
+				d := b[j]
+				if c.Kind != d.Kind || c.Name != d.Name || c.From != d.From || differ[c.Name] {
+					conflict(k, i, j)
+				} else {
+					matchedA[i], matchedB[j] = true, true
+				}
+			}
+		}
+	}
+	contained := func(x, y []Change, ours bool) {
+		for i, c := range x {
+			old := dropped(c)
+			if old == "" {
+				continue
+			}
+			for j, d := range y {
+				if d.Kind == Removed {
+					continue
+				}
+				for _, k := range changeKeys(d) {
+					if strings.HasPrefix(k, old+".") {
+						if ours {
+							conflict(old, i, j)
+						} else {
+							conflict(old, j, i)
+						}
+						break
+					}
+				}
+			}
+		}
+	}
+	contained(a, b, true)
+	contained(b, a, false)
+
+	for i, c := range a {
+		switch {
+		case conflictA[i]:
+		case matchedA[i]:
+			m.Both = append(m.Both, c)
+		default:
+			m.Ours = append(m.Ours, c)
+		}
+	}
+	for j, c := range b {
+		if !conflictB[j] && !matchedB[j] {
+			m.Theirs = append(m.Theirs, c)
+		}
+	}
+	return m
+}
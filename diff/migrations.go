@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// compareMigrations reports how many migrations to is behind or ahead of
+// from, per bookkeeping table.
+func compareMigrations(from, to []inspect.Migrations) []Change {
+	var changes []Change
+	for _, f := range from {
+		var t *inspect.Migrations
+		for i := range to {
+			if to[i].Table == f.Table {
+				t = &to[i]
+			}
+		}
+		if t == nil {
+			changes = append(changes, Change{Kind: Removed, Object: "migrations", Name: f.Table})
+			continue
+		}
+		var d details
+		if f.Tool == "golang-migrate" && t.Tool == "golang-migrate" {
+			compareVersion(&d, f.Applied, t.Applied)
+		} else {
+			if missing := subtract(f.Applied, t.Applied); len(missing) > 0 {
+				d.add(false, "%d migrations behind (%s)", len(missing), strings.Join(missing, ", "))
+			}
+			if extra := subtract(t.Applied, f.Applied); len(extra) > 0 {
+				d.add(false, "%d migrations ahead (%s)", len(extra), strings.Join(extra, ", "))
+			}
+		}
+		if t.Dirty && !f.Dirty {
+			d.add(false, "dirty")
+		}
+		if d.text != "" {
+			changes = append(changes, Change{Kind: Modified, Object: "migrations", Name: t.Table, Detail: d.text})
+		}
+	}
+	for _, t := range to {
+		found := false
+		for _, f := range from {
+			found = found || f.Table == t.Table
+		}
+		if !found {
+			changes = append(changes, Change{Kind: Added, Object: "migrations", Name: t.Table,
+				Detail: fmt.Sprintf("%s, %d applied", t.Tool, len(t.Applied))})
+		}
+	}
+	return changes
+}
+
+// maxSequentialVersion is the highest version counted as a sequence number;
+// higher ones are timestamps, e.g. 20240131120000, whose difference is not a
+// number of migrations.
+const maxSequentialVersion = 1000000
+
+// compareVersion describes how far the golang-migrate version of to is from
+// that of from. golang-migrate keeps only the current version, all versions
+// up to it count as applied; an empty table is version 0.
+func compareVersion(d *details, from, to []string) {
+	f, t := "0", "0"
+	if len(from) > 0 {
+		f = from[len(from)-1]
+	}
+	if len(to) > 0 {
+		t = to[len(to)-1]
+	}
+	if f == t {
+		return
+	}
+	fv, ferr := strconv.ParseInt(f, 10, 64)
+	tv, terr := strconv.ParseInt(t, 10, 64)
+	if ferr != nil || terr != nil {
+		d.add(false, "version %q -> %q", f, t)
+		return
+	}
+	dir, n := "behind", fv-tv
+	if n < 0 {
+		dir, n = "ahead", -n
+	}
+	if fv < maxSequentialVersion && tv < maxSequentialVersion {
+		d.add(false, "%d migrations %s (version %d -> %d)", n, dir, fv, tv)
+		return
+	}
+	d.add(false, "migrations %s (version %d -> %d)", dir, fv, tv)
+}
+
+// subtract returns versions in a which are not in b.
+func subtract(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, v := range b {
+		in[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if !in[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
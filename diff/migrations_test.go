@@ -0,0 +1,42 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func TestCompareMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     string
+		from, to []string
+		want     string
+	}{
+		{"same", "goose", []string{"1", "2"}, []string{"1", "2"}, ""},
+		{"behind", "goose", []string{"1", "2", "3"}, []string{"1"}, "2 migrations behind (2, 3)"},
+		{"ahead", "flyway", []string{"1"}, []string{"1", "2"}, "1 migrations ahead (2)"},
+		{"golang-migrate same", "golang-migrate", []string{"8"}, []string{"8"}, ""},
+		{"golang-migrate behind", "golang-migrate", []string{"8"}, []string{"5"}, "3 migrations behind (version 8 -> 5)"},
+		{"golang-migrate ahead", "golang-migrate", []string{"5"}, []string{"8"}, "3 migrations ahead (version 5 -> 8)"},
+		{"golang-migrate timestamps", "golang-migrate", []string{"20240301120000"}, []string{"20240101090000"},
+			"migrations behind (version 20240301120000 -> 20240101090000)"},
+		{"golang-migrate empty", "golang-migrate", nil, []string{"2"}, "2 migrations ahead (version 0 -> 2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := []inspect.Migrations{{Tool: tt.tool, Table: "public.schema_migrations", Applied: tt.from}}
+			to := []inspect.Migrations{{Tool: tt.tool, Table: "public.schema_migrations", Applied: tt.to}}
+			changes := compareMigrations(from, to)
+			if tt.want == "" {
+				if len(changes) != 0 {
+					t.Errorf("got %v, want no changes", changes)
+				}
+				return
+			}
+			if len(changes) != 1 || changes[0].Detail != tt.want {
+				t.Errorf("got %v, want %q", changes, tt.want)
+			}
+		})
+	}
+}
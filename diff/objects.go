@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// objectAttr is a compared attribute of a schema object other than a table.
+type objectAttr struct {
+	name  string
+	value string
+	// breaking is set when changing the attribute can break queries or
+	// indexes using the object.
+	breaking bool
+}
+
+// schemaObject is an operator, operator class or family or aggregate
+// flattened for comparison.
+type schemaObject struct {
+	kind  string
+	key   string // name and arguments or index method, unique within the kind
+	name  string // plain name, matched against Options.IgnoreObjects
+	attrs []objectAttr
+}
+
+func schemaObjects(s *inspect.Schema) []schemaObject {
+	var objs []schemaObject
+	for _, o := range s.Operators {
+		objs = append(objs, schemaObject{"operator", o.Signature(), o.Name, []objectAttr{
+			{"result", o.Result, true},
+			{"function", o.Function, false},
+			{"commutator", o.Commutator, false},
+			{"negator", o.Negator, false},
+			{"restrict", o.Restrict, false},
+			{"join", o.Join, false},
+			{"hashes", fmt.Sprint(o.Hashes), false},
+			{"merges", fmt.Sprint(o.Merges), false},
+			{"owner", o.Owner, false},
+			{"comment", o.Comment, false},
+		}})
+	}
+	for _, c := range s.OperatorClasses {
+		objs = append(objs, schemaObject{"operator class", c.Name + " USING " + c.Method, c.Name, []objectAttr{
+			{"type", c.Type, true},
+			{"family", c.Family, false},
+			{"default", fmt.Sprint(c.Default), true},
+			{"storage", c.Storage, true},
+			{"operators", strings.Join(c.Operators, ", "), true},
+			{"functions", strings.Join(c.Functions, ", "), true},
+			{"owner", c.Owner, false},
+		}})
+	}
+	for _, f := range s.OperatorFamilies {
+		objs = append(objs, schemaObject{"operator family", f.Name + " USING " + f.Method, f.Name, []objectAttr{
+			{"owner", f.Owner, false},
+		}})
+	}
+	for _, a := range s.Aggregates {
+		objs = append(objs, schemaObject{"aggregate", a.Signature(), a.Name, []objectAttr{
+			{"result", a.Result, true},
+			{"kind", a.Kind, true},
+			{"state function", a.StateFunction, false},
+			{"state type", a.StateType, false},
+			{"final function", a.FinalFunction, false},
+			{"combine function", a.CombineFunction, false},
+			{"initial value", a.InitialValue, false},
+			{"sort operator", a.SortOperator, false},
+			{"owner", a.Owner, false},
+			{"comment", a.Comment, false},
+		}})
+	}
+	return objs
+}
+
+// compareObjects returns the changes to the operators, operator classes
+// and families and aggregates of schema from into those of to. Dropped
+// objects and changes to results and index support are breaking.
+func compareObjects(from, to *inspect.Schema, opts Options) []Change {
+	old := make(map[string]schemaObject)
+	for _, o := range schemaObjects(from) {
+		old[o.kind+"\x00"+o.key] = o
+	}
+	var changes []Change
+	seen := make(map[string]bool)
+	for _, o := range schemaObjects(to) {
+		if opts.ignored(to.Name + "." + o.name) {
+			continue
+		}
+		id := o.kind + "\x00" + o.key
+		seen[id] = true
+		prev, ok := old[id]
+		if !ok {
+			changes = append(changes, Change{Kind: Added, Object: o.kind, Name: to.Name + "." + o.key})
+			continue
+		}
+		var d details
+		for i, a := range o.attrs {
+			if b := prev.attrs[i]; b.value != a.value && opts.compares(a.name) {
+				d.add(a.breaking, "%s %q -> %q", a.name, b.value, a.value)
+			}
+		}
+		if d.text != "" {
+			changes = append(changes, Change{Kind: Modified, Object: o.kind, Name: to.Name + "." + o.key,
+				Detail: d.text, Breaking: d.breaking})
+		}
+	}
+	for _, o := range schemaObjects(from) {
+		if !seen[o.kind+"\x00"+o.key] && !opts.ignored(from.Name+"."+o.name) {
+			changes = append(changes, Change{Kind: Removed, Object: o.kind, Name: from.Name + "." + o.key, Breaking: true})
+		}
+	}
+	return changes
+}
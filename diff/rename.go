@@ -0,0 +1,170 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// minRenameScore is the similarity below which a dropped and an added object
+// are reported separately.
+const minRenameScore = 0.7
+
+type rename struct {
+	from, to int
+	score    float64
+}
+
+// matchRenames pairs removed objects with added ones, best matches first.
+// Each object is used in at most one pair.
+func matchRenames(removed, added int, similarity func(i, j int) float64) []rename {
+	var candidates []rename
+	for i := 0; i < removed; i++ {
+		for j := 0; j < added; j++ {
+			if s := similarity(i, j); s >= minRenameScore {
+				candidates = append(candidates, rename{from: i, to: j, score: s})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	var renames []rename
+	usedFrom := make(map[int]bool)
+	usedTo := make(map[int]bool)
+	for _, c := range candidates {
+		if usedFrom[c.from] || usedTo[c.to] {
+			continue
+		}
+		usedFrom[c.from], usedTo[c.to] = true, true
+		renames = append(renames, c)
+	}
+	sort.Slice(renames, func(i, j int) bool { return renames[i].to < renames[j].to })
+	return renames
+}
+
+// columnSimilarity scores two columns of different names. Columns of
+// different types are never a rename, nor are columns whose names have
+// little in common unless they hold the same position, as a column keeps
+// its position when renamed while a dropped and an added one do not. Equal
+// defaults and comments only count when set.
+func columnSimilarity(a, b *inspect.Column) float64 {
+	if a.TypeName() != b.TypeName() {
+		return 0
+	}
+	names := nameSimilarity(a.Name, b.Name)
+	if names < 0.5 && a.Position != b.Position {
+		return 0
+	}
+	score := 0.4 + 0.3*names
+	if a.Position == b.Position {
+		score += 0.2
+	}
+	if a.Nullable == b.Nullable {
+		score += 0.1
+	}
+	if a.Default != "" && a.Default == b.Default {
+		score += 0.1
+	}
+	if a.Comment != "" && a.Comment == b.Comment {
+		score += 0.1
+	}
+	if score > 1 {
+		score = 1
+	}
+	return score
+}
+
+// nameSimilarity is the larger of the edit distance similarity and the
+// share of common words of two snake_case names, from 0 to 1.
+func nameSimilarity(a, b string) float64 {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	if n == 0 {
+		return 0
+	}
+	edits := 1 - float64(levenshtein(a, b))/float64(n)
+
+	wa, wb := strings.FieldsFunc(a, isNameSeparator), strings.FieldsFunc(b, isNameSeparator)
+	in := make(map[string]bool, len(wa))
+	for _, w := range wa {
+		in[w] = true
+	}
+	common := 0
+	for _, w := range wb {
+		if in[w] {
+			common++
+			in[w] = false
+		}
+	}
+	words := 0.0
+	if total := len(wa) + len(wb) - common; total > 0 {
+		words = float64(common) / float64(total)
+	}
+	if words > edits {
+		return words
+	}
+	return edits
+}
+
+func isNameSeparator(r rune) bool { return r == '_' || r == '-' || r == ' ' }
+
+// levenshtein is the number of single byte edits turning a into b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// tableSimilarity is the share of columns with the same name and type in
+// two tables of different names.
+func tableSimilarity(a, b *inspect.Table) float64 {
+	if a.Type != b.Type {
+		return 0
+	}
+	n := len(a.Columns)
+	if len(b.Columns) > n {
+		n = len(b.Columns)
+	}
+	if n == 0 {
+		return 0
+	}
+	same := 0
+	for _, c := range a.Columns {
+		if o := b.Column(c.Name); o != nil && o.UDTName == c.UDTName {
+			same++
+		}
+	}
+	score := float64(same) / float64(n)
+	if a.Comment != b.Comment && score > 0 {
+		score -= 0.1
+	}
+	return score
+}
@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func TestColumnRenames(t *testing.T) {
+	tests := []struct {
+		name           string
+		removed, added inspect.Column
+		rename         bool
+	}{
+		{"similar name",
+			inspect.Column{Name: "created_at", UDTName: "timestamptz", Position: 3},
+			inspect.Column{Name: "created_on", UDTName: "timestamptz", Position: 7},
+			true},
+		{"shared word",
+			inspect.Column{Name: "customer_id", UDTName: "int8", Position: 2, Nullable: true},
+			inspect.Column{Name: "id_customer", UDTName: "int8", Position: 9, Nullable: true},
+			true},
+		{"same position",
+			inspect.Column{Name: "amount", UDTName: "numeric", Position: 4},
+			inspect.Column{Name: "total", UDTName: "numeric", Position: 4},
+			true},
+		{"unrelated nullable columns",
+			inspect.Column{Name: "note", UDTName: "text", Position: 4, Nullable: true},
+			inspect.Column{Name: "referrer", UDTName: "text", Position: 9, Nullable: true},
+			false},
+		{"unrelated columns with the same default",
+			inspect.Column{Name: "flagged", UDTName: "bool", Position: 4, Default: "false"},
+			inspect.Column{Name: "archived", UDTName: "bool", Position: 9, Default: "false"},
+			false},
+		{"similar name of another type",
+			inspect.Column{Name: "created_at", UDTName: "timestamptz", Position: 3},
+			inspect.Column{Name: "created_on", UDTName: "date", Position: 3},
+			false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := &inspect.Table{Schema: "public", Name: "orders", Type: "BASE TABLE",
+				Columns: []inspect.Column{{Name: "id", UDTName: "int8", Position: 1}, tt.removed}}
+			to := &inspect.Table{Schema: "public", Name: "orders", Type: "BASE TABLE",
+				Columns: []inspect.Column{{Name: "id", UDTName: "int8", Position: 1}, tt.added}}
+			changes := CompareTables(from, to, Options{DetectRenames: true})
+			renamed := len(changes) == 1 && changes[0].Kind == Renamed
+			if renamed != tt.rename {
+				t.Errorf("got %v, want rename %t", changes, tt.rename)
+			}
+			if renamed && (changes[0].From != "public.orders."+tt.removed.Name || !changes[0].Breaking) {
+				t.Errorf("got %v, want a breaking rename from %s", changes[0], tt.removed.Name)
+			}
+		})
+	}
+}
+
+func TestTableRenames(t *testing.T) {
+	columns := []inspect.Column{
+		{Name: "id", UDTName: "int8", Position: 1},
+		{Name: "email", UDTName: "text", Position: 2},
+		{Name: "created_at", UDTName: "timestamptz", Position: 3},
+	}
+	from := &inspect.Schema{Name: "public", Tables: []*inspect.Table{
+		{Schema: "public", Name: "users", Type: "BASE TABLE", Columns: columns},
+		{Schema: "public", Name: "audit", Type: "BASE TABLE", Columns: columns[:1]},
+	}}
+	to := &inspect.Schema{Name: "public", Tables: []*inspect.Table{
+		{Schema: "public", Name: "accounts", Type: "BASE TABLE", Columns: columns},
+		{Schema: "public", Name: "events", Type: "BASE TABLE", Columns: []inspect.Column{
+			{Name: "payload", UDTName: "jsonb", Position: 1},
+		}},
+	}}
+	changes := CompareSchemas(from, to, Options{DetectRenames: true})
+	want := []Change{
+		{Kind: Renamed, Object: "table", Name: "public.accounts", From: "public.users", Confidence: 1, Breaking: true},
+		{Kind: Removed, Object: "table", Name: "public.audit", Breaking: true},
+		{Kind: Added, Object: "table", Name: "public.events"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %v, want %v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"created_at", "created_at", 1, 1},
+		{"created_at", "created_on", 0.75, 0.85},
+		{"customer_id", "id_customer", 1, 1},
+		{"note", "referrer", 0, 0.3},
+	}
+	for _, tt := range tests {
+		if s := nameSimilarity(tt.a, tt.b); s < tt.min || s > tt.max {
+			t.Errorf("nameSimilarity(%q, %q) = %.2f, want %.2f to %.2f", tt.a, tt.b, s, tt.min, tt.max)
+		}
+	}
+}
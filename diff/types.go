@@ -0,0 +1,37 @@
+package diff
+
+import "github.com/datainq/pq-inspector/inspect"
+
+// widenings lists type changes which keep every existing value valid.
+var widenings = map[string][]string{
+	"int2":    {"int4", "int8", "numeric"},
+	"int4":    {"int8", "numeric"},
+	"int8":    {"numeric"},
+	"float4":  {"float8"},
+	"varchar": {"text"},
+	"bpchar":  {"varchar", "text"},
+}
+
+// narrows reports whether changing the type of column from to that of to
+// may reject values or clients expecting the old type.
+func narrows(from, to *inspect.Column) bool {
+	if from.UDTName == to.UDTName {
+		switch {
+		case from.MaxLength != to.MaxLength:
+			return to.MaxLength != 0 && (from.MaxLength == 0 || to.MaxLength < from.MaxLength)
+		case from.UDTName == "numeric":
+			if to.Precision == 0 {
+				return false
+			}
+			return from.Precision == 0 || to.Scale < from.Scale ||
+				to.Precision-to.Scale < from.Precision-from.Scale
+		}
+		return false
+	}
+	for _, t := range widenings[from.UDTName] {
+		if t == to.UDTName {
+			return to.UDTName == "varchar" && to.MaxLength != 0 && to.MaxLength < from.MaxLength
+		}
+	}
+	return true
+}
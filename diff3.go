@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/datainq/pq-inspector/diff"
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// isConnString reports whether s is a connection string rather than the
+// path or URL of a snapshot.
+func isConnString(s string) bool {
+	if strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") {
+		return true
+	}
+	return !strings.Contains(s, "://") && strings.Contains(s, "=")
+}
+
+// loadSide inspects the database of a connection string or reads a
+// snapshot or migrations:dir, see loadReference.
+func (a *app) loadSide(s string) (*inspect.Database, error) {
+	if !isConnString(s) {
+		return a.loadReference(s)
+	}
+	conn, err := a.open(s)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return a.inspect(conn.NewSession(nil))
+}
+
+// writeMerged writes the changes of each side and the conflicts.
+func writeMerged(w io.Writer, m diff.Merged) error {
+	bw := bufio.NewWriter(w)
+	for _, part := range []struct {
+		title   string
+		changes []diff.Change
+	}{{"ours", m.Ours}, {"theirs", m.Theirs}, {"both", m.Both}} {
+		if len(part.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "== %s: %s ==\n", part.title, diff.Summary(part.changes))
+		for _, c := range part.changes {
+			fmt.Fprintln(bw, c)
+		}
+	}
+	if len(m.Conflicts) > 0 {
+		fmt.Fprintf(bw, "== %d conflicts ==\n", len(m.Conflicts))
+	}
+	for _, c := range m.Conflicts {
+		fmt.Fprintln(bw, c)
+	}
+	return bw.Flush()
+}
+
+// runDiff3 compares two databases derived from a base snapshot, e.g. the
+// databases of two branches, and exits with 1 if their changes conflict.
+func (a *app) runDiff3(args []string) {
+	fs := flag.NewFlagSet("diff3", flag.ContinueOnError)
+	base := fs.String("base", "", "Snapshot or migrations:dir both sides derive from.")
+	ours := fs.String("ours", "", "Connection string or snapshot of the first side, -db if empty.")
+	theirs := fs.String("theirs", "", "Connection string or snapshot of the second side.")
+	renames := fs.Bool("renames", true, "Report similar dropped and added objects as renames.")
+	format := fs.String("format", "text", "Output format: text or json.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+	if *base == "" || *theirs == "" {
+		a.log.Fatal("diff3 requires -base and -theirs")
+	}
+	if *format != "text" && *format != "json" {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+
+	from, err := a.loadReference(*base)
+	if err != nil {
+		a.log.WithError(err).Fatal("read snapshot")
+	}
+	var left *inspect.Database
+	if *ours == "" {
+		left, err = a.load()
+	} else {
+		left, err = a.loadSide(*ours)
+	}
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect -ours")
+	}
+	right, err := a.loadSide(*theirs)
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect -theirs")
+	}
+	opts := a.diffOptions(*renames)
+	if strings.HasPrefix(*base, migrationsPrefix) {
+		opts = ignoreBookkeeping(opts, left, right)
+	}
+	m := diff.Merge(from, left, right, opts)
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(m)
+	} else {
+		err = writeMerged(w, m)
+	}
+	if err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if len(m.Conflicts) > 0 {
+		a.log.Exit(exitFindings)
+	}
+}
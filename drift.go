@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datainq/pq-inspector/diff"
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func (a *app) diffOptions(renames bool) diff.Options {
+	return diff.Options{
+		DetectRenames:    renames,
+		IgnoreObjects:    a.cfg.Diff.IgnoreObjects,
+		IgnoreAttributes: a.cfg.Diff.IgnoreAttributes,
+	}
+}
+
+// runDrift compares the database with a snapshot and exits with 1 when they
+// differ in a way selected by -fail-on.
+func (a *app) runDrift(args []string) {
+	fs := flag.NewFlagSet("drift", flag.ContinueOnError)
+	against := fs.String("against", "", "Snapshot file to compare the database with, or migrations:dir for the schema of a directory of migrations.")
+	renames := fs.Bool("renames", true, "Report similar dropped and added objects as renames.")
+	failOn := fs.String("fail-on", "any", "Exit with 1 on any, breaking or no (none) changes.")
+	a.parseFlags(fs, args)
+	if *against == "" {
+		a.log.Fatal("drift requires -against")
+	}
+	switch *failOn {
+	case "any", "breaking", "none":
+	default:
+		a.log.Fatalf("invalid -fail-on %q", *failOn)
+	}
+
+	want, err := a.loadReference(*against)
+	if err != nil {
+		a.log.WithError(err).Fatal("read snapshot")
+	}
+	got, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	a.recordHistory(got)
+
+	opts := a.diffOptions(*renames)
+	if strings.HasPrefix(*against, migrationsPrefix) {
+		opts = ignoreBookkeeping(opts, got)
+	}
+	changes := diff.Compare(want, got, opts)
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) == 0 {
+		return
+	}
+	a.notify(got.Name, changes)
+	if *failOn == "any" || *failOn == "breaking" && len(diff.Breaking(changes)) > 0 {
+		a.log.Exit(exitFindings)
+	}
+}
+
+// runWatch inspects the database periodically and reports changes between
+// consecutive inspections.
+func (a *app) runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := fs.Duration("interval", 5*time.Minute, "Time between inspections.")
+	renames := fs.Bool("renames", true, "Report similar dropped and added objects as renames.")
+	a.parseFlags(fs, args)
+
+	conn, err := a.connect()
+	if err != nil {
+		a.log.WithError(err).Fatal("connect")
+	}
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+	// The inspector re-reads only the schemas changed since the last tick.
+	in := &inspect.Inspector{Schemas: a.schemas}
+	prev, err := a.inspectWith(sess, a.schemas, in.Load)
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	a.recordHistory(prev)
+	a.log.Infof("watching %s every %s", prev.Name, *interval)
+	for range time.Tick(*interval) {
+		cur, err := a.inspectWith(sess, a.schemas, in.Load)
+		if err != nil {
+			a.log.WithError(err).Warn("inspect database")
+			continue
+		}
+		a.recordHistory(cur)
+		changes := diff.Compare(prev, cur, a.diffOptions(*renames))
+		if len(changes) > 0 {
+			a.log.Infof("schema of %s changed: %s", cur.Name, diff.Summary(changes))
+			for _, c := range changes {
+				a.log.Info(c)
+			}
+			a.notify(cur.Name, changes)
+		}
+		prev = cur
+	}
+}
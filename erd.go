@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// erdOptions change what the diagram shows.
+type erdOptions struct {
+	// collapseJoinTables draws pure join tables as many-to-many edges
+	// between the tables they link instead of as tables.
+	collapseJoinTables bool
+	// focus is highlighted.
+	focus *inspect.Table
+}
+
+// erdFormats render the entity relationship diagram, by -format name.
+var erdFormats = map[string]func(w io.Writer, db *inspect.Database, opts erdOptions) error{
+	"dot": writeDOT,
+	"d2":  writeD2,
+}
+
+func qualifiedName(t *inspect.Table) string {
+	return t.Schema + "." + t.Name
+}
+
+// inDiagram reports whether fk points to a table in the diagram.
+func inDiagram(db *inspect.Database, fk inspect.ForeignKey) bool {
+	return hasTable(db, fk.RefSchema, fk.RefTable)
+}
+
+func hasTable(db *inspect.Database, schema, table string) bool {
+	s := db.Schema(schema)
+	return s != nil && s.Table(table) != nil
+}
+
+func isPKColumn(t *inspect.Table, column string) bool {
+	for _, c := range t.PK.Columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// isPureJoinTable reports whether t only links two tables many to many,
+// having no columns besides its two foreign keys.
+func isPureJoinTable(t *inspect.Table) bool {
+	if !t.IsJoinTable() {
+		return false
+	}
+	for _, c := range t.Columns {
+		if !isFKColumn(t, c.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// collapsed reports whether t is drawn as an edge instead of a table.
+func (o erdOptions) collapsed(t *inspect.Table) bool {
+	return o.collapseJoinTables && isPureJoinTable(t)
+}
+
+func isFKColumn(t *inspect.Table, column string) bool {
+	for _, fk := range t.FKs {
+		for _, c := range fk.Columns {
+			if c == column {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// crowsFoot returns the arrow shapes, in crow's foot notation, at the
+// referencing and the referenced end of the edge drawn for fk.
+func crowsFoot(fk inspect.ForeignKey) (from, to string) {
+	if fk.Cardinality == inspect.OneToOne {
+		return "one", "one"
+	}
+	return "many", "one"
+}
+
+// dotArrows maps crow's foot ends to Graphviz arrow shapes.
+var dotArrows = map[string]string{"one": "tee", "many": "crow"}
+
+func writeDOT(w io.Writer, db *inspect.Database, opts erdOptions) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %q {\n\trankdir=LR;\n\tnode [shape=plaintext];\n", db.Name)
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if opts.collapsed(t) {
+				continue
+			}
+			fmt.Fprintf(bw, "\t%q [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\">", qualifiedName(t))
+			header := "lightgrey"
+			if t == opts.focus {
+				header = "lightblue"
+			}
+			fmt.Fprintf(bw, "<tr><td bgcolor=%q><b>%s</b></td></tr>", header, html.EscapeString(qualifiedName(t)))
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				name := html.EscapeString(c.Name)
+				if isPKColumn(t, c.Name) {
+					name = "<u>" + name + "</u>"
+				}
+				fmt.Fprintf(bw, "<tr><td port=\"%s\" align=\"left\">%s <i>%s</i></td></tr>",
+					html.EscapeString(c.Name), name, html.EscapeString(c.TypeName()))
+			}
+			bw.WriteString("</table>>];\n")
+		}
+	}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if opts.collapsed(t) {
+				a, b := t.FKs[0], t.FKs[1]
+				if inDiagram(db, a) && inDiagram(db, b) {
+					fmt.Fprintf(bw, "\t%q -> %q [dir=both, arrowtail=crow, arrowhead=crow, label=%q];\n",
+						a.RefSchema+"."+a.RefTable, b.RefSchema+"."+b.RefTable, qualifiedName(t))
+				}
+				continue
+			}
+			for _, fk := range t.FKs {
+				if !inDiagram(db, fk) {
+					continue
+				}
+				from, to := crowsFoot(fk)
+				fmt.Fprintf(bw, "\t%q:%q -> %q:%q [dir=both, arrowtail=%s, arrowhead=%s];\n", qualifiedName(t), fk.Columns[0],
+					fk.RefSchema+"."+fk.RefTable, fk.RefColumns[0], dotArrows[from], dotArrows[to])
+			}
+			for _, src := range t.Sources {
+				if hasTable(db, src.Schema, src.Table) {
+					fmt.Fprintf(bw, "\t%q -> %q [style=dashed];\n", qualifiedName(t), src.Schema+"."+src.Table)
+				}
+			}
+		}
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// d2Key quotes a D2 key so that dots do not nest it.
+func d2Key(s string) string {
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+// writeD2 renders tables as D2 sql_table shapes with typed columns.
+func writeD2(w io.Writer, db *inspect.Database, opts erdOptions) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if opts.collapsed(t) {
+				continue
+			}
+			fmt.Fprintf(bw, "%s: {\n  shape: sql_table\n", d2Key(qualifiedName(t)))
+			if t == opts.focus {
+				bw.WriteString("  style.fill: lightblue\n")
+			}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				var constraints []string
+				if isPKColumn(t, c.Name) {
+					constraints = append(constraints, "primary_key")
+				}
+				if isFKColumn(t, c.Name) {
+					constraints = append(constraints, "foreign_key")
+				}
+				if t.IsUnique(c.Name) && len(constraints) == 0 {
+					constraints = append(constraints, "unique")
+				}
+				fmt.Fprintf(bw, "  %s: %s", d2Key(c.Name), d2Key(c.TypeName()))
+				switch len(constraints) {
+				case 0:
+				case 1:
+					fmt.Fprintf(bw, " {constraint: %s}", constraints[0])
+				default:
+					fmt.Fprintf(bw, " {constraint: [%s]}", strings.Join(constraints, "; "))
+				}
+				bw.WriteString("\n")
+			}
+			bw.WriteString("}\n")
+		}
+	}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if opts.collapsed(t) {
+				a, b := t.FKs[0], t.FKs[1]
+				if inDiagram(db, a) && inDiagram(db, b) {
+					fmt.Fprintf(bw, "%s <-> %s: %s {source-arrowhead.shape: cf-many; target-arrowhead.shape: cf-many}\n",
+						d2Key(a.RefSchema+"."+a.RefTable), d2Key(b.RefSchema+"."+b.RefTable), d2Key(qualifiedName(t)))
+				}
+				continue
+			}
+			for _, fk := range t.FKs {
+				if !inDiagram(db, fk) {
+					continue
+				}
+				from, to := crowsFoot(fk)
+				fmt.Fprintf(bw, "%s.%s <-> %s.%s: {source-arrowhead.shape: cf-%s; target-arrowhead.shape: cf-%s}\n",
+					d2Key(qualifiedName(t)), d2Key(fk.Columns[0]), d2Key(fk.RefSchema+"."+fk.RefTable), d2Key(fk.RefColumns[0]),
+					from, to)
+			}
+			for _, src := range t.Sources {
+				if hasTable(db, src.Schema, src.Table) {
+					fmt.Fprintf(bw, "%s -> %s: {style.stroke-dash: 3}\n", d2Key(qualifiedName(t)),
+						d2Key(src.Schema+"."+src.Table))
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// runERD writes the entity relationship diagram of the inspected tables.
+func (a *app) runERD(args []string) {
+	fs := flag.NewFlagSet("erd", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	format := fs.String("format", "dot", "Diagram language: dot or d2.")
+	var opts erdOptions
+	fs.BoolVar(&opts.collapseJoinTables, "collapse-join-tables", false,
+		"Draw join tables with no other columns as many-to-many edges.")
+	cluster := fs.String("cluster", "", "Draw a diagram per schema or per name prefix: schema or prefix.")
+	prefixSep := fs.String("prefix-sep", "_", "Separator ending the name prefix of -cluster=prefix.")
+	dir := fs.String("dir", "erd", "Directory to write the diagrams of -cluster and their index.html to.")
+	focus := fs.String("focus", "", "Draw only this schema.table and the tables within -depth foreign keys of it.")
+	depth := fs.Int("depth", 2, "Foreign keys to follow from the -focus table, in either direction.")
+	a.parseFlags(fs, args)
+	render := erdFormats[*format]
+	if render == nil {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	if *focus != "" {
+		t := findTable(db, *focus)
+		if t == nil {
+			a.log.Fatalf("table %s not found", *focus)
+		}
+		near := erdNeighborhood(db, t, *depth)
+		db = erdSubset(db, func(t *inspect.Table) bool { return near[t] })
+		opts.focus = t
+	}
+	if *cluster != "" {
+		a.writeERDClusters(db, render, opts, *dir, *cluster, *prefixSep, *format)
+		return
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := render(w, db, opts); err != nil {
+		a.log.WithError(err).Fatal("write diagram")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write diagram")
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// erdSubset returns the part of db with the tables keep accepts, for
+// drawing. Foreign keys to the tables left out are not drawn.
+func erdSubset(db *inspect.Database, keep func(t *inspect.Table) bool) *inspect.Database {
+	sub := &inspect.Database{Name: db.Name}
+	for _, s := range db.Schemas {
+		var tables []*inspect.Table
+		for _, t := range s.Tables {
+			if keep(t) {
+				tables = append(tables, t)
+			}
+		}
+		if len(tables) > 0 {
+			sub.Schemas = append(sub.Schemas, &inspect.Schema{Name: s.Name, Tables: tables})
+		}
+	}
+	return sub
+}
+
+// erdNeighborhood returns t and the tables within depth foreign keys of it,
+// following keys in both directions.
+func erdNeighborhood(db *inspect.Database, t *inspect.Table, depth int) map[*inspect.Table]bool {
+	near := map[*inspect.Table]bool{t: true}
+	frontier := []*inspect.Table{t}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []*inspect.Table
+		visit := func(schema, name string) {
+			if s := db.Schema(schema); s != nil {
+				if n := s.Table(name); n != nil && !near[n] {
+					near[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		for _, t := range frontier {
+			for _, fk := range t.FKs {
+				visit(fk.RefSchema, fk.RefTable)
+			}
+			for _, r := range t.ReferencedBy {
+				visit(r.Schema, r.Table)
+			}
+		}
+		frontier = next
+	}
+	return near
+}
+
+// erdCluster is a group of tables drawn as one diagram.
+type erdCluster struct {
+	name   string
+	file   string
+	tables []*inspect.Table
+	// refs are the other clusters the tables have foreign keys to.
+	refs []string
+}
+
+// clusterKey returns the cluster of t: its schema, or with by prefix its
+// schema and the start of its name up to sep, e.g. public.billing for
+// public.billing_invoices.
+func clusterKey(t *inspect.Table, by, sep string) string {
+	if by == "schema" {
+		return t.Schema
+	}
+	prefix := t.Name
+	if i := strings.Index(t.Name, sep); i > 0 && sep != "" {
+		prefix = t.Name[:i]
+	}
+	return t.Schema + "." + prefix
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// erdClusters groups the tables of db by schema or name prefix.
+func erdClusters(db *inspect.Database, by, sep, ext string) ([]*erdCluster, error) {
+	if by != "schema" && by != "prefix" {
+		return nil, fmt.Errorf("unknown clustering %q, use schema or prefix", by)
+	}
+	byName := make(map[string]*erdCluster)
+	var clusters []*erdCluster
+	of := make(map[string]string)
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			key := clusterKey(t, by, sep)
+			c := byName[key]
+			if c == nil {
+				c = &erdCluster{name: key, file: unsafeFileChars.ReplaceAllString(key, "_") + "." + ext}
+				byName[key] = c
+				clusters = append(clusters, c)
+			}
+			c.tables = append(c.tables, t)
+			of[t.Schema+"."+t.Name] = key
+		}
+	}
+	for _, c := range clusters {
+		seen := make(map[string]bool)
+		for _, t := range c.tables {
+			for _, fk := range t.FKs {
+				if ref, ok := of[fk.RefSchema+"."+fk.RefTable]; ok && ref != c.name && !seen[ref] {
+					seen[ref] = true
+					c.refs = append(c.refs, ref)
+				}
+			}
+		}
+		sort.Strings(c.refs)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].name < clusters[j].name })
+	return clusters, nil
+}
+
+// writeERDIndex writes an HTML page linking the diagrams of the clusters.
+func writeERDIndex(w io.Writer, db *inspect.Database, clusters []*erdCluster) error {
+	files := make(map[string]string, len(clusters))
+	for _, c := range clusters {
+		files[c.name] = c.file
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Diagrams of %[1]s</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse}th,td{border:1px solid #ccc;padding:2px 6px;font-size:small;text-align:left}</style>
+</head><body><h1>Diagrams of %[1]s</h1>
+<table><tr><th>Diagram</th><th>Tables</th><th>References</th></tr>
+`, html.EscapeString(db.Name))
+	for _, c := range clusters {
+		refs := make([]string, len(c.refs))
+		for i, r := range c.refs {
+			refs[i] = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(files[r]), html.EscapeString(r))
+		}
+		fmt.Fprintf(bw, "<tr><td><a href=\"%s\">%s</a></td><td>%d</td><td>%s</td></tr>\n",
+			html.EscapeString(c.file), html.EscapeString(c.name), len(c.tables), strings.Join(refs, ", "))
+	}
+	bw.WriteString("</table></body></html>\n")
+	return bw.Flush()
+}
+
+// writeERDClusters renders a diagram per cluster into dir along with an
+// index.html linking them.
+func (a *app) writeERDClusters(db *inspect.Database, render func(io.Writer, *inspect.Database, erdOptions) error,
+	opts erdOptions, dir, by, sep, ext string) {
+	clusters, err := erdClusters(db, by, sep, ext)
+	if err != nil {
+		a.log.WithError(err).Fatal("cluster diagram")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		a.log.WithError(err).Fatal("create diagram dir")
+	}
+	for _, c := range clusters {
+		in := make(map[*inspect.Table]bool, len(c.tables))
+		for _, t := range c.tables {
+			in[t] = true
+		}
+		path := filepath.Join(dir, c.file)
+		f, err := os.Create(path)
+		if err != nil {
+			a.log.WithError(err).Fatalf("create %s", path)
+		}
+		if err := render(f, erdSubset(db, func(t *inspect.Table) bool { return in[t] }), opts); err != nil {
+			a.log.WithError(err).Fatalf("write %s", path)
+		}
+		if err := f.Close(); err != nil {
+			a.log.WithError(err).Fatalf("write %s", path)
+		}
+	}
+	path := filepath.Join(dir, "index.html")
+	f, err := os.Create(path)
+	if err != nil {
+		a.log.WithError(err).Fatalf("create %s", path)
+	}
+	if err := writeERDIndex(f, db, clusters); err != nil {
+		a.log.WithError(err).Fatalf("write %s", path)
+	}
+	if err := f.Close(); err != nil {
+		a.log.WithError(err).Fatalf("write %s", path)
+	}
+}
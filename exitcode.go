@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/sirupsen/logrus"
+)
+
+// Exit codes of pg-inspector, for scripts to branch on.
+const (
+	exitOK         = 0
+	exitFindings   = 1 // drift, conflicts, violations or findings of a check
+	exitConnection = 2 // the server cannot be reached, or timed out
+	exitPermission = 3 // authentication failed or a privilege is missing
+	exitUsage      = 4 // invalid command, flags or arguments
+	exitFailure    = 5 // any other error
+)
+
+// fatalExitCode is the exit code of a fatal log entry: by the kind of its
+// error, or exitUsage without one, as fatal messages without an error
+// report invalid arguments.
+func fatalExitCode(e *logrus.Entry) int {
+	err, ok := e.Data[logrus.ErrorKey].(error)
+	if !ok {
+		return exitUsage
+	}
+	switch inspect.ErrorKind(err) {
+	case inspect.ErrConnection, inspect.ErrTimeout:
+		return exitConnection
+	case inspect.ErrPermissionDenied:
+		return exitPermission
+	}
+	return exitFailure
+}
+
+// exitCodeHook keeps the exit code of a fatal entry, which logrus exits
+// with 1, for the exit function of the logger.
+type exitCodeHook struct {
+	code *int
+}
+
+func (exitCodeHook) Levels() []logrus.Level { return []logrus.Level{logrus.FatalLevel} }
+
+func (h exitCodeHook) Fire(e *logrus.Entry) error {
+	*h.code = fatalExitCode(e)
+	return nil
+}
+
+// parseFlags parses the flags of a command created with
+// flag.ContinueOnError. Invalid flags exit with exitUsage after the flag
+// package printed the error, -h with exitOK after the usage.
+func (a *app) parseFlags(fs *flag.FlagSet, args []string) {
+	switch err := fs.Parse(args); err {
+	case nil:
+	case flag.ErrHelp:
+		a.log.Exit(exitOK)
+	default:
+		a.log.Exit(exitUsage)
+	}
+}
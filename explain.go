@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// queryLog is the event receiver of every connection the app opens. It
+// counts the queries and their time and, with -explain, prints each one.
+type queryLog struct {
+	dbr.NullEventReceiver
+	w io.Writer
+
+	mu      sync.Mutex
+	queries int
+	elapsed time.Duration
+}
+
+// TimingKv is called by dbr after every query with its SQL in kvs.
+func (q *queryLog) TimingKv(event string, nanos int64, kvs map[string]string) {
+	d := time.Duration(nanos)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queries++
+	q.elapsed += d
+	if q.w != nil {
+		fmt.Fprintf(q.w, "%10s  %s\n", d.Round(time.Microsecond), strings.Join(strings.Fields(kvs["sql"]), " "))
+	}
+}
+
+// Totals returns the number of queries run so far and their summed time.
+func (q *queryLog) Totals() (int, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.queries, q.elapsed
+}
+
+// Summary prints the totals when -explain is set.
+func (q *queryLog) Summary() {
+	if q.w == nil {
+		return
+	}
+	n, d := q.Totals()
+	fmt.Fprintf(q.w, "%d queries in %s\n", n, d.Round(time.Microsecond))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// runFingerprint prints the fingerprint of the database. With -expect it
+// exits with 1 when the fingerprint is different.
+func (a *app) runFingerprint(args []string) {
+	fs := flag.NewFlagSet("fingerprint", flag.ContinueOnError)
+	var opts inspect.FingerprintOptions
+	fs.BoolVar(&opts.IgnoreComments, "ignore-comments", false, "Leave comments out of the fingerprint.")
+	fs.BoolVar(&opts.IgnoreTablespaces, "ignore-tablespaces", false, "Leave tablespaces out of the fingerprint.")
+	fs.BoolVar(&opts.IgnoreStats, "ignore-stats", true, "Leave row estimates and sizes out of the fingerprint.")
+	expect := fs.String("expect", "", "Expected fingerprint.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	sum := inspect.Fingerprint(db, opts)
+	fmt.Println(sum)
+	if *expect != "" && *expect != sum {
+		a.log.Errorf("fingerprint mismatch, expected %s", *expect)
+		a.log.Exit(exitFindings)
+	}
+}
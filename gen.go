@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// generators are the targets of `gen <target>`.
+var generators = map[string]func(a *app, args []string){
+	"anonymize":  (*app).genAnonymize,
+	"atlas":      (*app).genAtlas,
+	"csv":        (*app).genCSV,
+	"dbt":        (*app).genDBT,
+	"dbml":       (*app).genDBML,
+	"descriptor": (*app).genDescriptor,
+	"go":         (*app).genGo,
+	"grants":     (*app).genGrants,
+	"insert":     (*app).genInsert,
+	"liquibase":  (*app).genLiquibase,
+	"pgmodeler":  (*app).genPgModeler,
+	"seed":       (*app).genSeed,
+	"sql":        (*app).genSQL,
+	"xlsx":       (*app).genXLSX,
+}
+
+func (a *app) runGen(args []string) {
+	if len(args) == 0 || generators[args[0]] == nil {
+		names := make([]string, 0, len(generators))
+		for name := range generators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		a.log.Fatalf("gen requires a target: %s", strings.Join(names, ", "))
+	}
+	generators[args[0]](a, args[1:])
+}
+
+// createOutput opens path for writing, stdout if path is empty or "-".
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// writeAnonymizePlan writes masking statements for the columns likely
+// holding personal data, each annotated with its kind and strategy.
+// Columns of types the masking does not fit are listed for manual review.
+func writeAnonymizePlan(w io.Writer, db *inspect.Database, format string) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("-- Anonymization plan, review before running.\n")
+	if format == "anon" {
+		bw.WriteString("CREATE EXTENSION IF NOT EXISTS anon CASCADE;\nSELECT anon.init();\n")
+	}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			var sets []string
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				k := detectPII(c)
+				if k == nil {
+					continue
+				}
+				if !k.types[c.UDTName] {
+					fmt.Fprintf(bw, "-- %s.%s: %s stored as %s, review manually\n", qualifiedName(t), c.Name, k.name, c.TypeName())
+					continue
+				}
+				fmt.Fprintf(bw, "-- %s.%s: %s, %s\n", qualifiedName(t), c.Name, k.name, k.strategy)
+				col := inspect.QuoteIdent(c.Name)
+				if format == "anon" {
+					rule := "MASKED WITH FUNCTION "
+					if strings.HasPrefix(k.anon, "'") {
+						rule = "MASKED WITH VALUE "
+					}
+					rule += strings.Replace(k.anon, "{col}", col, -1)
+					fmt.Fprintf(bw, "SECURITY LABEL FOR anon ON COLUMN %s.%s IS %s;\n", tableRef(t), col, sqlLiteral(rule))
+					continue
+				}
+				expr := strings.Replace(k.sql, "{col}", col, -1)
+				if c.MaxLength > 0 {
+					expr = fmt.Sprintf("left(%s, %d)", expr, c.MaxLength)
+				}
+				sets = append(sets, fmt.Sprintf("%s = %s", col, expr))
+			}
+			if len(sets) > 0 {
+				fmt.Fprintf(bw, "UPDATE %s SET\n\t%s;\n", tableRef(t), strings.Join(sets, ",\n\t"))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// genAnonymize writes the masking plan as UPDATE statements or as rules of
+// the PostgreSQL Anonymizer extension.
+func (a *app) genAnonymize(args []string) {
+	fs := flag.NewFlagSet("gen anonymize", flag.ContinueOnError)
+	format := fs.String("format", "sql", "Plan format: sql for UPDATE statements or anon for PostgreSQL Anonymizer rules.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+	if *format != "sql" && *format != "anon" {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeAnonymizePlan(w, db, *format); err != nil {
+		a.log.WithError(err).Fatal("write plan")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write plan")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// atlasTypes are the Atlas HCL types of built-in PostgreSQL types by
+// udt_name. Other types are written as sql("...").
+var atlasTypes = map[string]string{
+	"int2": "smallint", "int4": "integer", "int8": "bigint",
+	"float4": "real", "float8": "double_precision",
+	"bool": "boolean", "text": "text", "uuid": "uuid", "bytea": "bytea",
+	"json": "json", "jsonb": "jsonb", "xml": "xml", "money": "money",
+	"date": "date", "time": "time", "timetz": "timetz", "timestamp": "timestamp", "timestamptz": "timestamptz",
+	"interval": "interval", "inet": "inet", "cidr": "cidr", "macaddr": "macaddr",
+}
+
+// hclString quotes s as an HCL string, escaping template sequences.
+func hclString(s string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(strconv.Quote(s))
+}
+
+// atlasRefs names tables in references: by name, or by schema and name
+// where several schemas have a table of that name.
+type atlasRefs map[string]int
+
+func newAtlasRefs(db *inspect.Database) atlasRefs {
+	refs := make(atlasRefs)
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			refs[t.Name]++
+		}
+	}
+	return refs
+}
+
+func (r atlasRefs) table(schema, name string) string {
+	if r[name] > 1 {
+		return "table." + schema + "." + name
+	}
+	return "table." + name
+}
+
+func atlasColumns(names []string) string {
+	refs := make([]string, len(names))
+	for i, n := range names {
+		refs[i] = "column." + n
+	}
+	return "[" + strings.Join(refs, ", ") + "]"
+}
+
+// atlasType is the HCL type of a column: an Atlas type, an enum reference
+// or raw SQL.
+func atlasType(c *inspect.Column, enums map[string]bool) string {
+	if t, ok := atlasTypes[c.UDTName]; ok && c.DataType != "ARRAY" {
+		return t
+	}
+	switch {
+	case c.DataType == "ARRAY":
+	case c.UDTName == "varchar" && c.MaxLength > 0:
+		return fmt.Sprintf("varchar(%d)", c.MaxLength)
+	case c.UDTName == "bpchar" && c.MaxLength > 0:
+		return fmt.Sprintf("char(%d)", c.MaxLength)
+	case c.UDTName == "varchar":
+		return "varchar"
+	case c.UDTName == "numeric" && c.Precision > 0:
+		return fmt.Sprintf("numeric(%d,%d)", c.Precision, c.Scale)
+	case c.UDTName == "numeric":
+		return "numeric"
+	case enums[c.UDTSchema+"."+c.UDTName]:
+		return "enum." + c.UDTName
+	}
+	return "sql(" + hclString(sqlType(c)) + ")"
+}
+
+// writeAtlas writes an Atlas HCL schema of the schemas, enums and base
+// tables of db with their columns, keys, checks and indexes.
+func writeAtlas(w io.Writer, db *inspect.Database) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Generated by pg-inspector from database %s.\n", db.Name)
+	refs := newAtlasRefs(db)
+	enums := make(map[string]bool)
+	for _, s := range db.Schemas {
+		fmt.Fprintf(bw, "\nschema %s {\n}\n", hclString(s.Name))
+		for _, e := range s.Enums {
+			enums[s.Name+"."+e.Name] = true
+			labels := make([]string, len(e.Labels))
+			for i, l := range e.Labels {
+				labels[i] = hclString(l)
+			}
+			fmt.Fprintf(bw, "\nenum %s {\n  schema = schema.%s\n  values = [%s]\n}\n", hclString(e.Name), s.Name,
+				strings.Join(labels, ", "))
+		}
+	}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type == "BASE TABLE" {
+				writeAtlasTable(bw, t, refs, enums)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func writeAtlasTable(w *bufio.Writer, t *inspect.Table, refs atlasRefs, enums map[string]bool) {
+	fmt.Fprintf(w, "\ntable %s {\n  schema = schema.%s\n", hclString(t.Name), t.Schema)
+	if t.Comment != "" {
+		fmt.Fprintf(w, "  comment = %s\n", hclString(t.Comment))
+	}
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		fmt.Fprintf(w, "  column %s {\n    null = %t\n    type = %s\n", hclString(c.Name), c.Nullable, atlasType(c, enums))
+		switch {
+		case c.Identity != "":
+			fmt.Fprintf(w, "    identity {\n      generated = %s\n    }\n", strings.Replace(c.Identity, " ", "_", -1))
+		case c.Generated != "":
+			fmt.Fprintf(w, "    as {\n      expr = %s\n      type = STORED\n    }\n", hclString(c.Generated))
+		case c.Default != "":
+			fmt.Fprintf(w, "    default = sql(%s)\n", hclString(c.Default))
+		}
+		if c.Comment != "" {
+			fmt.Fprintf(w, "    comment = %s\n", hclString(c.Comment))
+		}
+		w.WriteString("  }\n")
+	}
+	if len(t.PK.Columns) > 0 {
+		fmt.Fprintf(w, "  primary_key {\n    columns = %s\n  }\n", atlasColumns(t.PK.Columns))
+	}
+	for _, fk := range t.FKs {
+		ref := make([]string, len(fk.RefColumns))
+		for i, c := range fk.RefColumns {
+			ref[i] = refs.table(fk.RefSchema, fk.RefTable) + ".column." + c
+		}
+		fmt.Fprintf(w, "  foreign_key %s {\n    columns     = %s\n    ref_columns = [%s]\n", hclString(fk.Name),
+			atlasColumns(fk.Columns), strings.Join(ref, ", "))
+		for _, action := range []struct{ name, value string }{{"on_update", fk.OnUpdate}, {"on_delete", fk.OnDelete}} {
+			if action.value != "" {
+				fmt.Fprintf(w, "    %s   = %s\n", action.name, strings.Replace(action.value, " ", "_", -1))
+			}
+		}
+		w.WriteString("  }\n")
+	}
+	for _, c := range t.Constraints {
+		if c.Type == "CHECK" {
+			fmt.Fprintf(w, "  check %s {\n    expr = %s\n  }\n", hclString(c.Name), hclString(checkExpr(c.Definition)))
+		}
+	}
+	for _, idx := range t.Indexes {
+		if idx.Primary {
+			continue
+		}
+		// Unique constraints are unique indexes in Atlas.
+		fmt.Fprintf(w, "  index %s {\n", hclString(idx.Name))
+		if idx.Unique {
+			w.WriteString("    unique  = true\n")
+		}
+		if plainColumns(t, idx.Columns) {
+			fmt.Fprintf(w, "    columns = %s\n", atlasColumns(idx.Columns))
+		} else {
+			for _, c := range idx.Columns {
+				if t.Column(c) != nil {
+					fmt.Fprintf(w, "    on {\n      column = column.%s\n    }\n", c)
+				} else {
+					fmt.Fprintf(w, "    on {\n      expr = %s\n    }\n", hclString(c))
+				}
+			}
+		}
+		if idx.Method != "" && idx.Method != "btree" {
+			fmt.Fprintf(w, "    type    = %s\n", strings.ToUpper(idx.Method))
+		}
+		if idx.Predicate != "" {
+			fmt.Fprintf(w, "    where   = %s\n", hclString(idx.Predicate))
+		}
+		w.WriteString("  }\n")
+	}
+	w.WriteString("}\n")
+}
+
+// plainColumns reports whether all index columns are columns of t rather
+// than expressions.
+func plainColumns(t *inspect.Table, columns []string) bool {
+	for _, c := range columns {
+		if t.Column(c) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// genAtlas writes an Atlas HCL schema, to bootstrap Atlas from an
+// existing database.
+func (a *app) genAtlas(args []string) {
+	fs := flag.NewFlagSet("gen atlas", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeAtlas(w, db); err != nil {
+		a.log.WithError(err).Fatal("write schema")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write schema")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// csvExports are the flat files written by `gen csv`, by name.
+var csvExports = map[string]func(db *inspect.Database) [][]string{
+	"tables":       tablesCSV,
+	"columns":      columnsCSV,
+	"foreign_keys": foreignKeysCSV,
+	"indexes":      indexesCSV,
+}
+
+func tablesCSV(db *inspect.Database) [][]string {
+	rows := [][]string{{"schema", "table", "type", "owner", "comment", "annotations", "row_estimate", "size_bytes", "columns", "primary_key"}}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			rows = append(rows, []string{t.Schema, t.Name, t.Type, t.Owner, t.Comment, inspect.FormatAnnotations(t.Annotations),
+				strconv.FormatInt(t.Stats.RowEstimate, 10), strconv.FormatInt(t.Stats.SizeBytes, 10),
+				strconv.Itoa(len(t.Columns)), strings.Join(t.PK.Columns, ", ")})
+		}
+	}
+	return rows
+}
+
+func columnsCSV(db *inspect.Database) [][]string {
+	rows := [][]string{{"schema", "table", "column", "position", "type", "nullable", "default", "default_kind", "comment", "annotations"}}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				rows = append(rows, []string{t.Schema, t.Name, c.Name, strconv.Itoa(c.Position), c.TypeName(),
+					strconv.FormatBool(c.Nullable), c.Default, c.DefaultKind(), c.Comment, inspect.FormatAnnotations(c.Annotations)})
+			}
+		}
+	}
+	return rows
+}
+
+func foreignKeysCSV(db *inspect.Database) [][]string {
+	rows := [][]string{{"schema", "table", "name", "columns", "ref_schema", "ref_table", "ref_columns", "on_update", "on_delete"}}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			for _, fk := range t.FKs {
+				rows = append(rows, []string{t.Schema, t.Name, fk.Name, strings.Join(fk.Columns, ", "),
+					fk.RefSchema, fk.RefTable, strings.Join(fk.RefColumns, ", "), fk.OnUpdate, fk.OnDelete})
+			}
+		}
+	}
+	return rows
+}
+
+func indexesCSV(db *inspect.Database) [][]string {
+	rows := [][]string{{"schema", "table", "name", "method", "columns", "unique", "primary", "predicate", "definition"}}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			for _, idx := range t.Indexes {
+				rows = append(rows, []string{t.Schema, t.Name, idx.Name, idx.Method, strings.Join(idx.Columns, ", "),
+					strconv.FormatBool(idx.Unique), strconv.FormatBool(idx.Primary), idx.Predicate, idx.Definition})
+			}
+		}
+	}
+	return rows
+}
+
+func writeCSV(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	cw.WriteAll(rows)
+	return cw.Error()
+}
+
+func writeCSVFile(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeCSV(f, rows); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// streamCSV writes the exports named by files while inspecting the database
+// table by table, so memory does not grow with the size of the catalog.
+func (a *app) streamCSV(files map[string]io.Writer) error {
+	writers := make(map[string]*csv.Writer, len(files))
+	for name, w := range files {
+		cw := csv.NewWriter(w)
+		cw.Write(csvExports[name](&inspect.Database{})[0])
+		writers[name] = cw
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = inspect.ForEachTable(context.Background(), conn.NewSession(nil), a.schemas, func(t *inspect.Table) error {
+		db := &inspect.Database{Schemas: []*inspect.Schema{{Name: t.Schema, Tables: []*inspect.Table{t}}}}
+		for name, cw := range writers {
+			cw.WriteAll(csvExports[name](db)[1:])
+			if err := cw.Error(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, cw := range writers {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genCSV writes one of the flat exports, or all of them with -csv-dir.
+func (a *app) genCSV(args []string) {
+	fs := flag.NewFlagSet("gen csv", flag.ContinueOnError)
+	kind := fs.String("kind", "columns", "Export to write: tables, columns, foreign_keys or indexes.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	dir := fs.String("csv-dir", "", "Directory to write all exports to, as <kind>.csv.")
+	stream := fs.Bool("stream", false, "Inspect and write table by table instead of loading the whole catalog.")
+	a.parseFlags(fs, args)
+	export := csvExports[*kind]
+	if export == nil && *dir == "" {
+		a.log.Fatalf("unknown export %q", *kind)
+	}
+	if *stream {
+		a.genCSVStream(*kind, *out, *dir)
+		return
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0755); err != nil {
+			a.log.WithError(err).Fatal("create csv dir")
+		}
+		for name, export := range csvExports {
+			path := filepath.Join(*dir, name+".csv")
+			if err := writeCSVFile(path, export(db)); err != nil {
+				a.log.WithError(err).Fatalf("write %s", path)
+			}
+		}
+		return
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeCSV(w, export(db)); err != nil {
+		a.log.WithError(err).Fatalf("write %s", *kind)
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatalf("write %s", *kind)
+	}
+}
+
+// genCSVStream is genCSV with -stream: every output is opened up front and
+// filled while the tables are streamed.
+func (a *app) genCSVStream(kind, out, dir string) {
+	files := make(map[string]io.Writer)
+	var closers []io.WriteCloser
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			a.log.WithError(err).Fatal("create csv dir")
+		}
+		for name := range csvExports {
+			path := filepath.Join(dir, name+".csv")
+			f, err := os.Create(path)
+			if err != nil {
+				a.log.WithError(err).Fatalf("create %s", path)
+			}
+			files[name] = f
+			closers = append(closers, f)
+		}
+	} else {
+		w, err := createOutput(out)
+		if err != nil {
+			a.log.WithError(err).Fatal("create output")
+		}
+		files[kind] = w
+		closers = append(closers, w)
+	}
+
+	if err := a.streamCSV(files); err != nil {
+		a.log.WithError(err).Fatal("stream csv")
+	}
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			a.log.WithError(err).Fatal("write csv")
+		}
+	}
+}
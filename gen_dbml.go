@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+var dbmlWord = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// dbmlName quotes a name unless it is a plain word.
+func dbmlName(s string) string {
+	if dbmlWord.MatchString(s) {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+func dbmlTable(schema, name string) string {
+	return dbmlName(schema) + "." + dbmlName(name)
+}
+
+// dbmlString is a quoted DBML string, in triple quotes if multi-line.
+func dbmlString(s string) string {
+	if strings.Contains(s, "\n") {
+		return "'''" + strings.Replace(s, "'''", `\'''`, -1) + "'''"
+	}
+	return "'" + strings.Replace(strings.Replace(s, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+}
+
+// dbmlType is the column type, quoted if it is more than a word with a
+// length, e.g. "timestamp with time zone".
+func dbmlType(c *inspect.Column) string {
+	t := sqlType(c)
+	if strings.ContainsAny(t, " []") {
+		return `"` + t + `"`
+	}
+	return t
+}
+
+// dbmlColumns is a column or a parenthesized list of columns.
+func dbmlColumns(columns []string) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = dbmlName(c)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+// writeDBML writes the schemas, enums and base tables of db with their
+// keys, indexes and foreign keys as DBML, the language of dbdiagram.io and
+// dbdocs.io.
+func writeDBML(w io.Writer, db *inspect.Database) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Project %s {\n  database_type: 'PostgreSQL'\n}\n", dbmlName(db.Name))
+	for _, s := range db.Schemas {
+		for _, e := range s.Enums {
+			fmt.Fprintf(bw, "\nEnum %s {\n", dbmlTable(s.Name, e.Name))
+			for _, l := range e.Labels {
+				fmt.Fprintf(bw, "  %s\n", dbmlName(l))
+			}
+			bw.WriteString("}\n")
+		}
+	}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type == "BASE TABLE" {
+				writeDBMLTable(bw, t)
+			}
+		}
+	}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			for _, fk := range t.FKs {
+				fmt.Fprintf(bw, "\nRef %s: %s.%s > %s.%s", dbmlName(fk.Name), dbmlTable(t.Schema, t.Name),
+					dbmlColumns(fk.Columns), dbmlTable(fk.RefSchema, fk.RefTable), dbmlColumns(fk.RefColumns))
+				var actions []string
+				if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+					actions = append(actions, "update: "+strings.ToLower(fk.OnUpdate))
+				}
+				if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+					actions = append(actions, "delete: "+strings.ToLower(fk.OnDelete))
+				}
+				if len(actions) > 0 {
+					fmt.Fprintf(bw, " [%s]", strings.Join(actions, ", "))
+				}
+				bw.WriteString("\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func writeDBMLTable(w *bufio.Writer, t *inspect.Table) {
+	fmt.Fprintf(w, "\nTable %s {\n", dbmlTable(t.Schema, t.Name))
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		var settings []string
+		pk := len(t.PK.Columns) == 1 && t.PK.Columns[0] == c.Name
+		if pk {
+			settings = append(settings, "pk")
+		}
+		if c.Identity != "" {
+			settings = append(settings, "increment")
+		}
+		if !c.Nullable {
+			settings = append(settings, "not null")
+		}
+		if !pk && t.IsUnique(c.Name) {
+			settings = append(settings, "unique")
+		}
+		switch {
+		case c.Generated != "":
+			settings = append(settings, "note: "+dbmlString("generated always as ("+c.Generated+") stored"))
+		case c.Default != "":
+			settings = append(settings, "default: `"+c.Default+"`")
+		}
+		if c.Comment != "" {
+			settings = append(settings, "note: "+dbmlString(c.Comment))
+		}
+		fmt.Fprintf(w, "  %s %s", dbmlName(c.Name), dbmlType(c))
+		if len(settings) > 0 {
+			fmt.Fprintf(w, " [%s]", strings.Join(settings, ", "))
+		}
+		w.WriteString("\n")
+	}
+
+	var indexes []string
+	if len(t.PK.Columns) > 1 {
+		indexes = append(indexes, fmt.Sprintf("%s [pk, name: %s]", dbmlColumns(t.PK.Columns), dbmlString(t.PK.Name)))
+	}
+	constraints := make(map[string]bool)
+	for _, c := range t.Constraints {
+		constraints[c.Name] = true
+		if c.Type == "UNIQUE" && len(c.Columns) > 1 {
+			indexes = append(indexes, fmt.Sprintf("%s [unique, name: %s]", dbmlColumns(c.Columns), dbmlString(c.Name)))
+		}
+	}
+	for _, idx := range t.Indexes {
+		if idx.Primary || constraints[idx.Name] {
+			continue
+		}
+		columns := make([]string, len(idx.Columns))
+		for i, c := range idx.Columns {
+			if t.Column(c) != nil {
+				columns[i] = dbmlName(c)
+			} else {
+				columns[i] = "`" + c + "`"
+			}
+		}
+		settings := []string{"name: " + dbmlString(idx.Name)}
+		if idx.Unique {
+			settings = append(settings, "unique")
+		}
+		// DBML knows btree and hash only.
+		if idx.Method == "hash" {
+			settings = append(settings, "type: hash")
+		}
+		if idx.Predicate != "" {
+			settings = append(settings, "note: "+dbmlString("where "+idx.Predicate))
+		}
+		indexes = append(indexes, fmt.Sprintf("(%s) [%s]", strings.Join(columns, ", "), strings.Join(settings, ", ")))
+	}
+	if len(indexes) > 0 {
+		fmt.Fprintf(w, "\n  Indexes {\n    %s\n  }\n", strings.Join(indexes, "\n    "))
+	}
+	if t.Comment != "" {
+		fmt.Fprintf(w, "\n  Note: %s\n", dbmlString(t.Comment))
+	}
+	w.WriteString("}\n")
+}
+
+// genDBML writes a DBML schema, to publish it with dbdocs or draw it on
+// dbdiagram.io.
+func (a *app) genDBML(args []string) {
+	fs := flag.NewFlagSet("gen dbml", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeDBML(w, db); err != nil {
+		a.log.WithError(err).Fatal("write schema")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write schema")
+	}
+}
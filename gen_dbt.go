@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type dbtSources struct {
+	Version int         `yaml:"version"`
+	Sources []dbtSource `yaml:"sources"`
+}
+
+type dbtSource struct {
+	Name     string     `yaml:"name"`
+	Database string     `yaml:"database,omitempty"`
+	Schema   string     `yaml:"schema"`
+	Tables   []dbtTable `yaml:"tables"`
+
+	Quoting *dbtQuoting `yaml:"quoting,omitempty"`
+}
+
+// dbtQuoting makes dbt quote names which are not plain lower case
+// identifiers; it leaves them unquoted by default.
+type dbtQuoting struct {
+	Schema     bool `yaml:"schema,omitempty"`
+	Identifier bool `yaml:"identifier,omitempty"`
+}
+
+type dbtTable struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description,omitempty"`
+	Meta        dbtMeta     `yaml:"meta,omitempty"`
+	Columns     []dbtColumn `yaml:"columns,omitempty"`
+
+	Quoting *dbtQuoting `yaml:"quoting,omitempty"`
+}
+
+type dbtColumn struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description,omitempty"`
+	Meta        dbtMeta       `yaml:"meta,omitempty"`
+	Quote       bool          `yaml:"quote,omitempty"`
+	Tests       []interface{} `yaml:"tests,omitempty"`
+}
+
+// dbtMeta are the comment annotations of a table or column, which dbt
+// keeps as meta properties.
+type dbtMeta map[string]string
+
+// jinjaString returns s as a single quoted Jinja string literal.
+func jinjaString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// dbtSourcesFor describes every schema as a dbt source. Column tests are
+// inferred from NOT NULL, single column unique keys and foreign keys.
+func dbtSourcesFor(db *inspect.Database, withDatabase bool) dbtSources {
+	out := dbtSources{Version: 2}
+	for _, s := range db.Schemas {
+		src := dbtSource{Name: s.Name, Schema: s.Name}
+		if withDatabase {
+			src.Database = db.Name
+		}
+		if inspect.NeedsQuoting(s.Name) {
+			src.Quoting = &dbtQuoting{Schema: true}
+		}
+		for _, t := range s.Tables {
+			dt := dbtTable{Name: t.Name, Description: t.Comment, Meta: t.Annotations}
+			if inspect.NeedsQuoting(t.Name) {
+				dt.Quoting = &dbtQuoting{Identifier: true}
+			}
+			for _, c := range t.Columns {
+				dc := dbtColumn{Name: c.Name, Description: c.Comment, Meta: c.Annotations, Quote: inspect.NeedsQuoting(c.Name)}
+				if !c.Nullable {
+					dc.Tests = append(dc.Tests, "not_null")
+				}
+				if t.IsUnique(c.Name) {
+					dc.Tests = append(dc.Tests, "unique")
+				}
+				for _, fk := range t.FKs {
+					if len(fk.Columns) != 1 || fk.Columns[0] != c.Name {
+						continue
+					}
+					dc.Tests = append(dc.Tests, map[string]interface{}{
+						"relationships": map[string]string{
+							"to":    fmt.Sprintf("source(%s, %s)", jinjaString(fk.RefSchema), jinjaString(fk.RefTable)),
+							"field": fk.RefColumns[0],
+						},
+					})
+				}
+				dt.Columns = append(dt.Columns, dc)
+			}
+			src.Tables = append(src.Tables, dt)
+		}
+		out.Sources = append(out.Sources, src)
+	}
+	return out
+}
+
+func writeDBTSources(w io.Writer, sources dbtSources) error {
+	b, err := yaml.Marshal(sources)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// genDBT writes a dbt sources.yml for the inspected schemas.
+func (a *app) genDBT(args []string) {
+	fs := flag.NewFlagSet("gen dbt", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	withDatabase := fs.Bool("database", false, "Set the database of each source.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeDBTSources(w, dbtSourcesFor(db, *withDatabase)); err != nil {
+		a.log.WithError(err).Fatal("write sources")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write sources")
+	}
+}
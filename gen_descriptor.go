@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+
+	"github.com/datainq/pq-inspector/descriptor"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// genDescriptor writes the schema descriptor, see descriptor/schema.json.
+func (a *app) genDescriptor(args []string) {
+	fs := flag.NewFlagSet("gen descriptor", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	format := fs.String("format", "yaml", "Output format: yaml or json.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	d := descriptor.FromDatabase(db)
+
+	var b []byte
+	switch *format {
+	case "yaml":
+		b, err = yaml.Marshal(d)
+	case "json":
+		b, err = json.MarshalIndent(d, "", "  ")
+		b = append(b, '\n')
+	default:
+		a.log.Fatalf("unknown format %q", *format)
+	}
+	if err != nil {
+		a.log.WithError(err).Fatal("encode descriptor")
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if _, err := w.Write(b); err != nil {
+		a.log.WithError(err).Fatal("write descriptor")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write descriptor")
+	}
+}
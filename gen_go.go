@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// goTypes maps PostgreSQL types to Go types. Types not listed, like enums,
+// are read as strings. PostGIS geometries are read in their text output,
+// hex-encoded EWKB, which WKB decoders like go-geom's ewkbhex accept.
+var goTypes = map[string]string{
+	"bool":        "bool",
+	"int2":        "int16",
+	"int4":        "int32",
+	"int8":        "int64",
+	"float4":      "float32",
+	"float8":      "float64",
+	"date":        "time.Time",
+	"timestamp":   "time.Time",
+	"timestamptz": "time.Time",
+	"bytea":       "[]byte",
+	"json":        "json.RawMessage",
+	"jsonb":       "json.RawMessage",
+	"geometry":    "string",
+	"geography":   "string",
+	"_bool":       "pq.BoolArray",
+	"_int2":       "pq.Int64Array",
+	"_int4":       "pq.Int64Array",
+	"_int8":       "pq.Int64Array",
+	"_float4":     "pq.Float64Array",
+	"_float8":     "pq.Float64Array",
+	"_text":       "pq.StringArray",
+	"_varchar":    "pq.StringArray",
+}
+
+// sqlboilerNullTypes are the github.com/volatiletech/null types sqlboiler
+// uses for nullable columns.
+var sqlboilerNullTypes = map[string]string{
+	"string":          "null.String",
+	"bool":            "null.Bool",
+	"int16":           "null.Int16",
+	"int32":           "null.Int32",
+	"int64":           "null.Int64",
+	"float32":         "null.Float32",
+	"float64":         "null.Float64",
+	"time.Time":       "null.Time",
+	"[]byte":          "null.Bytes",
+	"json.RawMessage": "null.JSON",
+}
+
+var goImports = map[string]string{
+	"time": "time",
+	"json": "encoding/json",
+	"pq":   "github.com/lib/pq",
+	"null": "github.com/volatiletech/null/v8",
+}
+
+type goGenerator struct {
+	flavor  string // plain, gorm or sqlboiler
+	imports map[string]bool
+	names   map[*inspect.Table]string
+}
+
+func newGoGenerator(db *inspect.Database, flavor string) *goGenerator {
+	g := &goGenerator{flavor: flavor, imports: make(map[string]bool), names: make(map[*inspect.Table]string)}
+	count := make(map[string]int)
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			count[camelCase(singular(t.Name))]++
+		}
+	}
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			name := camelCase(singular(t.Name))
+			if count[name] > 1 {
+				name = camelCase(s.Name) + name
+			}
+			g.names[t] = name
+		}
+	}
+	return g
+}
+
+// goType returns the Go type of column c and records its import.
+func (g *goGenerator) goType(c *inspect.Column) string {
+	typ, ok := goTypes[c.UDTName]
+	if !ok {
+		typ = "string"
+	}
+	if c.Nullable {
+		switch {
+		case g.flavor == "sqlboiler" && sqlboilerNullTypes[typ] != "":
+			typ = sqlboilerNullTypes[typ]
+		case typ != "[]byte" && !strings.HasPrefix(typ, "pq.") && typ != "json.RawMessage":
+			typ = "*" + typ
+		}
+	}
+	if i := strings.Index(typ, "."); i > 0 {
+		g.imports[goImports[strings.TrimPrefix(typ[:i], "*")]] = true
+	}
+	return typ
+}
+
+func (g *goGenerator) fieldTag(t *inspect.Table, c *inspect.Column) string {
+	omit := ""
+	if c.Nullable {
+		omit = ",omitempty"
+	}
+	switch g.flavor {
+	case "gorm":
+		opts := "column:" + c.Name
+		for _, pk := range t.PK.Columns {
+			if pk == c.Name {
+				opts += ";primaryKey"
+			}
+		}
+		if !c.Nullable {
+			opts += ";not null"
+		}
+		if c.ReadOnly() {
+			opts += ";->"
+		}
+		switch c.DefaultKind() {
+		case inspect.DefaultSequence:
+			opts += ";autoIncrement"
+		case inspect.DefaultUUID, inspect.DefaultTimestamp:
+			// Left out of inserts when zero, for the server to fill.
+			if !strings.ContainsAny(c.Default, ";:") {
+				opts += ";default:" + c.Default
+			}
+		}
+		return fmt.Sprintf(`gorm:%q json:%q`, opts, c.Name+omit)
+	case "sqlboiler":
+		return fmt.Sprintf(`boil:%q json:%q toml:%q yaml:%q`, c.Name, c.Name+omit, c.Name, c.Name+omit)
+	}
+	return fmt.Sprintf(`db:%q json:%q`, c.Name, c.Name+omit)
+}
+
+// goTag returns tag as a struct tag literal, raw unless a column name
+// contains a backquote.
+func goTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// goRelation is a field holding related rows. fk is the foreign key of the
+// table for a belongs-to relation and the key of the other table for the
+// others; a many-to-many relation goes through the join table of fk to the
+// table referenced by through.
+type goRelation struct {
+	name, typ string
+	kind      string // belongs-to, has-one, has-many or many-to-many
+	fk        inspect.ForeignKey
+	join      *inspect.Table
+	through   inspect.ForeignKey
+}
+
+// relationName returns name, numbered if a field has it already, and
+// records it.
+func relationName(name string, fields map[string]bool) string {
+	for base, i := name, 2; fields[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	fields[name] = true
+	return name
+}
+
+// relations returns a field for every foreign key to an inspected table
+// and, by the cardinality of the foreign keys pointing at t, one for the
+// referencing row or rows. Belongs-to fields are named after the key column
+// without its _id suffix, has-one fields after the referencing type and
+// has-many and many-to-many fields after the other table.
+func (g *goGenerator) relations(db *inspect.Database, t *inspect.Table, fields map[string]bool) []goRelation {
+	var rels []goRelation
+	table := func(schema, name string) *inspect.Table {
+		if s := db.Schema(schema); s != nil {
+			return s.Table(name)
+		}
+		return nil
+	}
+	for _, fk := range t.FKs {
+		rt := table(fk.RefSchema, fk.RefTable)
+		if rt == nil {
+			continue
+		}
+		name := g.names[rt]
+		if len(fk.Columns) == 1 && strings.HasSuffix(fk.Columns[0], "_id") {
+			name = camelCase(strings.TrimSuffix(fk.Columns[0], "_id"))
+		}
+		rels = append(rels, goRelation{name: relationName(name, fields), typ: "*" + g.names[rt], kind: "belongs-to", fk: fk})
+	}
+	for _, r := range t.ReferencedBy {
+		child := table(r.Schema, r.Table)
+		if child == nil {
+			continue
+		}
+		switch r.FK.Cardinality {
+		case inspect.OneToOne:
+			rels = append(rels, goRelation{name: relationName(g.names[child], fields), typ: "*" + g.names[child],
+				kind: "has-one", fk: r.FK})
+		case inspect.ManyToMany:
+			through := child.FKs[0]
+			if through.Name == r.FK.Name {
+				through = child.FKs[1]
+			}
+			other := table(through.RefSchema, through.RefTable)
+			if other == nil {
+				continue
+			}
+			rels = append(rels, goRelation{name: relationName(camelCase(other.Name), fields), typ: "[]*" + g.names[other],
+				kind: "many-to-many", fk: r.FK, join: child, through: through})
+		default:
+			rels = append(rels, goRelation{name: relationName(camelCase(child.Name), fields), typ: "[]*" + g.names[child],
+				kind: "has-many", fk: r.FK})
+		}
+	}
+	return rels
+}
+
+// gormTag returns the gorm tag of the relation field r.
+func gormTag(r goRelation) string {
+	var fks, refs []string
+	for i := range r.fk.Columns {
+		fks = append(fks, camelCase(r.fk.Columns[i]))
+		refs = append(refs, camelCase(r.fk.RefColumns[i]))
+	}
+	if r.kind != "many-to-many" {
+		return fmt.Sprintf("foreignKey:%s;references:%s", strings.Join(fks, ","), strings.Join(refs, ","))
+	}
+	var joinFKs, joinRefs []string
+	for i := range r.through.Columns {
+		joinFKs = append(joinFKs, camelCase(r.through.Columns[i]))
+		joinRefs = append(joinRefs, camelCase(r.through.RefColumns[i]))
+	}
+	return fmt.Sprintf("many2many:%s;foreignKey:%s;joinForeignKey:%s;references:%s;joinReferences:%s",
+		r.join.Schema+"."+r.join.Name, strings.Join(refs, ","), strings.Join(fks, ","),
+		strings.Join(joinRefs, ","), strings.Join(joinFKs, ","))
+}
+
+func (g *goGenerator) writeTable(buf *bytes.Buffer, db *inspect.Database, t *inspect.Table) {
+	name := g.names[t]
+	fields := make(map[string]bool)
+	fmt.Fprintf(buf, "// %s is the %s.%s %s.\n", name, t.Schema, t.Name, strings.ToLower(t.Type))
+	if t.Comment != "" {
+		fmt.Fprintf(buf, "//\n// %s\n", strings.Replace(t.Comment, "\n", "\n// ", -1))
+	}
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		field := camelCase(c.Name)
+		fields[field] = true
+		if c.Comment != "" {
+			fmt.Fprintf(buf, "// %s\n", strings.Replace(c.Comment, "\n", "\n// ", -1))
+		}
+		switch {
+		case c.Generated != "":
+			fmt.Fprintf(buf, "// Read only, generated as %s.\n", strings.Replace(c.Generated, "\n", " ", -1))
+		case c.Identity == "ALWAYS":
+			buf.WriteString("// Read only, generated always as identity.\n")
+		}
+		typ := g.goType(c)
+		if vt := g.valuesType(t, c); vt != "" {
+			typ = strings.Replace(typ, "string", vt, 1)
+		}
+		fmt.Fprintf(buf, "%s %s %s\n", field, typ, goTag(g.fieldTag(t, c)))
+	}
+
+	rels := g.relations(db, t, fields)
+	switch {
+	case g.flavor == "gorm":
+		for _, r := range rels {
+			fmt.Fprintf(buf, "%s %s %s\n", r.name, r.typ,
+				goTag(fmt.Sprintf(`gorm:%q json:%q`, gormTag(r), strings.ToLower(r.name)+",omitempty")))
+		}
+	case g.flavor == "sqlboiler" && len(rels) > 0:
+		fmt.Fprintf(buf, "\nR *%sR `boil:\"-\" json:\"-\" toml:\"-\" yaml:\"-\"`\n", lowerFirst(name))
+	}
+	buf.WriteString("}\n\n")
+
+	if g.flavor == "sqlboiler" && len(rels) > 0 {
+		fmt.Fprintf(buf, "// %sR holds the relationships of %s.\n", lowerFirst(name), name)
+		fmt.Fprintf(buf, "type %sR struct {\n", lowerFirst(name))
+		for _, r := range rels {
+			fmt.Fprintf(buf, "%s %s `boil:\"%s\" json:\"%s\" toml:\"%s\" yaml:\"%s\"`\n",
+				r.name, r.typ, r.name, r.name, r.name, r.name)
+		}
+		buf.WriteString("}\n\n")
+	}
+
+	fmt.Fprintf(buf, "// TableName returns the qualified name of the %s table.\n", t.Name)
+	fmt.Fprintf(buf, "func (%s) TableName() string { return %q }\n\n", name, t.Schema+"."+t.Name)
+
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		vt := g.valuesType(t, c)
+		if vt == "" {
+			continue
+		}
+		fmt.Fprintf(buf, "// %s is a value of %s.%s allowed by its check constraint.\n", vt, t.Name, c.Name)
+		fmt.Fprintf(buf, "type %s string\n\n", vt)
+		fmt.Fprintf(buf, "// Values of %s.\nconst (\n", vt)
+		seen := make(map[string]bool)
+		for j, v := range c.AllowedValues {
+			constName := vt + camelCase(v)
+			if seen[constName] {
+				constName = fmt.Sprintf("%s%d", constName, j)
+			}
+			seen[constName] = true
+			fmt.Fprintf(buf, "%s %s = %s\n", constName, vt, strconv.Quote(v))
+		}
+		buf.WriteString(")\n\n")
+	}
+}
+
+// valuesType returns the name of the string type generated for a text
+// column limited to AllowedValues by a check constraint, e.g. OrderStatus
+// for orders.status, or an empty string.
+func (g *goGenerator) valuesType(t *inspect.Table, c *inspect.Column) string {
+	if len(c.AllowedValues) == 0 || g.flavor == "sqlboiler" && c.Nullable {
+		return ""
+	}
+	if typ, ok := goTypes[c.UDTName]; ok && typ != "string" {
+		return ""
+	}
+	return g.names[t] + camelCase(c.Name)
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// generate returns formatted Go source with a struct per table.
+func (g *goGenerator) generate(db *inspect.Database, pkg string) ([]byte, error) {
+	var body bytes.Buffer
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			g.writeTable(&body, db, t)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by pg-inspector from database %s. DO NOT EDIT.\n\n", db.Name)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for imp := range g.imports {
+			imports = append(imports, imp)
+		}
+		// Standard library imports go first, separated from the rest.
+		sort.Slice(imports, func(i, j int) bool {
+			si, sj := !strings.Contains(imports[i], "."), !strings.Contains(imports[j], ".")
+			if si != sj {
+				return si
+			}
+			return imports[i] < imports[j]
+		})
+		buf.WriteString("import (\n")
+		for i, imp := range imports {
+			if i > 0 && !strings.Contains(imports[i-1], ".") && strings.Contains(imp, ".") {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(&buf, "%q\n", imp)
+		}
+		buf.WriteString(")\n\n")
+	}
+	buf.Write(body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// genGo writes Go structs for the inspected tables.
+func (a *app) genGo(args []string) {
+	fs := flag.NewFlagSet("gen go", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	pkg := fs.String("package", "models", "Package name of the generated file.")
+	flavor := fs.String("flavor", "plain", "Struct tags and relations for: plain, gorm or sqlboiler.")
+	a.parseFlags(fs, args)
+	switch *flavor {
+	case "plain", "gorm", "sqlboiler":
+	default:
+		a.log.Fatalf("unknown flavor %q", *flavor)
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	src, err := newGoGenerator(db, *flavor).generate(db, *pkg)
+	if err != nil {
+		a.log.WithError(err).Fatal("generate Go code")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if _, err := w.Write(src); err != nil {
+		a.log.WithError(err).Fatal("write Go code")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write Go code")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// grantRow is a table or column of the privileges matrix with the
+// privileges of each grantee.
+type grantRow struct {
+	object string
+	kind   string
+	cells  map[string]string // by grantee, PUBLIC for all roles
+}
+
+// grantsMatrix returns the grantees and a row per table and per column
+// with direct grants. Privileges inherited through role membership are not
+// expanded.
+func grantsMatrix(db *inspect.Database) ([]string, []grantRow) {
+	seen := map[string]bool{}
+	cells := func(acl []string) map[string]string {
+		m := map[string]string{}
+		for _, s := range acl {
+			item, err := inspect.ParseACLItem(s)
+			if err != nil {
+				continue
+			}
+			grantee := item.Grantee
+			if grantee == "" {
+				grantee = "PUBLIC"
+			}
+			seen[grantee] = true
+			m[grantee] = inspect.PrivilegeList(item.Privileges)
+		}
+		return m
+	}
+	var rows []grantRow
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			rows = append(rows, grantRow{qualifiedName(t), "table", cells(t.ACL)})
+			for _, c := range t.Columns {
+				if len(c.ACL) > 0 {
+					rows = append(rows, grantRow{qualifiedName(t) + "." + c.Name, "column", cells(c.ACL)})
+				}
+			}
+		}
+	}
+	grantees := make([]string, 0, len(seen))
+	for g := range seen {
+		grantees = append(grantees, g)
+	}
+	sort.Strings(grantees)
+	return grantees, rows
+}
+
+func writeGrantsCSV(w io.Writer, db *inspect.Database) error {
+	grantees, rows := grantsMatrix(db)
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"object", "type"}, grantees...))
+	for _, r := range rows {
+		record := []string{r.object, r.kind}
+		for _, g := range grantees {
+			record = append(record, r.cells[g])
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeGrantsHTML(w io.Writer, db *inspect.Database) error {
+	grantees, rows := grantsMatrix(db)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Privileges of %[1]s</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse}th,td{border:1px solid #ccc;padding:2px 6px;font-size:small}tr.column td:first-child{padding-left:2em}</style>
+</head><body><h1>Privileges of %[1]s</h1>
+<table><tr><th>Object</th>`, html.EscapeString(db.Name))
+	for _, g := range grantees {
+		fmt.Fprintf(bw, "<th>%s</th>", html.EscapeString(g))
+	}
+	bw.WriteString("</tr>\n")
+	for _, r := range rows {
+		fmt.Fprintf(bw, "<tr class=%q><td>%s</td>", r.kind, html.EscapeString(r.object))
+		for _, g := range grantees {
+			fmt.Fprintf(bw, "<td>%s</td>", html.EscapeString(r.cells[g]))
+		}
+		bw.WriteString("</tr>\n")
+	}
+	bw.WriteString("</table></body></html>\n")
+	return bw.Flush()
+}
+
+// genGrants writes the privileges matrix: tables and columns by grantees.
+func (a *app) genGrants(args []string) {
+	fs := flag.NewFlagSet("gen grants", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	format := fs.String("format", "csv", "Output format: csv or html.")
+	a.parseFlags(fs, args)
+	render := map[string]func(io.Writer, *inspect.Database) error{"csv": writeGrantsCSV, "html": writeGrantsHTML}[*format]
+	if render == nil {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := render(w, db); err != nil {
+		a.log.WithError(err).Fatal("write grants")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write grants")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// conflictTarget is the primary key, or else the first unique constraint,
+// whose columns are all inserted. It is empty if there is none.
+func conflictTarget(t *inspect.Table, inserted map[string]bool) []string {
+	sets := uniqueSets(t)
+	for _, set := range sets {
+		ok := true
+		for _, c := range set {
+			ok = ok && inserted[c]
+		}
+		if ok {
+			return set
+		}
+	}
+	return nil
+}
+
+// writeInsertTemplates writes a parameterized INSERT and, where a conflict
+// target exists, an UPSERT per base table. The statements are annotated in
+// the sqlc query format.
+func writeInsertTemplates(w io.Writer, db *inspect.Database, placeholder string) error {
+	bw := bufio.NewWriter(w)
+	first := true
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			var columns, returning, params []string
+			inserted := make(map[string]bool)
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				if c.ServerGenerated() {
+					returning = append(returning, c.Name)
+					continue
+				}
+				columns = append(columns, c.Name)
+				inserted[c.Name] = true
+				if placeholder == "question" {
+					params = append(params, "?")
+				} else {
+					params = append(params, "$"+strconv.Itoa(len(columns)))
+				}
+			}
+			if len(columns) == 0 {
+				continue
+			}
+			kind, ret := ":exec", ""
+			if len(returning) > 0 {
+				kind, ret = ":one", "\nRETURNING "+sqlIdents(returning)
+			}
+			insert := fmt.Sprintf("INSERT INTO %s (%s)\nVALUES (%s)", tableRef(t), sqlIdents(columns), strings.Join(params, ", "))
+			name := camelCase(singular(t.Name))
+			if !first {
+				bw.WriteString("\n")
+			}
+			first = false
+			fmt.Fprintf(bw, "-- name: Insert%s %s\n%s%s;\n", name, kind, insert, ret)
+
+			target := conflictTarget(t, inserted)
+			if target == nil {
+				continue
+			}
+			var sets []string
+			for _, c := range columns {
+				if !containsString(target, c) {
+					sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", inspect.QuoteIdent(c), inspect.QuoteIdent(c)))
+				}
+			}
+			action := "DO NOTHING"
+			if len(sets) > 0 {
+				action = "DO UPDATE SET\n    " + strings.Join(sets, ",\n    ")
+			}
+			fmt.Fprintf(bw, "\n-- name: Upsert%s %s\n%s\nON CONFLICT (%s) %s%s;\n", name, kind, insert,
+				sqlIdents(target), action, ret)
+		}
+	}
+	return bw.Flush()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// genInsert writes INSERT and UPSERT templates for hand-written loaders.
+func (a *app) genInsert(args []string) {
+	fs := flag.NewFlagSet("gen insert", flag.ContinueOnError)
+	placeholder := fs.String("placeholder", "dollar", "Parameter style: dollar ($1) or question (?).")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+	if *placeholder != "dollar" && *placeholder != "question" {
+		a.log.Fatalf("unknown placeholder style %q", *placeholder)
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeInsertTemplates(w, db, *placeholder); err != nil {
+		a.log.WithError(err).Fatal("write templates")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write templates")
+	}
+}
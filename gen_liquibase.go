@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// A Liquibase changelog, encoded as YAML or XML. The YAML format wraps
+// every list item in a single key map, e.g. - changeSet: {...}, which the
+// MarshalXML methods of the *Item types and lbChange unwrap to plain XML
+// elements.
+type lbChangeLog struct {
+	XMLName    xml.Name          `yaml:"-" xml:"databaseChangeLog"`
+	Xmlns      string            `yaml:"-" xml:"xmlns,attr"`
+	ChangeSets []lbChangeSetItem `yaml:"databaseChangeLog" xml:"changeSet"`
+}
+
+type lbChangeSetItem struct {
+	ChangeSet lbChangeSet `yaml:"changeSet"`
+}
+
+func (i lbChangeSetItem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(i.ChangeSet, start)
+}
+
+type lbChangeSet struct {
+	ID      string     `yaml:"id" xml:"id,attr"`
+	Author  string     `yaml:"author" xml:"author,attr"`
+	Changes []lbChange `yaml:"changes" xml:"change"`
+}
+
+// lbChange is one change, only one field is set.
+type lbChange struct {
+	SQL                     *lbSQL                     `yaml:"sql,omitempty"`
+	CreateTable             *lbCreateTable             `yaml:"createTable,omitempty"`
+	AddUniqueConstraint     *lbAddUniqueConstraint     `yaml:"addUniqueConstraint,omitempty"`
+	AddForeignKeyConstraint *lbAddForeignKeyConstraint `yaml:"addForeignKeyConstraint,omitempty"`
+}
+
+func (c lbChange) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	var name string
+	var v interface{}
+	switch {
+	case c.SQL != nil:
+		name, v = "sql", c.SQL
+	case c.CreateTable != nil:
+		name, v = "createTable", c.CreateTable
+	case c.AddUniqueConstraint != nil:
+		name, v = "addUniqueConstraint", c.AddUniqueConstraint
+	case c.AddForeignKeyConstraint != nil:
+		name, v = "addForeignKeyConstraint", c.AddForeignKeyConstraint
+	default:
+		return nil
+	}
+	return e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+type lbSQL struct {
+	SQL string `yaml:"sql" xml:",chardata"`
+}
+
+type lbCreateTable struct {
+	SchemaName string         `yaml:"schemaName" xml:"schemaName,attr"`
+	TableName  string         `yaml:"tableName" xml:"tableName,attr"`
+	Remarks    string         `yaml:"remarks,omitempty" xml:"remarks,attr,omitempty"`
+	Columns    []lbColumnItem `yaml:"columns" xml:"column"`
+}
+
+type lbColumnItem struct {
+	Column lbColumn `yaml:"column"`
+}
+
+func (i lbColumnItem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(i.Column, start)
+}
+
+type lbColumn struct {
+	Name                 string         `yaml:"name" xml:"name,attr"`
+	Type                 string         `yaml:"type" xml:"type,attr"`
+	AutoIncrement        bool           `yaml:"autoIncrement,omitempty" xml:"autoIncrement,attr,omitempty"`
+	GenerationType       string         `yaml:"generationType,omitempty" xml:"generationType,attr,omitempty"`
+	DefaultValueComputed string         `yaml:"defaultValueComputed,omitempty" xml:"defaultValueComputed,attr,omitempty"`
+	Remarks              string         `yaml:"remarks,omitempty" xml:"remarks,attr,omitempty"`
+	Constraints          *lbConstraints `yaml:"constraints,omitempty" xml:"constraints,omitempty"`
+}
+
+type lbConstraints struct {
+	Nullable       bool   `yaml:"nullable" xml:"nullable,attr"`
+	PrimaryKey     bool   `yaml:"primaryKey,omitempty" xml:"primaryKey,attr,omitempty"`
+	PrimaryKeyName string `yaml:"primaryKeyName,omitempty" xml:"primaryKeyName,attr,omitempty"`
+}
+
+type lbAddUniqueConstraint struct {
+	SchemaName     string `yaml:"schemaName" xml:"schemaName,attr"`
+	TableName      string `yaml:"tableName" xml:"tableName,attr"`
+	ColumnNames    string `yaml:"columnNames" xml:"columnNames,attr"`
+	ConstraintName string `yaml:"constraintName" xml:"constraintName,attr"`
+}
+
+type lbAddForeignKeyConstraint struct {
+	BaseTableSchemaName       string `yaml:"baseTableSchemaName" xml:"baseTableSchemaName,attr"`
+	BaseTableName             string `yaml:"baseTableName" xml:"baseTableName,attr"`
+	BaseColumnNames           string `yaml:"baseColumnNames" xml:"baseColumnNames,attr"`
+	ConstraintName            string `yaml:"constraintName" xml:"constraintName,attr"`
+	ReferencedTableSchemaName string `yaml:"referencedTableSchemaName" xml:"referencedTableSchemaName,attr"`
+	ReferencedTableName       string `yaml:"referencedTableName" xml:"referencedTableName,attr"`
+	ReferencedColumnNames     string `yaml:"referencedColumnNames" xml:"referencedColumnNames,attr"`
+	OnUpdate                  string `yaml:"onUpdate,omitempty" xml:"onUpdate,attr,omitempty"`
+	OnDelete                  string `yaml:"onDelete,omitempty" xml:"onDelete,attr,omitempty"`
+}
+
+const lbAuthor = "pg-inspector"
+
+// liquibaseChangeLog builds a changelog with a change set creating the
+// schemas and enums, one per base table with its columns, keys, checks
+// and indexes, and one per table adding its foreign keys after all tables
+// exist. What Liquibase has no change type for is raw SQL.
+func liquibaseChangeLog(db *inspect.Database) *lbChangeLog {
+	log := &lbChangeLog{Xmlns: "http://www.liquibase.org/xml/ns/dbchangelog"}
+	add := func(id string, changes []lbChange) {
+		if len(changes) > 0 {
+			log.ChangeSets = append(log.ChangeSets, lbChangeSetItem{lbChangeSet{ID: id, Author: lbAuthor, Changes: changes}})
+		}
+	}
+	sql := func(format string, args ...interface{}) lbChange {
+		return lbChange{SQL: &lbSQL{SQL: fmt.Sprintf(format, args...)}}
+	}
+
+	var types []lbChange
+	for _, s := range db.Schemas {
+		if s.Name != "public" {
+			types = append(types, sql("CREATE SCHEMA IF NOT EXISTS %s", inspect.QuoteIdent(s.Name)))
+		}
+		for _, e := range s.Enums {
+			labels := make([]string, len(e.Labels))
+			for i, l := range e.Labels {
+				labels[i] = sqlLiteral(l)
+			}
+			types = append(types, sql("CREATE TYPE %s AS ENUM (%s)", inspect.QualifiedName(s.Name, e.Name),
+				strings.Join(labels, ", ")))
+		}
+	}
+	add("schemas", types)
+
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			create := &lbCreateTable{SchemaName: t.Schema, TableName: t.Name, Remarks: t.Comment}
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				// Only a single column key fits the column constraints.
+				pk := len(t.PK.Columns) == 1 && t.PK.Columns[0] == c.Name
+				col := lbColumn{Name: c.Name, Type: sqlType(c), Remarks: c.Comment}
+				switch {
+				case c.Identity != "":
+					col.AutoIncrement = true
+					col.GenerationType = c.Identity
+				case c.Generated != "":
+					// Liquibase passes unknown types through verbatim.
+					col.Type += " GENERATED ALWAYS AS (" + c.Generated + ") STORED"
+				case c.Default != "":
+					col.DefaultValueComputed = c.Default
+				}
+				if !c.Nullable || pk {
+					col.Constraints = &lbConstraints{Nullable: c.Nullable}
+				}
+				if pk {
+					col.Constraints.PrimaryKey = true
+					col.Constraints.PrimaryKeyName = t.PK.Name
+				}
+				create.Columns = append(create.Columns, lbColumnItem{col})
+			}
+			changes := []lbChange{{CreateTable: create}}
+			if len(t.PK.Columns) > 1 {
+				changes = append(changes, sql("ALTER TABLE %s ADD CONSTRAINT %s PRIMARY KEY (%s)",
+					inspect.QualifiedName(t.Schema, t.Name), inspect.QuoteIdent(t.PK.Name), sqlIdents(t.PK.Columns)))
+			}
+			constraints := make(map[string]bool)
+			for _, c := range t.Constraints {
+				constraints[c.Name] = true
+				if c.Type == "UNIQUE" {
+					changes = append(changes, lbChange{AddUniqueConstraint: &lbAddUniqueConstraint{
+						SchemaName: t.Schema, TableName: t.Name, ColumnNames: strings.Join(c.Columns, ", "), ConstraintName: c.Name,
+					}})
+					continue
+				}
+				changes = append(changes, sql("ALTER TABLE %s ADD CONSTRAINT %s %s",
+					inspect.QualifiedName(t.Schema, t.Name), inspect.QuoteIdent(c.Name), c.Definition))
+			}
+			for _, idx := range t.Indexes {
+				if idx.Primary || constraints[idx.Name] {
+					continue
+				}
+				changes = append(changes, sql("%s", idx.Definition))
+			}
+			add(qualifiedName(t), changes)
+		}
+	}
+
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			var changes []lbChange
+			for _, fk := range t.FKs {
+				changes = append(changes, lbChange{AddForeignKeyConstraint: &lbAddForeignKeyConstraint{
+					BaseTableSchemaName:       t.Schema,
+					BaseTableName:             t.Name,
+					BaseColumnNames:           strings.Join(fk.Columns, ", "),
+					ConstraintName:            fk.Name,
+					ReferencedTableSchemaName: fk.RefSchema,
+					ReferencedTableName:       fk.RefTable,
+					ReferencedColumnNames:     strings.Join(fk.RefColumns, ", "),
+					OnUpdate:                  fk.OnUpdate,
+					OnDelete:                  fk.OnDelete,
+				}})
+			}
+			add(qualifiedName(t)+"-foreign-keys", changes)
+		}
+	}
+	return log
+}
+
+// writeLiquibase writes the changelog of db as yaml or xml.
+func writeLiquibase(w io.Writer, db *inspect.Database, format string) error {
+	log := liquibaseChangeLog(db)
+	if format == "xml" {
+		io.WriteString(w, xml.Header)
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(log); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+	b, err := yaml.Marshal(log)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// genLiquibase writes a Liquibase changelog creating the schema, to
+// baseline Liquibase on an existing database.
+func (a *app) genLiquibase(args []string) {
+	fs := flag.NewFlagSet("gen liquibase", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	format := fs.String("format", "yaml", "Changelog format: yaml or xml.")
+	a.parseFlags(fs, args)
+	if *format != "yaml" && *format != "xml" {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeLiquibase(w, db, *format); err != nil {
+		a.log.WithError(err).Fatal("write changelog")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write changelog")
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"io"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// A pgModeler model, the .dbm file format. Objects come in dependency
+// order, as pgModeler reads them: schemas, types, tables with their
+// columns, keys and checks, indexes, then foreign keys. pgModeler draws
+// the relationships of the foreign keys itself.
+type dbmModel struct {
+	XMLName       xml.Name        `xml:"dbmodel"`
+	Version       string          `xml:"pgmodeler-ver,attr"`
+	DefaultSchema string          `xml:"default-schema,attr"`
+	Database      dbmDatabase     `xml:"database"`
+	Schemas       []dbmSchema     `xml:"schema"`
+	Types         []dbmUserType   `xml:"usertype"`
+	Tables        []dbmTable      `xml:"table"`
+	Indexes       []dbmIndex      `xml:"index"`
+	ForeignKeys   []dbmConstraint `xml:"constraint"`
+}
+
+type dbmDatabase struct {
+	Name string `xml:"name,attr"`
+}
+
+// dbmRef refers to another object by name, e.g. <schema name="public"/>.
+type dbmRef struct {
+	Name string `xml:"name,attr"`
+}
+
+type dbmText struct {
+	Text string `xml:",cdata"`
+}
+
+func newDBMText(s string) *dbmText {
+	if s == "" {
+		return nil
+	}
+	return &dbmText{s}
+}
+
+type dbmSchema struct {
+	Name        string `xml:"name,attr"`
+	FillColor   string `xml:"fill-color,attr"`
+	SQLDisabled bool   `xml:"sql-disabled,attr,omitempty"`
+}
+
+type dbmUserType struct {
+	Name          string  `xml:"name,attr"`
+	Configuration string  `xml:"configuration,attr"`
+	Schema        dbmRef  `xml:"schema"`
+	Enumeration   dbmEnum `xml:"enumeration"`
+}
+
+type dbmEnum struct {
+	Values string `xml:"values,attr"`
+}
+
+type dbmTable struct {
+	Name        string          `xml:"name,attr"`
+	Schema      dbmRef          `xml:"schema"`
+	Comment     *dbmText        `xml:"comment,omitempty"`
+	Position    dbmPosition     `xml:"position"`
+	Columns     []dbmColumn     `xml:"column"`
+	Constraints []dbmConstraint `xml:"constraint"`
+}
+
+type dbmPosition struct {
+	X int `xml:"x,attr"`
+	Y int `xml:"y,attr"`
+}
+
+type dbmColumn struct {
+	Name         string   `xml:"name,attr"`
+	NotNull      bool     `xml:"not-null,attr,omitempty"`
+	DefaultValue string   `xml:"default-value,attr,omitempty"`
+	IdentityType string   `xml:"identity-type,attr,omitempty"`
+	Generated    bool     `xml:"generated,attr,omitempty"`
+	Type         dbmType  `xml:"type"`
+	Comment      *dbmText `xml:"comment,omitempty"`
+}
+
+type dbmType struct {
+	Name      string `xml:"name,attr"`
+	Length    int    `xml:"length,attr"`
+	Precision int    `xml:"precision,attr,omitempty"`
+	Dimension int    `xml:"dimension,attr,omitempty"`
+}
+
+// dbmConstraint is a pk-constr, uq-constr, ck-constr or fk-constr.
+type dbmConstraint struct {
+	Name           string       `xml:"name,attr"`
+	Type           string       `xml:"type,attr"`
+	ComparisonType string       `xml:"comparison-type,attr,omitempty"`
+	UpdateAction   string       `xml:"upd-action,attr,omitempty"`
+	DeleteAction   string       `xml:"del-action,attr,omitempty"`
+	RefTable       string       `xml:"ref-table,attr,omitempty"`
+	Table          string       `xml:"table,attr"`
+	Columns        []dbmColumns `xml:"columns"`
+	Expression     *dbmText     `xml:"expression,omitempty"`
+}
+
+type dbmColumns struct {
+	Names   string `xml:"names,attr"`
+	RefType string `xml:"ref-type,attr"` // src-columns or dst-columns
+}
+
+type dbmIndex struct {
+	Name      string            `xml:"name,attr"`
+	Table     string            `xml:"table,attr"`
+	Unique    bool              `xml:"unique,attr"`
+	IndexType string            `xml:"index-type,attr"`
+	Elements  []dbmIndexElement `xml:"idxelement"`
+	Predicate *dbmText          `xml:"predicate,omitempty"`
+}
+
+type dbmIndexElement struct {
+	UseSorting bool     `xml:"use-sorting,attr"`
+	Column     *dbmRef  `xml:"column,omitempty"`
+	Expression *dbmText `xml:"expression,omitempty"`
+}
+
+// dbmTypeNames are pgModeler's names of types by udt_name.
+var dbmTypeNames = map[string]string{
+	"int2": "smallint", "int4": "integer", "int8": "bigint",
+	"float4": "real", "float8": "double precision",
+	"bool": "boolean", "bpchar": "char",
+}
+
+func dbmColumnType(c *inspect.Column) dbmType {
+	name := strings.TrimPrefix(c.UDTName, "_")
+	var typ dbmType
+	if c.DataType == "ARRAY" {
+		typ.Dimension = 1
+	}
+	switch {
+	case c.DataType == "USER-DEFINED":
+		typ.Name = c.UDTSchema + "." + c.UDTName
+	case dbmTypeNames[name] != "":
+		typ.Name = dbmTypeNames[name]
+	default:
+		typ.Name = name
+	}
+	if c.UDTName == "numeric" {
+		typ.Length, typ.Precision = c.Precision, c.Scale
+	} else {
+		typ.Length = c.MaxLength
+	}
+	return typ
+}
+
+// dbmGrid is the number of tables in a row of the initial layout, which
+// the designer is expected to rearrange.
+const dbmGrid = 5
+
+// pgModelerModel builds a model of the schemas, enums and base tables of
+// db with their keys, checks and indexes.
+func pgModelerModel(db *inspect.Database) *dbmModel {
+	m := &dbmModel{Version: "1.0.0", DefaultSchema: "public", Database: dbmDatabase{Name: db.Name}}
+	n := 0
+	for _, s := range db.Schemas {
+		// public exists in every database.
+		m.Schemas = append(m.Schemas, dbmSchema{Name: s.Name, FillColor: "#e1e1e1", SQLDisabled: s.Name == "public"})
+		for _, e := range s.Enums {
+			m.Types = append(m.Types, dbmUserType{Name: e.Name, Configuration: "enumeration", Schema: dbmRef{s.Name},
+				Enumeration: dbmEnum{strings.Join(e.Labels, ",")}})
+		}
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			m.addTable(t, dbmPosition{X: 60 + n%dbmGrid*320, Y: 60 + n/dbmGrid*320})
+			n++
+		}
+	}
+	return m
+}
+
+func (m *dbmModel) addTable(t *inspect.Table, pos dbmPosition) {
+	name := qualifiedName(t)
+	dt := dbmTable{Name: t.Name, Schema: dbmRef{t.Schema}, Comment: newDBMText(t.Comment), Position: pos}
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		col := dbmColumn{Name: c.Name, NotNull: !c.Nullable, Type: dbmColumnType(c), Comment: newDBMText(c.Comment)}
+		switch {
+		case c.Identity != "":
+			col.IdentityType = c.Identity
+		case c.Generated != "":
+			col.DefaultValue, col.Generated = c.Generated, true
+		default:
+			col.DefaultValue = c.Default
+		}
+		dt.Columns = append(dt.Columns, col)
+	}
+	if len(t.PK.Columns) > 0 {
+		dt.Constraints = append(dt.Constraints, dbmConstraint{Name: t.PK.Name, Type: "pk-constr", Table: name,
+			Columns: []dbmColumns{{strings.Join(t.PK.Columns, ","), "src-columns"}}})
+	}
+	constraints := make(map[string]bool)
+	for _, c := range t.Constraints {
+		switch c.Type {
+		case "UNIQUE":
+			dt.Constraints = append(dt.Constraints, dbmConstraint{Name: c.Name, Type: "uq-constr", Table: name,
+				Columns: []dbmColumns{{strings.Join(c.Columns, ","), "src-columns"}}})
+		case "CHECK":
+			dt.Constraints = append(dt.Constraints, dbmConstraint{Name: c.Name, Type: "ck-constr", Table: name,
+				Expression: newDBMText(checkExpr(c.Definition))})
+		default:
+			// Exclusion constraints are kept as their indexes.
+			continue
+		}
+		constraints[c.Name] = true
+	}
+	m.Tables = append(m.Tables, dt)
+
+	for _, idx := range t.Indexes {
+		// Indexes of primary keys and unique constraints come with them.
+		if idx.Primary || constraints[idx.Name] {
+			continue
+		}
+		di := dbmIndex{Name: idx.Name, Table: name, Unique: idx.Unique, IndexType: idx.Method,
+			Predicate: newDBMText(idx.Predicate)}
+		for _, c := range idx.Columns {
+			if t.Column(c) != nil {
+				di.Elements = append(di.Elements, dbmIndexElement{Column: &dbmRef{c}})
+			} else {
+				di.Elements = append(di.Elements, dbmIndexElement{Expression: newDBMText(c)})
+			}
+		}
+		m.Indexes = append(m.Indexes, di)
+	}
+
+	for _, fk := range t.FKs {
+		m.ForeignKeys = append(m.ForeignKeys, dbmConstraint{
+			Name: fk.Name, Type: "fk-constr", ComparisonType: "MATCH SIMPLE",
+			UpdateAction: fk.OnUpdate, DeleteAction: fk.OnDelete,
+			RefTable: fk.RefSchema + "." + fk.RefTable, Table: name,
+			Columns: []dbmColumns{
+				{strings.Join(fk.Columns, ","), "src-columns"},
+				{strings.Join(fk.RefColumns, ","), "dst-columns"},
+			},
+		})
+	}
+}
+
+// writePgModeler writes the pgModeler model of db.
+func writePgModeler(w io.Writer, db *inspect.Database) error {
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(pgModelerModel(db)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// genPgModeler writes a pgModeler .dbm model, to continue designing an
+// existing database in pgModeler.
+func (a *app) genPgModeler(args []string) {
+	fs := flag.NewFlagSet("gen pgmodeler", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writePgModeler(w, db); err != nil {
+		a.log.WithError(err).Fatal("write model")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write model")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// seedInsertBatch is the number of rows per INSERT statement.
+const seedInsertBatch = 100
+
+// parseTableRows parses "schema.table=N,..." into row counts by qualified
+// name.
+func parseTableRows(s string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if s == "" {
+		return counts, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.LastIndex(kv, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid table rows %q, want schema.table=N", kv)
+		}
+		n, err := strconv.Atoi(kv[i+1:])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid row count in %q", kv)
+		}
+		counts[kv[:i]] = n
+	}
+	return counts, nil
+}
+
+// seed generates rows for all base tables in load order.
+func seed(db *inspect.Database, rows int, tableRows map[string]int, rndSeed int64) ([]*seedTable, []string) {
+	tables, deferredRefs := db.LoadOrder()
+	deferred := make(map[string]bool, len(deferredRefs))
+	for _, r := range deferredRefs {
+		deferred[r.FK.Name] = true
+	}
+	s := newSeeder(db, rndSeed)
+	var out []*seedTable
+	for _, t := range tables {
+		n, ok := tableRows[qualifiedName(t)]
+		if !ok {
+			n = rows
+		}
+		out = append(out, s.generate(t, n, deferred))
+	}
+	return out, s.warnings
+}
+
+func writeSeedSQL(w io.Writer, tables []*seedTable) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("BEGIN;\nSET CONSTRAINTS ALL DEFERRED;\n")
+	for _, st := range tables {
+		t := st.table
+		// Generated columns cannot be written, identity columns only when
+		// overriding the sequence.
+		var names []string
+		overriding := ""
+		for _, c := range t.Columns {
+			if c.Generated == "" {
+				names = append(names, c.Name)
+			}
+			if c.Identity == "ALWAYS" {
+				overriding = " OVERRIDING SYSTEM VALUE"
+			}
+		}
+		name := inspect.QualifiedName(t.Schema, t.Name)
+		for i, row := range st.rows {
+			if i%seedInsertBatch == 0 {
+				fmt.Fprintf(bw, "\nINSERT INTO %s (%s)%s VALUES\n", name, sqlIdents(names), overriding)
+			}
+			var values []string
+			for j, v := range row {
+				switch {
+				case t.Columns[j].Generated != "":
+				case v == nil:
+					values = append(values, "NULL")
+				default:
+					values = append(values, sqlLiteral(*v))
+				}
+			}
+			sep := ","
+			if i%seedInsertBatch == seedInsertBatch-1 || i == len(st.rows)-1 {
+				sep = ";"
+			}
+			fmt.Fprintf(bw, "\t(%s)%s\n", strings.Join(values, ", "), sep)
+		}
+		// Serial and identity columns were given explicit values, move
+		// their sequences past them.
+		for _, c := range t.Columns {
+			if c.DefaultKind() == inspect.DefaultSequence && len(st.rows) > 0 {
+				fmt.Fprintf(bw, "SELECT setval(pg_get_serial_sequence(%s, %s), max(%s)) FROM %s;\n",
+					sqlLiteral(name), sqlLiteral(c.Name), inspect.QuoteIdent(c.Name), name)
+			}
+		}
+	}
+	bw.WriteString("\nCOMMIT;\n")
+	return bw.Flush()
+}
+
+func seedCSV(st *seedTable) [][]string {
+	var header []string
+	for _, c := range st.table.Columns {
+		if c.Generated == "" {
+			header = append(header, c.Name)
+		}
+	}
+	rows := [][]string{header}
+	for _, row := range st.rows {
+		var r []string
+		for i, v := range row {
+			switch {
+			case st.table.Columns[i].Generated != "":
+			case v == nil:
+				r = append(r, "")
+			default:
+				r = append(r, *v)
+			}
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+// genSeed writes fake rows as INSERT statements or, with -csv-dir, as CSV
+// files to load with COPY.
+func (a *app) genSeed(args []string) {
+	fs := flag.NewFlagSet("gen seed", flag.ContinueOnError)
+	rows := fs.Int("rows", 10, "Rows per table.")
+	tableRows := fs.String("table-rows", "", "Comma separated row counts overriding -rows, e.g. public.users=100.")
+	rndSeed := fs.Int64("seed", 1, "Random seed, the same seed generates the same data.")
+	out := fs.String("o", "", "Output SQL file, stdout if empty.")
+	dir := fs.String("csv-dir", "", "Directory to write <schema>.<table>.csv files to instead of SQL.")
+	a.parseFlags(fs, args)
+	counts, err := parseTableRows(*tableRows)
+	if err != nil {
+		a.log.WithError(err).Fatal("parse -table-rows")
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	tables, warnings := seed(db, *rows, counts, *rndSeed)
+	for _, w := range warnings {
+		a.log.Warn(w)
+	}
+
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0755); err != nil {
+			a.log.WithError(err).Fatal("create csv dir")
+		}
+		for _, st := range tables {
+			path := filepath.Join(*dir, qualifiedName(st.table)+".csv")
+			if err := writeCSVFile(path, seedCSV(st)); err != nil {
+				a.log.WithError(err).Fatalf("write %s", path)
+			}
+		}
+		return
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeSeedSQL(w, tables); err != nil {
+		a.log.WithError(err).Fatal("write seed")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write seed")
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "flag"
+
+// genSQL writes a cleaned schema.sql, e.g. as the schema input of sqlc.
+func (a *app) genSQL(args []string) {
+	fs := flag.NewFlagSet("gen sql", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeDDL(w, db); err != nil {
+		a.log.WithError(err).Fatal("write schema")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write schema")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/datainq/pq-inspector/lint"
+)
+
+// xlsxReport builds a workbook with a summary, the largest tables, the lint
+// findings and a sheet of tables per schema.
+func xlsxReport(db *inspect.Database, findings []lint.Finding) *workbook {
+	wb := &workbook{}
+	summary := wb.addSheet("Summary", "schema", "owner", "tables", "views", "columns", "size_bytes", "size")
+	sizes := wb.addSheet("Sizes", "schema", "table", "row_estimate", "size_bytes", "size")
+	findingsSheet := wb.addSheet("Lint", "severity", "rule", "object", "message")
+	for _, f := range findings {
+		findingsSheet.add(string(f.Severity), f.Rule, f.Object, f.Message)
+	}
+
+	var all []*inspect.Table
+	for _, s := range db.Schemas {
+		var tables, views, columns int
+		var size int64
+		sh := wb.addSheet(s.Name, "table", "type", "owner", "columns", "primary_key", "foreign_keys",
+			"indexes", "row_estimate", "size_bytes", "size", "comment")
+		for _, t := range s.Tables {
+			if t.Type == "VIEW" || t.Type == "MATERIALIZED VIEW" {
+				views++
+			} else {
+				tables++
+			}
+			columns += len(t.Columns)
+			size += t.Stats.SizeBytes
+			all = append(all, t)
+			sh.add(t.Name, t.Type, t.Owner, len(t.Columns), strings.Join(t.PK.Columns, ", "), len(t.FKs),
+				len(t.Indexes), t.Stats.RowEstimate, t.Stats.SizeBytes, humanBytes(t.Stats.SizeBytes), t.Comment)
+		}
+		summary.add(s.Name, s.Owner, tables, views, columns, size, humanBytes(size))
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Stats.SizeBytes > all[j].Stats.SizeBytes })
+	for _, t := range all {
+		sizes.add(t.Schema, t.Name, t.Stats.RowEstimate, t.Stats.SizeBytes, humanBytes(t.Stats.SizeBytes))
+	}
+	return wb
+}
+
+// genXLSX writes an Excel workbook report.
+func (a *app) genXLSX(args []string) {
+	fs := flag.NewFlagSet("gen xlsx", flag.ContinueOnError)
+	out := fs.String("o", "report.xlsx", "Output file.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := xlsxReport(db, lint.Run(db, a.lintRules())).write(w); err != nil {
+		a.log.WithError(err).Fatal("write workbook")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write workbook")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// growthPoint is the size of a table or database at an inspection.
+type growthPoint struct {
+	at   time.Time
+	rows int64
+	size int64
+}
+
+// growth is the change in size of a table, or of the whole database,
+// across the inspections in the history.
+type growth struct {
+	name   string
+	points []growthPoint
+}
+
+func (g *growth) first() growthPoint { return g.points[0] }
+func (g *growth) last() growthPoint  { return g.points[len(g.points)-1] }
+
+// perDay is the least squares slope of the size over time in bytes per
+// day, 0 with fewer than two inspections.
+func (g *growth) perDay() float64 {
+	if len(g.points) < 2 {
+		return 0
+	}
+	origin := g.first().at
+	var sx, sy, sxx, sxy float64
+	for _, p := range g.points {
+		x, y := p.at.Sub(origin).Hours()/24, float64(p.size)
+		sx, sy, sxx, sxy = sx+x, sy+y, sxx+x*x, sxy+x*y
+	}
+	n := float64(len(g.points))
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / d
+}
+
+// forecast projects the size after the given period from the trend,
+// never below zero.
+func (g *growth) forecast(after time.Duration) int64 {
+	size := float64(g.last().size) + g.perDay()*after.Hours()/24
+	if size < 0 {
+		return 0
+	}
+	return int64(size)
+}
+
+// signedBytes formats a change in size, e.g. +1.5 GiB or -20 B.
+func signedBytes(n int64) string {
+	if n < 0 {
+		return "-" + humanBytes(-n)
+	}
+	return "+" + humanBytes(n)
+}
+
+// tableGrowth groups the recorded table sizes by table, in the order of
+// the stats. The database total sums the tables of each inspection.
+func tableGrowth(stats []tableStat) (total *growth, tables []*growth) {
+	total = &growth{name: "(database)"}
+	byName := make(map[string]*growth)
+	var inspection int64
+	for _, s := range stats {
+		name := s.schema + "." + s.name
+		g := byName[name]
+		if g == nil {
+			g = &growth{name: name}
+			byName[name] = g
+			tables = append(tables, g)
+		}
+		p := growthPoint{at: s.at, rows: s.rowEstimate, size: s.sizeBytes}
+		g.points = append(g.points, p)
+		if n := len(total.points); n > 0 && s.inspection == inspection {
+			total.points[n-1].rows += p.rows
+			total.points[n-1].size += p.size
+		} else {
+			total.points = append(total.points, p)
+			inspection = s.inspection
+		}
+	}
+	return total, tables
+}
+
+// writeGrowth writes the growth of the database and of the top tables by
+// size increase, with the sizes forecast after the given period.
+func writeGrowth(w io.Writer, database string, total *growth, tables []*growth, top int, after time.Duration) error {
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].last().size-tables[i].first().size > tables[j].last().size-tables[j].first().size
+	})
+	if top > 0 && len(tables) > top {
+		tables = tables[:top]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Database %s, %d inspections from %s to %s\n\n", database, len(total.points),
+		total.first().at.Format("2006-01-02 15:04"), total.last().at.Format("2006-01-02 15:04"))
+	fmt.Fprintf(tw, "TABLE\tROWS\tROWS CHANGE\tSIZE\tSIZE CHANGE\tPER DAY\tIN %s\n", humanDuration(after))
+	for _, g := range append([]*growth{total}, tables...) {
+		first, last := g.first(), g.last()
+		fmt.Fprintf(tw, "%s\t%d\t%+d\t%s\t%s\t%s\t%s\n", g.name, last.rows, last.rows-first.rows, humanBytes(last.size),
+			signedBytes(last.size-first.size), signedBytes(int64(g.perDay())), humanBytes(g.forecast(after)))
+	}
+	return tw.Flush()
+}
+
+// runGrowth reports the growth in rows and size of the database and its
+// tables across the inspections recorded in the -history store, with a
+// linear forecast.
+func (a *app) runGrowth(args []string) {
+	fs := flag.NewFlagSet("growth", flag.ContinueOnError)
+	database := fs.String("database", "", "Database to report, the only one in the history if empty.")
+	since := fs.Duration("since", 0, "Report the growth of this last period only, all the history if 0.")
+	top := fs.Int("top", 10, "Number of fastest growing tables to list, all if 0.")
+	after := fs.Duration("forecast", 90*24*time.Hour, "Period after the last inspection to forecast the sizes for.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	h := a.openHistory()
+	defer h.Close()
+	name := a.historyDatabase(h, *database)
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+	stats, err := h.tableStats(name, from)
+	if err != nil {
+		a.log.WithError(err).Fatal("read history")
+	}
+	total, tables := tableGrowth(stats)
+	if len(total.points) < 2 {
+		a.log.Fatalf("growth needs at least two inspections of %q with tables", name)
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeGrowth(w, name, total, tables, *top, *after); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/datainq/pq-inspector/diff"
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// historyCommands are the subcommands of history.
+var historyCommands = map[string]func(a *app, args []string){
+	"list":   (*app).historyList,
+	"record": (*app).historyRecord,
+	"show":   (*app).historyShow,
+	"prune":  (*app).historyPrune,
+}
+
+// openHistory opens the -history store, failing if there is none.
+func (a *app) openHistory() *historyStore {
+	if a.history == "" {
+		a.log.Fatal("no history store, set -history or history.path in the config")
+	}
+	h, err := openHistory(a.history)
+	if err != nil {
+		a.log.WithError(err).Fatal("open history")
+	}
+	return h
+}
+
+// recordHistory adds db to the -history store, if any, and applies the
+// retention of the config. Failures are logged, recording never stops
+// the command that inspected db.
+func (a *app) recordHistory(db *inspect.Database) {
+	if a.history == "" {
+		return
+	}
+	h, err := openHistory(a.history)
+	if err == nil {
+		_, err = a.record(h, db)
+		h.Close()
+	}
+	if err != nil {
+		a.log.WithError(err).Warn("record history")
+	}
+}
+
+// record adds db to h and deletes the inspections beyond the retention.
+func (a *app) record(h *historyStore, db *inspect.Database) (int64, error) {
+	maxAge, err := a.cfg.History.maxAge()
+	if err != nil {
+		return 0, err
+	}
+	id, err := h.record(db)
+	if err != nil {
+		return 0, err
+	}
+	_, err = h.prune(a.cfg.History.Keep, maxAge)
+	return id, err
+}
+
+// runHistory manages the inspections recorded in the -history store.
+func (a *app) runHistory(args []string) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	if historyCommands[args[0]] == nil {
+		names := make([]string, 0, len(historyCommands))
+		for name := range historyCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		a.log.Fatalf("unknown history command %q: %s", args[0], strings.Join(names, ", "))
+	}
+	historyCommands[args[0]](a, args[1:])
+}
+
+// historyList prints the recorded inspections, marking those whose
+// structure changed since the previous inspection of their database.
+func (a *app) historyList(args []string) {
+	fs := flag.NewFlagSet("history list", flag.ContinueOnError)
+	database := fs.String("database", "", "Database to list, all if empty.")
+	since := fs.Duration("since", 0, "List the inspections of this last period only, all if 0.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	h := a.openHistory()
+	defer h.Close()
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+	list, err := h.list(*database, from)
+	if err != nil {
+		a.log.WithError(err).Fatal("list history")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeHistory(w, list); err != nil {
+		a.log.WithError(err).Fatal("write history")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write history")
+	}
+}
+
+// writeHistory writes the inspections, oldest first.
+func writeHistory(w io.Writer, list []inspection) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tINSPECTED\tDATABASE\tFINGERPRINT\tCHANGED\tTABLES\tSIZE")
+	prev := make(map[string]string)
+	for _, in := range list {
+		changed := ""
+		if p, ok := prev[in.database]; ok && p != in.fingerprint {
+			changed = "yes"
+		}
+		prev[in.database] = in.fingerprint
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n", in.id, in.inspectedAt.Format("2006-01-02 15:04"), in.database,
+			in.fingerprint[:12], changed, in.tables, humanBytes(in.size))
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(tw, "no inspections")
+	}
+	return tw.Flush()
+}
+
+// historyRecord inspects the database and records it.
+func (a *app) historyRecord(args []string) {
+	fs := flag.NewFlagSet("history record", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+
+	h := a.openHistory()
+	defer h.Close()
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	id, err := a.record(h, db)
+	if err != nil {
+		a.log.WithError(err).Fatal("record history")
+	}
+	a.log.Infof("recorded inspection %d of %s", id, db.Name)
+}
+
+// historyShow writes the snapshot of a recorded inspection.
+func (a *app) historyShow(args []string) {
+	fs := flag.NewFlagSet("history show", flag.ContinueOnError)
+	id := fs.Int64("id", 0, "Inspection to show, see history list.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+	if *id == 0 {
+		a.log.Fatal("history show requires -id")
+	}
+
+	h := a.openHistory()
+	defer h.Close()
+	db, err := h.snapshot(*id)
+	if err != nil {
+		a.log.WithError(err).Fatal("read history")
+	}
+	if err := writeSnapshot(*out, db); err != nil {
+		a.log.WithError(err).Fatal("write snapshot")
+	}
+}
+
+// historyPrune deletes old inspections, by default per the retention of
+// the config.
+func (a *app) historyPrune(args []string) {
+	maxAge, err := a.cfg.History.maxAge()
+	if err != nil {
+		a.log.WithError(err).Fatal("load config")
+	}
+	fs := flag.NewFlagSet("history prune", flag.ContinueOnError)
+	keep := fs.Int("keep", a.cfg.History.Keep, "Inspections to keep per database, all if 0.")
+	fs.DurationVar(&maxAge, "max-age", maxAge, "Delete inspections older than this, none if 0.")
+	a.parseFlags(fs, args)
+
+	h := a.openHistory()
+	defer h.Close()
+	n, err := h.prune(*keep, maxAge)
+	if err != nil {
+		a.log.WithError(err).Fatal("prune history")
+	}
+	a.log.Infof("deleted %d inspections", n)
+}
+
+// historyDatabase returns the database the history of which is reported:
+// the given one or the only database in the store.
+func (a *app) historyDatabase(h *historyStore, database string) string {
+	if database != "" {
+		return database
+	}
+	list, err := h.list("", time.Time{})
+	if err != nil {
+		a.log.WithError(err).Fatal("list history")
+	}
+	seen := make(map[string]bool)
+	for _, in := range list {
+		seen[in.database] = true
+		database = in.database
+	}
+	if len(seen) > 1 {
+		a.log.Fatal("the history has several databases, select one with -database")
+	}
+	return database
+}
+
+// runChangelog prints the structural changes between consecutive recorded
+// inspections of a database, skipping those with the same fingerprint.
+func (a *app) runChangelog(args []string) {
+	fs := flag.NewFlagSet("changelog", flag.ContinueOnError)
+	database := fs.String("database", "", "Database to report, the only one in the history if empty.")
+	since := fs.Duration("since", 0, "Report the changes of this last period only, all if 0.")
+	renames := fs.Bool("renames", true, "Report similar dropped and added objects as renames.")
+	a.parseFlags(fs, args)
+
+	h := a.openHistory()
+	defer h.Close()
+	name := a.historyDatabase(h, *database)
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+	list, err := h.list(name, from)
+	if err != nil {
+		a.log.WithError(err).Fatal("list history")
+	}
+
+	var prev *inspect.Database
+	var prevFingerprint string
+	for _, in := range list {
+		if prev != nil && in.fingerprint == prevFingerprint {
+			continue
+		}
+		cur, err := h.snapshot(in.id)
+		if err != nil {
+			a.log.WithError(err).Fatal("read history")
+		}
+		if prev != nil {
+			changes := diff.Compare(prev, cur, a.diffOptions(*renames))
+			fmt.Printf("%s  inspection %d, %s\n", in.inspectedAt.Format("2006-01-02 15:04"), in.id, diff.Summary(changes))
+			for _, c := range changes {
+				fmt.Printf("  %s\n", c)
+			}
+		}
+		prev, prevFingerprint = cur, in.fingerprint
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// historySchema creates the tables of the history store. Each inspection
+// keeps its gzipped snapshot and the stats of its tables, which growth
+// queries read without decoding the snapshots.
+const historySchema = `
+CREATE TABLE IF NOT EXISTS inspections (
+	id           INTEGER PRIMARY KEY,
+	database     TEXT NOT NULL,
+	inspected_at INTEGER NOT NULL,
+	fingerprint  TEXT NOT NULL,
+	snapshot     BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS inspections_database ON inspections (database, inspected_at);
+CREATE TABLE IF NOT EXISTS table_stats (
+	inspection_id INTEGER NOT NULL REFERENCES inspections (id) ON DELETE CASCADE,
+	schema        TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	row_estimate  INTEGER NOT NULL,
+	size_bytes    INTEGER NOT NULL,
+	PRIMARY KEY (inspection_id, schema, name)
+);`
+
+// historyStore records inspections in a local SQLite file.
+type historyStore struct {
+	db *sql.DB
+}
+
+// inspection is a recorded inspection without its snapshot.
+type inspection struct {
+	id          int64
+	database    string
+	inspectedAt time.Time
+	fingerprint string
+	tables      int
+	size        int64
+}
+
+// openHistory opens the history store at path, creating it if needed.
+func openHistory(path string) (*historyStore, error) {
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create history %s: %v", path, err)
+	}
+	return &historyStore{db: db}, nil
+}
+
+func (h *historyStore) Close() error {
+	return h.db.Close()
+}
+
+// record adds the inspection db and returns its id.
+func (h *historyStore) record(db *inspect.Database) (int64, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(db); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	fingerprint := inspect.Fingerprint(db, inspect.FingerprintOptions{IgnoreStats: true})
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`INSERT INTO inspections (database, inspected_at, fingerprint, snapshot) VALUES (?, ?, ?, ?)`,
+		db.Name, db.InspectedAt.Unix(), fingerprint, buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO table_stats (inspection_id, schema, name, row_estimate, size_bytes) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if _, err := stmt.Exec(id, t.Schema, t.Name, t.Stats.RowEstimate, t.Stats.SizeBytes); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return id, tx.Commit()
+}
+
+// list returns the inspections of database, all databases if empty, since
+// the given time, oldest first.
+func (h *historyStore) list(database string, since time.Time) ([]inspection, error) {
+	rows, err := h.db.Query(`SELECT i.id, i.database, i.inspected_at, i.fingerprint,
+	(SELECT count(*) FROM table_stats s WHERE s.inspection_id = i.id),
+	(SELECT coalesce(sum(size_bytes), 0) FROM table_stats s WHERE s.inspection_id = i.id)
+FROM inspections i
+WHERE (? = '' OR i.database = ?) AND i.inspected_at >= ?
+ORDER BY i.inspected_at, i.id`, database, database, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []inspection
+	for rows.Next() {
+		var in inspection
+		var at int64
+		if err := rows.Scan(&in.id, &in.database, &at, &in.fingerprint, &in.tables, &in.size); err != nil {
+			return nil, err
+		}
+		in.inspectedAt = time.Unix(at, 0).UTC()
+		out = append(out, in)
+	}
+	return out, rows.Err()
+}
+
+// snapshot returns the database recorded by the inspection id.
+func (h *historyStore) snapshot(id int64) (*inspect.Database, error) {
+	var b []byte
+	if err := h.db.QueryRow(`SELECT snapshot FROM inspections WHERE id = ?`, id).Scan(&b); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no inspection %d", id)
+		}
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if b, err = ioutil.ReadAll(zr); err != nil {
+		return nil, err
+	}
+	db := &inspect.Database{}
+	if err := json.Unmarshal(b, db); err != nil {
+		return nil, fmt.Errorf("parse inspection %d: %v", id, err)
+	}
+	db.Sort()
+	db.LinkReferences()
+	return db, nil
+}
+
+// prune deletes the inspections of every database beyond the newest keep
+// ones or older than maxAge; zero disables either limit. It returns the
+// number of deleted inspections.
+func (h *historyStore) prune(keep int, maxAge time.Duration) (int64, error) {
+	var deleted int64
+	if keep > 0 {
+		res, err := h.db.Exec(`DELETE FROM inspections WHERE id IN (
+	SELECT id FROM (SELECT id, row_number() OVER (PARTITION BY database ORDER BY inspected_at DESC, id DESC) AS n FROM inspections)
+	WHERE n > ?)`, keep)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if maxAge > 0 {
+		res, err := h.db.Exec(`DELETE FROM inspections WHERE inspected_at < ?`, time.Now().Add(-maxAge).Unix())
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// tableStat is the size of a table at an inspection.
+type tableStat struct {
+	inspection  int64
+	at          time.Time
+	schema      string
+	name        string
+	rowEstimate int64
+	sizeBytes   int64
+}
+
+// tableStats returns the table sizes recorded by the inspections of
+// database since the given time, oldest first.
+func (h *historyStore) tableStats(database string, since time.Time) ([]tableStat, error) {
+	rows, err := h.db.Query(`SELECT i.id, i.inspected_at, s.schema, s.name, s.row_estimate, s.size_bytes
+FROM table_stats s JOIN inspections i ON i.id = s.inspection_id
+WHERE i.database = ? AND i.inspected_at >= ?
+ORDER BY i.inspected_at, i.id, s.schema, s.name`, database, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []tableStat
+	for rows.Next() {
+		var s tableStat
+		var at int64
+		if err := rows.Scan(&s.inspection, &at, &s.schema, &s.name, &s.rowEstimate, &s.sizeBytes); err != nil {
+			return nil, err
+		}
+		s.at = time.Unix(at, 0).UTC()
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func (a *app) runImpact(args []string) {
+	fs := flag.NewFlagSet("impact", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+	if fs.NArg() != 2 || fs.Arg(0) != "drop" {
+		a.log.Fatal("usage: impact drop schema.table[.column]")
+	}
+
+	schema, table, column := "public", "", ""
+	switch parts := strings.Split(fs.Arg(1), "."); len(parts) {
+	case 1:
+		table = parts[0]
+	case 2:
+		schema, table = parts[0], parts[1]
+	case 3:
+		schema, table, column = parts[0], parts[1], parts[2]
+	default:
+		a.log.Fatalf("invalid object name %q", fs.Arg(1))
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		a.log.WithError(err).Fatal("connect")
+	}
+	defer conn.Close()
+	deps, err := inspect.DropImpact(conn.NewSession(nil), schema, table, column)
+	if err != nil {
+		a.log.WithError(err).Fatal("impact")
+	}
+
+	if len(deps) == 0 {
+		fmt.Printf("nothing depends on %s\n", fs.Arg(1))
+		return
+	}
+	fmt.Printf("dropping %s affects, in drop order:\n", fs.Arg(1))
+	for _, d := range deps {
+		effect := "dropped with it"
+		if d.Cascade {
+			effect = "requires CASCADE"
+		}
+		fmt.Printf("  %s %s (%s)\n", d.Type, d.Identity, effect)
+	}
+}
@@ -1,119 +1,117 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"os"
+	"strings"
 
-	"github.com/Sirupsen/logrus"
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/datainq/pq-inspector/otelinspect"
 	"github.com/gocraft/dbr"
 	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
 )
 
-type (
-	CardinalNumber dbr.NullInt64
-	CharacterData dbr.NullString
-	SQLIdentifier dbr.NullString
-	TimeStamp dbr.NullTime
-	YesOrNo dbr.NullString
-)
+// app holds the global flags shared by all commands.
+type app struct {
+	log      *logrus.Logger
+	cfg      *Config
+	connStr  string
+	schemas  []string
+	queries  *queryLog
+	retry    inspect.RetryPolicy
+	service  map[string]string
+	password string    // prompted for with -W
+	history  string    // path of the history store, see history.go
+	scratch  string    // server to apply migrations on, see migrate.go
+	image    string    // container image to apply migrations in
+	manifest *manifest // written at exit with -manifest, see manifest.go
+
+	// otel traces inspections within the command span of ctx when
+	// -telemetry is set, see telemetry.go.
+	otel *otelinspect.Instrumentation
+	ctx  context.Context
+}
+
+// connect opens the -db database.
+func (a *app) connect() (*dbr.Connection, error) {
+	return a.open(a.connStr)
+}
 
-// Data types:
-// CardinalNumber
-// A nonnegative integer.
-//
-// CharacterData
-// A character string (without specific maximum length).
-//
-// SQLIdentifier
-// A character string. This type is used for SQL identifiers, the type
-// CharacterData is used for any other kind of text data.
-//
-// time_stamp
-// A domain over the type timestamp with time zone
-//
-// YesOrNo
-// A character string domain that contains either YES or NO. This is used
-// to represent Boolean (true/false) data in the information schema.
-// (The information schema was invented before the type boolean
-// was added to the SQL standard, so this convention is necessary
-// to keep the information schema backward compatible.)
-
-// https://www.postgresql.org/docs/9.6/infoschema-schemata.html
-type TSchemata struct {
-	CatalogName                SQLIdentifier `db:"catalog_name"` // Name of the database that the schema is contained in (always the current database)
-	SchemaName                 SQLIdentifier                     // Name of the schema
-	SchemaOwner                SQLIdentifier                     // Name of the owner of the schema
-	DefaultCharacterSetCatalog SQLIdentifier                     // Applies to a feature not available in PostgreSQL
-	DefaultCharacterSetSchema  SQLIdentifier                     // Applies to a feature not available in PostgreSQL
-	DefaultCharacterSetName    SQLIdentifier                     // Applies to a feature not available in PostgreSQL
-	SQLPath                    CharacterData                     // Applies to a feature not available in PostgreSQL
+// load connects to the -db database and inspects it, retrying transient
+// failures.
+func (a *app) load() (*inspect.Database, error) {
+	dbConn, err := a.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+	return a.inspect(dbConn.NewSession(nil))
 }
 
-// https://www.postgresql.org/docs/9.6/infoschema-tables.html
-type TTables struct {
-	TableCatalog              SQLIdentifier `db:"table_catalog"`                // Name of the database that contains the table (always the current database)
-	TableSchema               SQLIdentifier `db:"table_schema"`                 // Name of the schema that contains the table
-	TableName                 SQLIdentifier `db:"table_name"`                   // Name of the table
-	TableType                 CharacterData `db:"table_type"`                   // Type of the table: BASE TABLE for a persistent base table (the normal table type), VIEW for a view, FOREIGN TABLE for a foreign table, or LOCAL TEMPORARY for a temporary table
-	SelfReferencingColumnName SQLIdentifier `db:"self_referencing_column_name"` // Applies to a feature not available in PostgreSQL
-	ReferenceGeneration       CharacterData `db:"reference_generation"`         // Applies to a feature not available in PostgreSQL
-	UserDefinedTypeCatalog    SQLIdentifier `db:"user_defined_type_catalog"`    // If the table is a typed table, the name of the database that contains the underlying data type (always the current database), else null.
-	UserDefinedTypeSchema     SQLIdentifier `db:"user_defined_type_schema"`     // If the table is a typed table, the name of the schema that contains the underlying data type, else null.
-	UserDefinedTypeName       SQLIdentifier `db:"user_defined_type_name"`       // If the table is a typed table, the name of the underlying data type, else null.
-	IsInsertableInto          YesOrNo       `db:"is_insertable_into"`           // YES if the table is insertable into, NO if not (Base tables are always insertable into, views not necessarily.)
-	IsTyped                   YesOrNo       `db:"is_typed"`                     // YES if the table is a typed table, NO if not
-	CommitAction              CharacterData `db:"commit_action"`                // Not yet implemented
+// inspect loads the -schemas of sess with the -retries policy.
+func (a *app) inspect(sess *dbr.Session) (*inspect.Database, error) {
+	return a.inspectWith(sess, a.schemas, func(sess *dbr.Session) (*inspect.Database, error) {
+		return inspect.Load(sess, a.schemas)
+	})
 }
 
-type TColumns struct {
-	TableCatalog           SQLIdentifier  `db:"table_catalog"`            // Name of the database containing the table (always the current database)
-	TableSchema            SQLIdentifier  `db:"table_schema"`             // Name of the schema containing the table
-	TableName              SQLIdentifier  `db:"table_name"`               // Name of the table
-	ColumnName             SQLIdentifier  `db:"column_name"`              // Name of the column
-	OrdinalPosition        CardinalNumber `db:"ordinal_position"`         // Ordinal position of the column within the table (count starts at 1)
-	ColumnDefault          CharacterData  `db:"column_default"`           // Default expression of the column
-	IsNullable             YesOrNo        `db:"is_nullable"`              // YES if the column is possibly nullable, NO if it is known not nullable. A not-null constraint is one way a column can be known not nullable, but there can be others.
-	DataType               CharacterData  `db:"data_type"`                // Data type of the column, if it is a built-in type, or ARRAY if it is some array (in that case, see the view element_types), else USER-DEFINED (in that case, the type is identified in udt_name and associated columns). If the column is based on a domain, this column refers to the type underlying the domain (and the domain is identified in domain_name and associated columns).
-	CharacterMaximumLength CardinalNumber `db:"character_maximum_length"` // If data_type identifies a character or bit string type, the declared maximum length; null for all other data types or if no maximum length was declared.
-	CharacterOctetLength   CardinalNumber `db:"character_octet_length"`   // If data_type identifies a character type, the maximum possible length in octets (bytes) of a datum; null for all other data types. The maximum octet length depends on the declared character maximum length (see above) and the server encoding.
-	NumericPrecision       CardinalNumber `db:"numeric_precision"`        // If data_type identifies a numeric type, this column contains the (declared or implicit) precision of the type for this column. The precision indicates the number of significant digits. It can be expressed in decimal (base 10) or binary (base 2) terms, as specified in the column numeric_precision_radix. For all other data types, this column is null.
-	NumericPrecisionRadix  CardinalNumber `db:"numeric_precision_radix"`  // If data_type identifies a numeric type, this column indicates in which base the values in the columns numeric_precision and numeric_scale are expressed. The value is either 2 or 10. For all other data types, this column is null.
-	NumericScale           CardinalNumber `db:"numeric_scale"`            // If data_type identifies an exact numeric type, this column contains the (declared or implicit) scale of the type for this column. The scale indicates the number of significant digits to the right of the decimal point. It can be expressed in decimal (base 10) or binary (base 2) terms, as specified in the column numeric_precision_radix. For all other data types, this column is null.
-	DatetimePrecision      CardinalNumber `db:"datetime_precision"`       // If data_type identifies a date, time, timestamp, or interval type, this column contains the (declared or implicit) fractional seconds precision of the type for this column, that is, the number of decimal digits maintained following the decimal point in the seconds value. For all other data types, this column is null.
-	IntervalType           CharacterData  `db:"interval_type"`            // If data_type identifies an interval type, this column contains the specification which fields the intervals include for this column, e.g., YEAR TO MONTH, DAY TO SECOND, etc. If no field restrictions were specified (that is, the interval accepts all fields), and for all other data types, this field is null.
-	IntervalPrecision      CardinalNumber `db:"interval_precision"`       // Applies to a feature not available in PostgreSQL (see datetime_precision for the fractional seconds precision of interval type columns)
-	CharacterSetCatalog    SQLIdentifier  `db:"character_set_catalog"`    // Applies to a feature not available in PostgreSQL
-	CharacterSetSchema     SQLIdentifier  `db:"character_set_schema"`     // Applies to a feature not available in PostgreSQL
-	CharacterSetName       SQLIdentifier  `db:"character_set_name"`       // Applies to a feature not available in PostgreSQL
-	CollationCatalog       SQLIdentifier  `db:"collation_catalog"`        // Name of the database containing the collation of the column (always the current database), null if default or the data type of the column is not collatable
-	CollationSchema        SQLIdentifier  `db:"collation_schema"`         // Name of the schema containing the collation of the column, null if default or the data type of the column is not collatable
-	CollationName          SQLIdentifier  `db:"collation_name"`           // Name of the collation of the column, null if default or the data type of the column is not collatable
-	DomainCatalog          SQLIdentifier  `db:"domain_catalog"`           // If the column has a domain type, the name of the database that the domain is defined in (always the current database), else null.
-	DomainSchema           SQLIdentifier  `db:"domain_schema"`            // If the column has a domain type, the name of the schema that the domain is defined in, else null.
-	DomainName             SQLIdentifier  `db:"domain_name"`              // If the column has a domain type, the name of the domain, else null.
-	UdtCatalog             SQLIdentifier  `db:"udt_catalog"`              // Name of the database that the column data type (the underlying type of the domain, if applicable) is defined in (always the current database)
-	UdtSchema              SQLIdentifier  `db:"udt_schema"`               // Name of the schema that the column data type (the underlying type of the domain, if applicable) is defined in
-	UdtName                SQLIdentifier  `db:"udt_name"`                 // Name of the column data type (the underlying type of the domain, if applicable)
-	ScopeCatalog           SQLIdentifier  `db:"scope_catalog"`            // Applies to a feature not available in PostgreSQL
-	ScopeSchema            SQLIdentifier  `db:"scope_schema"`             // Applies to a feature not available in PostgreSQL
-	ScopeName              SQLIdentifier  `db:"scope_name"`               // Applies to a feature not available in PostgreSQL
-	MaximumCardinality     CardinalNumber `db:"maximum_cardinality"`      // Always null, because arrays always have unlimited maximum cardinality in PostgreSQL
-	DtdIdentifier          SQLIdentifier  `db:"dtd_identifier"`           // An identifier of the data type descriptor of the column, unique among the data type descriptors pertaining to the table. This is mainly useful for joining with other instances of such identifiers. (The specific format of the identifier is not defined and not guaranteed to remain the same in future versions.)
-	IsSelfReferencing      YesOrNo        `db:"is_self_referencing"`      // Applies to a feature not available in PostgreSQL
-	IsIdentity             YesOrNo        `db:"is_identity"`              // Applies to a feature not available in PostgreSQL
-	IdentityGeneration     CharacterData  `db:"identity_generation"`      // Applies to a feature not available in PostgreSQL
-	IdentityStart          CharacterData  `db:"identity_start"`           // Applies to a feature not available in PostgreSQL
-	IdentityIncrement      CharacterData  `db:"identity_increment"`       // Applies to a feature not available in PostgreSQL
-	IdentityMaximum        CharacterData  `db:"identity_maximum"`         // Applies to a feature not available in PostgreSQL
-	IdentityMinimum        CharacterData  `db:"identity_minimum"`         // Applies to a feature not available in PostgreSQL
-	IdentityCycle          YesOrNo        `db:"identity_cycle"`           // Applies to a feature not available in PostgreSQL
-	IsGenerated            CharacterData  `db:"is_generated"`             // Applies to a feature not available in PostgreSQL
-	GenerationExpression   CharacterData  `db:"generation_expression"`    // Applies to a feature not available in PostgreSQL
-	IsUpdatable            YesOrNo        `db:"is_updatable"`             // YES if the column is updatable, NO if not (Columns in base tables are always updatable, columns in views not necessarily)
+// inspectWith inspects schemas of sess with load, e.g. the Load method of
+// an inspect.Inspector, retrying, tracing and recording it in the manifest
+// as inspect does.
+func (a *app) inspectWith(sess *dbr.Session, schemas []string,
+	load func(sess *dbr.Session) (*inspect.Database, error)) (*inspect.Database, error) {
+	defer a.manifest.phase("inspect")()
+	var db *inspect.Database
+	err := inspect.Retry(a.retry, func() error {
+		var err error
+		if a.otel != nil {
+			db, err = a.otel.LoadWith(a.ctx, sess, schemas, load)
+		} else {
+			db, err = load(sess)
+		}
+		if err != nil && inspect.Temporary(err) {
+			a.log.WithError(err).Warn("inspect database attempt failed")
+		}
+		return err
+	})
+	if err == nil && a.manifest != nil {
+		var version string
+		if err := sess.SelectBySql("SHOW server_version").LoadOne(&version); err != nil {
+			a.log.WithError(err).Warn("read server version")
+		}
+		a.manifest.inspected(sess.Connection, version, db)
+	}
+	return db, err
 }
 
 func main() {
-	connStr := flag.String("db", "", "PostgreSQL connection string.")
-	flag.Parse()
+	// Invalid flags exit with exitUsage rather than the 2 of the flag package.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	connStr := flag.String("db", "", "PostgreSQL connection string, completed from the PG* environment variables and ~/.pgpass.")
+	prompt := flag.Bool("W", false, "Prompt for the password if the connection string has none.")
+	service := flag.String("service", os.Getenv("PGSERVICE"), "Connection service from pg_service.conf whose settings complete -db.")
+	configPath := flag.String("config", "", "Path to a JSON config file.")
+	schemaList := flag.String("schemas", "", "Comma separated list of schemas to inspect, all user schemas if empty.")
+	retries := flag.Int("retries", inspect.DefaultRetry.Attempts-1, "Times to retry an inspection failing on a connection, timeout or serialization error.")
+	retryDelay := flag.Duration("retry-delay", inspect.DefaultRetry.Delay, "Delay before the first retry, doubled after each one.")
+	explain := flag.Bool("explain", false, "Print the catalog queries executed with their timings to stderr.")
+	allDatabases := flag.Bool("all-databases", false, "Run the command on every database of the cluster, replacing {db} in its arguments.")
+	includeDatabases := flag.String("include-databases", "*", "Comma separated patterns of the databases -all-databases runs on.")
+	excludeDatabases := flag.String("exclude-databases", "", "Comma separated patterns of the databases -all-databases skips.")
+	historyPath := flag.String("history", "", "SQLite file recording each inspection, history.path of the config if empty.")
+	migrationsDir := flag.String("migrations", "", "Directory of SQL migrations to apply to a temporary database, inspected instead of -db.")
+	image := flag.String("image", "postgres:16-alpine", "Docker image of the container migrations are applied in.")
+	scratch := flag.String("scratch-db", "", "Connection string of a server to apply migrations on in a temporary database instead of a container.")
+	manifestPath := flag.String("manifest", "", "Write a JSON manifest of the run to this file: versions, targets, filters, timings and object counts.")
+	telemetry := flag.String("telemetry", "", "Export traces and metrics of inspections with OpenTelemetry: otlp or stdout, telemetry.exporter of the config if empty.")
+	applyConnection := connectionFlags(flag.CommandLine)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err == flag.ErrHelp {
+		os.Exit(exitOK)
+	} else if err != nil {
+		os.Exit(exitUsage)
+	}
 
 	log := logrus.New()
 	log.Formatter = &logrus.TextFormatter{
@@ -122,79 +120,201 @@ func main() {
 		TimestampFormat: "Jan 02, 15:04:06",
 	}
 
-	dbConn, err := dbr.Open("postgres", *connStr, nil)
-	if err != nil {
-		log.WithError(err).Fatal("Cannot connect to db")
+	a := &app{log: log, cfg: &Config{}, connStr: *connStr, queries: &queryLog{}, scratch: *scratch, image: *image}
+	// Fatal entries exit by the kind of their error, see exitcode.go, and
+	// every exit records its code in the manifest.
+	fatalCode := 0
+	log.Hooks.Add(exitCodeHook{&fatalCode})
+	log.ExitFunc = func(code int) {
+		if code == 1 && fatalCode != 0 {
+			code = fatalCode
+		}
+		if err := a.manifest.write(code); err != nil {
+			log.WithError(err).Error("write manifest")
+		}
+		os.Exit(code)
 	}
-	defer dbConn.Close()
-
-	dbS := dbConn.NewSession(nil)
-	var dbName string
-	if err = dbS.Select("*").From("information_schema.information_schema_catalog_name").LoadOne(&dbName); err != nil {
-		log.WithError(err).Fatal("load database name")
+	if *explain {
+		a.queries.w = os.Stderr
 	}
-
-	log.Infof("db name: %s", dbName)
-
-	var schemaWhitelist []string = []string{
-		"swipe",
+	if *manifestPath != "" {
+		var args []string
+		if flag.NArg() > 1 {
+			args = flag.Args()[1:]
+		}
+		path := *manifestPath
+		if *allDatabases {
+			// {db} is for the manifests of the per-database runs.
+			path = strings.Replace(path, "{db}", "all", -1)
+		}
+		a.manifest = newManifest(path, flag.Arg(0), args)
+		for name, value := range map[string]string{"schemas": *schemaList, "migrations": *migrationsDir} {
+			if value != "" {
+				a.manifest.Filters[name] = value
+			}
+		}
+		if *allDatabases {
+			a.manifest.Filters["include-databases"] = *includeDatabases
+			a.manifest.Filters["exclude-databases"] = *excludeDatabases
+		}
+		// Runs last on return, after the cleanups deferred below.
+		defer func() {
+			if err := a.manifest.write(0); err != nil {
+				log.WithError(err).Fatal("write manifest")
+			}
+		}()
 	}
-
-	var schemas []TSchemata
-	n, err := dbS.SelectBySql("SELECT * FROM information_schema.schemata WHERE schema_name IN ?", schemaWhitelist).Load(&schemas)
-	if err != nil {
-		log.WithError(err).Fatal("select schemas")
+	a.retry = inspect.DefaultRetry
+	a.retry.Attempts, a.retry.Delay = *retries+1, *retryDelay
+	if *configPath != "" {
+		var err error
+		if a.cfg, err = loadConfig(*configPath); err != nil {
+			log.WithError(err).Fatal("load config")
+		}
 	}
-	if n == 0 {
-		log.Warn("no schemas available")
-		return
+	applyConnection(&a.cfg.Connection)
+	a.history = *historyPath
+	if a.history == "" {
+		a.history = a.cfg.History.Path
 	}
-	for _, v := range schemas {
-		log.Debugf("schema %s owned by %s", v.SchemaName.String, v.SchemaOwner.String)
+	if *service != "" {
+		var err error
+		if a.service, err = lookupService(*service); err != nil {
+			log.WithError(err).Fatal("load connection service")
+		}
 	}
-
-	var tables []TTables
-	n, err = dbS.SelectBySql("SELECT * FROM information_schema.tables WHERE table_schema IN ?", schemaWhitelist).Load(&tables)
-	if err != nil {
-		log.WithError(err).Fatal("select tables")
+	if *prompt {
+		var err error
+		if a.password, err = readPassword("Password: "); err != nil {
+			log.WithError(err).Fatal("prompt for password")
+		}
 	}
-	if n == 0 {
-		log.Warn("no tables available")
-		return
+	// lib/pq panics on the service variables it does not support.
+	for _, v := range []string{"PGSERVICE", "PGSERVICEFILE", "PGSYSCONFDIR"} {
+		os.Unsetenv(v)
 	}
-	for _, v := range tables {
-		log.Debugf("table %s.%s type %s", v.TableSchema.String, v.TableName.String, v.TableType.String)
+	if *schemaList != "" {
+		a.schemas = strings.Split(*schemaList, ",")
 	}
 
-	var columns []TColumns
-	n, err = dbS.SelectBySql("SELECT * FROM information_schema.columns WHERE table_schema IN ?", schemaWhitelist).Load(&columns)
-	if err != nil {
-		log.WithError(err).Fatal("select columns")
-	}
-	if n == 0 {
-		log.Warn("no columns available")
-		return
+	if *telemetry != "" {
+		a.cfg.Telemetry.Exporter = *telemetry
 	}
-	for _, v := range columns {
-		log.Debugf("column %s.%s.%s", v.TableSchema.String, v.TableName.String, v.ColumnName.String)
+	if a.cfg.Telemetry.Exporter != "" {
+		shutdown, err := a.startTelemetry(a.cfg.Telemetry, flag.Arg(0))
+		if err != nil {
+			log.WithError(err).Fatal("start telemetry")
+		}
+		logrus.RegisterExitHandler(shutdown)
+		defer shutdown()
 	}
-}
 
-type Column struct {
-	Name       string
-	ParseValue interface{}
-}
+	if *migrationsDir != "" {
+		done := a.manifest.phase("migrations")
+		dsn, cleanup, err := a.applyMigrations(*migrationsDir, *image)
+		done()
+		if err != nil {
+			log.WithError(err).Fatal("apply migrations")
+		}
+		a.connStr = dsn
+		// Fatal errors and exit codes leave through the exit handlers.
+		logrus.RegisterExitHandler(cleanup)
+		defer cleanup()
+	}
 
-type Table struct {
-	Schema string
-	Name   string
+	if *allDatabases {
+		a.runAllDatabases(*service, *includeDatabases, *excludeDatabases)
+		return
+	}
 
-	Columns []Column
-	FKs     []ForeignKey
-	PK      PrimaryKey
+	args := flag.Args()
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+		a.runInspect()
+	case "serve":
+		a.runServe(args)
+	case "snapshot":
+		a.runSnapshot(args)
+	case "drift":
+		a.runDrift(args)
+	case "watch":
+		a.runWatch(args)
+	case "diff3":
+		a.runDiff3(args)
+	case "verify":
+		a.runVerify(args)
+	case "fingerprint":
+		a.runFingerprint(args)
+	case "gen":
+		a.runGen(args)
+	case "erd":
+		a.runERD(args)
+	case "describe":
+		a.runDescribe(args)
+	case "impact":
+		a.runImpact(args)
+	case "lineage":
+		a.runLineage(args)
+	case "order":
+		a.runOrder(args)
+	case "subset":
+		a.runSubset(args)
+	case "reconcile":
+		a.runReconcile(args)
+	case "analyze":
+		a.runAnalyze(args)
+	case "lint":
+		a.runLint(args)
+	case "tenants":
+		a.runTenants(args)
+	case "security":
+		a.runSecurity(args)
+	case "bench":
+		a.runBench(args)
+	case "cluster":
+		a.runCluster(args)
+	case "replication":
+		a.runReplication(args)
+	case "textsearch":
+		a.runTextSearch(args)
+	case "summary":
+		a.runSummary(args)
+	case "teams":
+		a.runTeams(args)
+	case "history":
+		a.runHistory(args)
+	case "changelog":
+		a.runChangelog(args)
+	case "growth":
+		a.runGrowth(args)
+	default:
+		log.Errorf("unknown command %q", cmd)
+		log.Exit(exitUsage)
+	}
+	a.queries.Summary()
 }
 
-type ForeignKey struct{}
-type PrimaryKey struct{}
+func (a *app) runInspect() {
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
 
-// /:schema/:table
+	a.log.Infof("db name: %s", db.Name)
+	if len(db.Schemas) == 0 {
+		a.log.Warn("no schemas available")
+		return
+	}
+	for _, s := range db.Schemas {
+		a.log.Debugf("schema %s owned by %s", s.Name, s.Owner)
+		for _, t := range s.Tables {
+			a.log.Debugf("table %s.%s type %s", t.Schema, t.Name, t.Type)
+			for _, c := range t.Columns {
+				a.log.Debugf("column %s.%s.%s", t.Schema, t.Name, c.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package inspect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// ACLItem is a parsed aclitem, e.g. app=arw/postgres. ACLs in the model
+// are never NULL: objects without explicit grants have their default
+// privileges, as reported by acldefault.
+type ACLItem struct {
+	Grantee    string // empty for PUBLIC
+	Privileges string // privilege letters, each optionally followed by * for WITH GRANT OPTION
+	Grantor    string
+}
+
+// PrivilegeNames are the privileges by their letter in an aclitem.
+var PrivilegeNames = map[byte]string{
+	'r': "SELECT", 'w': "UPDATE", 'a': "INSERT", 'd': "DELETE", 'D': "TRUNCATE",
+	'x': "REFERENCES", 't': "TRIGGER", 'X': "EXECUTE", 'U': "USAGE", 'C': "CREATE",
+	'c': "CONNECT", 'T': "TEMPORARY", 'm': "MAINTAIN",
+}
+
+// ParseACLItem parses the text form of an aclitem. Role names may be
+// double quoted.
+func ParseACLItem(s string) (ACLItem, error) {
+	var item ACLItem
+	grantee, rest, err := aclRole(s)
+	if err != nil || !strings.HasPrefix(rest, "=") {
+		return item, fmt.Errorf("invalid aclitem %q", s)
+	}
+	slash := strings.IndexByte(rest, '/')
+	if slash < 0 {
+		return item, fmt.Errorf("invalid aclitem %q", s)
+	}
+	grantor, tail, err := aclRole(rest[slash+1:])
+	if err != nil || tail != "" {
+		return item, fmt.Errorf("invalid aclitem %q", s)
+	}
+	return ACLItem{Grantee: grantee, Privileges: rest[1:slash], Grantor: grantor}, nil
+}
+
+// aclRole reads a possibly quoted role name from the start of s.
+func aclRole(s string) (name, rest string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, "=/")
+		if end < 0 {
+			end = len(s)
+		}
+		return s[:end], s[end:], nil
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '"' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '"' {
+			b.WriteByte('"')
+			i++
+			continue
+		}
+		return b.String(), s[i+1:], nil
+	}
+	return "", "", fmt.Errorf("unterminated role name in %q", s)
+}
+
+// PrivilegeList names the privileges of the letters in privs, e.g.
+// SELECT, INSERT WITH GRANT OPTION for ra*.
+func PrivilegeList(privs string) string {
+	var names []string
+	for i := 0; i < len(privs); i++ {
+		n := PrivilegeNames[privs[i]]
+		if n == "" {
+			continue
+		}
+		if i+1 < len(privs) && privs[i+1] == '*' {
+			n += " WITH GRANT OPTION"
+		}
+		names = append(names, n)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Has reports whether the item grants the privilege with the given letter.
+func (i ACLItem) Has(privilege byte) bool {
+	return strings.IndexByte(i.Privileges, privilege) >= 0
+}
+
+// PublicPrivileges returns the privilege letters granted to PUBLIC by acl.
+func PublicPrivileges(acl []string) string {
+	var privs string
+	for _, s := range acl {
+		if item, err := ParseACLItem(s); err == nil && item.Grantee == "" {
+			privs += strings.Replace(item.Privileges, "*", "", -1)
+		}
+	}
+	return privs
+}
+
+// DefaultPrivilege is an ALTER DEFAULT PRIVILEGES entry: the privileges
+// granted on objects Role creates in Schema, or in any schema if Schema is
+// empty.
+type DefaultPrivilege struct {
+	Role       string   `json:"role" db:"role"`
+	Schema     string   `json:"schema,omitempty" db:"schema"`
+	ObjectType string   `json:"object_type" db:"object_type"` // tables, sequences, functions, types or schemas
+	ACL        []string `json:"acl" db:"-"`
+}
+
+type defaultPrivilegeRow struct {
+	DefaultPrivilege
+	ACL pq.StringArray `db:"acl"`
+}
+
+func loadDefaultPrivileges(sess *dbr.Session, schemas []string, db *Database) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []defaultPrivilegeRow
+	_, err := sess.SelectBySql(`SELECT pg_get_userbyid(d.defaclrole) AS role, COALESCE(n.nspname, '') AS schema,
+	CASE d.defaclobjtype WHEN 'r' THEN 'tables' WHEN 'S' THEN 'sequences' WHEN 'f' THEN 'functions'
+		WHEN 'T' THEN 'types' ELSE 'schemas' END AS object_type,
+	d.defaclacl::text[] AS acl
+FROM pg_default_acl d
+	LEFT JOIN pg_namespace n ON n.oid = d.defaclnamespace
+WHERE d.defaclnamespace = 0 OR (`+where+`)`, args...).Load(&rows)
+	if err != nil {
+		return queryError("select default privileges", err)
+	}
+	for _, v := range rows {
+		p := v.DefaultPrivilege
+		p.ACL = v.ACL
+		db.DefaultPrivileges = append(db.DefaultPrivileges, p)
+	}
+	return nil
+}
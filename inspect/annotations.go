@@ -0,0 +1,49 @@
+package inspect
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// annotation matches @key=value, @key="quoted value" or a bare @key in a
+// comment. The @ starts a word, so e-mail addresses are not annotations.
+var annotation = regexp.MustCompile(`(?:^|\s)@([A-Za-z][\w.-]*)(?:=(?:"([^"]*)"|(\S+)))?`)
+
+// ParseAnnotations reads the key=value annotations of a comment, e.g.
+// "Invoices. @owner=payments @pii=email". A bare @key is "true" and keys
+// given more than once collect their values separated by commas.
+func ParseAnnotations(comment string) map[string]string {
+	var out map[string]string
+	for _, m := range annotation.FindAllStringSubmatch(comment, -1) {
+		if out == nil {
+			out = make(map[string]string)
+		}
+		v := m[2] + m[3]
+		if !strings.Contains(m[0], "=") {
+			v = "true"
+		}
+		if prev, ok := out[m[1]]; ok {
+			v = prev + "," + v
+		}
+		out[m[1]] = v
+	}
+	return out
+}
+
+// FormatAnnotations writes annotations back as sorted @key=value words.
+func FormatAnnotations(annotations map[string]string) string {
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		v := annotations[k]
+		if v == "" || strings.ContainsAny(v, " \t\n") {
+			v = `"` + v + `"`
+		}
+		keys[i] = "@" + k + "=" + v
+	}
+	return strings.Join(keys, " ")
+}
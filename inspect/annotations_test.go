@@ -0,0 +1,56 @@
+package inspect
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    map[string]string
+	}{
+		{"", nil},
+		{"Invoices of customers.", nil},
+		{"Invoices. @owner=payments @pii=email", map[string]string{"owner": "payments", "pii": "email"}},
+		{"@owner=payments", map[string]string{"owner": "payments"}},
+		{"Legacy. @deprecated", map[string]string{"deprecated": "true"}},
+		{`@note="two words" @owner=payments`, map[string]string{"note": "two words", "owner": "payments"}},
+		{`@note=""`, map[string]string{"note": ""}},
+		{"@pii=email @pii=phone", map[string]string{"pii": "email,phone"}},
+		{"@pii @pii=phone", map[string]string{"pii": "true,phone"}},
+		{"Mail ops@example.com or sales@example.com.", nil},
+		{"Owned by@team", nil},
+		{"@retention.days=30 @data-class=internal",
+			map[string]string{"retention.days": "30", "data-class": "internal"}},
+		{"@1st=x @_x=y", nil},
+		{"Line one.\n@owner=payments\t@tier=1", map[string]string{"owner": "payments", "tier": "1"}},
+	}
+	for _, tt := range tests {
+		if got := ParseAnnotations(tt.comment); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAnnotations(%q) = %v, want %v", tt.comment, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAnnotations(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		want        string
+	}{
+		{nil, ""},
+		{map[string]string{"owner": "payments", "deprecated": "true"}, "@deprecated=true @owner=payments"},
+		{map[string]string{"note": "two words"}, `@note="two words"`},
+		{map[string]string{"note": ""}, `@note=""`},
+		{map[string]string{"pii": "email,phone"}, "@pii=email,phone"},
+	}
+	for _, tt := range tests {
+		s := FormatAnnotations(tt.annotations)
+		if s != tt.want {
+			t.Errorf("FormatAnnotations(%v) = %q, want %q", tt.annotations, s, tt.want)
+		}
+		if back := ParseAnnotations(s); len(tt.annotations) > 0 && !reflect.DeepEqual(back, tt.annotations) {
+			t.Errorf("%q parses as %v, want %v", s, back, tt.annotations)
+		}
+	}
+}
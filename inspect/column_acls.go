@@ -0,0 +1,39 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+type columnACLRow struct {
+	TableSchema string         `db:"table_schema"`
+	TableName   string         `db:"table_name"`
+	ColumnName  string         `db:"column_name"`
+	ACL         pq.StringArray `db:"acl"`
+}
+
+// loadColumnACLs reads the privileges granted on single columns. Unlike
+// tables, columns without such grants have no ACL.
+func loadColumnACLs(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []columnACLRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name,
+	a.attname AS column_name, a.attacl::text[] AS acl
+FROM pg_attribute a
+	JOIN pg_class c ON c.oid = a.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE a.attacl IS NOT NULL AND a.attnum > 0 AND NOT a.attisdropped AND `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select column privileges", err)
+	}
+	for _, v := range rows {
+		t := byTable[v.TableSchema+"."+v.TableName]
+		if t == nil {
+			continue
+		}
+		if c := t.Column(v.ColumnName); c != nil {
+			c.ACL = v.ACL
+		}
+	}
+	return nil
+}
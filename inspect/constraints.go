@@ -0,0 +1,156 @@
+package inspect
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+type constraintRow struct {
+	TableSchema string         `db:"table_schema"`
+	TableName   string         `db:"table_name"`
+	Name        string         `db:"name"`
+	Type        string         `db:"type"`
+	Columns     pq.StringArray `db:"columns"`
+	RefSchema   string         `db:"ref_schema"`
+	RefTable    string         `db:"ref_table"`
+	RefColumns  pq.StringArray `db:"ref_columns"`
+	OnUpdate    string         `db:"on_update"`
+	OnDelete    string         `db:"on_delete"`
+	Definition  string         `db:"definition"`
+	OID         int64          `db:"oid"`
+	RefOID      int64          `db:"ref_oid"`
+	IndexOID    int64          `db:"index_oid"`
+	IndexSchema string         `db:"index_schema"`
+	IndexName   string         `db:"index_name"`
+}
+
+// referentialActions maps pg_constraint action codes to their SQL names.
+var referentialActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+// loadConstraints reads primary keys, foreign keys, unique, check and
+// exclusion constraints. Columns are listed in the order of the constraint.
+func loadConstraints(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []constraintRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name,
+	con.conname AS name, con.contype AS type,
+	ARRAY(SELECT a.attname FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+		ORDER BY k.ord)::text[] AS columns,
+	COALESCE(fn.nspname, '') AS ref_schema, COALESCE(fc.relname, '') AS ref_table,
+	ARRAY(SELECT a.attname FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+		ORDER BY k.ord)::text[] AS ref_columns,
+	con.confupdtype AS on_update, con.confdeltype AS on_delete,
+	pg_get_constraintdef(con.oid) AS definition,
+	con.oid, con.confrelid AS ref_oid, con.conindid AS index_oid,
+	COALESCE(icn.nspname, '') AS index_schema, COALESCE(ic.relname, '') AS index_name
+FROM pg_constraint con
+	JOIN pg_class c ON c.oid = con.conrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_class fc ON fc.oid = con.confrelid
+	LEFT JOIN pg_namespace fn ON fn.oid = fc.relnamespace
+	LEFT JOIN pg_class ic ON ic.oid = con.conindid
+	LEFT JOIN pg_namespace icn ON icn.oid = ic.relnamespace
+WHERE con.contype IN ('p', 'f', 'u', 'c', 'x') AND `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select constraints", err)
+	}
+	for _, v := range rows {
+		t := byTable[v.TableSchema+"."+v.TableName]
+		if t == nil {
+			continue
+		}
+		id := ObjectID{Class: "pg_constraint", OID: v.OID, Schema: v.TableSchema, Name: v.Name}
+		var index *ObjectID
+		if v.IndexOID != 0 {
+			index = &ObjectID{Class: "pg_class", OID: v.IndexOID, Schema: v.IndexSchema, Name: v.IndexName}
+		}
+		switch v.Type {
+		case "p":
+			t.PK = PrimaryKey{Name: v.Name, Columns: v.Columns, ID: &id, Index: index}
+		case "f":
+			t.FKs = append(t.FKs, ForeignKey{
+				Name:       v.Name,
+				Columns:    v.Columns,
+				RefSchema:  v.RefSchema,
+				RefTable:   v.RefTable,
+				RefColumns: v.RefColumns,
+				OnUpdate:   referentialActions[v.OnUpdate],
+				OnDelete:   referentialActions[v.OnDelete],
+				Definition: v.Definition,
+				ID:         id,
+				RefID:      ObjectID{Class: "pg_class", OID: v.RefOID, Schema: v.RefSchema, Name: v.RefTable},
+				RefIndex:   index,
+			})
+		case "u":
+			t.Constraints = append(t.Constraints, Constraint{Name: v.Name, Type: "UNIQUE", Columns: v.Columns,
+				Definition: v.Definition, ID: id, Index: index})
+		case "x":
+			t.Constraints = append(t.Constraints, Constraint{Name: v.Name, Type: "EXCLUDE", Columns: v.Columns,
+				Definition: v.Definition, ID: id, Index: index})
+		case "c":
+			t.Constraints = append(t.Constraints, Constraint{Name: v.Name, Type: "CHECK", Columns: v.Columns,
+				Definition: v.Definition, ID: id})
+			if name, values := CheckValues(v.Definition); name != "" {
+				if c := t.Column(name); c != nil {
+					c.AllowedValues = values
+				}
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	// checkIn matches a CHECK constraint allowing a list of constants, as
+	// pg_get_constraintdef prints col IN (...), e.g.
+	// CHECK (((status)::text = ANY ((ARRAY['a'::character varying])::text[]))).
+	checkIn = regexp.MustCompile(`^CHECK \(+("(?:[^"]|"")+"|\w+)\)?(?:::[\w ]+)? (?:= ANY \(\(?ARRAY\[(.*?)\](?:\)::[\w ]+\[\])?|IN \((.*?))\)+$`)
+	// checkConstant matches the first constant of a list with its cast;
+	// negative numbers are printed quoted, e.g. '-1'::integer.
+	checkConstant = regexp.MustCompile(`^(?:'((?:[^']|'')*)'|(-?[0-9][0-9.]*))(?:::[\w ]+)?`)
+)
+
+// CheckValues parses a CHECK constraint limiting a column to a list of
+// constants, returning the column and the values. The column is empty for
+// any other constraint.
+func CheckValues(definition string) (string, []string) {
+	m := checkIn.FindStringSubmatch(strings.TrimSuffix(definition, " NOT VALID"))
+	if m == nil {
+		return "", nil
+	}
+	column := m[1]
+	if strings.HasPrefix(column, `"`) {
+		column = strings.Replace(column[1:len(column)-1], `""`, `"`, -1)
+	}
+	list := m[2] + m[3]
+	var values []string
+	for list != "" {
+		v := checkConstant.FindStringSubmatch(list)
+		if v == nil {
+			// An expression.
+			return "", nil
+		}
+		if v[2] != "" {
+			values = append(values, v[2])
+		} else {
+			values = append(values, strings.Replace(v[1], "''", "'", -1))
+		}
+		list = list[len(v[0]):]
+		if list != "" && !strings.HasPrefix(list, ", ") {
+			return "", nil
+		}
+		list = strings.TrimPrefix(list, ", ")
+	}
+	return column, values
+}
@@ -0,0 +1,37 @@
+package inspect
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckValues(t *testing.T) {
+	tests := []struct {
+		definition string
+		column     string
+		values     []string
+	}{
+		{`CHECK ((status = ANY (ARRAY['new'::text, 'paid'::text])))`, "status", []string{"new", "paid"}},
+		{`CHECK (((status)::text = ANY ((ARRAY['new'::character varying, 'done'::character varying])::text[])))`,
+			"status", []string{"new", "done"}},
+		{`CHECK ((level = ANY (ARRAY[1, 2, 3])))`, "level", []string{"1", "2", "3"}},
+		{`CHECK ((delta = ANY (ARRAY['-1'::integer, 0, 1])))`, "delta", []string{"-1", "0", "1"}},
+		{`CHECK ((rate = ANY (ARRAY[0.5, 1.5])))`, "rate", []string{"0.5", "1.5"}},
+		{`CHECK (("Status" = ANY (ARRAY['a'::text, 'b'::text])))`, "Status", []string{"a", "b"}},
+		{`CHECK ((("Order Status")::text = ANY ((ARRAY['new'::character varying])::text[])))`,
+			"Order Status", []string{"new"}},
+		{`CHECK ((label = ANY (ARRAY['a, b'::text, 'it''s'::text, 'x]'::text])))`, "label", []string{"a, b", "it's", "x]"}},
+		{`CHECK ((status = ANY (ARRAY['new'::text, 'paid'::text]))) NOT VALID`, "status", []string{"new", "paid"}},
+		{`CHECK ((kind IN ('x', 'y')))`, "kind", []string{"x", "y"}},
+		{`CHECK ((price > (0)::numeric))`, "", nil},
+		{`CHECK ((status = ANY (ARRAY[lower('A'::text), 'b'::text])))`, "", nil},
+		{`CHECK (((a = ANY (ARRAY[1, 2])) OR (b IS NULL)))`, "", nil},
+		{`UNIQUE (status)`, "", nil},
+	}
+	for _, tt := range tests {
+		column, values := CheckValues(tt.definition)
+		if column != tt.column || !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("CheckValues(%s) = %q, %q, want %q, %q", tt.definition, column, values, tt.column, tt.values)
+		}
+	}
+}
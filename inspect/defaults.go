@@ -0,0 +1,62 @@
+package inspect
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of column defaults, see Column.DefaultKind.
+const (
+	DefaultNone       = ""
+	DefaultLiteral    = "literal"    // a constant, e.g. 0, 'new'::text or '{}'::text[]
+	DefaultSequence   = "sequence"   // nextval of a sequence, serial and identity columns
+	DefaultTimestamp  = "timestamp"  // the current time, e.g. now() or CURRENT_TIMESTAMP
+	DefaultUUID       = "uuid"       // a new UUID, e.g. gen_random_uuid()
+	DefaultGenerated  = "generated"  // a generated column's expression
+	DefaultExpression = "expression" // anything else
+)
+
+var (
+	literalDefault   = regexp.MustCompile(`^\(?(?:'(?:[^']|'')*'|-?[0-9]+(?:\.[0-9]+)?|true|false|NULL)\)?(?:::[\w ."\[\]]+)?$`)
+	timestampDefault = regexp.MustCompile(`(?i)\b(now\(\)|current_timestamp|current_date|current_time|localtimestamp|localtime|` +
+		`clock_timestamp\(\)|statement_timestamp\(\)|transaction_timestamp\(\))`)
+	uuidDefault = regexp.MustCompile(`\b(gen_random_uuid|uuid_generate_v1|uuid_generate_v1mc|uuid_generate_v4|uuid_generate_v7|uuidv4|uuidv7)\(`)
+)
+
+// DefaultKind classifies how the server fills the column when an INSERT
+// leaves it out, one of the Default constants.
+func (c *Column) DefaultKind() string {
+	switch {
+	case c.Generated != "":
+		return DefaultGenerated
+	case c.Identity != "" || strings.HasPrefix(c.Default, "nextval("):
+		return DefaultSequence
+	case c.Default == "":
+		return DefaultNone
+	case literalDefault.MatchString(c.Default):
+		return DefaultLiteral
+	case uuidDefault.MatchString(c.Default):
+		return DefaultUUID
+	case timestampDefault.MatchString(c.Default):
+		return DefaultTimestamp
+	}
+	return DefaultExpression
+}
+
+// ServerGenerated reports whether the server assigns the value of the
+// column, which clients then read back instead of writing: generated,
+// identity and serial columns and columns defaulting to a new UUID.
+func (c *Column) ServerGenerated() bool {
+	switch c.DefaultKind() {
+	case DefaultGenerated, DefaultSequence, DefaultUUID:
+		return true
+	}
+	return false
+}
+
+// ReadOnly reports whether clients cannot write the column: generated
+// columns and GENERATED ALWAYS identity columns, which need OVERRIDING
+// SYSTEM VALUE.
+func (c *Column) ReadOnly() bool {
+	return c.Generated != "" || c.Identity == "ALWAYS"
+}
@@ -0,0 +1,56 @@
+package inspect
+
+import "testing"
+
+func TestDefaultKind(t *testing.T) {
+	tests := []struct {
+		name            string
+		column          Column
+		kind            string
+		serverGenerated bool
+		readOnly        bool
+	}{
+		{"none", Column{}, DefaultNone, false, false},
+		{"identity by default", Column{Identity: "BY DEFAULT"}, DefaultSequence, true, false},
+		{"identity always", Column{Identity: "ALWAYS"}, DefaultSequence, true, true},
+		{"serial", Column{Default: "nextval('orders_id_seq'::regclass)"}, DefaultSequence, true, false},
+		{"serial in a schema", Column{Default: `nextval('"Sales".orders_id_seq'::regclass)`}, DefaultSequence, true, false},
+		{"generated", Column{Generated: "(price * quantity)"}, DefaultGenerated, true, true},
+		{"gen_random_uuid", Column{Default: "gen_random_uuid()"}, DefaultUUID, true, false},
+		{"uuid-ossp", Column{Default: "public.uuid_generate_v4()"}, DefaultUUID, true, false},
+		{"uuidv7", Column{Default: "uuidv7()"}, DefaultUUID, true, false},
+		{"now", Column{Default: "now()"}, DefaultTimestamp, false, false},
+		{"CURRENT_TIMESTAMP", Column{Default: "CURRENT_TIMESTAMP"}, DefaultTimestamp, false, false},
+		{"CURRENT_DATE", Column{Default: "CURRENT_DATE"}, DefaultTimestamp, false, false},
+		{"now in UTC", Column{Default: "(now() AT TIME ZONE 'utc'::text)"}, DefaultTimestamp, false, false},
+		{"clock_timestamp", Column{Default: "clock_timestamp()"}, DefaultTimestamp, false, false},
+		{"integer", Column{Default: "0"}, DefaultLiteral, false, false},
+		{"negative", Column{Default: "'-1'::integer"}, DefaultLiteral, false, false},
+		{"negative in parentheses", Column{Default: "(-1)"}, DefaultLiteral, false, false},
+		{"decimal", Column{Default: "0.00"}, DefaultLiteral, false, false},
+		{"boolean", Column{Default: "false"}, DefaultLiteral, false, false},
+		{"text", Column{Default: "'new'::text"}, DefaultLiteral, false, false},
+		{"quote", Column{Default: "'it''s'::character varying"}, DefaultLiteral, false, false},
+		{"empty array", Column{Default: "'{}'::text[]"}, DefaultLiteral, false, false},
+		{"enum", Column{Default: `'new'::"Sales".status`}, DefaultLiteral, false, false},
+		{"jsonb", Column{Default: "'{}'::jsonb"}, DefaultLiteral, false, false},
+		{"timestamp constant", Column{Default: "'2024-01-01 00:00:00'::timestamp without time zone"}, DefaultLiteral, false, false},
+		{"NULL", Column{Default: "NULL::text"}, DefaultLiteral, false, false},
+		{"function", Column{Default: "current_user"}, DefaultExpression, false, false},
+		{"arithmetic", Column{Default: "(now() + '30 days'::interval)"}, DefaultTimestamp, false, false},
+		{"random", Column{Default: "random()"}, DefaultExpression, false, false},
+		{"md5", Column{Default: "md5((random())::text)"}, DefaultExpression, false, false},
+	}
+	for _, tt := range tests {
+		c := tt.column
+		if got := c.DefaultKind(); got != tt.kind {
+			t.Errorf("%s: DefaultKind of %q = %q, want %q", tt.name, c.Default, got, tt.kind)
+		}
+		if got := c.ServerGenerated(); got != tt.serverGenerated {
+			t.Errorf("%s: ServerGenerated = %t, want %t", tt.name, got, tt.serverGenerated)
+		}
+		if got := c.ReadOnly(); got != tt.readOnly {
+			t.Errorf("%s: ReadOnly = %t, want %t", tt.name, got, tt.readOnly)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+// Object is a catalog object as pg_depend identifies it: the catalog it is
+// stored in, its OID there and, for a column, its number.
+type Object struct {
+	Class    string `json:"class" db:"class"` // catalog, e.g. pg_class or pg_proc
+	OID      int64  `json:"oid" db:"oid"`
+	SubID    int    `json:"subid,omitempty" db:"subid"` // column number, 0 for the whole object
+	Type     string `json:"type" db:"type"`             // as reported by pg_identify_object, e.g. view or table column
+	Identity string `json:"identity" db:"identity"`     // qualified name of the object
+}
+
+// Dependency is an edge of the dependency graph: Object depends on Ref.
+type Dependency struct {
+	Object Object `json:"object"`
+	Ref    Object `json:"ref"`
+	// Kind is normal, auto, internal, extension, auto extension, partition
+	// primary or partition secondary for pg_depend entries and owner, acl,
+	// policy, initial acl or tablespace for the pg_shdepend entries on
+	// roles and tablespaces.
+	Kind string `json:"kind"`
+}
+
+type dependencyRow struct {
+	Object
+	RefClass    string `db:"ref_class"`
+	RefOID      int64  `db:"ref_oid"`
+	RefSubID    int    `db:"ref_subid"`
+	RefType     string `db:"ref_type"`
+	RefIdentity string `db:"ref_identity"`
+	Kind        string `db:"kind"`
+}
+
+// dependencyQuery reads the dependencies of user objects, those with OIDs
+// from FirstNormalObjectId on, and the shared dependencies of objects of
+// the current database on roles and tablespaces. The _RETURN rule of a
+// view is replaced by the view itself so that objects reading the view
+// depend on it.
+const dependencyQuery = `WITH d AS (
+	SELECT CASE WHEN r.oid IS NULL THEN d.classid ELSE 'pg_class'::regclass::oid END AS classid,
+		COALESCE(r.ev_class, d.objid) AS objid, CASE WHEN r.oid IS NULL THEN d.objsubid ELSE 0 END AS objsubid,
+		d.refclassid, d.refobjid, d.refobjsubid,
+		CASE d.deptype WHEN 'n' THEN 'normal' WHEN 'a' THEN 'auto' WHEN 'i' THEN 'internal'
+			WHEN 'e' THEN 'extension' WHEN 'x' THEN 'auto extension' WHEN 'P' THEN 'partition primary'
+			WHEN 'S' THEN 'partition secondary' ELSE d.deptype::text END AS kind
+	FROM pg_depend d
+		LEFT JOIN pg_rewrite r ON d.classid = 'pg_rewrite'::regclass AND r.oid = d.objid AND r.rulename = '_RETURN'
+	WHERE d.objid >= 16384 AND d.deptype <> 'p'
+	UNION ALL
+	SELECT s.classid, s.objid, s.objsubid, s.refclassid, s.refobjid, 0,
+		CASE s.deptype WHEN 'o' THEN 'owner' WHEN 'a' THEN 'acl' WHEN 'r' THEN 'policy'
+			WHEN 'i' THEN 'initial acl' WHEN 't' THEN 'tablespace' ELSE s.deptype::text END
+	FROM pg_shdepend s
+		JOIN pg_database db ON db.oid = s.dbid
+	WHERE db.datname = current_database()
+)
+SELECT classid::regclass::text AS class, objid AS oid, objsubid AS subid,
+	(pg_identify_object(classid, objid, objsubid)).type AS type,
+	(pg_identify_object(classid, objid, objsubid)).identity AS identity,
+	refclassid::regclass::text AS ref_class, refobjid AS ref_oid, refobjsubid AS ref_subid,
+	(pg_identify_object(refclassid, refobjid, refobjsubid)).type AS ref_type,
+	(pg_identify_object(refclassid, refobjid, refobjsubid)).identity AS ref_identity,
+	kind
+FROM d
+WHERE classid <> refclassid OR objid <> refobjid`
+
+type objectKey struct {
+	class string
+	oid   int64
+}
+
+func keyOf(o Object) objectKey {
+	return objectKey{o.Class, o.OID}
+}
+
+// DependencyGraph holds the dependencies between the objects of a
+// database, from foreign keys, views, defaults, triggers, extensions,
+// ownership and grants alike, as pg_depend and pg_shdepend record them.
+type DependencyGraph struct {
+	Dependencies []Dependency
+
+	byObject map[objectKey][]int
+	byRef    map[objectKey][]int
+}
+
+// LoadDependencies reads the dependency graph of the database sess is
+// connected to.
+func LoadDependencies(sess *dbr.Session) (*DependencyGraph, error) {
+	var rows []dependencyRow
+	if _, err := sess.SelectBySql(dependencyQuery).Load(&rows); err != nil {
+		return nil, queryError("select dependencies", err)
+	}
+	deps := make([]Dependency, len(rows))
+	for i, v := range rows {
+		deps[i] = Dependency{
+			Object: v.Object,
+			Ref:    Object{Class: v.RefClass, OID: v.RefOID, SubID: v.RefSubID, Type: v.RefType, Identity: v.RefIdentity},
+			Kind:   v.Kind,
+		}
+	}
+	return NewDependencyGraph(deps), nil
+}
+
+// NewDependencyGraph indexes deps.
+func NewDependencyGraph(deps []Dependency) *DependencyGraph {
+	g := &DependencyGraph{
+		Dependencies: deps,
+		byObject:     make(map[objectKey][]int),
+		byRef:        make(map[objectKey][]int),
+	}
+	for i, d := range deps {
+		g.byObject[keyOf(d.Object)] = append(g.byObject[keyOf(d.Object)], i)
+		g.byRef[keyOf(d.Ref)] = append(g.byRef[keyOf(d.Ref)], i)
+	}
+	return g
+}
+
+// DependsOn returns the dependencies of obj on other objects. For a whole
+// object, with SubID 0, those of its columns are included.
+func (g *DependencyGraph) DependsOn(obj Object) []Dependency {
+	var deps []Dependency
+	for _, i := range g.byObject[keyOf(obj)] {
+		if d := g.Dependencies[i]; obj.SubID == 0 || d.Object.SubID == obj.SubID {
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// Dependents returns the dependencies of other objects on obj. For a whole
+// object, with SubID 0, those on its columns are included.
+func (g *DependencyGraph) Dependents(obj Object) []Dependency {
+	var deps []Dependency
+	for _, i := range g.byRef[keyOf(obj)] {
+		if d := g.Dependencies[i]; obj.SubID == 0 || d.Ref.SubID == obj.SubID {
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// Find returns the object with the given type and identity as reported by
+// pg_identify_object, e.g. "table" and "public.users".
+func (g *DependencyGraph) Find(typ, identity string) (Object, bool) {
+	for _, d := range g.Dependencies {
+		if d.Object.Type == typ && d.Object.Identity == identity {
+			return d.Object, true
+		}
+		if d.Ref.Type == typ && d.Ref.Identity == identity {
+			return d.Ref, true
+		}
+	}
+	return Object{}, false
+}
@@ -0,0 +1,38 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+type Enum struct {
+	Name   string   `json:"name"`
+	Labels []string `json:"labels"` // in sort order
+}
+
+type enumRow struct {
+	Schema string         `db:"schema"`
+	Name   string         `db:"name"`
+	Labels pq.StringArray `db:"labels"`
+}
+
+func loadEnums(sess *dbr.Session, schemas []string, bySchema map[string]*Schema) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []enumRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS schema, t.typname AS name,
+	array_agg(e.enumlabel ORDER BY e.enumsortorder)::text[] AS labels
+FROM pg_type t
+	JOIN pg_enum e ON e.enumtypid = t.oid
+	JOIN pg_namespace n ON n.oid = t.typnamespace
+WHERE `+where+`
+GROUP BY n.nspname, t.typname`, args...).Load(&rows)
+	if err != nil {
+		return queryError("select enums", err)
+	}
+	for _, v := range rows {
+		if s := bySchema[v.Schema]; s != nil {
+			s.Enums = append(s.Enums, Enum{Name: v.Name, Labels: v.Labels})
+		}
+	}
+	return nil
+}
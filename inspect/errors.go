@@ -0,0 +1,117 @@
+package inspect
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of failures returned by the loaders, see ErrorKind.
+var (
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrConnection       = errors.New("connection failed")
+	ErrTimeout          = errors.New("timeout")
+)
+
+// QueryError is returned when a catalog query fails. Kind is one of the Err
+// variables above, nil if the failure is not classified.
+type QueryError struct {
+	Op   string
+	Kind error
+	Err  error
+}
+
+func (e *QueryError) Error() string { return e.Op + ": " + e.Err.Error() }
+
+// Unwrap and Is let errors.Is match both the driver error and the kind.
+func (e *QueryError) Unwrap() error        { return e.Err }
+func (e *QueryError) Is(target error) bool { return e.Kind != nil && target == e.Kind }
+
+// ErrorKind returns ErrPermissionDenied, ErrConnection or ErrTimeout for
+// failures of those kinds, nil for any other error.
+func ErrorKind(err error) error {
+	if e, ok := err.(*QueryError); ok {
+		return e.Kind
+	}
+	return classify(err)
+}
+
+// Temporary reports whether err is worth retrying: a connection failure, a
+// timeout or a serialization failure.
+func Temporary(err error) bool {
+	if e, ok := err.(*QueryError); ok {
+		err = e.Err
+	}
+	if k := classify(err); k == ErrConnection || k == ErrTimeout {
+		return true
+	}
+	if e, ok := err.(*pq.Error); ok {
+		return e.Code == "40001" || e.Code == "40P01"
+	}
+	return false
+}
+
+func queryError(op string, err error) error {
+	return &QueryError{Op: op, Kind: classify(err), Err: err}
+}
+
+func classify(err error) error {
+	switch e := err.(type) {
+	case *pq.Error:
+		switch {
+		// insufficient_privilege, and the failed authentications of
+		// class 28.
+		case e.Code == "42501", e.Code.Class() == "28":
+			return ErrPermissionDenied
+		case e.Code == "57014":
+			return ErrTimeout
+		case e.Code.Class() == "08", e.Code == "53300", e.Code == "57P01", e.Code == "57P03":
+			return ErrConnection
+		}
+		return nil
+	case net.Error:
+		if e.Timeout() {
+			return ErrTimeout
+		}
+		return ErrConnection
+	}
+	switch err {
+	case context.DeadlineExceeded:
+		return ErrTimeout
+	case driver.ErrBadConn, io.EOF, io.ErrUnexpectedEOF:
+		return ErrConnection
+	}
+	return nil
+}
+
+// RetryPolicy is how often and how long a failed inspection is retried.
+// Delays double after every attempt up to MaxDelay.
+type RetryPolicy struct {
+	Attempts int
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// DefaultRetry retries three times, waiting 0.5, 1 and 2 seconds.
+var DefaultRetry = RetryPolicy{Attempts: 4, Delay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// Retry calls fn until it succeeds, returns an error that is not Temporary
+// or p.Attempts calls were made, and returns the last error.
+func Retry(p RetryPolicy, fn func() error) error {
+	delay := p.Delay
+	var err error
+	for i := 0; ; i++ {
+		if err = fn(); err == nil || !Temporary(err) || i+1 >= p.Attempts {
+			return err
+		}
+		time.Sleep(delay)
+		if delay *= 2; p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
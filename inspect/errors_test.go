@@ -0,0 +1,97 @@
+package inspect
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestErrorKind(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name      string
+		err       error
+		kind      error
+		temporary bool
+	}{
+		{"insufficient privilege", &pq.Error{Code: "42501"}, ErrPermissionDenied, false},
+		{"password authentication failed", &pq.Error{Code: "28P01"}, ErrPermissionDenied, false},
+		{"invalid authorization", &pq.Error{Code: "28000"}, ErrPermissionDenied, false},
+		{"statement timeout", &pq.Error{Code: "57014"}, ErrTimeout, true},
+		{"connection failure", &pq.Error{Code: "08006"}, ErrConnection, true},
+		{"too many connections", &pq.Error{Code: "53300"}, ErrConnection, true},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, ErrConnection, true},
+		{"cannot connect now", &pq.Error{Code: "57P03"}, ErrConnection, true},
+		{"serialization failure", &pq.Error{Code: "40001"}, nil, true},
+		{"deadlock", &pq.Error{Code: "40P01"}, nil, true},
+		{"undefined table", &pq.Error{Code: "42P01"}, nil, false},
+		{"syntax error", &pq.Error{Code: "42601"}, nil, false},
+		{"refused", refused, ErrConnection, true},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", Name: "db", IsTimeout: true}, ErrTimeout, true},
+		{"deadline", context.DeadlineExceeded, ErrTimeout, true},
+		{"bad connection", driver.ErrBadConn, ErrConnection, true},
+		{"eof", io.EOF, ErrConnection, true},
+		{"unexpected eof", io.ErrUnexpectedEOF, ErrConnection, true},
+		{"canceled", context.Canceled, nil, false},
+		{"other", errors.New("boom"), nil, false},
+	}
+	for _, tt := range tests {
+		if got := ErrorKind(tt.err); got != tt.kind {
+			t.Errorf("%s: ErrorKind = %v, want %v", tt.name, got, tt.kind)
+		}
+		if got := Temporary(tt.err); got != tt.temporary {
+			t.Errorf("%s: Temporary = %t, want %t", tt.name, got, tt.temporary)
+		}
+		qerr := queryError("select tables", tt.err)
+		if got := ErrorKind(qerr); got != tt.kind {
+			t.Errorf("%s: ErrorKind of QueryError = %v, want %v", tt.name, got, tt.kind)
+		}
+		if got := Temporary(qerr); got != tt.temporary {
+			t.Errorf("%s: Temporary of QueryError = %t, want %t", tt.name, got, tt.temporary)
+		}
+		if tt.kind != nil && !errors.Is(qerr, tt.kind) {
+			t.Errorf("%s: errors.Is(%v, %v) is false", tt.name, qerr, tt.kind)
+		}
+		if !errors.Is(qerr, tt.err) {
+			t.Errorf("%s: QueryError does not wrap %v", tt.name, tt.err)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		calls    int
+		err      error
+	}{
+		{"success", []error{nil}, 3, 1, nil},
+		{"retried", []error{io.EOF, io.EOF, nil}, 3, 3, nil},
+		{"attempts exhausted", []error{io.EOF, io.EOF, io.EOF, nil}, 3, 3, io.EOF},
+		{"permission denied", []error{&pq.Error{Code: "42501"}, nil}, 3, 1, ErrPermissionDenied},
+		{"single attempt", []error{io.EOF, nil}, 1, 1, io.EOF},
+		{"serialization failure", []error{&pq.Error{Code: "40001"}, nil}, 3, 2, nil},
+	}
+	for _, tt := range tests {
+		calls := 0
+		err := Retry(RetryPolicy{Attempts: tt.attempts}, func() error {
+			calls++
+			if err := tt.errs[calls-1]; err != nil {
+				return queryError("select tables", err)
+			}
+			return nil
+		})
+		if calls != tt.calls {
+			t.Errorf("%s: %d calls, want %d", tt.name, calls, tt.calls)
+		}
+		if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: Retry = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
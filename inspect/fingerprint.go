@@ -0,0 +1,101 @@
+package inspect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// FingerprintOptions select attributes left out of a fingerprint.
+type FingerprintOptions struct {
+	IgnoreComments    bool
+	IgnoreTablespaces bool
+	IgnoreStats       bool
+}
+
+// Fingerprint returns a SHA-256 hash of the normalized structure of the
+// database. Databases with the same structure have the same fingerprint
+// regardless of their name, inspection time, recorded migrations and the
+// roles of their cluster.
+func Fingerprint(db *Database, opts FingerprintOptions) string {
+	b, err := json.Marshal(Normalize(db, opts))
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Normalize returns a sorted copy of db without what Fingerprint leaves
+// out: the name, inspection time, migrations, roles, OIDs and the
+// attributes selected by opts.
+func Normalize(db *Database, opts FingerprintOptions) *Database {
+	c := db.Copy()
+	c.Name = ""
+	c.InspectedAt = time.Time{}
+	c.Migrations = nil
+	c.Roles = nil
+	for _, s := range c.Schemas {
+		if opts.IgnoreStats {
+			for i := range s.Sequences {
+				s.Sequences[i].LastValue = 0
+			}
+		}
+		for _, t := range s.Tables {
+			t.Toast = "" // named after the table's oid
+			clearIDs(t)
+			if opts.IgnoreComments {
+				t.Comment, t.Annotations = "", nil
+				for i := range t.Columns {
+					t.Columns[i].Comment, t.Columns[i].Annotations = "", nil
+				}
+			}
+			if opts.IgnoreTablespaces {
+				t.Tablespace = ""
+			}
+			if opts.IgnoreStats {
+				t.Stats = TableStats{}
+			}
+		}
+	}
+	c.Sort()
+	return c
+}
+
+// clearIDs removes the OIDs, which differ between databases, from the
+// IDs of t and its indexes and constraints.
+func clearIDs(t *Table) {
+	t.ID.OID = 0
+	for i := range t.Indexes {
+		t.Indexes[i].ID.OID = 0
+		if c := t.Indexes[i].Constraint; c != nil {
+			c.OID = 0
+		}
+	}
+	for i := range t.Constraints {
+		t.Constraints[i].ID.OID = 0
+		if idx := t.Constraints[i].Index; idx != nil {
+			idx.OID = 0
+		}
+	}
+	for i := range t.FKs {
+		fk := &t.FKs[i]
+		fk.ID.OID, fk.RefID.OID = 0, 0
+		if idx := fk.RefIndex; idx != nil {
+			idx.OID = 0
+		}
+	}
+	for _, id := range []*ObjectID{t.PK.ID, t.PK.Index} {
+		if id != nil {
+			id.OID = 0
+		}
+	}
+	for i := range t.ReferencedBy {
+		fk := &t.ReferencedBy[i].FK
+		fk.ID.OID, fk.RefID.OID = 0, 0
+		if idx := fk.RefIndex; idx != nil {
+			idx.OID = 0
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package inspect
+
+import (
+	"strings"
+
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// Function is a function or procedure. Aggregates and functions belonging
+// to extensions are left out.
+type Function struct {
+	Name            string   `json:"name"`
+	Arguments       string   `json:"arguments"` // identity arguments, e.g. a integer, b text
+	Result          string   `json:"result,omitempty"`
+	Language        string   `json:"language"`
+	Owner           string   `json:"owner"`
+	SecurityDefiner bool     `json:"security_definer,omitempty"`
+	Volatility      string   `json:"volatility"`       // immutable, stable or volatile
+	Parallel        string   `json:"parallel"`         // safe, restricted or unsafe
+	Config          []string `json:"config,omitempty"` // SET clauses, e.g. search_path=pg_catalog
+	ACL             []string `json:"acl,omitempty"`
+	Comment         string   `json:"comment,omitempty"`
+}
+
+// Signature is the name and identity arguments, e.g. add(a integer, b integer).
+func (f *Function) Signature() string {
+	return f.Name + "(" + f.Arguments + ")"
+}
+
+// SearchPath returns the search_path the function sets, and false if it
+// runs with the search_path of the caller.
+func (f *Function) SearchPath() (string, bool) {
+	for _, c := range f.Config {
+		if strings.HasPrefix(c, "search_path=") {
+			return strings.TrimPrefix(c, "search_path="), true
+		}
+	}
+	return "", false
+}
+
+type functionRow struct {
+	Schema          string         `db:"schema"`
+	Name            string         `db:"name"`
+	Arguments       string         `db:"arguments"`
+	Result          string         `db:"result"`
+	Language        string         `db:"language"`
+	Owner           string         `db:"owner"`
+	SecurityDefiner bool           `db:"security_definer"`
+	Volatility      string         `db:"volatility"`
+	Parallel        string         `db:"parallel"`
+	Config          pq.StringArray `db:"config"`
+	ACL             pq.StringArray `db:"acl"`
+	Comment         string         `db:"comment"`
+}
+
+func loadFunctions(sess *dbr.Session, schemas []string, bySchema map[string]*Schema) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []functionRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS schema, p.proname AS name,
+	pg_get_function_identity_arguments(p.oid) AS arguments,
+	COALESCE(pg_get_function_result(p.oid), '') AS result,
+	l.lanname AS language, pg_get_userbyid(p.proowner) AS owner, p.prosecdef AS security_definer,
+	CASE p.provolatile WHEN 'i' THEN 'immutable' WHEN 's' THEN 'stable' ELSE 'volatile' END AS volatility,
+	CASE p.proparallel WHEN 's' THEN 'safe' WHEN 'r' THEN 'restricted' ELSE 'unsafe' END AS parallel,
+	COALESCE(p.proconfig, '{}')::text[] AS config,
+	COALESCE(p.proacl, acldefault('f', p.proowner))::text[] AS acl,
+	COALESCE(obj_description(p.oid, 'pg_proc'), '') AS comment
+FROM pg_proc p
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+	JOIN pg_language l ON l.oid = p.prolang
+WHERE NOT EXISTS (SELECT 1 FROM pg_aggregate a WHERE a.aggfnoid = p.oid)
+	AND NOT EXISTS (SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')
+	AND `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select functions", err)
+	}
+	for _, v := range rows {
+		s := bySchema[v.Schema]
+		if s == nil {
+			continue
+		}
+		s.Functions = append(s.Functions, Function{
+			Name:            v.Name,
+			Arguments:       v.Arguments,
+			Result:          v.Result,
+			Language:        v.Language,
+			Owner:           v.Owner,
+			SecurityDefiner: v.SecurityDefiner,
+			Volatility:      v.Volatility,
+			Parallel:        v.Parallel,
+			Config:          v.Config,
+			ACL:             v.ACL,
+			Comment:         v.Comment,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package inspect
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gocraft/dbr"
+)
+
+// Dependent is an object affected by dropping another one.
+type Dependent struct {
+	Type     string `json:"type" db:"type"`         // as reported by pg_identify_object, e.g. view or table constraint
+	Identity string `json:"identity" db:"identity"` // qualified name of the object
+	// Cascade is set when the drop fails unless it cascades to the object,
+	// otherwise the object is dropped along silently.
+	Cascade bool `json:"cascade" db:"cascade"`
+	// Depth is the length of the dependency chain from the dropped object.
+	Depth int `json:"depth" db:"depth"`
+}
+
+// DropImpact returns the objects depending on a table, or on one of its
+// columns if column is not empty, in the order they have to be dropped.
+func DropImpact(sess *dbr.Session, schema, table, column string) ([]Dependent, error) {
+	var rel struct {
+		OID     int64 `db:"oid"`
+		RowType int64 `db:"row_type"`
+	}
+	err := sess.SelectBySql(`SELECT c.oid, c.reltype AS row_type
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = ? AND c.relname = ?`, schema, table).LoadOne(&rel)
+	if err == dbr.ErrNotFound {
+		return nil, fmt.Errorf("relation %s.%s does not exist", schema, table)
+	}
+	if err != nil {
+		return nil, queryError("select relation", err)
+	}
+
+	attnum := 0
+	if column != "" {
+		err := sess.SelectBySql(`SELECT attnum FROM pg_attribute
+WHERE attrelid = ? AND attname = ? AND NOT attisdropped`, rel.OID, column).LoadOne(&attnum)
+		if err == dbr.ErrNotFound {
+			return nil, fmt.Errorf("column %s.%s.%s does not exist", schema, table, column)
+		}
+		if err != nil {
+			return nil, queryError("select column", err)
+		}
+	}
+
+	g, err := LoadDependencies(sess)
+	if err != nil {
+		return nil, err
+	}
+	start := []Object{{Class: "pg_class", OID: rel.OID, SubID: attnum}}
+	if attnum == 0 {
+		start = append(start, Object{Class: "pg_type", OID: rel.RowType})
+	}
+	return g.Impact(start...), nil
+}
+
+// maxImpactDepth bounds the dependency chains Impact follows.
+const maxImpactDepth = 16
+
+// Impact walks the normal and auto dependencies from objs, the first of
+// which is the object dropped, and returns the objects affected in the
+// order they have to be dropped.
+func (g *DependencyGraph) Impact(objs ...Object) []Dependent {
+	type objectSubKey struct {
+		objectKey
+		subid int
+	}
+	found := make(map[objectSubKey]*Dependent)
+	frontier := objs
+	for depth := 1; depth <= maxImpactDepth && len(frontier) > 0; depth++ {
+		var next []Object
+		for _, o := range frontier {
+			for _, d := range g.Dependents(o) {
+				k := keyOf(d.Object)
+				if d.Kind != "normal" && d.Kind != "auto" || k == keyOf(objs[0]) || k == keyOf(o) {
+					continue
+				}
+				sk := objectSubKey{k, d.Object.SubID}
+				dep := found[sk]
+				if dep == nil {
+					dep = &Dependent{Type: d.Object.Type, Identity: d.Object.Identity}
+					found[sk] = dep
+				}
+				dep.Cascade = dep.Cascade || d.Kind == "normal"
+				if depth > dep.Depth {
+					dep.Depth = depth
+					next = append(next, Object{Class: d.Object.Class, OID: d.Object.OID})
+				}
+			}
+		}
+		frontier = next
+	}
+
+	deps := make([]Dependent, 0, len(found))
+	for _, d := range found {
+		deps = append(deps, *d)
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		a, b := deps[i], deps[j]
+		if a.Depth != b.Depth {
+			return a.Depth > b.Depth
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Identity < b.Identity
+	})
+	return deps
+}
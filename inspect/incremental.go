@@ -0,0 +1,185 @@
+package inspect
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// schemaVersionParts aggregate the oid, or another key, and xmin of the
+// catalog rows describing the objects of the schema n, by catalog. Any DDL
+// updates, inserts or deletes such rows and so changes the hash; VACUUM and
+// ANALYZE update statistics in place and do not.
+var schemaVersionParts = []struct {
+	catalog, query string
+}{
+	{"pg_class", `SELECT string_agg(c.oid::text || ':' || c.xmin::text, ',' ORDER BY c.oid)
+		FROM pg_class c WHERE c.relnamespace = n.oid`},
+	{"pg_attribute", `SELECT string_agg(a.attrelid::text || '.' || a.attnum::text || ':' || a.xmin::text, ',' ORDER BY a.attrelid, a.attnum)
+		FROM pg_attribute a JOIN pg_class c ON c.oid = a.attrelid WHERE c.relnamespace = n.oid`},
+	{"pg_constraint", `SELECT string_agg(o.oid::text || ':' || o.xmin::text, ',' ORDER BY o.oid)
+		FROM pg_constraint o WHERE o.connamespace = n.oid`},
+	// Foreign keys print the names of the referenced table, its schema and
+	// columns, which may live in another schema.
+	{"pg_class", `SELECT string_agg(o.oid::text || ':' || fc.xmin::text || ':' || fn.xmin::text || ':' ||
+			(SELECT string_agg(a.xmin::text, ',' ORDER BY a.attnum) FROM pg_attribute a
+				WHERE a.attrelid = o.confrelid AND a.attnum = ANY (o.confkey)), ',' ORDER BY o.oid)
+		FROM pg_constraint o JOIN pg_class fc ON fc.oid = o.confrelid JOIN pg_namespace fn ON fn.oid = fc.relnamespace
+		WHERE o.connamespace = n.oid AND o.contype = 'f'`},
+	{"pg_trigger", `SELECT string_agg(t.oid::text || ':' || t.xmin::text, ',' ORDER BY t.oid)
+		FROM pg_trigger t JOIN pg_class c ON c.oid = t.tgrelid WHERE c.relnamespace = n.oid`},
+	{"pg_policy", `SELECT string_agg(p.oid::text || ':' || p.xmin::text, ',' ORDER BY p.oid)
+		FROM pg_policy p JOIN pg_class c ON c.oid = p.polrelid WHERE c.relnamespace = n.oid`},
+	{"pg_rewrite", `SELECT string_agg(r.oid::text || ':' || r.xmin::text, ',' ORDER BY r.oid)
+		FROM pg_rewrite r JOIN pg_class c ON c.oid = r.ev_class WHERE c.relnamespace = n.oid`},
+	// Comments on the schema and on any object in it, of any catalog.
+	// Objects below FirstNormalObjectId are built in.
+	{"pg_description", `SELECT string_agg(d.classoid::text || '.' || d.objoid::text || '.' || d.objsubid::text || ':' || d.xmin::text,
+			',' ORDER BY d.classoid, d.objoid, d.objsubid)
+		FROM pg_description d
+		WHERE d.objoid >= 16384 AND (d.classoid = 'pg_namespace'::regclass AND d.objoid = n.oid
+			OR (pg_identify_object(d.classoid, d.objoid, d.objsubid)).schema = n.nspname)`},
+	{"pg_sequence", `SELECT string_agg(s.seqrelid::text || ':' || s.xmin::text, ',' ORDER BY s.seqrelid)
+		FROM pg_sequence s JOIN pg_class c ON c.oid = s.seqrelid WHERE c.relnamespace = n.oid`},
+	// The columns owning sequences, set by ALTER SEQUENCE OWNED BY and by
+	// serial and identity columns.
+	{"pg_depend", `SELECT string_agg(d.objid::text || ':' || d.refobjid::text || '.' || d.refobjsubid::text || ':' || d.xmin::text,
+			',' ORDER BY d.objid, d.refobjid, d.refobjsubid)
+		FROM pg_depend d JOIN pg_class c ON c.oid = d.objid
+		WHERE d.classid = 'pg_class'::regclass AND d.refclassid = 'pg_class'::regclass
+			AND d.deptype IN ('a', 'i') AND c.relkind = 'S' AND c.relnamespace = n.oid`},
+	{"pg_proc", `SELECT string_agg(p.oid::text || ':' || p.xmin::text, ',' ORDER BY p.oid)
+		FROM pg_proc p WHERE p.pronamespace = n.oid`},
+	{"pg_enum", `SELECT string_agg(e.oid::text || ':' || e.xmin::text, ',' ORDER BY e.oid)
+		FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid WHERE t.typnamespace = n.oid`},
+	{"pg_ts_config", `SELECT string_agg(c.oid::text || ':' || c.xmin::text, ',' ORDER BY c.oid)
+		FROM pg_ts_config c WHERE c.cfgnamespace = n.oid`},
+	{"pg_ts_dict", `SELECT string_agg(d.oid::text || ':' || d.xmin::text, ',' ORDER BY d.oid)
+		FROM pg_ts_dict d WHERE d.dictnamespace = n.oid`},
+	{"pg_operator", `SELECT string_agg(o.oid::text || ':' || o.xmin::text, ',' ORDER BY o.oid)
+		FROM pg_operator o WHERE o.oprnamespace = n.oid`},
+	{"pg_opclass", `SELECT string_agg(c.oid::text || ':' || c.xmin::text, ',' ORDER BY c.oid)
+		FROM pg_opclass c WHERE c.opcnamespace = n.oid`},
+	{"pg_opfamily", `SELECT string_agg(f.oid::text || ':' || f.xmin::text, ',' ORDER BY f.oid)
+		FROM pg_opfamily f WHERE f.opfnamespace = n.oid`},
+	{"pg_amop", `SELECT string_agg(o.oid::text || ':' || o.xmin::text, ',' ORDER BY o.oid)
+		FROM pg_amop o JOIN pg_opfamily f ON f.oid = o.amopfamily WHERE f.opfnamespace = n.oid`},
+	{"pg_aggregate", `SELECT string_agg(a.aggfnoid::text || ':' || a.xmin::text, ',' ORDER BY a.aggfnoid)
+		FROM pg_aggregate a JOIN pg_proc p ON p.oid = a.aggfnoid WHERE p.pronamespace = n.oid`},
+}
+
+// schemaVersionQuery returns the query hashing schemaVersionParts of each
+// schema, to be completed with the condition on n.nspname.
+func schemaVersionQuery() string {
+	parts := make([]string, len(schemaVersionParts))
+	for i, p := range schemaVersionParts {
+		parts[i] = "(" + p.query + ")"
+	}
+	return `SELECT n.nspname AS schema, md5(concat_ws('|', n.xmin::text,
+	` + strings.Join(parts, ",\n\t") + `
+)) AS version
+FROM pg_namespace n
+WHERE `
+}
+
+// SchemaVersions returns a hash of the catalog entries of each schema,
+// which changes whenever an object in the schema is created, altered or
+// dropped.
+func SchemaVersions(sess *dbr.Session, schemas []string) (map[string]string, error) {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []struct {
+		Schema  string `db:"schema"`
+		Version string `db:"version"`
+	}
+	if _, err := sess.SelectBySql(schemaVersionQuery()+where, args...).Load(&rows); err != nil {
+		return nil, queryError("select schema versions", err)
+	}
+	versions := make(map[string]string, len(rows))
+	for _, v := range rows {
+		versions[v.Schema] = v.Version
+	}
+	return versions, nil
+}
+
+// Inspector inspects a database repeatedly, as watch and serve do. After
+// the first Load it re-reads only the schemas whose catalog entries
+// changed, see SchemaVersions, which makes watching a database with
+// thousands of tables cheap. Roles, default privileges and applied
+// migrations are re-read every time. Table statistics and sequence values
+// are refreshed only with their schema.
+type Inspector struct {
+	// Schemas are the schemas to inspect, all user schemas if empty.
+	Schemas []string
+
+	db       *Database
+	versions map[string]string
+}
+
+// Load returns the current structure of the database. The returned
+// database is not modified by later calls.
+func (in *Inspector) Load(sess *dbr.Session) (*Database, error) {
+	versions, err := SchemaVersions(sess, in.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	if in.db == nil {
+		db, err := Load(sess, in.Schemas)
+		if err != nil {
+			return nil, err
+		}
+		in.db, in.versions = db, versions
+		return db, nil
+	}
+
+	var changed []string
+	for name, v := range versions {
+		if in.versions[name] != v {
+			changed = append(changed, name)
+		}
+	}
+	db := &Database{Name: in.db.Name, InspectedAt: time.Now().UTC(), Schemas: in.unchanged(versions)}
+	if len(changed) > 0 {
+		partial, err := loadSchemas(sess, changed)
+		if err != nil {
+			return nil, err
+		}
+		partial.Sort()
+		db.Schemas = append(db.Schemas, partial.Schemas...)
+	}
+
+	if err := loadRoles(sess, db); err != nil {
+		return nil, err
+	}
+	if err := loadDefaultPrivileges(sess, in.Schemas, db); err != nil {
+		return nil, err
+	}
+	if err := loadMigrations(sess, db); err != nil {
+		return nil, err
+	}
+	db.sortLists()
+	db.LinkReferences()
+	in.db, in.versions = db, versions
+	return db, nil
+}
+
+// unchanged returns the schemas of the last database whose version is the
+// same in versions. They share everything with the last database but the
+// tables and their foreign keys, which LinkReferences modifies.
+func (in *Inspector) unchanged(versions map[string]string) []*Schema {
+	var kept []*Schema
+	for _, s := range in.db.Schemas {
+		if v, ok := versions[s.Name]; !ok || in.versions[s.Name] != v {
+			continue
+		}
+		c := *s
+		c.Tables = make([]*Table, len(s.Tables))
+		for i, t := range s.Tables {
+			ct := *t
+			ct.FKs = append([]ForeignKey(nil), t.FKs...)
+			c.Tables[i] = &ct
+		}
+		kept = append(kept, &c)
+	}
+	return kept
+}
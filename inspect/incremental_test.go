@@ -0,0 +1,89 @@
+package inspect
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersionParts(t *testing.T) {
+	// The catalogs statements write to; the version of the schema changes
+	// only if rows of them are hashed.
+	tests := []struct {
+		statement, catalog string
+	}{
+		{"ALTER TABLE ... RENAME", "pg_class"},
+		{"ALTER TABLE ... ADD COLUMN", "pg_attribute"},
+		{"ALTER TABLE ... ADD CONSTRAINT", "pg_constraint"},
+		{"CREATE TRIGGER", "pg_trigger"},
+		{"CREATE POLICY", "pg_policy"},
+		{"CREATE RULE", "pg_rewrite"},
+		{"COMMENT ON TABLE, COLUMN, SCHEMA, FUNCTION, OPERATOR, TEXT SEARCH CONFIGURATION", "pg_description"},
+		{"ALTER SEQUENCE ... INCREMENT BY, MAXVALUE", "pg_sequence"},
+		{"ALTER SEQUENCE ... OWNED BY", "pg_depend"},
+		{"CREATE FUNCTION", "pg_proc"},
+		{"ALTER TYPE ... ADD VALUE", "pg_enum"},
+		{"CREATE TEXT SEARCH CONFIGURATION", "pg_ts_config"},
+		{"CREATE TEXT SEARCH DICTIONARY", "pg_ts_dict"},
+		{"CREATE OPERATOR", "pg_operator"},
+		{"CREATE OPERATOR CLASS", "pg_opclass"},
+		{"CREATE OPERATOR FAMILY", "pg_opfamily"},
+		{"ALTER OPERATOR FAMILY ... ADD OPERATOR", "pg_amop"},
+		{"CREATE AGGREGATE", "pg_aggregate"},
+	}
+	hashed := make(map[string]string)
+	for _, p := range schemaVersionParts {
+		if !strings.Contains(p.query, p.catalog+" ") {
+			t.Errorf("part of %s does not read it: %s", p.catalog, p.query)
+		}
+		hashed[p.catalog] += p.query
+	}
+	for _, tt := range tests {
+		if hashed[tt.catalog] == "" {
+			t.Errorf("%s writes %s, which is not hashed", tt.statement, tt.catalog)
+		}
+	}
+	// Comments on objects other than tables and columns count as well.
+	if strings.Contains(hashed["pg_description"], "'pg_class'::regclass") {
+		t.Errorf("only comments of relations are hashed: %s", hashed["pg_description"])
+	}
+	if q := schemaVersionQuery(); strings.Count(q, "(") != strings.Count(q, ")") {
+		t.Errorf("unbalanced parentheses in %s", q)
+	}
+}
+
+func TestInspectorKeepsReturnedDatabase(t *testing.T) {
+	old := catalog()
+	old.Sort()
+	old.LinkReferences()
+	before, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// shop did not change, audit.log was renamed to audit.entries.
+	in := &Inspector{db: old, versions: map[string]string{"shop": "1", "audit": "1"}}
+	db := &Database{Name: old.Name, Schemas: in.unchanged(map[string]string{"shop": "1", "audit": "2"})}
+	if len(db.Schemas) != 1 || db.Schemas[0].Name != "shop" {
+		t.Fatalf("kept %d schemas, want shop", len(db.Schemas))
+	}
+	audit := &Schema{Name: "audit", Owner: "auditor", Tables: []*Table{{Schema: "audit", Name: "entries", Type: "BASE TABLE",
+		Columns: []Column{{Name: "order_id", DataType: "bigint", UDTName: "int8", Position: 1}}}}}
+	db.Schemas = append(db.Schemas, audit)
+	db.sortLists()
+	db.LinkReferences()
+
+	after, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("the database returned before changed:\n%s\nwant\n%s", after, before)
+	}
+	if customers := db.Schemas[1].Table("customers"); len(customers.ReferencedBy) != 1 {
+		t.Errorf("shop.customers is referenced by %v, want shop.orders", customers.ReferencedBy)
+	}
+	if &db.Schemas[1].Sequences[0] != &old.Schemas[1].Sequences[0] {
+		t.Error("the sequences of an unchanged schema are copied")
+	}
+}
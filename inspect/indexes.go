@@ -0,0 +1,85 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+type Index struct {
+	Name       string   `json:"name"`
+	Method     string   `json:"method"`  // btree, hash, gin, gist, brin, ...
+	Columns    []string `json:"columns"` // unquoted column names or expressions
+	Unique     bool     `json:"unique,omitempty"`
+	Primary    bool     `json:"primary,omitempty"`
+	Predicate  string   `json:"predicate,omitempty"` // WHERE clause of a partial index
+	Definition string   `json:"definition"`
+
+	// Spatial is set on GiST, SP-GiST and BRIN indexes of PostGIS columns.
+	Spatial bool `json:"spatial,omitempty"`
+
+	// ID is the pg_class entry of the index and Constraint the primary
+	// key, unique or exclusion constraint it enforces, if any.
+	ID         ObjectID  `json:"id"`
+	Constraint *ObjectID `json:"constraint,omitempty"`
+}
+
+type indexRow struct {
+	TableSchema string         `db:"table_schema"`
+	TableName   string         `db:"table_name"`
+	Name        string         `db:"name"`
+	Method      string         `db:"method"`
+	Columns     pq.StringArray `db:"columns"`
+	Unique      bool           `db:"is_unique"`
+	Primary     bool           `db:"is_primary"`
+	Predicate   string         `db:"predicate"`
+	Definition  string         `db:"definition"`
+	OID         int64          `db:"oid"`
+	ConOID      int64          `db:"constraint_oid"`
+	ConName     string         `db:"constraint_name"`
+}
+
+func loadIndexes(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []indexRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name,
+	i.relname AS name, am.amname AS method,
+	ARRAY(SELECT CASE WHEN x.indkey[k - 1] = 0 THEN pg_get_indexdef(x.indexrelid, k, true)
+			ELSE (SELECT a.attname::text FROM pg_attribute a WHERE a.attrelid = x.indrelid AND a.attnum = x.indkey[k - 1]) END
+		FROM generate_series(1, x.indnatts) k ORDER BY k)::text[] AS columns,
+	x.indisunique AS is_unique, x.indisprimary AS is_primary,
+	COALESCE(pg_get_expr(x.indpred, x.indrelid, true), '') AS predicate,
+	pg_get_indexdef(x.indexrelid) AS definition,
+	i.oid, COALESCE(con.oid, 0) AS constraint_oid, COALESCE(con.conname, '') AS constraint_name
+FROM pg_index x
+	JOIN pg_class i ON i.oid = x.indexrelid
+	JOIN pg_class c ON c.oid = x.indrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_am am ON am.oid = i.relam
+	LEFT JOIN pg_constraint con ON con.conindid = x.indexrelid AND con.conrelid = x.indrelid
+		AND con.contype IN ('p', 'u', 'x')
+WHERE `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select indexes", err)
+	}
+	for _, v := range rows {
+		t := byTable[v.TableSchema+"."+v.TableName]
+		if t == nil {
+			continue
+		}
+		idx := Index{
+			Name:       v.Name,
+			Method:     v.Method,
+			Columns:    v.Columns,
+			Unique:     v.Unique,
+			Primary:    v.Primary,
+			Predicate:  v.Predicate,
+			Definition: v.Definition,
+			ID:         ObjectID{Class: "pg_class", OID: v.OID, Schema: v.TableSchema, Name: v.Name},
+		}
+		if v.ConOID != 0 {
+			idx.Constraint = &ObjectID{Class: "pg_constraint", OID: v.ConOID, Schema: v.TableSchema, Name: v.ConName}
+		}
+		t.Indexes = append(t.Indexes, idx)
+	}
+	return nil
+}
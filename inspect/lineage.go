@@ -0,0 +1,58 @@
+package inspect
+
+// ColumnReader is a view reading a column, directly or through other views.
+type ColumnReader struct {
+	View string `json:"view"` // qualified name
+	// Column is the column of the view carrying the value further, when the
+	// view exposes a column of the same name. Lineage stops at views
+	// without one, since renamed or computed columns cannot be traced from
+	// the catalog.
+	Column string `json:"column,omitempty"`
+	Depth  int    `json:"depth"` // 1 for views reading the column directly
+}
+
+// ColumnReaders returns the views reading column of schema.table, breadth
+// first.
+func (d *Database) ColumnReaders(schema, table, column string) []ColumnReader {
+	type node struct{ schema, table, column string }
+	var readers []ColumnReader
+	seen := map[node]bool{{schema, table, column}: true}
+	queue := []node{{schema, table, column}}
+	for depth := 1; len(queue) > 0; depth++ {
+		var next []node
+		for _, n := range queue {
+			for _, s := range d.Schemas {
+				for _, v := range s.Tables {
+					if !v.reads(n.schema, n.table, n.column) {
+						continue
+					}
+					r := ColumnReader{View: v.Schema + "." + v.Name, Depth: depth}
+					if v.Column(n.column) != nil {
+						r.Column = n.column
+						if c := (node{v.Schema, v.Name, n.column}); !seen[c] {
+							seen[c] = true
+							next = append(next, c)
+						}
+					}
+					readers = append(readers, r)
+				}
+			}
+		}
+		queue = next
+	}
+	return readers
+}
+
+func (t *Table) reads(schema, table, column string) bool {
+	for _, src := range t.Sources {
+		if src.Schema != schema || src.Table != table {
+			continue
+		}
+		for _, c := range src.Columns {
+			if c == column {
+				return true
+			}
+		}
+	}
+	return false
+}
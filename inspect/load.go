@@ -0,0 +1,304 @@
+package inspect
+
+import (
+	"time"
+
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// schemaFilter returns a condition on column restricting rows to the given
+// schemas, or to all non-system schemas when the list is empty.
+func schemaFilter(column string, schemas []string) (string, []interface{}) {
+	if len(schemas) == 0 {
+		return column + " NOT IN ('information_schema') AND " + column + " NOT LIKE 'pg\\_%'", nil
+	}
+	return column + " IN ?", []interface{}{schemas}
+}
+
+// Load reads schemas, tables and columns of the database sess is connected
+// to. Only the listed schemas are read, all user schemas if none are given.
+func Load(sess *dbr.Session, schemas []string) (*Database, error) {
+	db, err := loadSchemas(sess, schemas)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadRoles(sess, db); err != nil {
+		return nil, err
+	}
+	if err := loadDefaultPrivileges(sess, schemas, db); err != nil {
+		return nil, err
+	}
+	if err := loadMigrations(sess, db); err != nil {
+		return nil, err
+	}
+	db.Sort()
+	db.LinkReferences()
+	return db, nil
+}
+
+// loadSchemas reads the listed schemas and their objects, leaving out the
+// database-wide roles, default privileges and migrations. The result is
+// neither sorted nor linked.
+func loadSchemas(sess *dbr.Session, schemas []string) (*Database, error) {
+	db := &Database{InspectedAt: time.Now().UTC()}
+	if err := sess.Select("*").From("information_schema.information_schema_catalog_name").LoadOne(&db.Name); err != nil {
+		return nil, queryError("load database name", err)
+	}
+
+	where, args := schemaFilter("schema_name", schemas)
+	var tSchemas []TSchemata
+	if _, err := sess.SelectBySql("SELECT * FROM information_schema.schemata WHERE "+where, args...).Load(&tSchemas); err != nil {
+		return nil, queryError("select schemas", err)
+	}
+	bySchema := make(map[string]*Schema, len(tSchemas))
+	for _, v := range tSchemas {
+		s := &Schema{Name: v.SchemaName.String, Owner: v.SchemaOwner.String}
+		db.Schemas = append(db.Schemas, s)
+		bySchema[s.Name] = s
+	}
+
+	where, args = schemaFilter("table_schema", schemas)
+	var tTables []TTables
+	if _, err := sess.SelectBySql("SELECT * FROM information_schema.tables WHERE "+where, args...).Load(&tTables); err != nil {
+		return nil, queryError("select tables", err)
+	}
+	byTable := make(map[string]*Table, len(tTables))
+	for _, v := range tTables {
+		s := bySchema[v.TableSchema.String]
+		if s == nil {
+			continue
+		}
+		t := &Table{
+			Schema: v.TableSchema.String,
+			Name:   v.TableName.String,
+			Type:   v.TableType.String,
+		}
+		s.Tables = append(s.Tables, t)
+		byTable[t.Schema+"."+t.Name] = t
+	}
+
+	if err := loadMaterializedViews(sess, schemas, bySchema, byTable); err != nil {
+		return nil, err
+	}
+
+	var tColumns []TColumns
+	if _, err := sess.SelectBySql("SELECT * FROM information_schema.columns WHERE "+where, args...).Load(&tColumns); err != nil {
+		return nil, queryError("select columns", err)
+	}
+	for _, v := range tColumns {
+		t := byTable[v.TableSchema.String+"."+v.TableName.String]
+		if t == nil {
+			continue
+		}
+		c := Column{
+			Name:      v.ColumnName.String,
+			Position:  int(v.OrdinalPosition.Int64),
+			DataType:  v.DataType.String,
+			UDTName:   v.UdtName.String,
+			UDTSchema: v.UdtSchema.String,
+			MaxLength: int(v.CharacterMaximumLength.Int64),
+			Precision: int(v.NumericPrecision.Int64),
+			Scale:     int(v.NumericScale.Int64),
+			Nullable:  v.IsNullable.String == "YES",
+			Default:   v.ColumnDefault.String,
+		}
+		if v.IsIdentity.String == "YES" {
+			c.Identity = v.IdentityGeneration.String
+		}
+		if v.IsGenerated.String == "ALWAYS" {
+			c.Generated = v.GenerationExpression.String
+		}
+		t.Columns = append(t.Columns, c)
+	}
+
+	if err := loadRelations(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadColumnComments(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadEnums(sess, schemas, bySchema); err != nil {
+		return nil, err
+	}
+	if err := loadSequences(sess, schemas, bySchema); err != nil {
+		return nil, err
+	}
+	if err := loadConstraints(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadIndexes(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadViewSources(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadTriggers(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadRules(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadPolicies(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadColumnACLs(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadColumnStorage(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadSpatial(sess, schemas, byTable); err != nil {
+		return nil, err
+	}
+	if err := loadNamespaces(sess, schemas, bySchema); err != nil {
+		return nil, err
+	}
+	if err := loadFunctions(sess, schemas, bySchema); err != nil {
+		return nil, err
+	}
+	if err := loadTextSearch(sess, schemas, bySchema); err != nil {
+		return nil, err
+	}
+	if err := loadOperators(sess, schemas, bySchema); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+type namespaceRow struct {
+	Schema  string         `db:"schema"`
+	Comment string         `db:"comment"`
+	ACL     pq.StringArray `db:"acl"`
+}
+
+// loadNamespaces fills in what information_schema does not have about
+// schemas.
+func loadNamespaces(sess *dbr.Session, schemas []string, bySchema map[string]*Schema) error {
+	where, args := schemaFilter("nspname", schemas)
+	var rows []namespaceRow
+	_, err := sess.SelectBySql(`SELECT nspname AS schema,
+	COALESCE(obj_description(oid, 'pg_namespace'), '') AS comment,
+	COALESCE(nspacl, acldefault('n', nspowner))::text[] AS acl
+FROM pg_namespace
+WHERE `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select schema privileges and comments", err)
+	}
+	for _, v := range rows {
+		if s := bySchema[v.Schema]; s != nil {
+			s.Comment = v.Comment
+			s.ACL = v.ACL
+		}
+	}
+	return nil
+}
+
+type relationRow struct {
+	TableSchema  string         `db:"table_schema"`
+	TableName    string         `db:"table_name"`
+	Owner        string         `db:"owner"`
+	Comment      string         `db:"comment"`
+	Tablespace   string         `db:"tablespace"`
+	RowEstimate  int64          `db:"row_estimate"`
+	SizeBytes    int64          `db:"size_bytes"`
+	HeapBytes    int64          `db:"heap_bytes"`
+	RowWidth     int64          `db:"row_width"`
+	Toast        string         `db:"toast"`
+	ToastBytes   int64          `db:"toast_bytes"`
+	RowSecurity  bool           `db:"row_security"`
+	ForceRLS     bool           `db:"force_row_security"`
+	PartitionKey string         `db:"partition_key"`
+	PartitionOf  string         `db:"partition_of"`
+	Bound        string         `db:"partition_bound"`
+	ACL          pq.StringArray `db:"acl"`
+	OID          int64          `db:"oid"`
+}
+
+// loadRelations fills in what information_schema does not have about tables.
+func loadRelations(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []relationRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name,
+	c.oid, pg_get_userbyid(c.relowner) AS owner,
+	COALESCE(obj_description(c.oid, 'pg_class'), '') AS comment,
+	COALESCE(ts.spcname, '') AS tablespace,
+	c.reltuples::bigint AS row_estimate,
+	pg_total_relation_size(c.oid) AS size_bytes,
+	pg_relation_size(c.oid) AS heap_bytes,
+	COALESCE((SELECT sum(s.avg_width) FROM pg_stats s
+		WHERE s.schemaname = n.nspname AND s.tablename = c.relname), 0) AS row_width,
+	COALESCE(tn.nspname || '.' || tc.relname, '') AS toast,
+	COALESCE(pg_total_relation_size(tc.oid), 0) AS toast_bytes,
+	c.relrowsecurity AS row_security, c.relforcerowsecurity AS force_row_security,
+	CASE WHEN c.relkind = 'p' THEN pg_get_partkeydef(c.oid) ELSE '' END AS partition_key,
+	COALESCE((SELECT pn.nspname || '.' || p.relname
+		FROM pg_inherits i
+			JOIN pg_class p ON p.oid = i.inhparent
+			JOIN pg_namespace pn ON pn.oid = p.relnamespace
+		WHERE c.relispartition AND i.inhrelid = c.oid), '') AS partition_of,
+	CASE WHEN c.relispartition THEN pg_get_expr(c.relpartbound, c.oid) ELSE '' END AS partition_bound
+FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_tablespace ts ON ts.oid = c.reltablespace
+	LEFT JOIN pg_class tc ON tc.oid = c.reltoastrelid
+	LEFT JOIN pg_namespace tn ON tn.oid = tc.relnamespace
+WHERE c.relkind IN ('r', 'v', 'm', 'f', 'p') AND `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select relations", err)
+	}
+	for _, v := range rows {
+		t := byTable[v.TableSchema+"."+v.TableName]
+		if t == nil {
+			continue
+		}
+		t.ID = ObjectID{Class: "pg_class", OID: v.OID, Schema: v.TableSchema, Name: v.TableName}
+		t.Owner = v.Owner
+		t.Comment = v.Comment
+		t.Annotations = ParseAnnotations(v.Comment)
+		t.Tablespace = v.Tablespace
+		t.Toast = v.Toast
+		t.Stats = TableStats{RowEstimate: v.RowEstimate, SizeBytes: v.SizeBytes, RowWidth: v.RowWidth, ToastBytes: v.ToastBytes,
+			HeapBytes: v.HeapBytes}
+		t.RowSecurity = v.RowSecurity
+		t.ForceRowSecurity = v.ForceRLS
+		t.PartitionKey = v.PartitionKey
+		t.PartitionOf = v.PartitionOf
+		t.PartitionBound = v.Bound
+		t.ACL = v.ACL
+	}
+	return nil
+}
+
+type columnCommentRow struct {
+	TableSchema string `db:"table_schema"`
+	TableName   string `db:"table_name"`
+	ColumnName  string `db:"column_name"`
+	Comment     string `db:"comment"`
+}
+
+func loadColumnComments(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []columnCommentRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name,
+	a.attname AS column_name, d.description AS comment
+FROM pg_description d
+	JOIN pg_class c ON c.oid = d.objoid AND d.classoid = 'pg_class'::regclass
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = d.objsubid
+WHERE d.objsubid > 0 AND `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select column comments", err)
+	}
+	for _, v := range rows {
+		t := byTable[v.TableSchema+"."+v.TableName]
+		if t == nil {
+			continue
+		}
+		if c := t.Column(v.ColumnName); c != nil {
+			c.Comment = v.Comment
+			c.Annotations = ParseAnnotations(v.Comment)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+type matviewRow struct {
+	TableSchema string `db:"table_schema"`
+	TableName   string `db:"table_name"`
+	Populated   bool   `db:"populated"`
+}
+
+type matviewColumnRow struct {
+	TableSchema string `db:"table_schema"`
+	TableName   string `db:"table_name"`
+	Name        string `db:"name"`
+	Position    int    `db:"position"`
+	DataType    string `db:"data_type"`
+	UDTName     string `db:"udt_name"`
+	UDTSchema   string `db:"udt_schema"`
+	MaxLength   int    `db:"max_length"`
+	Precision   int    `db:"precision"`
+	Scale       int    `db:"scale"`
+	Nullable    bool   `db:"nullable"`
+}
+
+// loadMaterializedViews adds the materialized views of the schemas, which
+// information_schema leaves out, as tables of type MATERIALIZED VIEW. Their
+// columns are described the way information_schema.columns would.
+func loadMaterializedViews(sess *dbr.Session, schemas []string, bySchema map[string]*Schema, byTable map[string]*Table) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var views []matviewRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name, c.relispopulated AS populated
+FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind = 'm' AND `+where, args...).Load(&views)
+	if err != nil {
+		return queryError("select materialized views", err)
+	}
+	for _, v := range views {
+		s := bySchema[v.TableSchema]
+		if s == nil {
+			continue
+		}
+		t := &Table{Schema: v.TableSchema, Name: v.TableName, Type: "MATERIALIZED VIEW", Unpopulated: !v.Populated}
+		s.Tables = append(s.Tables, t)
+		byTable[t.Schema+"."+t.Name] = t
+	}
+	if len(views) == 0 {
+		return nil
+	}
+
+	var columns []matviewColumnRow
+	_, err = sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name,
+	a.attname AS name, a.attnum AS position,
+	CASE WHEN t.typcategory = 'A' THEN 'ARRAY'
+		WHEN t.typtype IN ('c', 'e', 'r') OR tn.nspname <> 'pg_catalog' THEN 'USER-DEFINED'
+		ELSE format_type(a.atttypid, NULL) END AS data_type,
+	t.typname AS udt_name, tn.nspname AS udt_schema,
+	COALESCE(information_schema._pg_char_max_length(a.atttypid, a.atttypmod), 0) AS max_length,
+	COALESCE(information_schema._pg_numeric_precision(a.atttypid, a.atttypmod), 0) AS precision,
+	COALESCE(information_schema._pg_numeric_scale(a.atttypid, a.atttypmod), 0) AS scale,
+	NOT a.attnotnull AS nullable
+FROM pg_attribute a
+	JOIN pg_class c ON c.oid = a.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_type t ON t.oid = a.atttypid
+	JOIN pg_namespace tn ON tn.oid = t.typnamespace
+WHERE c.relkind = 'm' AND a.attnum > 0 AND NOT a.attisdropped AND `+where, args...).Load(&columns)
+	if err != nil {
+		return queryError("select materialized view columns", err)
+	}
+	for _, v := range columns {
+		if t := byTable[v.TableSchema+"."+v.TableName]; t != nil {
+			t.Columns = append(t.Columns, Column{
+				Name:      v.Name,
+				Position:  v.Position,
+				DataType:  v.DataType,
+				UDTName:   v.UDTName,
+				UDTSchema: v.UDTSchema,
+				MaxLength: v.MaxLength,
+				Precision: v.Precision,
+				Scale:     v.Scale,
+				Nullable:  v.Nullable,
+			})
+		}
+	}
+	return nil
+}
+
+// CanRefreshConcurrently reports whether the materialized view t has a
+// unique index on plain columns without a predicate, which REFRESH
+// MATERIALIZED VIEW CONCURRENTLY requires.
+func (t *Table) CanRefreshConcurrently() bool {
+	for _, idx := range t.Indexes {
+		if !idx.Unique || idx.Predicate != "" {
+			continue
+		}
+		plain := true
+		for _, c := range idx.Columns {
+			if t.Column(c) == nil {
+				plain = false
+			}
+		}
+		if plain {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package inspect
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gocraft/dbr"
+)
+
+// Migrations are the versions applied by a migration tool, read from its
+// bookkeeping table.
+type Migrations struct {
+	Tool    string   `json:"tool"`            // golang-migrate, goose, flyway, django or rails
+	Table   string   `json:"table"`           // qualified name of the bookkeeping table
+	Applied []string `json:"applied"`         // the current version only for golang-migrate
+	Dirty   bool     `json:"dirty,omitempty"` // golang-migrate failed half way
+}
+
+// migrationTool recognizes the bookkeeping table of a tool and reads it.
+type migrationTool struct {
+	name    string
+	table   string
+	columns []string
+	load    func(sess *dbr.Session, table string) (*Migrations, error)
+}
+
+// migrationTools are checked in order; the first match wins for a table so
+// golang-migrate, which has a dirty column, is tried before rails.
+var migrationTools = []migrationTool{
+	{"golang-migrate", "schema_migrations", []string{"version", "dirty"}, loadGolangMigrate},
+	{"rails", "schema_migrations", []string{"version"}, loadVersions("SELECT version FROM %s ORDER BY version")},
+	{"goose", "goose_db_version", []string{"version_id", "is_applied"}, loadGoose},
+	{"flyway", "flyway_schema_history", []string{"version", "success"},
+		loadVersions("SELECT version FROM %s WHERE success AND version IS NOT NULL ORDER BY installed_rank")},
+	{"django", "django_migrations", []string{"app", "name"},
+		loadVersions("SELECT app || '.' || name FROM %s ORDER BY id")},
+}
+
+func (m migrationTool) matches(t *Table) bool {
+	if t.Name != m.table || t.Type != "BASE TABLE" {
+		return false
+	}
+	for _, c := range m.columns {
+		if t.Column(c) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// loadMigrations reads the bookkeeping tables of known migration tools
+// among the inspected tables.
+func loadMigrations(sess *dbr.Session, db *Database) error {
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			for _, tool := range migrationTools {
+				if !tool.matches(t) {
+					continue
+				}
+				m, err := tool.load(sess, QualifiedName(t.Schema, t.Name))
+				if err != nil {
+					return queryError(fmt.Sprintf("load %s migrations from %s.%s", tool.name, t.Schema, t.Name), err)
+				}
+				m.Tool = tool.name
+				m.Table = t.Schema + "." + t.Name
+				db.Migrations = append(db.Migrations, *m)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func loadVersions(query string) func(sess *dbr.Session, table string) (*Migrations, error) {
+	return func(sess *dbr.Session, table string) (*Migrations, error) {
+		m := &Migrations{}
+		_, err := sess.SelectBySql(fmt.Sprintf(query, table)).Load(&m.Applied)
+		return m, err
+	}
+}
+
+// loadGolangMigrate reads the single row golang-migrate keeps, which holds
+// only the current version. Applied is that version alone; diff compares
+// golang-migrate versions by number instead of as sets.
+func loadGolangMigrate(sess *dbr.Session, table string) (*Migrations, error) {
+	var rows []struct {
+		Version int64 `db:"version"`
+		Dirty   bool  `db:"dirty"`
+	}
+	if _, err := sess.SelectBySql("SELECT version, dirty FROM " + table).Load(&rows); err != nil {
+		return nil, err
+	}
+	m := &Migrations{}
+	for _, r := range rows {
+		m.Applied = append(m.Applied, fmt.Sprint(r.Version))
+		m.Dirty = m.Dirty || r.Dirty
+	}
+	return m, nil
+}
+
+// loadGoose replays the goose log, in which rolled back versions are
+// recorded with is_applied = false.
+func loadGoose(sess *dbr.Session, table string) (*Migrations, error) {
+	var rows []struct {
+		VersionID int64 `db:"version_id"`
+		IsApplied bool  `db:"is_applied"`
+	}
+	if _, err := sess.SelectBySql("SELECT version_id, is_applied FROM " + table + " ORDER BY id").Load(&rows); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]bool)
+	for _, r := range rows {
+		if r.VersionID == 0 {
+			continue
+		}
+		if r.IsApplied {
+			applied[r.VersionID] = true
+		} else {
+			delete(applied, r.VersionID)
+		}
+	}
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	m := &Migrations{}
+	for _, v := range versions {
+		m.Applied = append(m.Applied, fmt.Sprint(v))
+	}
+	return m, nil
+}
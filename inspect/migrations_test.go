@@ -0,0 +1,39 @@
+package inspect
+
+import "testing"
+
+func TestMigrationToolMatches(t *testing.T) {
+	table := func(name, typ string, columns ...string) *Table {
+		t := &Table{Schema: "public", Name: name, Type: typ}
+		for i, c := range columns {
+			t.Columns = append(t.Columns, Column{Name: c, Position: i + 1})
+		}
+		return t
+	}
+	tests := []struct {
+		name  string
+		table *Table
+		want  string
+	}{
+		{"golang-migrate", table("schema_migrations", "BASE TABLE", "version", "dirty"), "golang-migrate"},
+		{"rails", table("schema_migrations", "BASE TABLE", "version"), "rails"},
+		{"goose", table("goose_db_version", "BASE TABLE", "id", "version_id", "is_applied", "tstamp"), "goose"},
+		{"flyway", table("flyway_schema_history", "BASE TABLE", "installed_rank", "version", "script", "success"), "flyway"},
+		{"django", table("django_migrations", "BASE TABLE", "id", "app", "name", "applied"), "django"},
+		{"view", table("schema_migrations", "VIEW", "version", "dirty"), ""},
+		{"other columns", table("goose_db_version", "BASE TABLE", "version"), ""},
+		{"other table", table("migrations", "BASE TABLE", "version"), ""},
+	}
+	for _, tt := range tests {
+		var got string
+		for _, tool := range migrationTools {
+			if tool.matches(tt.table) {
+				got = tool.name
+				break
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: recognized as %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
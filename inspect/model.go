@@ -0,0 +1,293 @@
+// Package inspect reads the structure of a PostgreSQL database into a simple
+// model that the rest of the tool renders, serves and compares.
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Database is the inspected structure of a single database.
+type Database struct {
+	Name        string       `json:"name"`
+	InspectedAt time.Time    `json:"inspected_at"` // when Load read the database
+	Schemas     []*Schema    `json:"schemas"`
+	Migrations  []Migrations `json:"migrations,omitempty"`
+	// Roles are the roles of the cluster, which own and are granted
+	// privileges on the objects of the database.
+	Roles []Role `json:"roles,omitempty"`
+	// DefaultPrivileges are granted on objects created in the future.
+	DefaultPrivileges []DefaultPrivilege `json:"default_privileges,omitempty"`
+}
+
+// Copy returns a deep copy of the database.
+func (d *Database) Copy() *Database {
+	b, err := json.Marshal(d)
+	if err != nil {
+		panic(err)
+	}
+	c := &Database{}
+	if err := json.Unmarshal(b, c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// ObjectCount is the number of objects of a kind, e.g. tables.
+type ObjectCount struct {
+	Kind string `json:"kind"`
+	N    int    `json:"n"`
+}
+
+// Counts returns the number of schemas, tables, columns, indexes and
+// foreign keys of the database.
+func (d *Database) Counts() []ObjectCount {
+	var tables, columns, indexes, fks int
+	for _, s := range d.Schemas {
+		tables += len(s.Tables)
+		for _, t := range s.Tables {
+			columns += len(t.Columns)
+			indexes += len(t.Indexes)
+			fks += len(t.FKs)
+		}
+	}
+	return []ObjectCount{
+		{"schemas", len(d.Schemas)},
+		{"tables", tables},
+		{"columns", columns},
+		{"indexes", indexes},
+		{"foreign_keys", fks},
+	}
+}
+
+// Schema returns the schema with the given name or nil.
+func (d *Database) Schema(name string) *Schema {
+	for _, s := range d.Schemas {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+type Schema struct {
+	Name    string   `json:"name"`
+	Owner   string   `json:"owner"`
+	Comment string   `json:"comment,omitempty"`
+	Tables  []*Table `json:"tables"`
+	Enums   []Enum   `json:"enums,omitempty"`
+
+	Sequences []Sequence `json:"sequences,omitempty"`
+	Functions []Function `json:"functions,omitempty"`
+	ACL       []string   `json:"acl,omitempty"` // aclitems, see ParseACLItem
+
+	TextSearchConfigs      []TextSearchConfig     `json:"text_search_configs,omitempty"`
+	TextSearchDictionaries []TextSearchDictionary `json:"text_search_dictionaries,omitempty"`
+	Operators              []Operator             `json:"operators,omitempty"`
+	OperatorClasses        []OperatorClass        `json:"operator_classes,omitempty"`
+	OperatorFamilies       []OperatorFamily       `json:"operator_families,omitempty"`
+	Aggregates             []Aggregate            `json:"aggregates,omitempty"`
+}
+
+// Table returns the table with the given name or nil.
+func (s *Schema) Table(name string) *Table {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+type Column struct {
+	Name       string      `json:"name"`
+	Position   int         `json:"position"`
+	DataType   string      `json:"data_type"`
+	UDTName    string      `json:"udt_name"`
+	UDTSchema  string      `json:"udt_schema"`
+	MaxLength  int         `json:"max_length,omitempty"` // of character types
+	Precision  int         `json:"precision,omitempty"`  // of numeric types
+	Scale      int         `json:"scale,omitempty"`      // of numeric types
+	Nullable   bool        `json:"nullable"`
+	Default    string      `json:"default,omitempty"`
+	Identity   string      `json:"identity,omitempty"`  // ALWAYS or BY DEFAULT for identity columns
+	Generated  string      `json:"generated,omitempty"` // expression of a generated column
+	Comment    string      `json:"comment,omitempty"`
+	ACL        []string    `json:"acl,omitempty"` // column grants, see ParseACLItem
+	ParseValue interface{} `json:"-"`
+
+	// Annotations are the @key=value words of the comment, see
+	// ParseAnnotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Storage is the TOAST strategy of a table column: plain, main,
+	// external or extended. Compression is pglz or lz4 when set on the
+	// column, empty for the server default.
+	Storage     string `json:"storage,omitempty"`
+	Compression string `json:"compression,omitempty"`
+
+	// Spatial is set on PostGIS geometry and geography columns.
+	Spatial *Spatial `json:"spatial,omitempty"`
+
+	// AllowedValues are the constants a CHECK (column IN (...))
+	// constraint limits the column to, an enum in all but type.
+	AllowedValues []string `json:"allowed_values,omitempty"`
+}
+
+type Table struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+	Type   string `json:"type"` // BASE TABLE, VIEW, MATERIALIZED VIEW, FOREIGN TABLE or LOCAL TEMPORARY
+	Owner  string `json:"owner"`
+
+	// ID is the pg_class entry of the relation.
+	ID ObjectID `json:"id"`
+
+	Comment    string     `json:"comment,omitempty"`
+	Tablespace string     `json:"tablespace,omitempty"`
+	Toast      string     `json:"toast,omitempty"` // TOAST relation, e.g. pg_toast.pg_toast_16384
+	Stats      TableStats `json:"stats"`
+
+	// Annotations are the @key=value words of the comment, e.g.
+	// @owner=payments, see ParseAnnotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// PartitionKey is the PARTITION BY clause of a partitioned table, e.g.
+	// RANGE (created_at), PartitionOf the parent of a partition and
+	// PartitionBound its FOR VALUES clause, e.g. FOR VALUES IN ('eu'), or
+	// DEFAULT.
+	PartitionKey   string `json:"partition_key,omitempty"`
+	PartitionOf    string `json:"partition_of,omitempty"`
+	PartitionBound string `json:"partition_bound,omitempty"`
+
+	// Unpopulated is set on a materialized view created WITH NO DATA and
+	// not refreshed since; it cannot be queried.
+	Unpopulated bool `json:"unpopulated,omitempty"`
+
+	Columns     []Column     `json:"columns"`
+	FKs         []ForeignKey `json:"foreign_keys,omitempty"`
+	PK          PrimaryKey   `json:"primary_key"`
+	Constraints []Constraint `json:"constraints,omitempty"` // unique and check
+	Indexes     []Index      `json:"indexes,omitempty"`
+	Triggers    []Trigger    `json:"triggers,omitempty"`
+	Rules       []Rule       `json:"rules,omitempty"`
+
+	// ReferencedBy are the foreign keys of other tables pointing at this
+	// one, see Database.LinkReferences.
+	ReferencedBy []Reference `json:"referenced_by,omitempty"`
+	// Sources are the relations a view reads.
+	Sources []ViewSource `json:"sources,omitempty"`
+
+	RowSecurity      bool     `json:"row_security,omitempty"`
+	ForceRowSecurity bool     `json:"force_row_security,omitempty"`
+	Policies         []Policy `json:"policies,omitempty"`
+
+	ACL []string `json:"acl,omitempty"` // aclitems, see ParseACLItem
+}
+
+// TypeName is the type of the column with its length or precision, e.g.
+// varchar(20) or numeric(10,2).
+func (c *Column) TypeName() string {
+	switch {
+	case c.MaxLength > 0:
+		return fmt.Sprintf("%s(%d)", c.UDTName, c.MaxLength)
+	case c.UDTName == "numeric" && c.Precision > 0:
+		return fmt.Sprintf("%s(%d,%d)", c.UDTName, c.Precision, c.Scale)
+	case c.Spatial != nil && c.Spatial.Modifier() != "":
+		return fmt.Sprintf("%s(%s)", c.UDTName, c.Spatial.Modifier())
+	}
+	return c.UDTName
+}
+
+// Column returns the column with the given name or nil.
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// TableStats are the planner estimates and on-disk size of a table. They
+// change with the data, not the structure.
+type TableStats struct {
+	RowEstimate int64 `json:"row_estimate"`
+	SizeBytes   int64 `json:"size_bytes"`          // including indexes and TOAST
+	RowWidth    int64 `json:"row_width,omitempty"` // average bytes of a row per pg_stats, 0 if not analyzed
+	ToastBytes  int64 `json:"toast_bytes,omitempty"`
+	HeapBytes   int64 `json:"heap_bytes,omitempty"` // the table's own pages, 0 if it never held rows or was truncated
+}
+
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefSchema  string   `json:"ref_schema"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+	OnUpdate   string   `json:"on_update"` // NO ACTION, RESTRICT, CASCADE, SET NULL or SET DEFAULT
+	OnDelete   string   `json:"on_delete"`
+	// Definition is as pg_get_constraintdef prints it, with DEFERRABLE,
+	// MATCH FULL and NOT VALID; empty in snapshots of old versions.
+	Definition string `json:"definition,omitempty"`
+
+	// Cardinality is OneToOne, OneToMany or ManyToMany, see
+	// Database.LinkReferences.
+	Cardinality string `json:"cardinality,omitempty"`
+
+	// ID is the constraint, RefID the referenced table and RefIndex the
+	// unique index of the referenced table the key relies on.
+	ID       ObjectID  `json:"id"`
+	RefID    ObjectID  `json:"ref_id"`
+	RefIndex *ObjectID `json:"ref_index,omitempty"`
+}
+
+// PrimaryKey has no columns if the table has no primary key.
+type PrimaryKey struct {
+	Name    string   `json:"name,omitempty"`
+	Columns []string `json:"columns,omitempty"`
+
+	ID    *ObjectID `json:"id,omitempty"`
+	Index *ObjectID `json:"index,omitempty"` // the index enforcing the key
+}
+
+type Constraint struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"` // UNIQUE, CHECK or EXCLUDE
+	Columns    []string `json:"columns"`
+	Definition string   `json:"definition"`
+
+	ID    ObjectID  `json:"id"`
+	Index *ObjectID `json:"index,omitempty"` // the index enforcing a unique or exclusion constraint
+}
+
+// IsUnique reports whether the primary key or a unique constraint of the
+// table consists of exactly the given columns.
+func (t *Table) IsUnique(columns ...string) bool {
+	if sameColumns(t.PK.Columns, columns) {
+		return true
+	}
+	for _, c := range t.Constraints {
+		if c.Type == "UNIQUE" && sameColumns(c.Columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	in := make(map[string]bool, len(a))
+	for _, c := range a {
+		in[c] = true
+	}
+	for _, c := range b {
+		if !in[c] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package inspect
+
+import (
+	"regexp"
+	"strings"
+)
+
+var plainIdent = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// reservedWords are the PostgreSQL keywords which cannot be used as table
+// or column names without quoting: the reserved ones and those which can
+// only name functions or types.
+var reservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true, "as": true,
+	"asc": true, "both": true, "case": true, "cast": true, "check": true, "collate": true, "column": true,
+	"constraint": true, "create": true, "current_date": true, "current_role": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true, "deferrable": true, "desc": true,
+	"distinct": true, "do": true, "else": true, "end": true, "except": true, "false": true, "fetch": true,
+	"for": true, "foreign": true, "from": true, "grant": true, "group": true, "having": true, "in": true,
+	"initially": true, "intersect": true, "into": true, "lateral": true, "leading": true, "limit": true,
+	"localtime": true, "localtimestamp": true, "not": true, "null": true, "offset": true, "on": true,
+	"only": true, "or": true, "order": true, "placing": true, "primary": true, "references": true,
+	"returning": true, "select": true, "session_user": true, "some": true, "symmetric": true, "table": true,
+	"then": true, "to": true, "trailing": true, "true": true, "union": true, "unique": true, "user": true,
+	"using": true, "variadic": true, "when": true, "where": true, "window": true, "with": true,
+
+	"authorization": true, "binary": true, "collation": true, "concurrently": true, "cross": true,
+	"current_schema": true, "freeze": true, "full": true, "ilike": true, "inner": true, "is": true,
+	"isnull": true, "join": true, "left": true, "like": true, "natural": true, "notnull": true,
+	"outer": true, "overlaps": true, "right": true, "similar": true, "tablesample": true, "verbose": true,
+}
+
+// IsReserved reports whether name is a keyword which needs quoting as a
+// table or column name.
+func IsReserved(name string) bool {
+	return reservedWords[name]
+}
+
+// NeedsQuoting reports whether name has to be written in double quotes in
+// SQL: it is reserved, has upper case letters or other characters than
+// lower case letters, digits and underscores.
+func NeedsQuoting(name string) bool {
+	return !plainIdent.MatchString(name) || reservedWords[name]
+}
+
+// QuoteIdent returns name as an SQL identifier, in double quotes if it
+// needs them.
+func QuoteIdent(name string) string {
+	if !NeedsQuoting(name) {
+		return name
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// QualifiedName returns the SQL name of the object name in schema, e.g.
+// public."Users".
+func QualifiedName(schema, name string) string {
+	return QuoteIdent(schema) + "." + QuoteIdent(name)
+}
@@ -0,0 +1,45 @@
+package inspect
+
+import "testing"
+
+func TestQuoteIdent(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"users", "users"},
+		{"_tmp2", "_tmp2"},
+		{"created_at", "created_at"},
+		{"Users", `"Users"`},
+		{"order", `"order"`},
+		{"user", `"user"`},
+		{"authorization", `"authorization"`},
+		{"name", "name"}, // unreserved keyword
+		{"2fa", `"2fa"`},
+		{"first name", `"first name"`},
+		{"e-mail", `"e-mail"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{"café", `"café"`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := QuoteIdent(tt.name); got != tt.want {
+			t.Errorf("QuoteIdent(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	tests := []struct {
+		schema, name, want string
+	}{
+		{"public", "users", "public.users"},
+		{"public", "Users", `public."Users"`},
+		{"Sales", "order", `"Sales"."order"`},
+		{"my.schema", "t", `"my.schema".t`},
+	}
+	for _, tt := range tests {
+		if got := QualifiedName(tt.schema, tt.name); got != tt.want {
+			t.Errorf("QualifiedName(%q, %q) = %s, want %s", tt.schema, tt.name, got, tt.want)
+		}
+	}
+}
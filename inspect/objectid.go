@@ -0,0 +1,132 @@
+package inspect
+
+import (
+	"fmt"
+
+	"github.com/gocraft/dbr"
+)
+
+// ObjectID identifies a catalog object by the catalog it is stored in and
+// its OID there, along with its unquoted schema and name. Objects are
+// matched by OID when they have one, so names needing quotes, in mixed
+// case or with dots, cannot be mistaken for others. OIDs are specific to
+// a database: IDs of snapshots of other databases only match by name.
+type ObjectID struct {
+	Class  string `json:"class"` // pg_class, pg_constraint, pg_type, ...
+	OID    int64  `json:"oid"`
+	Schema string `json:"schema,omitempty"`
+	Name   string `json:"name"`
+}
+
+// Valid reports whether the ID has an OID.
+func (id ObjectID) Valid() bool {
+	return id.OID != 0
+}
+
+// Is reports whether id and other identify the same object: by OID if
+// both have one, otherwise by class, schema and name.
+func (id ObjectID) Is(other ObjectID) bool {
+	if id.Class != other.Class {
+		return false
+	}
+	if id.Valid() && other.Valid() {
+		return id.OID == other.OID
+	}
+	return id.Schema == other.Schema && id.Name == other.Name
+}
+
+// Object returns the node of id in a DependencyGraph.
+func (id ObjectID) Object() Object {
+	return Object{Class: id.Class, OID: id.OID}
+}
+
+// String returns the name of the object as written in SQL, quoted where
+// needed and qualified by its schema if it has one.
+func (id ObjectID) String() string {
+	if id.Schema == "" {
+		return QuoteIdent(id.Name)
+	}
+	return QualifiedName(id.Schema, id.Name)
+}
+
+// TableByID returns the table or view with the given ID or nil.
+func (d *Database) TableByID(id ObjectID) *Table {
+	for _, s := range d.Schemas {
+		for _, t := range s.Tables {
+			if t.ID.Is(id) || !t.ID.Valid() && id.Schema == t.Schema && id.Name == t.Name {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// IndexByID returns the index of t with the given ID or nil.
+func (t *Table) IndexByID(id ObjectID) *Index {
+	for i := range t.Indexes {
+		if t.Indexes[i].ID.Is(id) {
+			return &t.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// objectNames reads the schema and name of an object by OID, per catalog.
+// Constraints are named without their table.
+var objectNames = map[string]string{
+	"pg_class": `SELECT n.nspname AS schema, c.relname AS name
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.oid = ?`,
+	"pg_type": `SELECT n.nspname AS schema, t.typname AS name
+FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace WHERE t.oid = ?`,
+	"pg_proc": `SELECT n.nspname AS schema, p.proname AS name
+FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace WHERE p.oid = ?`,
+	"pg_constraint": `SELECT n.nspname AS schema, c.conname AS name
+FROM pg_constraint c JOIN pg_namespace n ON n.oid = c.connamespace WHERE c.oid = ?`,
+	"pg_namespace": `SELECT '' AS schema, nspname AS name FROM pg_namespace WHERE oid = ?`,
+}
+
+// objectOIDs finds an object by schema and name, per catalog.
+var objectOIDs = map[string]string{
+	"pg_class": `SELECT c.oid FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = ? AND c.relname = ?`,
+	"pg_type": `SELECT t.oid FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = ? AND t.typname = ?`,
+	"pg_namespace": `SELECT oid FROM pg_namespace WHERE ? = '' AND nspname = ?`,
+}
+
+// ResolveName returns the ID of the object with the given OID in class,
+// one of pg_class, pg_type, pg_proc, pg_constraint or pg_namespace.
+func ResolveName(sess *dbr.Session, class string, oid int64) (ObjectID, error) {
+	query, ok := objectNames[class]
+	if !ok {
+		return ObjectID{}, fmt.Errorf("cannot resolve objects of %s", class)
+	}
+	id := ObjectID{Class: class, OID: oid}
+	err := sess.SelectBySql(query, oid).LoadOne(&id)
+	if err == dbr.ErrNotFound {
+		return ObjectID{}, fmt.Errorf("%s object %d does not exist", class, oid)
+	}
+	if err != nil {
+		return ObjectID{}, queryError("select object name", err)
+	}
+	return id, nil
+}
+
+// ResolveOID returns the ID of the object of class, one of pg_class,
+// pg_type or pg_namespace, with the given unquoted schema and name. The
+// schema of a namespace is empty.
+func ResolveOID(sess *dbr.Session, class, schema, name string) (ObjectID, error) {
+	query, ok := objectOIDs[class]
+	if !ok {
+		return ObjectID{}, fmt.Errorf("cannot resolve objects of %s by name", class)
+	}
+	id := ObjectID{Class: class, Schema: schema, Name: name}
+	err := sess.SelectBySql(query, schema, name).LoadOne(&id.OID)
+	if err == dbr.ErrNotFound {
+		return ObjectID{}, fmt.Errorf("%s object %s does not exist", class, id)
+	}
+	if err != nil {
+		return ObjectID{}, queryError("select object oid", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,215 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// Operator is a user defined operator. Operators belonging to extensions
+// are left out, as with functions.
+type Operator struct {
+	Name       string `json:"name" db:"name"`
+	Left       string `json:"left,omitempty" db:"left"` // operand types, empty for a prefix operator
+	Right      string `json:"right" db:"right"`
+	Result     string `json:"result" db:"result"`
+	Function   string `json:"function" db:"function"`
+	Commutator string `json:"commutator,omitempty" db:"commutator"`
+	Negator    string `json:"negator,omitempty" db:"negator"`
+	Restrict   string `json:"restrict,omitempty" db:"restrict"` // selectivity estimators
+	Join       string `json:"join,omitempty" db:"join"`
+	Hashes     bool   `json:"hashes,omitempty" db:"hashes"`
+	Merges     bool   `json:"merges,omitempty" db:"merges"`
+	Owner      string `json:"owner" db:"owner"`
+	Comment    string `json:"comment,omitempty" db:"comment"`
+}
+
+// Signature is the name and operand types, e.g. <(semver, semver) or
+// -(NONE, money) for a prefix operator.
+func (o *Operator) Signature() string {
+	left := o.Left
+	if left == "" {
+		left = "NONE"
+	}
+	return o.Name + "(" + left + ", " + o.Right + ")"
+}
+
+// OperatorClass tells an index method how to use the operators of a type.
+type OperatorClass struct {
+	Name      string   `json:"name"`
+	Method    string   `json:"method"` // btree, hash, gist, ...
+	Type      string   `json:"type"`
+	Family    string   `json:"family"` // qualified name of the operator family
+	Default   bool     `json:"default,omitempty"`
+	Storage   string   `json:"storage,omitempty"`   // type stored in the index if not Type
+	Operators []string `json:"operators,omitempty"` // strategy number and operator, e.g. 1 <(semver,semver)
+	Functions []string `json:"functions,omitempty"` // support number and function
+	Owner     string   `json:"owner"`
+}
+
+// OperatorFamily groups operator classes of compatible types.
+type OperatorFamily struct {
+	Name   string `json:"name" db:"name"`
+	Method string `json:"method" db:"method"`
+	Owner  string `json:"owner" db:"owner"`
+}
+
+// Aggregate is a user defined aggregate function.
+type Aggregate struct {
+	Name            string `json:"name" db:"name"`
+	Arguments       string `json:"arguments" db:"arguments"`
+	Result          string `json:"result" db:"result"`
+	Kind            string `json:"kind" db:"kind"` // normal, ordered-set or hypothetical
+	StateFunction   string `json:"state_function" db:"state_function"`
+	StateType       string `json:"state_type" db:"state_type"`
+	FinalFunction   string `json:"final_function,omitempty" db:"final_function"`
+	CombineFunction string `json:"combine_function,omitempty" db:"combine_function"`
+	InitialValue    string `json:"initial_value,omitempty" db:"initial_value"`
+	SortOperator    string `json:"sort_operator,omitempty" db:"sort_operator"`
+	Owner           string `json:"owner" db:"owner"`
+	Comment         string `json:"comment,omitempty" db:"comment"`
+}
+
+// Signature is the name and identity arguments, e.g. semver_max(semver).
+func (a *Aggregate) Signature() string {
+	return a.Name + "(" + a.Arguments + ")"
+}
+
+type operatorRow struct {
+	Schema string `db:"schema"`
+	Operator
+}
+
+type operatorClassRow struct {
+	Schema    string         `db:"schema"`
+	Name      string         `db:"name"`
+	Method    string         `db:"method"`
+	Type      string         `db:"type"`
+	Family    string         `db:"family"`
+	Default   bool           `db:"is_default"`
+	Storage   string         `db:"storage"`
+	Operators pq.StringArray `db:"operators"`
+	Functions pq.StringArray `db:"functions"`
+	Owner     string         `db:"owner"`
+}
+
+type operatorFamilyRow struct {
+	Schema string `db:"schema"`
+	OperatorFamily
+}
+
+type aggregateRow struct {
+	Schema string `db:"schema"`
+	Aggregate
+}
+
+// notInExtension filters out catalog rows of class that belong to an
+// extension, the row's oid is alias.oid.
+func notInExtension(class, alias string) string {
+	return `NOT EXISTS (SELECT 1 FROM pg_depend d
+		WHERE d.classid = '` + class + `'::regclass AND d.objid = ` + alias + `.oid AND d.deptype = 'e')`
+}
+
+// loadOperators reads the user defined operators, operator classes and
+// families and aggregates of the schemas.
+func loadOperators(sess *dbr.Session, schemas []string, bySchema map[string]*Schema) error {
+	where, args := schemaFilter("n.nspname", schemas)
+
+	var operators []operatorRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS schema, o.oprname AS name,
+	COALESCE(format_type(NULLIF(o.oprleft, 0), NULL), '') AS left,
+	format_type(o.oprright, NULL) AS right, format_type(o.oprresult, NULL) AS result,
+	o.oprcode::text AS function,
+	COALESCE(NULLIF(o.oprcom, 0)::regoperator::text, '') AS commutator,
+	COALESCE(NULLIF(o.oprnegate, 0)::regoperator::text, '') AS negator,
+	COALESCE(NULLIF(o.oprrest::oid, 0)::regproc::text, '') AS restrict,
+	COALESCE(NULLIF(o.oprjoin::oid, 0)::regproc::text, '') AS join,
+	o.oprcanhash AS hashes, o.oprcanmerge AS merges, pg_get_userbyid(o.oprowner) AS owner,
+	COALESCE(obj_description(o.oid, 'pg_operator'), '') AS comment
+FROM pg_operator o
+	JOIN pg_namespace n ON n.oid = o.oprnamespace
+WHERE `+notInExtension("pg_operator", "o")+` AND `+where, args...).Load(&operators)
+	if err != nil {
+		return queryError("select operators", err)
+	}
+	for _, v := range operators {
+		if s := bySchema[v.Schema]; s != nil {
+			s.Operators = append(s.Operators, v.Operator)
+		}
+	}
+
+	var classes []operatorClassRow
+	_, err = sess.SelectBySql(`SELECT n.nspname AS schema, c.opcname AS name, am.amname AS method,
+	format_type(c.opcintype, NULL) AS type, fn.nspname || '.' || f.opfname AS family, c.opcdefault AS is_default,
+	COALESCE(format_type(NULLIF(c.opckeytype, 0), NULL), '') AS storage,
+	ARRAY(SELECT o.amopstrategy || ' ' || o.amopopr::regoperator::text FROM pg_amop o
+		WHERE o.amopfamily = c.opcfamily AND o.amoplefttype = c.opcintype
+		ORDER BY o.amopstrategy, o.amoprighttype)::text[] AS operators,
+	ARRAY(SELECT p.amprocnum || ' ' || p.amproc::regprocedure::text FROM pg_amproc p
+		WHERE p.amprocfamily = c.opcfamily AND p.amproclefttype = c.opcintype
+		ORDER BY p.amprocnum, p.amprocrighttype)::text[] AS functions,
+	pg_get_userbyid(c.opcowner) AS owner
+FROM pg_opclass c
+	JOIN pg_namespace n ON n.oid = c.opcnamespace
+	JOIN pg_am am ON am.oid = c.opcmethod
+	JOIN pg_opfamily f ON f.oid = c.opcfamily
+	JOIN pg_namespace fn ON fn.oid = f.opfnamespace
+WHERE `+notInExtension("pg_opclass", "c")+` AND `+where, args...).Load(&classes)
+	if err != nil {
+		return queryError("select operator classes", err)
+	}
+	for _, v := range classes {
+		if s := bySchema[v.Schema]; s != nil {
+			s.OperatorClasses = append(s.OperatorClasses, OperatorClass{
+				Name:      v.Name,
+				Method:    v.Method,
+				Type:      v.Type,
+				Family:    v.Family,
+				Default:   v.Default,
+				Storage:   v.Storage,
+				Operators: v.Operators,
+				Functions: v.Functions,
+				Owner:     v.Owner,
+			})
+		}
+	}
+
+	var families []operatorFamilyRow
+	_, err = sess.SelectBySql(`SELECT n.nspname AS schema, f.opfname AS name, am.amname AS method,
+	pg_get_userbyid(f.opfowner) AS owner
+FROM pg_opfamily f
+	JOIN pg_namespace n ON n.oid = f.opfnamespace
+	JOIN pg_am am ON am.oid = f.opfmethod
+WHERE `+notInExtension("pg_opfamily", "f")+` AND `+where, args...).Load(&families)
+	if err != nil {
+		return queryError("select operator families", err)
+	}
+	for _, v := range families {
+		if s := bySchema[v.Schema]; s != nil {
+			s.OperatorFamilies = append(s.OperatorFamilies, v.OperatorFamily)
+		}
+	}
+
+	var aggregates []aggregateRow
+	_, err = sess.SelectBySql(`SELECT n.nspname AS schema, p.proname AS name,
+	pg_get_function_identity_arguments(p.oid) AS arguments, pg_get_function_result(p.oid) AS result,
+	CASE a.aggkind WHEN 'o' THEN 'ordered-set' WHEN 'h' THEN 'hypothetical' ELSE 'normal' END AS kind,
+	a.aggtransfn::text AS state_function, format_type(a.aggtranstype, NULL) AS state_type,
+	COALESCE(NULLIF(a.aggfinalfn::oid, 0)::regproc::text, '') AS final_function,
+	COALESCE(NULLIF(a.aggcombinefn::oid, 0)::regproc::text, '') AS combine_function,
+	COALESCE(a.agginitval, '') AS initial_value,
+	COALESCE(NULLIF(a.aggsortop, 0)::regoperator::text, '') AS sort_operator,
+	pg_get_userbyid(p.proowner) AS owner, COALESCE(obj_description(p.oid, 'pg_proc'), '') AS comment
+FROM pg_aggregate a
+	JOIN pg_proc p ON p.oid = a.aggfnoid
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE `+notInExtension("pg_proc", "p")+` AND `+where, args...).Load(&aggregates)
+	if err != nil {
+		return queryError("select aggregates", err)
+	}
+	for _, v := range aggregates {
+		if s := bySchema[v.Schema]; s != nil {
+			s.Aggregates = append(s.Aggregates, v.Aggregate)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package inspect
+
+// LoadOrder returns the base tables in an order in which rows can be
+// inserted without violating foreign keys: referenced tables come before
+// the tables referencing them. Reversed it is a safe order to delete rows
+// in.
+//
+// Foreign keys forming a cycle cannot be satisfied by any order. The
+// returned deferred references are those which have to be deferred, or
+// dropped and recreated, to load the tables in the given order. Self
+// references are always deferred since the order of rows in the table
+// matters for them.
+func (d *Database) LoadOrder() (tables []*Table, deferred []Reference) {
+	var remaining []*Table
+	for _, s := range d.Schemas {
+		for _, t := range s.Tables {
+			if t.Type == "BASE TABLE" {
+				remaining = append(remaining, t)
+			}
+		}
+	}
+	done := make(map[*Table]bool, len(remaining))
+	// pending returns the foreign keys of t to tables not loaded yet.
+	pending := func(t *Table) []ForeignKey {
+		var fks []ForeignKey
+		for _, fk := range t.FKs {
+			s := d.Schema(fk.RefSchema)
+			if s == nil {
+				continue
+			}
+			if r := s.Table(fk.RefTable); r != nil && r != t && !done[r] {
+				fks = append(fks, fk)
+			}
+		}
+		return fks
+	}
+
+	for len(remaining) > 0 {
+		// Load the first table which is ready, otherwise break the cycle
+		// at the table waiting for the fewest others.
+		next, fewest := -1, 0
+		for i, t := range remaining {
+			n := len(pending(t))
+			if n == 0 {
+				next = i
+				break
+			}
+			if next < 0 || n < fewest {
+				next, fewest = i, n
+			}
+		}
+		t := remaining[next]
+		for _, fk := range pending(t) {
+			deferred = append(deferred, Reference{Schema: t.Schema, Table: t.Name, FK: fk})
+		}
+		for _, fk := range t.FKs {
+			if fk.RefSchema == t.Schema && fk.RefTable == t.Name {
+				deferred = append(deferred, Reference{Schema: t.Schema, Table: t.Name, FK: fk})
+			}
+		}
+		done[t] = true
+		tables = append(tables, t)
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return tables, deferred
+}
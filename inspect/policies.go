@@ -0,0 +1,54 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// Policy is a row level security policy.
+type Policy struct {
+	Name       string   `json:"name"`
+	Permissive bool     `json:"permissive"`
+	Roles      []string `json:"roles"`
+	Command    string   `json:"command"` // ALL, SELECT, INSERT, UPDATE or DELETE
+	Using      string   `json:"using,omitempty"`
+	Check      string   `json:"check,omitempty"`
+}
+
+type policyRow struct {
+	TableSchema string         `db:"schemaname"`
+	TableName   string         `db:"tablename"`
+	Name        string         `db:"policyname"`
+	Permissive  string         `db:"permissive"`
+	Roles       pq.StringArray `db:"roles"`
+	Command     string         `db:"cmd"`
+	Using       string         `db:"qual"`
+	Check       string         `db:"with_check"`
+}
+
+func loadPolicies(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("schemaname", schemas)
+	var rows []policyRow
+	_, err := sess.SelectBySql(`SELECT schemaname, tablename, policyname, permissive,
+	roles::text[] AS roles, cmd, COALESCE(qual, '') AS qual, COALESCE(with_check, '') AS with_check
+FROM pg_policies
+WHERE `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select policies", err)
+	}
+	for _, v := range rows {
+		t := byTable[v.TableSchema+"."+v.TableName]
+		if t == nil {
+			continue
+		}
+		t.Policies = append(t.Policies, Policy{
+			Name:       v.Name,
+			Permissive: v.Permissive == "PERMISSIVE",
+			Roles:      v.Roles,
+			Command:    v.Command,
+			Using:      v.Using,
+			Check:      v.Check,
+		})
+	}
+	return nil
+}
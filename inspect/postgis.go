@@ -0,0 +1,104 @@
+package inspect
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// Spatial is the type modifier of a PostGIS geometry or geography column.
+type Spatial struct {
+	Type       string `json:"type"`       // POINT, LINESTRING, POLYGON, ..., GEOMETRY when any
+	SRID       int    `json:"srid"`       // 0 when unknown
+	Dimensions int    `json:"dimensions"` // 2 to 4
+}
+
+// Modifier is the type modifier as written in a column definition, e.g.
+// Point,4326 or PointZ,0. It is empty for unconstrained columns.
+func (s *Spatial) Modifier() string {
+	typ := strings.ToUpper(s.Type)
+	if typ == "GEOMETRY" && s.SRID == 0 && s.Dimensions <= 2 {
+		return ""
+	}
+	switch {
+	case s.Dimensions == 4 && !strings.HasSuffix(typ, "ZM"):
+		typ += "ZM"
+	case s.Dimensions == 3 && !strings.HasSuffix(typ, "M") && !strings.HasSuffix(typ, "Z"):
+		typ += "Z"
+	}
+	return fmt.Sprintf("%s,%d", typ, s.SRID)
+}
+
+type spatialRow struct {
+	TableSchema string `db:"table_schema"`
+	TableName   string `db:"table_name"`
+	ColumnName  string `db:"column_name"`
+	Type        string `db:"type"`
+	SRID        int    `db:"srid"`
+	Dimensions  int    `db:"dimensions"`
+}
+
+// spatialMethods are the index methods usable for spatial queries.
+var spatialMethods = map[string]bool{"gist": true, "spgist": true, "brin": true}
+
+// loadSpatial reads the SRID, geometry type and dimensions of geometry and
+// geography columns from the PostGIS geometry_columns and
+// geography_columns views and marks the indexes on them that serve spatial
+// queries. It does nothing when PostGIS is not installed.
+func loadSpatial(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	var postgis []string
+	if _, err := sess.SelectBySql(`SELECT n.nspname FROM pg_extension e
+	JOIN pg_namespace n ON n.oid = e.extnamespace
+WHERE e.extname = 'postgis'`).Load(&postgis); err != nil {
+		return queryError("select postgis extension", err)
+	}
+	if len(postgis) == 0 {
+		return nil
+	}
+	ext := pq.QuoteIdentifier(postgis[0])
+
+	where, args := schemaFilter("f_table_schema", schemas)
+	var rows []spatialRow
+	_, err := sess.SelectBySql(`SELECT f_table_schema AS table_schema, f_table_name AS table_name,
+	f_geometry_column AS column_name, type, srid, coord_dimension AS dimensions
+FROM `+ext+`.geometry_columns WHERE `+where+`
+UNION ALL
+SELECT f_table_schema, f_table_name, f_geography_column, type, srid, coord_dimension
+FROM `+ext+`.geography_columns WHERE `+where, append(args, args...)...).Load(&rows)
+	if err != nil {
+		return queryError("select spatial columns", err)
+	}
+	for _, v := range rows {
+		t := byTable[v.TableSchema+"."+v.TableName]
+		if t == nil {
+			continue
+		}
+		if c := t.Column(v.ColumnName); c != nil {
+			c.Spatial = &Spatial{Type: v.Type, SRID: v.SRID, Dimensions: v.Dimensions}
+		}
+	}
+
+	for _, t := range byTable {
+		for i := range t.Indexes {
+			idx := &t.Indexes[i]
+			if !spatialMethods[idx.Method] {
+				continue
+			}
+			for _, c := range t.Columns {
+				if c.Spatial == nil {
+					continue
+				}
+				word := regexp.MustCompile(`\b` + regexp.QuoteMeta(c.Name) + `\b`)
+				for _, expr := range idx.Columns {
+					if expr == c.Name || word.MatchString(expr) {
+						idx.Spatial = true
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
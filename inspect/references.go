@@ -0,0 +1,73 @@
+package inspect
+
+// Reference is a foreign key of another table pointing at a table.
+type Reference struct {
+	Schema string     `json:"schema"` // of the referencing table
+	Table  string     `json:"table"`
+	FK     ForeignKey `json:"foreign_key"`
+}
+
+// Cardinalities of the relationship a foreign key implements.
+const (
+	OneToOne   = "1:1" // the key columns are unique
+	OneToMany  = "1:N"
+	ManyToMany = "M:N" // the key is one of the two of a join table
+)
+
+// IsJoinTable reports whether t links two tables many to many: it has two
+// foreign keys whose columns together form its primary key.
+func (t *Table) IsJoinTable() bool {
+	if len(t.FKs) != 2 {
+		return false
+	}
+	columns := append(append([]string(nil), t.FKs[0].Columns...), t.FKs[1].Columns...)
+	return sameColumns(t.PK.Columns, columns)
+}
+
+// cardinality classifies fk of t by the uniqueness of its columns.
+func (t *Table) cardinality(fk ForeignKey) string {
+	switch {
+	case t.IsJoinTable():
+		return ManyToMany
+	case t.IsUnique(fk.Columns...):
+		return OneToOne
+	}
+	return OneToMany
+}
+
+// LinkReferences fills ReferencedBy of every table from the foreign keys
+// of all tables and classifies their Cardinality. Only references between
+// inspected tables are found. A foreign key whose RefID is the OID of an
+// inspected table gets RefSchema and RefTable from it, so the keys Inspector
+// keeps for unchanged schemas follow renames of the referenced table.
+func (d *Database) LinkReferences() {
+	byOID := make(map[int64]*Table)
+	byName := make(map[string]*Table)
+	for _, s := range d.Schemas {
+		for _, t := range s.Tables {
+			t.ReferencedBy = nil
+			if t.ID.Valid() {
+				byOID[t.ID.OID] = t
+			}
+			byName[t.Schema+"."+t.Name] = t
+		}
+	}
+	for _, s := range d.Schemas {
+		for _, t := range s.Tables {
+			for i := range t.FKs {
+				fk := &t.FKs[i]
+				fk.Cardinality = t.cardinality(*fk)
+				rt := byOID[fk.RefID.OID]
+				if rt != nil && fk.RefID.Valid() {
+					fk.RefSchema, fk.RefTable = rt.Schema, rt.Name
+					fk.RefID.Schema, fk.RefID.Name = rt.Schema, rt.Name
+				} else {
+					rt = byName[fk.RefSchema+"."+fk.RefTable]
+				}
+				if rt != nil {
+					rt.ReferencedBy = append(rt.ReferencedBy, Reference{Schema: t.Schema, Table: t.Name, FK: *fk})
+				}
+			}
+		}
+	}
+}
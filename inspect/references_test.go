@@ -0,0 +1,52 @@
+package inspect
+
+import "testing"
+
+func TestLinkReferencesFollowsRenames(t *testing.T) {
+	// public.customers was renamed to crm.clients; the foreign key of
+	// sales.orders was read before, as Inspector keeps it when sales did
+	// not change.
+	clients := &Table{Schema: "crm", Name: "clients", Type: "BASE TABLE",
+		ID:      ObjectID{Class: "pg_class", OID: 16400, Schema: "crm", Name: "clients"},
+		Columns: []Column{{Name: "id", UDTName: "int8", Position: 1}},
+		PK:      PrimaryKey{Name: "customers_pkey", Columns: []string{"id"}}}
+	orders := &Table{Schema: "sales", Name: "orders", Type: "BASE TABLE",
+		ID:      ObjectID{Class: "pg_class", OID: 16500, Schema: "sales", Name: "orders"},
+		Columns: []Column{{Name: "id", UDTName: "int8", Position: 1}, {Name: "customer_id", UDTName: "int8", Position: 2}},
+		PK:      PrimaryKey{Name: "orders_pkey", Columns: []string{"id"}},
+		FKs: []ForeignKey{{Name: "orders_customer_id_fkey", Columns: []string{"customer_id"},
+			RefSchema: "public", RefTable: "customers", RefColumns: []string{"id"},
+			RefID: ObjectID{Class: "pg_class", OID: 16400, Schema: "public", Name: "customers"}}},
+	}
+	db := &Database{Schemas: []*Schema{
+		{Name: "crm", Tables: []*Table{clients}},
+		{Name: "sales", Tables: []*Table{orders}},
+	}}
+	db.LinkReferences()
+
+	fk := orders.FKs[0]
+	if fk.RefSchema != "crm" || fk.RefTable != "clients" || fk.RefID.Schema != "crm" || fk.RefID.Name != "clients" {
+		t.Errorf("foreign key references %s.%s (ID %s.%s), want crm.clients",
+			fk.RefSchema, fk.RefTable, fk.RefID.Schema, fk.RefID.Name)
+	}
+	if len(clients.ReferencedBy) != 1 || clients.ReferencedBy[0].FK.RefTable != "clients" {
+		t.Errorf("crm.clients is referenced by %v, want orders_customer_id_fkey", clients.ReferencedBy)
+	}
+}
+
+func TestLinkReferencesByName(t *testing.T) {
+	// Snapshots of old versions have no OIDs.
+	users := &Table{Schema: "public", Name: "users", Type: "BASE TABLE",
+		PK: PrimaryKey{Name: "users_pkey", Columns: []string{"id"}}}
+	posts := &Table{Schema: "public", Name: "posts", Type: "BASE TABLE",
+		FKs: []ForeignKey{{Name: "posts_user_id_fkey", Columns: []string{"user_id"},
+			RefSchema: "public", RefTable: "users", RefColumns: []string{"id"}}}}
+	db := &Database{Schemas: []*Schema{{Name: "public", Tables: []*Table{posts, users}}}}
+	db.LinkReferences()
+	if len(users.ReferencedBy) != 1 || users.ReferencedBy[0].Table != "posts" {
+		t.Errorf("public.users is referenced by %v, want posts", users.ReferencedBy)
+	}
+	if posts.FKs[0].Cardinality != OneToMany {
+		t.Errorf("cardinality %q, want %q", posts.FKs[0].Cardinality, OneToMany)
+	}
+}
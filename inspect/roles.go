@@ -0,0 +1,77 @@
+package inspect
+
+import (
+	"strings"
+
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// Role is a role of the cluster the database belongs to. The predefined
+// pg_* roles are left out, though roles may be members of them.
+type Role struct {
+	Name        string   `json:"name" db:"name"`
+	Superuser   bool     `json:"superuser,omitempty" db:"superuser"`
+	Login       bool     `json:"login,omitempty" db:"login"`
+	CreateRole  bool     `json:"create_role,omitempty" db:"create_role"`
+	CreateDB    bool     `json:"create_db,omitempty" db:"create_db"`
+	Replication bool     `json:"replication,omitempty" db:"replication"`
+	BypassRLS   bool     `json:"bypass_rls,omitempty" db:"bypass_rls"`
+	MemberOf    []string `json:"member_of,omitempty" db:"-"`
+}
+
+// Role returns the role with the given name or nil.
+func (d *Database) Role(name string) *Role {
+	for i := range d.Roles {
+		if d.Roles[i].Name == name {
+			return &d.Roles[i]
+		}
+	}
+	return nil
+}
+
+// IsSuperuser reports whether the role with the given name is a superuser.
+func (d *Database) IsSuperuser(name string) bool {
+	r := d.Role(name)
+	return r != nil && r.Superuser
+}
+
+// EachOwned calls fn for every schema, relation and function of the
+// database with its kind, qualified name and owner. Kinds are schema,
+// function and the lower case table types, e.g. table or view.
+func (d *Database) EachOwned(fn func(kind, name, owner string)) {
+	for _, s := range d.Schemas {
+		fn("schema", s.Name, s.Owner)
+		for _, t := range s.Tables {
+			fn(strings.ToLower(strings.TrimPrefix(t.Type, "BASE ")), t.Schema+"."+t.Name, t.Owner)
+		}
+		for _, f := range s.Functions {
+			fn("function", s.Name+"."+f.Signature(), f.Owner)
+		}
+	}
+}
+
+type roleRow struct {
+	Role
+	MemberOf pq.StringArray `db:"member_of"`
+}
+
+func loadRoles(sess *dbr.Session, db *Database) error {
+	var rows []roleRow
+	_, err := sess.SelectBySql(`SELECT r.rolname AS name, r.rolsuper AS superuser, r.rolcanlogin AS login,
+	r.rolcreaterole AS create_role, r.rolcreatedb AS create_db, r.rolreplication AS replication,
+	r.rolbypassrls AS bypass_rls,
+	ARRAY(SELECT g.rolname FROM pg_auth_members m JOIN pg_roles g ON g.oid = m.roleid
+		WHERE m.member = r.oid ORDER BY 1)::text[] AS member_of
+FROM pg_roles r
+WHERE r.rolname NOT LIKE 'pg\_%'`).Load(&rows)
+	if err != nil {
+		return queryError("select roles", err)
+	}
+	for _, v := range rows {
+		r := v.Role
+		r.MemberOf = v.MemberOf
+		db.Roles = append(db.Roles, r)
+	}
+	return nil
+}
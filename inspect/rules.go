@@ -0,0 +1,43 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+// Rule is a rewrite rule of a table or view created with CREATE RULE. The
+// _RETURN rules implementing views are left out.
+type Rule struct {
+	Name       string `json:"name" db:"name"`
+	Event      string `json:"event" db:"event"` // SELECT, INSERT, UPDATE or DELETE
+	Instead    bool   `json:"instead,omitempty" db:"instead"`
+	Enabled    bool   `json:"enabled" db:"enabled"`
+	Definition string `json:"definition" db:"definition"`
+}
+
+type ruleRow struct {
+	TableSchema string `db:"table_schema"`
+	TableName   string `db:"table_name"`
+	Rule
+}
+
+// loadRules reads the rewrite rules of tables and views.
+func loadRules(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []ruleRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name, r.rulename AS name,
+	CASE r.ev_type WHEN '1' THEN 'SELECT' WHEN '2' THEN 'UPDATE' WHEN '3' THEN 'INSERT' ELSE 'DELETE' END AS event,
+	r.is_instead AS instead, r.ev_enabled <> 'D' AS enabled, pg_get_ruledef(r.oid, true) AS definition
+FROM pg_rewrite r
+	JOIN pg_class c ON c.oid = r.ev_class
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE r.rulename <> '_RETURN' AND `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select rules", err)
+	}
+	for _, v := range rows {
+		if t := byTable[v.TableSchema+"."+v.TableName]; t != nil {
+			t.Rules = append(t.Rules, v.Rule)
+		}
+	}
+	return nil
+}
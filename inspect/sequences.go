@@ -0,0 +1,52 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+// Sequence is a sequence and its current value, which like TableStats
+// changes with the data.
+type Sequence struct {
+	Name      string `json:"name" db:"name"`
+	DataType  string `json:"data_type" db:"data_type"` // smallint, integer or bigint
+	LastValue int64  `json:"last_value" db:"last_value"`
+	MaxValue  int64  `json:"max_value" db:"max_value"`
+	Increment int64  `json:"increment" db:"increment"`
+	// OwnedBy is the schema.table.column of the serial or identity column
+	// the sequence belongs to.
+	OwnedBy string `json:"owned_by,omitempty" db:"owned_by"`
+}
+
+type sequenceRow struct {
+	Schema string `db:"schema"`
+	Sequence
+}
+
+// loadSequences reads pg_sequences, available since PostgreSQL 10. The
+// last value of a sequence that was never used, or that the user may not
+// read, is 0.
+func loadSequences(sess *dbr.Session, schemas []string, bySchema map[string]*Schema) error {
+	where, args := schemaFilter("s.schemaname", schemas)
+	var rows []sequenceRow
+	_, err := sess.SelectBySql(`SELECT s.schemaname AS schema, s.sequencename AS name, s.data_type::text AS data_type,
+	COALESCE(s.last_value, 0) AS last_value, s.max_value, s.increment_by AS increment,
+	COALESCE(tn.nspname || '.' || t.relname || '.' || a.attname, '') AS owned_by
+FROM pg_sequences s
+	JOIN pg_namespace n ON n.nspname = s.schemaname
+	JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = s.sequencename
+	LEFT JOIN pg_depend d ON d.classid = 'pg_class'::regclass AND d.objid = c.oid
+		AND d.refclassid = 'pg_class'::regclass AND d.refobjsubid > 0 AND d.deptype IN ('a', 'i')
+	LEFT JOIN pg_class t ON t.oid = d.refobjid
+	LEFT JOIN pg_namespace tn ON tn.oid = t.relnamespace
+	LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+WHERE `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select sequences", err)
+	}
+	for _, v := range rows {
+		if s := bySchema[v.Schema]; s != nil {
+			s.Sequences = append(s.Sequences, v.Sequence)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package inspect
+
+import "sort"
+
+// Sort orders schemas, tables, enums, sequences, functions, text search
+// objects, operators, operator classes and families, aggregates,
+// constraints, indexes, triggers, rules, policies, view sources, migration tables,
+// roles and default privileges by name and columns by their position so
+// that output does not depend on the order the catalog returned rows in.
+func (d *Database) Sort() {
+	for _, s := range d.Schemas {
+		s.sort()
+	}
+	d.sortLists()
+}
+
+// sortLists orders the schemas, migration tables, roles and default
+// privileges of d, leaving the objects of the schemas as they are.
+func (d *Database) sortLists() {
+	sort.Slice(d.Schemas, func(i, j int) bool { return d.Schemas[i].Name < d.Schemas[j].Name })
+	sort.Slice(d.Migrations, func(i, j int) bool { return d.Migrations[i].Table < d.Migrations[j].Table })
+	sort.Slice(d.Roles, func(i, j int) bool { return d.Roles[i].Name < d.Roles[j].Name })
+	sort.Slice(d.DefaultPrivileges, func(i, j int) bool {
+		a, b := d.DefaultPrivileges[i], d.DefaultPrivileges[j]
+		return a.Role+"\x00"+a.Schema+"\x00"+a.ObjectType < b.Role+"\x00"+b.Schema+"\x00"+b.ObjectType
+	})
+}
+
+func (s *Schema) sort() {
+	sort.Slice(s.Tables, func(i, j int) bool { return s.Tables[i].Name < s.Tables[j].Name })
+	sort.Slice(s.Enums, func(i, j int) bool { return s.Enums[i].Name < s.Enums[j].Name })
+	sort.Slice(s.Sequences, func(i, j int) bool { return s.Sequences[i].Name < s.Sequences[j].Name })
+	sort.Slice(s.Functions, func(i, j int) bool { return s.Functions[i].Signature() < s.Functions[j].Signature() })
+	sort.Slice(s.TextSearchConfigs, func(i, j int) bool { return s.TextSearchConfigs[i].Name < s.TextSearchConfigs[j].Name })
+	sort.Slice(s.TextSearchDictionaries, func(i, j int) bool {
+		return s.TextSearchDictionaries[i].Name < s.TextSearchDictionaries[j].Name
+	})
+	sort.Slice(s.Operators, func(i, j int) bool { return s.Operators[i].Signature() < s.Operators[j].Signature() })
+	sort.Slice(s.OperatorClasses, func(i, j int) bool {
+		a, b := s.OperatorClasses[i], s.OperatorClasses[j]
+		return a.Name+"\x00"+a.Method < b.Name+"\x00"+b.Method
+	})
+	sort.Slice(s.OperatorFamilies, func(i, j int) bool {
+		a, b := s.OperatorFamilies[i], s.OperatorFamilies[j]
+		return a.Name+"\x00"+a.Method < b.Name+"\x00"+b.Method
+	})
+	sort.Slice(s.Aggregates, func(i, j int) bool { return s.Aggregates[i].Signature() < s.Aggregates[j].Signature() })
+	for _, t := range s.Tables {
+		t.sort()
+	}
+}
+
+func (t *Table) sort() {
+	sort.Slice(t.Columns, func(i, j int) bool { return t.Columns[i].Position < t.Columns[j].Position })
+	sort.Slice(t.FKs, func(i, j int) bool { return t.FKs[i].Name < t.FKs[j].Name })
+	sort.Slice(t.Constraints, func(i, j int) bool { return t.Constraints[i].Name < t.Constraints[j].Name })
+	sort.Slice(t.Indexes, func(i, j int) bool { return t.Indexes[i].Name < t.Indexes[j].Name })
+	sort.Slice(t.Triggers, func(i, j int) bool { return t.Triggers[i].Name < t.Triggers[j].Name })
+	sort.Slice(t.Rules, func(i, j int) bool { return t.Rules[i].Name < t.Rules[j].Name })
+	sort.Slice(t.Policies, func(i, j int) bool { return t.Policies[i].Name < t.Policies[j].Name })
+	sort.Slice(t.Sources, func(i, j int) bool {
+		return t.Sources[i].Schema+"."+t.Sources[i].Table < t.Sources[j].Schema+"."+t.Sources[j].Table
+	})
+}
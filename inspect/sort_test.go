@@ -0,0 +1,176 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var updateSorted = flag.Bool("update-sorted", false, "Write testdata/sorted.json and sorted.yaml instead of comparing with them.")
+
+// catalog returns a database in the order PostgreSQL might return it; every
+// list Sort orders is out of order.
+func catalog() *Database {
+	orders := &Table{Schema: "shop", Name: "orders", Type: "BASE TABLE", Owner: "app", Comment: "@owner=sales Orders.",
+		Annotations: map[string]string{"owner": "sales"},
+		Columns: []Column{
+			{Name: "total", DataType: "numeric", UDTName: "numeric", Position: 3, Nullable: true},
+			{Name: "id", DataType: "bigint", UDTName: "int8", Position: 1, Default: "nextval('shop.orders_id_seq'::regclass)"},
+			{Name: "customer_id", DataType: "bigint", UDTName: "int8", Position: 2},
+		},
+		PK: PrimaryKey{Name: "orders_pkey", Columns: []string{"id"}},
+		FKs: []ForeignKey{
+			{Name: "orders_customer_id_fkey", Columns: []string{"customer_id"}, RefSchema: "shop", RefTable: "customers",
+				RefColumns: []string{"id"}, OnUpdate: "NO ACTION", OnDelete: "CASCADE"},
+			{Name: "orders_audit_fkey", Columns: []string{"id"}, RefSchema: "audit", RefTable: "log",
+				RefColumns: []string{"order_id"}, OnUpdate: "NO ACTION", OnDelete: "NO ACTION"},
+		},
+		Constraints: []Constraint{
+			{Name: "orders_total_check", Type: "CHECK", Columns: []string{"total"}, Definition: "CHECK ((total > (0)::numeric))"},
+			{Name: "orders_customer_id_total_key", Type: "UNIQUE", Columns: []string{"customer_id", "total"},
+				Definition: "UNIQUE (customer_id, total)"},
+		},
+		Indexes: []Index{
+			{Name: "orders_pkey", Method: "btree", Columns: []string{"id"}, Unique: true, Primary: true,
+				Definition: "CREATE UNIQUE INDEX orders_pkey ON shop.orders USING btree (id)"},
+			{Name: "orders_customer_id_total_key", Method: "btree", Columns: []string{"customer_id", "total"}, Unique: true,
+				Definition: "CREATE UNIQUE INDEX orders_customer_id_total_key ON shop.orders USING btree (customer_id, total)"},
+			{Name: "orders_customer_id_idx", Method: "btree", Columns: []string{"customer_id"},
+				Definition: "CREATE INDEX orders_customer_id_idx ON shop.orders USING btree (customer_id)"},
+		},
+		Triggers: []Trigger{
+			{Name: "orders_touch", Timing: "BEFORE", Events: []string{"UPDATE"}, Level: "ROW", Function: "shop.touch()", Enabled: true},
+			{Name: "orders_audit", Timing: "AFTER", Events: []string{"INSERT", "UPDATE"}, Level: "ROW", Function: "audit.record()", Enabled: true},
+		},
+		Policies: []Policy{
+			{Name: "orders_tenant", Permissive: true, Roles: []string{"app"}, Command: "ALL", Using: "(tenant = current_user)"},
+			{Name: "orders_admin", Permissive: true, Roles: []string{"admin"}, Command: "ALL", Using: "true"},
+		},
+	}
+	customers := &Table{Schema: "shop", Name: "customers", Type: "BASE TABLE", Owner: "app",
+		Columns: []Column{
+			{Name: "name", DataType: "text", UDTName: "text", Position: 2},
+			{Name: "id", DataType: "bigint", UDTName: "int8", Position: 1},
+		},
+		PK: PrimaryKey{Name: "customers_pkey", Columns: []string{"id"}},
+	}
+	log := &Table{Schema: "audit", Name: "log", Type: "BASE TABLE", Owner: "auditor",
+		Columns: []Column{{Name: "order_id", DataType: "bigint", UDTName: "int8", Position: 1}},
+		Rules: []Rule{
+			{Name: "log_no_update", Event: "UPDATE", Instead: true, Enabled: true},
+			{Name: "log_no_delete", Event: "DELETE", Instead: true, Enabled: true},
+		},
+	}
+	return &Database{
+		Name:        "app",
+		InspectedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Schemas: []*Schema{
+			{Name: "shop", Owner: "app",
+				Tables: []*Table{orders, customers},
+				Enums:  []Enum{{Name: "status", Labels: []string{"new", "paid", "shipped"}}, {Name: "channel", Labels: []string{"web", "store"}}},
+				Sequences: []Sequence{
+					{Name: "orders_id_seq", DataType: "bigint", LastValue: 42, MaxValue: 9223372036854775807, Increment: 1, OwnedBy: "shop.orders.id"},
+					{Name: "invoice_numbers", DataType: "integer", MaxValue: 2147483647, Increment: 1},
+				},
+				Functions: []Function{
+					{Name: "touch", Result: "trigger", Language: "plpgsql", Owner: "app", Volatility: "volatile", Parallel: "unsafe"},
+					{Name: "total", Arguments: "order_id bigint", Result: "numeric", Language: "sql", Owner: "app",
+						Volatility: "stable", Parallel: "safe"},
+				},
+			},
+			{Name: "audit", Owner: "auditor", Tables: []*Table{log}},
+		},
+		Roles: []Role{{Name: "auditor", Login: true}, {Name: "app", Login: true}, {Name: "admin", Superuser: true}},
+		DefaultPrivileges: []DefaultPrivilege{
+			{Role: "app", Schema: "shop", ObjectType: "tables", ACL: []string{"auditor=r/app"}},
+			{Role: "app", Schema: "audit", ObjectType: "tables", ACL: []string{"auditor=arw/app"}},
+		},
+		Migrations: []Migrations{
+			{Tool: "goose", Table: "shop.goose_db_version", Applied: []string{"1", "2"}},
+			{Tool: "golang-migrate", Table: "public.schema_migrations", Applied: []string{"7"}},
+		},
+	}
+}
+
+// shuffle reorders every list of db that Sort orders.
+func shuffle(r *rand.Rand, db *Database) {
+	perm := func(n int, swap func(i, j int)) { r.Shuffle(n, swap) }
+	perm(len(db.Schemas), func(i, j int) { db.Schemas[i], db.Schemas[j] = db.Schemas[j], db.Schemas[i] })
+	perm(len(db.Roles), func(i, j int) { db.Roles[i], db.Roles[j] = db.Roles[j], db.Roles[i] })
+	perm(len(db.Migrations), func(i, j int) { db.Migrations[i], db.Migrations[j] = db.Migrations[j], db.Migrations[i] })
+	perm(len(db.DefaultPrivileges), func(i, j int) {
+		db.DefaultPrivileges[i], db.DefaultPrivileges[j] = db.DefaultPrivileges[j], db.DefaultPrivileges[i]
+	})
+	for _, s := range db.Schemas {
+		perm(len(s.Tables), func(i, j int) { s.Tables[i], s.Tables[j] = s.Tables[j], s.Tables[i] })
+		perm(len(s.Enums), func(i, j int) { s.Enums[i], s.Enums[j] = s.Enums[j], s.Enums[i] })
+		perm(len(s.Sequences), func(i, j int) { s.Sequences[i], s.Sequences[j] = s.Sequences[j], s.Sequences[i] })
+		perm(len(s.Functions), func(i, j int) { s.Functions[i], s.Functions[j] = s.Functions[j], s.Functions[i] })
+		for _, t := range s.Tables {
+			perm(len(t.Columns), func(i, j int) { t.Columns[i], t.Columns[j] = t.Columns[j], t.Columns[i] })
+			perm(len(t.FKs), func(i, j int) { t.FKs[i], t.FKs[j] = t.FKs[j], t.FKs[i] })
+			perm(len(t.Constraints), func(i, j int) { t.Constraints[i], t.Constraints[j] = t.Constraints[j], t.Constraints[i] })
+			perm(len(t.Indexes), func(i, j int) { t.Indexes[i], t.Indexes[j] = t.Indexes[j], t.Indexes[i] })
+			perm(len(t.Triggers), func(i, j int) { t.Triggers[i], t.Triggers[j] = t.Triggers[j], t.Triggers[i] })
+			perm(len(t.Rules), func(i, j int) { t.Rules[i], t.Rules[j] = t.Rules[j], t.Rules[i] })
+			perm(len(t.Policies), func(i, j int) { t.Policies[i], t.Policies[j] = t.Policies[j], t.Policies[i] })
+		}
+	}
+}
+
+func TestSortIsStable(t *testing.T) {
+	formats := []struct {
+		golden  string
+		marshal func(db *Database) ([]byte, error)
+	}{
+		{"sorted.json", func(db *Database) ([]byte, error) {
+			b, err := json.MarshalIndent(db, "", "  ")
+			return append(b, '\n'), err
+		}},
+		{"sorted.yaml", func(db *Database) ([]byte, error) { return yaml.Marshal(db) }},
+	}
+	for _, f := range formats {
+		t.Run(f.golden, func(t *testing.T) {
+			golden := filepath.Join("testdata", f.golden)
+			db := catalog()
+			db.Sort()
+			db.LinkReferences()
+			want, err := f.marshal(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *updateSorted {
+				if err := ioutil.WriteFile(golden, want, 0644); err != nil {
+					t.Fatal(err)
+				}
+			} else if b, err := ioutil.ReadFile(golden); err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(b, want) {
+				t.Fatalf("sorted catalog differs from %s, run the tests with -update-sorted to accept the changes:\n%s",
+					golden, want)
+			}
+
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < 20; i++ {
+				db := catalog()
+				shuffle(r, db)
+				db.Sort()
+				db.LinkReferences()
+				got, err := f.marshal(db)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("shuffle %d sorts differently:\n%s", i, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package inspect
+
+import (
+	"context"
+
+	"github.com/gocraft/dbr"
+)
+
+// StreamBatch is the number of tables ForEachTable aims to hold in memory at
+// once. A schema is never split, so a schema larger than StreamBatch is
+// loaded as a single batch.
+const StreamBatch = 1000
+
+type schemaSize struct {
+	Schema string `db:"schema"`
+	Tables int    `db:"tables"`
+}
+
+// ForEachTable inspects the listed schemas, all user schemas if none are
+// given, and calls fn for every table in schema and name order. Schemas are
+// read in batches of about StreamBatch tables and each batch is released
+// before the next one is read, so memory stays bounded on catalogs too large
+// for Load. Tables handed to fn are fully loaded, but ReferencedBy only lists
+// referencing tables from the same batch. Iteration stops at the first error
+// returned by fn or when ctx is done.
+func ForEachTable(ctx context.Context, sess *dbr.Session, schemas []string, fn func(t *Table) error) error {
+	where, args := schemaFilter("table_schema", schemas)
+	var sizes []schemaSize
+	if _, err := sess.SelectBySql("SELECT table_schema AS schema, count(*) AS tables FROM information_schema.tables WHERE "+
+		where+" GROUP BY table_schema ORDER BY table_schema", args...).Load(&sizes); err != nil {
+		return queryError("count tables", err)
+	}
+
+	var batch []string
+	var n int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		db, err := loadSchemas(sess, batch)
+		if err != nil {
+			return err
+		}
+		db.Sort()
+		db.LinkReferences()
+		batch, n = nil, 0
+		for _, s := range db.Schemas {
+			for _, t := range s.Tables {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := fn(t); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, s := range sizes {
+		if n > 0 && n+s.Tables > StreamBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		batch = append(batch, s.Schema)
+		n += s.Tables
+	}
+	return flush()
+}
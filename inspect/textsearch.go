@@ -0,0 +1,158 @@
+package inspect
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// TextSearchConfig is a user defined text search configuration. Parser
+// and dictionaries outside pg_catalog are qualified with their schema.
+type TextSearchConfig struct {
+	Name         string   `json:"name"`
+	Owner        string   `json:"owner"`
+	Parser       string   `json:"parser"`
+	Dictionaries []string `json:"dictionaries"` // mapped to any token type
+	Comment      string   `json:"comment,omitempty"`
+}
+
+// TextSearchDictionary is a user defined text search dictionary.
+type TextSearchDictionary struct {
+	Name     string `json:"name"`
+	Owner    string `json:"owner"`
+	Template string `json:"template"`
+	Options  string `json:"options,omitempty"` // e.g. stopwords = 'english'
+	Comment  string `json:"comment,omitempty"`
+}
+
+type textSearchConfigRow struct {
+	Schema       string         `db:"schema"`
+	Name         string         `db:"name"`
+	Owner        string         `db:"owner"`
+	Parser       string         `db:"parser"`
+	Comment      string         `db:"comment"`
+	Dictionaries pq.StringArray `db:"dictionaries"`
+}
+
+type textSearchDictionaryRow struct {
+	Schema   string `db:"schema"`
+	Name     string `db:"name"`
+	Owner    string `db:"owner"`
+	Template string `db:"template"`
+	Options  string `db:"options"`
+	Comment  string `db:"comment"`
+}
+
+// loadTextSearch reads the text search configurations and dictionaries of
+// the schemas.
+func loadTextSearch(sess *dbr.Session, schemas []string, bySchema map[string]*Schema) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var configs []textSearchConfigRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS schema, c.cfgname AS name, pg_get_userbyid(c.cfgowner) AS owner,
+	CASE WHEN pn.nspname = 'pg_catalog' THEN p.prsname ELSE pn.nspname || '.' || p.prsname END AS parser,
+	COALESCE(obj_description(c.oid, 'pg_ts_config'), '') AS comment,
+	ARRAY(SELECT DISTINCT CASE WHEN dn.nspname = 'pg_catalog' THEN d.dictname ELSE dn.nspname || '.' || d.dictname END
+		FROM pg_ts_config_map m
+			JOIN pg_ts_dict d ON d.oid = m.mapdict
+			JOIN pg_namespace dn ON dn.oid = d.dictnamespace
+		WHERE m.mapcfg = c.oid ORDER BY 1)::text[] AS dictionaries
+FROM pg_ts_config c
+	JOIN pg_namespace n ON n.oid = c.cfgnamespace
+	JOIN pg_ts_parser p ON p.oid = c.cfgparser
+	JOIN pg_namespace pn ON pn.oid = p.prsnamespace
+WHERE `+where, args...).Load(&configs)
+	if err != nil {
+		return queryError("select text search configurations", err)
+	}
+	for _, v := range configs {
+		if s := bySchema[v.Schema]; s != nil {
+			s.TextSearchConfigs = append(s.TextSearchConfigs, TextSearchConfig{
+				Name: v.Name, Owner: v.Owner, Parser: v.Parser, Dictionaries: v.Dictionaries, Comment: v.Comment})
+		}
+	}
+
+	var dicts []textSearchDictionaryRow
+	_, err = sess.SelectBySql(`SELECT n.nspname AS schema, d.dictname AS name, pg_get_userbyid(d.dictowner) AS owner,
+	CASE WHEN tn.nspname = 'pg_catalog' THEN t.tmplname ELSE tn.nspname || '.' || t.tmplname END AS template,
+	COALESCE(d.dictinitoption, '') AS options,
+	COALESCE(obj_description(d.oid, 'pg_ts_dict'), '') AS comment
+FROM pg_ts_dict d
+	JOIN pg_namespace n ON n.oid = d.dictnamespace
+	JOIN pg_ts_template t ON t.oid = d.dicttemplate
+	JOIN pg_namespace tn ON tn.oid = t.tmplnamespace
+WHERE `+where, args...).Load(&dicts)
+	if err != nil {
+		return queryError("select text search dictionaries", err)
+	}
+	for _, v := range dicts {
+		if s := bySchema[v.Schema]; s != nil {
+			s.TextSearchDictionaries = append(s.TextSearchDictionaries, TextSearchDictionary{
+				Name: v.Name, Owner: v.Owner, Template: v.Template, Options: v.Options, Comment: v.Comment})
+		}
+	}
+	return nil
+}
+
+// SearchColumn is a tsvector column and how it is kept up to date.
+type SearchColumn struct {
+	Table  *Table
+	Column *Column
+	// Source is "generated" for a generated column, "trigger" when
+	// tsvector_update_trigger maintains it, "trigger?" when a row trigger
+	// of the table may, and "" when nothing seems to.
+	Source string
+	// Config is the text search configuration used, when it is spelled out.
+	Config string
+	// Definition is the generation expression or the trigger definitions.
+	Definition string
+}
+
+var (
+	tsGenerated = regexp.MustCompile(`to_tsvector\('([^']+)'::regconfig`)
+	tsTrigger   = regexp.MustCompile(`tsvector_update_trigger(_column)?\('([^']*)', '([^']*)'`)
+)
+
+// SearchColumns lists the tsvector columns of the tables of db.
+func SearchColumns(db *Database) []SearchColumn {
+	var cols []SearchColumn
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			for i := range t.Columns {
+				c := &t.Columns[i]
+				if c.UDTName != "tsvector" {
+					continue
+				}
+				sc := SearchColumn{Table: t, Column: c}
+				if c.Generated != "" {
+					sc.Source, sc.Definition = "generated", c.Generated
+					if m := tsGenerated.FindStringSubmatch(c.Generated); m != nil {
+						sc.Config = m[1]
+					}
+					cols = append(cols, sc)
+					continue
+				}
+				var maybe []string
+				for _, tg := range t.Triggers {
+					m := tsTrigger.FindStringSubmatch(tg.Definition)
+					if m != nil && m[2] == c.Name {
+						sc.Source, sc.Definition = "trigger", tg.Definition
+						if m[1] == "" {
+							sc.Config = m[3]
+						}
+						break
+					}
+					if tg.Level == "ROW" && tg.Timing == "BEFORE" && m == nil {
+						maybe = append(maybe, tg.Definition)
+					}
+				}
+				if sc.Source == "" && len(maybe) > 0 {
+					sc.Source, sc.Definition = "trigger?", strings.Join(maybe, "; ")
+				}
+				cols = append(cols, sc)
+			}
+		}
+	}
+	return cols
+}
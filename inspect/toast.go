@@ -0,0 +1,50 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+type columnStorageRow struct {
+	TableSchema string `db:"table_schema"`
+	TableName   string `db:"table_name"`
+	ColumnName  string `db:"column_name"`
+	Storage     string `db:"storage"`
+	Compression string `db:"compression"`
+}
+
+// loadColumnStorage reads the TOAST strategy and compression of the columns
+// of tables and materialized views. Per-column compression needs
+// PostgreSQL 14.
+func loadColumnStorage(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	var version int
+	if err := sess.SelectBySql(`SELECT current_setting('server_version_num')::int`).LoadOne(&version); err != nil {
+		return queryError("select server version", err)
+	}
+	compression := "''"
+	if version >= 140000 {
+		compression = `CASE a.attcompression WHEN 'p' THEN 'pglz' WHEN 'l' THEN 'lz4' ELSE '' END`
+	}
+
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []columnStorageRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name, a.attname AS column_name,
+	CASE a.attstorage WHEN 'p' THEN 'plain' WHEN 'm' THEN 'main' WHEN 'e' THEN 'external' ELSE 'extended' END AS storage,
+	`+compression+` AS compression
+FROM pg_attribute a
+	JOIN pg_class c ON c.oid = a.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'm', 'p') AND a.attnum > 0 AND NOT a.attisdropped AND `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select column storage", err)
+	}
+	for _, v := range rows {
+		t := byTable[v.TableSchema+"."+v.TableName]
+		if t == nil {
+			continue
+		}
+		if c := t.Column(v.ColumnName); c != nil {
+			c.Storage, c.Compression = v.Storage, v.Compression
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+// Trigger is a user defined trigger of a table.
+type Trigger struct {
+	Name       string   `json:"name"`
+	Timing     string   `json:"timing"` // BEFORE, AFTER or INSTEAD OF
+	Events     []string `json:"events"` // INSERT, UPDATE, DELETE and TRUNCATE
+	Level      string   `json:"level"`  // ROW or STATEMENT
+	Function   string   `json:"function"`
+	Enabled    bool     `json:"enabled"`
+	Definition string   `json:"definition"`
+}
+
+// Bits of pg_trigger.tgtype.
+const (
+	tgtypeRow      = 1 << 0
+	tgtypeBefore   = 1 << 1
+	tgtypeInsert   = 1 << 2
+	tgtypeDelete   = 1 << 3
+	tgtypeUpdate   = 1 << 4
+	tgtypeTruncate = 1 << 5
+	tgtypeInstead  = 1 << 6
+)
+
+type triggerRow struct {
+	TableSchema string `db:"table_schema"`
+	TableName   string `db:"table_name"`
+	Name        string `db:"name"`
+	Type        int    `db:"type"`
+	Function    string `db:"function"`
+	Enabled     string `db:"enabled"`
+	Definition  string `db:"definition"`
+}
+
+func (r *triggerRow) trigger() Trigger {
+	t := Trigger{
+		Name:       r.Name,
+		Timing:     "AFTER",
+		Level:      "STATEMENT",
+		Function:   r.Function,
+		Enabled:    r.Enabled != "D",
+		Definition: r.Definition,
+	}
+	switch {
+	case r.Type&tgtypeBefore != 0:
+		t.Timing = "BEFORE"
+	case r.Type&tgtypeInstead != 0:
+		t.Timing = "INSTEAD OF"
+	}
+	if r.Type&tgtypeRow != 0 {
+		t.Level = "ROW"
+	}
+	for _, e := range []struct {
+		bit  int
+		name string
+	}{{tgtypeInsert, "INSERT"}, {tgtypeUpdate, "UPDATE"}, {tgtypeDelete, "DELETE"}, {tgtypeTruncate, "TRUNCATE"}} {
+		if r.Type&e.bit != 0 {
+			t.Events = append(t.Events, e.name)
+		}
+	}
+	return t
+}
+
+// loadTriggers reads user defined triggers, leaving out the internal ones
+// which implement foreign keys.
+func loadTriggers(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("n.nspname", schemas)
+	var rows []triggerRow
+	_, err := sess.SelectBySql(`SELECT n.nspname AS table_schema, c.relname AS table_name,
+	tg.tgname AS name, tg.tgtype::int AS type,
+	pn.nspname || '.' || p.proname AS function,
+	tg.tgenabled AS enabled, pg_get_triggerdef(tg.oid, true) AS definition
+FROM pg_trigger tg
+	JOIN pg_class c ON c.oid = tg.tgrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_proc p ON p.oid = tg.tgfoid
+	JOIN pg_namespace pn ON pn.oid = p.pronamespace
+WHERE NOT tg.tgisinternal AND `+where, args...).Load(&rows)
+	if err != nil {
+		return queryError("select triggers", err)
+	}
+	for i := range rows {
+		if t := byTable[rows[i].TableSchema+"."+rows[i].TableName]; t != nil {
+			t.Triggers = append(t.Triggers, rows[i].trigger())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package inspect_test
+
+import (
+	"database/sql"
+	"os/exec"
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/datainq/pq-inspector/pgcontainer"
+	"github.com/gocraft/dbr"
+)
+
+func TestSchemaVersionsChange(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a PostgreSQL container")
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not installed")
+	}
+	c, err := pgcontainer.Start(pgcontainer.Options{})
+	if err != nil {
+		t.Skipf("no PostgreSQL container: %v", err)
+	}
+	defer c.Stop()
+	db, err := sql.Open("postgres", c.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	conn, err := dbr.Open("postgres", c.DSN, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	if _, err := db.Exec(`CREATE SCHEMA app;
+CREATE SCHEMA ref;
+CREATE TABLE ref.parents (id integer PRIMARY KEY);
+CREATE TABLE app.items (id integer, parent_id integer REFERENCES ref.parents (id));
+CREATE SEQUENCE app.numbers;
+CREATE FUNCTION app.one() RETURNS integer LANGUAGE sql AS 'SELECT 1';
+CREATE OPERATOR app.=== (LEFTARG = integer, RIGHTARG = integer, FUNCTION = int4eq);
+CREATE TEXT SEARCH CONFIGURATION app.search (COPY = english);`); err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		"COMMENT ON SCHEMA app IS 'Application.'",
+		"COMMENT ON TABLE app.items IS 'Items.'",
+		"COMMENT ON COLUMN app.items.id IS 'Key.'",
+		"COMMENT ON FUNCTION app.one() IS 'One.'",
+		"COMMENT ON OPERATOR app.=== (integer, integer) IS 'Equal.'",
+		"COMMENT ON TEXT SEARCH CONFIGURATION app.search IS 'Search.'",
+		"ALTER SEQUENCE app.numbers INCREMENT BY 2",
+		"ALTER SEQUENCE app.numbers MAXVALUE 1000",
+		"ALTER SEQUENCE app.numbers OWNED BY app.items.id",
+		"ALTER SEQUENCE app.numbers OWNED BY NONE",
+		"ALTER TABLE ref.parents RENAME TO ancestors",
+		"ALTER TABLE ref.ancestors RENAME COLUMN id TO ancestor_id",
+		"ALTER SCHEMA ref RENAME TO refs",
+	}
+	before, err := inspect.SchemaVersions(sess, []string{"app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+		after, err := inspect.SchemaVersions(sess, []string{"app"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after["app"] == before["app"] {
+			t.Errorf("%s does not change the version of app", stmt)
+		}
+		before = after
+	}
+
+	if _, err := db.Exec("VACUUM ANALYZE"); err != nil {
+		t.Fatal(err)
+	}
+	after, err := inspect.SchemaVersions(sess, []string{"app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after["app"] != before["app"] {
+		t.Error("VACUUM ANALYZE changes the version of app")
+	}
+}
@@ -0,0 +1,52 @@
+package inspect
+
+import (
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// ViewSource is a relation read by a view and the columns of it the view
+// uses.
+type ViewSource struct {
+	Schema  string   `json:"schema"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns,omitempty"`
+}
+
+type viewSourceRow struct {
+	ViewSchema  string         `db:"view_schema"`
+	ViewName    string         `db:"view_name"`
+	TableSchema string         `db:"table_schema"`
+	TableName   string         `db:"table_name"`
+	Columns     pq.StringArray `db:"columns"`
+}
+
+// loadViewSources reads the relations and columns each view depends on
+// from the dependencies of its rewrite rule.
+func loadViewSources(sess *dbr.Session, schemas []string, byTable map[string]*Table) error {
+	where, args := schemaFilter("vn.nspname", schemas)
+	var rows []viewSourceRow
+	_, err := sess.SelectBySql(`SELECT vn.nspname AS view_schema, v.relname AS view_name,
+	tn.nspname AS table_schema, t.relname AS table_name,
+	array_remove(array_agg(DISTINCT a.attname::text), NULL) AS columns
+FROM pg_rewrite r
+	JOIN pg_class v ON v.oid = r.ev_class
+	JOIN pg_namespace vn ON vn.oid = v.relnamespace
+	JOIN pg_depend d ON d.classid = 'pg_rewrite'::regclass AND d.objid = r.oid
+		AND d.refclassid = 'pg_class'::regclass AND d.deptype = 'n'
+	JOIN pg_class t ON t.oid = d.refobjid
+	JOIN pg_namespace tn ON tn.oid = t.relnamespace
+	LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid AND d.refobjsubid > 0
+WHERE v.relkind IN ('v', 'm') AND t.oid <> v.oid AND `+where+`
+GROUP BY vn.nspname, v.relname, tn.nspname, t.relname
+ORDER BY tn.nspname, t.relname`, args...).Load(&rows)
+	if err != nil {
+		return queryError("select view sources", err)
+	}
+	for _, v := range rows {
+		if t := byTable[v.ViewSchema+"."+v.ViewName]; t != nil {
+			t.Sources = append(t.Sources, ViewSource{Schema: v.TableSchema, Table: v.TableName, Columns: v.Columns})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package inspectortest locks the schema of a test database to a golden
+// file, so application test suites can assert the end state of their
+// migrations:
+//
+//	func TestMigrations(t *testing.T) {
+//		// ... apply the migrations to the test database
+//		db := inspectortest.Inspect(t, dsn, "public")
+//		inspectortest.AssertSchemaMatches(t, db, "testdata/schema.golden.json")
+//	}
+//
+// Run the tests with -update-golden, or with PG_INSPECTOR_UPDATE_GOLDEN=1,
+// to write the golden files instead of comparing with them.
+package inspectortest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/datainq/pq-inspector/diff"
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/datainq/pq-inspector/pgcontainer"
+	"github.com/gocraft/dbr"
+	_ "github.com/lib/pq"
+)
+
+var update = flag.Bool("update-golden", false, "Write the golden schema files of inspectortest instead of comparing with them.")
+
+// Updating reports whether golden files are written rather than compared.
+func Updating() bool {
+	return *update || os.Getenv("PG_INSPECTOR_UPDATE_GOLDEN") == "1"
+}
+
+// Options are the attributes left out of golden files. Names, inspection
+// times, migrations, roles and OIDs are always left out.
+var Options = inspect.FingerprintOptions{IgnoreStats: true}
+
+// Inspect loads the given schemas, all user schemas if none, of the
+// database at the connection string dsn and fails the test on errors.
+func Inspect(t testing.TB, dsn string, schemas ...string) *inspect.Database {
+	t.Helper()
+	conn, err := dbr.Open("postgres", dsn, nil)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	db, err := inspect.Load(conn.NewSession(nil), schemas)
+	if err != nil {
+		t.Fatalf("inspect database: %v", err)
+	}
+	return db
+}
+
+// AssertSchemaMatches fails the test if the structure of db differs from
+// the snapshot in the golden file, listing the differences. When
+// updating, it writes the golden file instead.
+func AssertSchemaMatches(t testing.TB, db *inspect.Database, golden string) {
+	t.Helper()
+	norm := inspect.Normalize(db, Options)
+	got, err := json.MarshalIndent(norm, "", "  ")
+	if err != nil {
+		t.Fatalf("encode schema: %v", err)
+	}
+	got = append(got, '\n')
+
+	if Updating() {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		t.Logf("updated %s", golden)
+		return
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s does not exist, run the tests with -update-golden to create it", golden)
+	}
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if bytes.Equal(want, got) {
+		return
+	}
+	wantDB := &inspect.Database{}
+	if err := json.Unmarshal(want, wantDB); err != nil {
+		t.Fatalf("parse golden file %s: %v", golden, err)
+	}
+	wantDB.Sort()
+	wantDB.LinkReferences()
+
+	var b strings.Builder
+	for _, c := range diff.Compare(wantDB, norm, diff.Options{}) {
+		b.WriteString("\n\t" + c.String())
+	}
+	if b.Len() == 0 {
+		b.WriteString("\n\t" + firstDifference(want, got))
+	}
+	t.Errorf("schema differs from %s:%s\nrun the tests with -update-golden to accept the changes", golden, b.String())
+}
+
+// firstDifference describes the first line where two golden files differ,
+// for differences the diff package does not report.
+func firstDifference(want, got []byte) string {
+	wl, gl := strings.Split(string(want), "\n"), strings.Split(string(got), "\n")
+	for i := 0; i < len(wl) && i < len(gl); i++ {
+		if wl[i] != gl[i] {
+			return fmt.Sprintf("line %d: %s, want %s", i+1, strings.TrimSpace(gl[i]), strings.TrimSpace(wl[i]))
+		}
+	}
+	return fmt.Sprintf("%d lines, want %d", len(gl), len(wl))
+}
+
+// FromMigrations applies the migrations in dir to a disposable PostgreSQL
+// container, see the pgcontainer package, and returns the given schemas
+// of the result. It skips the test if there is no docker command.
+func FromMigrations(t testing.TB, dir string, schemas ...string) *inspect.Database {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+	db, err := pgcontainer.Inspect(dir, schemas, pgcontainer.Options{})
+	if err != nil {
+		t.Fatalf("inspect migrations: %v", err)
+	}
+	return db
+}
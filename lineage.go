@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// runLineage prints the views and the relations they read or, given a
+// column, the views reading it.
+func (a *app) runLineage(args []string) {
+	fs := flag.NewFlagSet("lineage", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+	if fs.NArg() > 1 {
+		a.log.Fatal("usage: lineage [schema.table.column]")
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	if fs.NArg() == 0 {
+		for _, s := range db.Schemas {
+			for _, t := range s.Tables {
+				for _, src := range t.Sources {
+					fmt.Printf("%s <- %s.%s", qualifiedName(t), src.Schema, src.Table)
+					if len(src.Columns) > 0 {
+						fmt.Printf(" (%s)", strings.Join(src.Columns, ", "))
+					}
+					fmt.Println()
+				}
+			}
+		}
+		return
+	}
+
+	parts := strings.Split(fs.Arg(0), ".")
+	if len(parts) != 3 {
+		a.log.Fatalf("invalid column name %q, want schema.table.column", fs.Arg(0))
+	}
+	for _, r := range db.ColumnReaders(parts[0], parts[1], parts[2]) {
+		if r.Column != "" {
+			fmt.Printf("%d %s.%s\n", r.Depth, r.View, r.Column)
+		} else {
+			fmt.Printf("%d %s (column not traced further)\n", r.Depth, r.View)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/datainq/pq-inspector/lint"
+)
+
+// writeFindings writes findings as text, one per line, or as JSON.
+func writeFindings(w io.Writer, findings []lint.Finding, format string) error {
+	if format == "json" {
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	}
+	for _, f := range findings {
+		if _, err := fmt.Fprintln(w, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lintRules returns the built-in rules configured by the config file and
+// the rules of its plugins.
+func (a *app) lintRules() []lint.Rule {
+	rules, err := lint.Builtin(a.cfg.Lint.Options)
+	if err != nil {
+		a.log.WithError(err).Fatal("configure lint rules")
+	}
+	for _, path := range a.cfg.Lint.Plugins {
+		extra, err := lint.LoadPlugin(path)
+		if err != nil {
+			a.log.WithError(err).Fatalf("load lint plugin %s", path)
+		}
+		if rules, err = lint.Merge(rules, extra...); err != nil {
+			a.log.WithError(err).Fatalf("load lint plugin %s", path)
+		}
+	}
+	return rules
+}
+
+// runLint checks the schema against the lint rules. It exits with 1 if
+// any finding not in the baseline is at least as severe as -fail-on.
+func (a *app) runLint(args []string) {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	format := fs.String("format", "text", "Output format: text, json or sarif.")
+	artifact := fs.String("sarif-artifact", "schema.sql", "Repository file SARIF results are attributed to.")
+	failOn := fs.String("fail-on", "error", "Exit with 1 on findings of this severity or worse: error, warning, note or none.")
+	baseline := fs.String("baseline", "", "Report only findings not recorded in this baseline file.")
+	writeBaseline := fs.String("write-baseline", "", "Record the current findings as the baseline in this file.")
+	a.parseFlags(fs, args)
+	if *format != "text" && *format != "json" && *format != "sarif" {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+	minSeverity, ok := lint.ParseSeverity(*failOn)
+	if !ok && *failOn != "none" {
+		a.log.Fatalf("invalid -fail-on %q", *failOn)
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	rules := a.lintRules()
+	findings := lint.Run(db, rules)
+	if *writeBaseline != "" {
+		if err := lint.WriteBaseline(*writeBaseline, findings); err != nil {
+			a.log.WithError(err).Fatal("write baseline")
+		}
+		a.log.Infof("recorded %d findings in %s", len(findings), *writeBaseline)
+		return
+	}
+	if *baseline != "" {
+		known, err := lint.ReadBaseline(*baseline)
+		if err != nil {
+			a.log.WithError(err).Fatal("read baseline")
+		}
+		all := len(findings)
+		findings = lint.New(findings, known)
+		a.log.Infof("%d of %d findings are in the baseline", all-len(findings), all)
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if *format == "sarif" {
+		err = writeSARIF(w, rules, findings, *artifact)
+	} else {
+		err = writeFindings(w, findings, *format)
+	}
+	if err != nil {
+		a.log.WithError(err).Fatal("write findings")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write findings")
+	}
+	if *failOn == "none" {
+		return
+	}
+	for _, f := range findings {
+		if f.Severity.AtLeast(minSeverity) {
+			a.log.Exit(exitFindings)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// AuditColumn is a column every table is expected to have, e.g.
+// created_at, for the audit-columns rule.
+type AuditColumn struct {
+	Name string `json:"name"`
+	// Types are the accepted types, e.g. ["timestamptz"]; any if empty.
+	Types []string `json:"types"`
+	// Default is a regular expression the column default must match,
+	// e.g. "^(now\\(\\)|CURRENT_TIMESTAMP)$"; any default or none if empty.
+	Default string `json:"default"`
+	// NotNull requires the column to be NOT NULL.
+	NotNull bool `json:"not_null"`
+	// Optional columns, like deleted_at, are only checked when present.
+	Optional bool `json:"optional"`
+}
+
+// auditColumns returns the audit-columns rule checking the configured
+// columns, which does nothing if none are.
+func auditColumns(columns []AuditColumn) (func(db *inspect.Database, report reportFunc), error) {
+	defaults := make([]*regexp.Regexp, len(columns))
+	for i, ac := range columns {
+		if ac.Name == "" {
+			return nil, fmt.Errorf("audit_columns: column without a name")
+		}
+		if ac.Default == "" {
+			continue
+		}
+		re, err := regexp.Compile(ac.Default)
+		if err != nil {
+			return nil, fmt.Errorf("audit_columns: default of %s: %v", ac.Name, err)
+		}
+		defaults[i] = re
+	}
+	return func(db *inspect.Database, report reportFunc) {
+		baseTables(db, func(t *inspect.Table) {
+			// Partitions get their columns from the parent.
+			if t.PartitionOf != "" {
+				return
+			}
+			for i, ac := range columns {
+				c := t.Column(ac.Name)
+				if c == nil {
+					if !ac.Optional {
+						report(qualified(t), ac.Name, "table has no %s column", ac.Name)
+					}
+					continue
+				}
+				object := qualified(t) + "." + c.Name
+				if len(ac.Types) > 0 && !contains(ac.Types, c.UDTName) {
+					report(object, "type", "column %s is %s, not %s", c.Name, c.TypeName(), strings.Join(ac.Types, " or "))
+				}
+				if ac.NotNull && c.Nullable {
+					report(object, "nullable", "column %s is nullable", c.Name)
+				}
+				if re := defaults[i]; re != nil && !re.MatchString(c.Default) {
+					def := c.Default
+					if def == "" {
+						def = "none"
+					}
+					report(object, "default", "column %s defaults to %s, expected a match of %s", c.Name, def, ac.Default)
+				}
+			}
+		})
+	}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
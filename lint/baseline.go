@@ -0,0 +1,71 @@
+package lint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// rank orders severities, a higher rank is more severe.
+var rank = map[Severity]int{Note: 1, Warning: 2, Error: 3}
+
+// AtLeast reports whether s is as severe as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return rank[s] >= rank[min]
+}
+
+// ParseSeverity returns the severity named s, and false for unknown names.
+func ParseSeverity(s string) (Severity, bool) {
+	sev := Severity(s)
+	return sev, rank[sev] > 0
+}
+
+// Identity identifies a finding across runs, in baselines and SARIF
+// fingerprints. The message is left out as it may hold sizes and counts,
+// e.g. the average row width.
+func (f Finding) Identity() string {
+	return f.Rule + "\x00" + f.Object + "\x00" + f.Key
+}
+
+// ReadBaseline reads findings written by WriteBaseline.
+func ReadBaseline(path string) ([]Finding, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	if err := json.Unmarshal(b, &findings); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// WriteBaseline records findings as accepted, so that later runs report
+// only new ones.
+func WriteBaseline(path string, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	b, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// New returns the findings which are not in baseline. A finding matches
+// by rule, object and key, so a finding on a renamed object, or on another
+// foreign key or index of a table, is new again while one whose message
+// only changed in numbers is not.
+func New(findings, baseline []Finding) []Finding {
+	known := make(map[string]bool, len(baseline))
+	for _, f := range baseline {
+		known[f.Identity()] = true
+	}
+	var fresh []Finding
+	for _, f := range findings {
+		if !known[f.Identity()] {
+			fresh = append(fresh, f)
+		}
+	}
+	return fresh
+}
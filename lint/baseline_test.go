@@ -0,0 +1,109 @@
+package lint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func wideTable(columns int, rowWidth int64) *inspect.Database {
+	t := &inspect.Table{Schema: "public", Name: "events", Type: "BASE TABLE",
+		PK: inspect.PrimaryKey{Name: "events_pkey", Columns: []string{"c0"}}, Comment: "Events."}
+	for i := 0; i < columns; i++ {
+		t.Columns = append(t.Columns, inspect.Column{Name: fmt.Sprintf("c%d", i), UDTName: "int8", Position: i + 1})
+	}
+	t.Stats.RowWidth = rowWidth
+	t.FKs = []inspect.ForeignKey{
+		{Name: "events_a_fkey", Columns: []string{"c1"}, RefSchema: "public", RefTable: "a", RefColumns: []string{"id"}},
+	}
+	return &inspect.Database{Name: "app", Schemas: []*inspect.Schema{{Name: "public", Tables: []*inspect.Table{t}}}}
+}
+
+func run(t *testing.T, db *inspect.Database) []Finding {
+	rules, err := Builtin(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Run(db, rules)
+}
+
+func TestBaselineIgnoresChangedNumbers(t *testing.T) {
+	baseline := run(t, wideTable(60, 3000))
+	rules := make(map[string]bool)
+	for _, f := range baseline {
+		rules[f.Rule] = true
+	}
+	if !rules["wide-table"] || !rules["wide-row"] {
+		t.Fatalf("baseline %v lacks wide-table and wide-row", baseline)
+	}
+	// The table gains columns and its rows grow, changing the messages of
+	// wide-table and wide-row.
+	if fresh := New(run(t, wideTable(64, 3500)), baseline); len(fresh) != 0 {
+		t.Errorf("new findings %v, want none", fresh)
+	}
+}
+
+func TestBaselineNewForeignKey(t *testing.T) {
+	baseline := run(t, wideTable(60, 0))
+	db := wideTable(60, 0)
+	tbl := db.Schemas[0].Tables[0]
+	tbl.FKs = append(tbl.FKs, inspect.ForeignKey{Name: "events_b_fkey", Columns: []string{"c2"},
+		RefSchema: "public", RefTable: "b", RefColumns: []string{"id"}})
+	fresh := New(run(t, db), baseline)
+	if len(fresh) != 1 || fresh[0].Rule != "fk-without-index" || fresh[0].Key != "events_b_fkey" {
+		t.Errorf("new findings %v, want fk-without-index of events_b_fkey", fresh)
+	}
+}
+
+func TestBaselineFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "baseline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "baseline.json")
+
+	findings := run(t, wideTable(60, 3000))
+	if err := WriteBaseline(path, findings); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(findings) {
+		t.Fatalf("read %d findings, wrote %d", len(read), len(findings))
+	}
+	for i := range read {
+		if read[i] != findings[i] {
+			t.Errorf("finding %d = %v, wrote %v", i, read[i], findings[i])
+		}
+	}
+
+	if err := WriteBaseline(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "[]\n" {
+		t.Errorf("empty baseline is %q, want []", b)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	if !Error.AtLeast(Warning) || Note.AtLeast(Warning) || !Warning.AtLeast(Warning) {
+		t.Error("wrong severity order")
+	}
+	if _, ok := ParseSeverity("fatal"); ok {
+		t.Error("parsed unknown severity")
+	}
+	if s, ok := ParseSeverity("note"); !ok || s != Note {
+		t.Errorf("ParseSeverity(note) = %v, %t", s, ok)
+	}
+}
@@ -0,0 +1,111 @@
+// Package lint checks an inspected database against rules of good schema
+// design.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// Severity is the level of a finding, named like SARIF levels.
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Note    Severity = "note"
+)
+
+// RulesURL documents the built-in rules, with an anchor per rule ID.
+const RulesURL = "https://github.com/datainq/pq-inspector/blob/master/lint/rules.md"
+
+// Finding is a violation of a rule by a database object.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Object   string   `json:"object"` // qualified name, e.g. public.users or public.users.email
+	Message  string   `json:"message"`
+	// Key tells apart findings of a rule on the same object, e.g. the name
+	// of a foreign key; empty if the rule reports an object once. Unlike
+	// the message it does not change with sizes or counts, see New.
+	Key string `json:"key,omitempty"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", f.Severity, f.Object, f.Message, f.Rule)
+}
+
+// Rule is a check of the model.
+type Rule interface {
+	// ID is a short kebab-case name, e.g. no-primary-key.
+	ID() string
+	Description() string
+	// HelpURI points to the documentation of the rule.
+	HelpURI() string
+	Severity() Severity
+	Check(db *inspect.Database) []Finding
+}
+
+// Run checks db against rules and returns the findings ordered by object
+// and rule. Findings disabled by a comment of their object, see
+// disabledRules, are left out.
+func Run(db *inspect.Database, rules []Rule) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		for _, f := range r.Check(db) {
+			if !suppressed(db, f) {
+				findings = append(findings, f)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Object != findings[j].Object {
+			return findings[i].Object < findings[j].Object
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	return findings
+}
+
+// builtin is a rule shipped with pg-inspector, documented in rules.md.
+type builtin struct {
+	id          string
+	description string
+	severity    Severity
+	check       func(db *inspect.Database, report reportFunc)
+}
+
+// reportFunc records a finding of the rule being checked, see Finding.Key
+// for key.
+type reportFunc func(object, key, format string, args ...interface{})
+
+func (r *builtin) ID() string          { return r.id }
+func (r *builtin) Description() string { return r.description }
+func (r *builtin) HelpURI() string     { return RulesURL + "#" + r.id }
+func (r *builtin) Severity() Severity  { return r.severity }
+
+func (r *builtin) Check(db *inspect.Database) []Finding {
+	var findings []Finding
+	r.check(db, func(object, key, format string, args ...interface{}) {
+		findings = append(findings, Finding{Rule: r.id, Severity: r.severity, Object: object,
+			Message: fmt.Sprintf(format, args...), Key: key})
+	})
+	return findings
+}
+
+// baseTables calls fn for every base table of db.
+func baseTables(db *inspect.Database, fn func(t *inspect.Table)) {
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type == "BASE TABLE" {
+				fn(t)
+			}
+		}
+	}
+}
+
+func qualified(t *inspect.Table) string {
+	return t.Schema + "." + t.Name
+}
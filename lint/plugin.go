@@ -0,0 +1,48 @@
+package lint
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// PluginSymbol is the function a rule plugin exports, of type
+// func() []lint.Rule. Plugins are built with
+//
+//	go build -buildmode=plugin -o rules.so ./rules
+//
+// against the same version of this package as pg-inspector.
+const PluginSymbol = "Rules"
+
+// LoadPlugin opens the Go plugin at path and returns its rules. Go plugins
+// are supported on Linux, FreeBSD and macOS only.
+func LoadPlugin(path string) ([]Rule, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	rules, ok := sym.(func() []Rule)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s is %T, not func() []lint.Rule", path, PluginSymbol, sym)
+	}
+	return rules(), nil
+}
+
+// Merge appends extra to rules, failing if a rule ID is used twice.
+func Merge(rules []Rule, extra ...Rule) ([]Rule, error) {
+	seen := make(map[string]bool, len(rules)+len(extra))
+	for _, r := range rules {
+		seen[r.ID()] = true
+	}
+	for _, r := range extra {
+		if seen[r.ID()] {
+			return nil, fmt.Errorf("duplicate lint rule %q", r.ID())
+		}
+		seen[r.ID()] = true
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
@@ -0,0 +1,364 @@
+package lint
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// Options configure the built-in rules, zero values select the defaults.
+type Options struct {
+	MaxColumns  int `json:"max_columns"`   // default 50
+	MaxIndexes  int `json:"max_indexes"`   // default 10
+	MaxRowWidth int `json:"max_row_width"` // average bytes, default 2032, the TOAST threshold
+	// AllowedOwners are path.Match patterns of the roles which may own
+	// objects. All roles may if empty.
+	AllowedOwners []string `json:"allowed_owners"`
+	// InstantColumns matches names of columns holding points in time,
+	// which should not be dates. Default DefaultInstantColumns.
+	InstantColumns string `json:"instant_columns"`
+	// AuditColumns are the columns every table should have, checked by
+	// audit-columns, which is off if none are set.
+	AuditColumns []AuditColumn `json:"audit_columns"`
+}
+
+// DefaultInstantColumns matches e.g. created_at, updated, expires_time.
+const DefaultInstantColumns = `(_at|_time|_timestamp)$|^(created|updated|modified|deleted)$`
+
+func orDefault(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// Builtin returns the rules shipped with pg-inspector.
+func Builtin(opts Options) ([]Rule, error) {
+	maxColumns := orDefault(opts.MaxColumns, 50)
+	maxIndexes := orDefault(opts.MaxIndexes, 10)
+	maxRowWidth := orDefault(opts.MaxRowWidth, 2032)
+	if opts.InstantColumns == "" {
+		opts.InstantColumns = DefaultInstantColumns
+	}
+	instant, err := regexp.Compile(opts.InstantColumns)
+	if err != nil {
+		return nil, fmt.Errorf("instant_columns: %v", err)
+	}
+	audit, err := auditColumns(opts.AuditColumns)
+	if err != nil {
+		return nil, err
+	}
+	return []Rule{
+		&builtin{"no-primary-key", "Tables should have a primary key.", Warning, noPrimaryKey},
+		&builtin{"fk-without-index", "Foreign key columns should be indexed.", Warning, fkWithoutIndex},
+		&builtin{"duplicate-index", "Indexes should not duplicate each other.", Warning, duplicateIndex},
+		&builtin{"rls-without-policy", "Tables with row level security enabled should have policies.", Error, rlsWithoutPolicy},
+		&builtin{"quoted-identifier", "Names should not need quoting.", Warning, quotedIdentifier},
+		&builtin{"wide-table", "Tables should not have too many columns.", Note, func(db *inspect.Database, report reportFunc) {
+			baseTables(db, func(t *inspect.Table) {
+				if len(t.Columns) > maxColumns {
+					report(qualified(t), "", "table has %d columns, more than %d", len(t.Columns), maxColumns)
+				}
+			})
+		}},
+		&builtin{"wide-row", "Rows should fit in a page without TOAST.", Note, func(db *inspect.Database, report reportFunc) {
+			baseTables(db, func(t *inspect.Table) {
+				if t.Stats.RowWidth > int64(maxRowWidth) {
+					report(qualified(t), "", "rows average %d bytes, more than %d, and are compressed or moved to TOAST",
+						t.Stats.RowWidth, maxRowWidth)
+				}
+			})
+		}},
+		&builtin{"too-many-indexes", "Tables should not have too many indexes.", Warning, func(db *inspect.Database, report reportFunc) {
+			baseTables(db, func(t *inspect.Table) {
+				if len(t.Indexes) > maxIndexes {
+					report(qualified(t), "", "table has %d indexes, more than %d, each slowing down writes",
+						len(t.Indexes), maxIndexes)
+				}
+			})
+		}},
+		&builtin{"timestamp-without-time-zone", "Points in time should be stored as timestamptz.", Warning, timestampWithoutTimeZone},
+		&builtin{"timetz", "Times of day should not carry a time zone.", Warning, timeWithTimeZone},
+		&builtin{"date-for-instant", "Columns named like points in time should not be dates.", Note, func(db *inspect.Database, report reportFunc) {
+			baseTables(db, func(t *inspect.Table) {
+				for _, c := range t.Columns {
+					if c.UDTName == "date" && instant.MatchString(c.Name) {
+						report(qualified(t)+"."+c.Name, "", "column %s is a date, its name suggests a point in time", c.Name)
+					}
+				}
+			})
+		}},
+		&builtin{"mixed-timestamps", "A schema should use one timestamp type.", Warning, mixedTimestamps},
+		&builtin{"owned-by-superuser", "Objects should not be owned by superusers.", Warning, ownedBySuperuser},
+		&builtin{"disallowed-owner", "Objects should be owned by the allowed roles.", Warning, func(db *inspect.Database, report reportFunc) {
+			if len(opts.AllowedOwners) == 0 {
+				return
+			}
+			db.EachOwned(func(kind, object, owner string) {
+				for _, p := range opts.AllowedOwners {
+					if ok, _ := path.Match(p, owner); ok {
+						return
+					}
+				}
+				report(object, "", "%s is owned by %s, not an allowed owner", kind, owner)
+			})
+		}},
+		&builtin{"definer-search-path", "Security definer functions should pin a safe search_path.", Error, definerSearchPath},
+		&builtin{"public-grant", "PUBLIC should not have privileges beyond the defaults.", Warning, publicGrant},
+		&builtin{"table-without-comment", "Tables should be documented with a comment.", Note, tableWithoutComment},
+		&builtin{"rewrite-rule", "Rules should be replaced by triggers.", Note, rewriteRule},
+		&builtin{"matview-without-unique-index", "Materialized views should be refreshable CONCURRENTLY.", Warning, matviewWithoutUniqueIndex},
+		&builtin{"audit-columns", "Tables should have the configured audit columns.", Warning, audit},
+	}, nil
+}
+
+func noPrimaryKey(db *inspect.Database, report reportFunc) {
+	baseTables(db, func(t *inspect.Table) {
+		// Partitions inherit the key of their parent.
+		if len(t.PK.Columns) == 0 && t.PartitionOf == "" {
+			report(qualified(t), "", "table has no primary key")
+		}
+	})
+}
+
+// coveredBy reports whether the leading columns of an index are exactly
+// columns, in any order.
+func coveredBy(t *inspect.Table, columns []string) bool {
+	for _, idx := range t.Indexes {
+		if idx.Predicate != "" || len(idx.Columns) < len(columns) {
+			continue
+		}
+		lead := make(map[string]bool, len(columns))
+		for _, c := range idx.Columns[:len(columns)] {
+			lead[c] = true
+		}
+		ok := true
+		for _, c := range columns {
+			ok = ok && lead[c]
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func fkWithoutIndex(db *inspect.Database, report reportFunc) {
+	baseTables(db, func(t *inspect.Table) {
+		for _, fk := range t.FKs {
+			if !coveredBy(t, fk.Columns) {
+				report(qualified(t), fk.Name, "foreign key %s (%s) has no index, deletes and updates of %s.%s scan the table",
+					fk.Name, strings.Join(fk.Columns, ", "), fk.RefSchema, fk.RefTable)
+			}
+		}
+	})
+}
+
+func duplicateIndex(db *inspect.Database, report reportFunc) {
+	baseTables(db, func(t *inspect.Table) {
+		for i, a := range t.Indexes {
+			for _, b := range t.Indexes[i+1:] {
+				if a.Method == b.Method && a.Predicate == b.Predicate &&
+					strings.Join(a.Columns, ",") == strings.Join(b.Columns, ",") {
+					report(qualified(t), a.Name+","+b.Name, "indexes %s and %s are on the same columns (%s)", a.Name, b.Name,
+						strings.Join(a.Columns, ", "))
+				}
+			}
+		}
+	})
+}
+
+func rlsWithoutPolicy(db *inspect.Database, report reportFunc) {
+	baseTables(db, func(t *inspect.Table) {
+		if t.RowSecurity && len(t.Policies) == 0 {
+			report(qualified(t), "", "row level security is enabled without policies, only the owner sees rows")
+		}
+	})
+}
+
+func tableWithoutComment(db *inspect.Database, report reportFunc) {
+	baseTables(db, func(t *inspect.Table) {
+		if t.Comment == "" && t.PartitionOf == "" {
+			report(qualified(t), "", "table has no comment")
+		}
+	})
+}
+
+func matviewWithoutUniqueIndex(db *inspect.Database, report reportFunc) {
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type == "MATERIALIZED VIEW" && !t.CanRefreshConcurrently() {
+				report(qualified(t), "", "materialized view has no unique index on plain columns, "+
+					"REFRESH cannot run CONCURRENTLY and blocks readers")
+			}
+		}
+	}
+}
+
+func rewriteRule(db *inspect.Database, report reportFunc) {
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			for _, r := range t.Rules {
+				alt := "a trigger"
+				if t.Type == "VIEW" {
+					alt = "an INSTEAD OF trigger"
+				}
+				report(qualified(t), r.Name, "rule %s rewrites %s statements, %s is easier to follow", r.Name, r.Event, alt)
+			}
+		}
+	}
+}
+
+// identifierHazard describes why name needs quoting, or is empty.
+func identifierHazard(name string) string {
+	switch {
+	case inspect.IsReserved(name):
+		return "is a reserved word"
+	case strings.ToLower(name) != name:
+		return "is mixed case"
+	case inspect.NeedsQuoting(name):
+		return "contains characters requiring quotes"
+	}
+	return ""
+}
+
+func quotedIdentifier(db *inspect.Database, report reportFunc) {
+	for _, s := range db.Schemas {
+		if h := identifierHazard(s.Name); h != "" {
+			report(s.Name, "", "schema name %q %s", s.Name, h)
+		}
+		for _, t := range s.Tables {
+			if h := identifierHazard(t.Name); h != "" {
+				report(qualified(t), "", "table name %q %s", t.Name, h)
+			}
+			for _, c := range t.Columns {
+				if h := identifierHazard(c.Name); h != "" {
+					report(qualified(t)+"."+c.Name, "", "column name %q %s", c.Name, h)
+				}
+			}
+		}
+	}
+}
+
+func timestampWithoutTimeZone(db *inspect.Database, report reportFunc) {
+	baseTables(db, func(t *inspect.Table) {
+		for _, c := range t.Columns {
+			if c.UDTName == "timestamp" {
+				report(qualified(t)+"."+c.Name, "", "column %s is timestamp without time zone, its meaning depends on the writer's time zone", c.Name)
+			}
+		}
+	})
+}
+
+func timeWithTimeZone(db *inspect.Database, report reportFunc) {
+	baseTables(db, func(t *inspect.Table) {
+		for _, c := range t.Columns {
+			if c.UDTName == "timetz" {
+				report(qualified(t)+"."+c.Name, "", "column %s is time with time zone, an offset without a date ignores daylight saving", c.Name)
+			}
+		}
+	})
+}
+
+// mixedTimestamps reports schemas with both timestamp and timestamptz
+// columns, naming the first column of the less used type.
+func mixedTimestamps(db *inspect.Database, report reportFunc) {
+	for _, s := range db.Schemas {
+		columns := map[string][]string{}
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			for _, c := range t.Columns {
+				if c.UDTName == "timestamp" || c.UDTName == "timestamptz" {
+					columns[c.UDTName] = append(columns[c.UDTName], t.Name+"."+c.Name)
+				}
+			}
+		}
+		plain, tz := columns["timestamp"], columns["timestamptz"]
+		if len(plain) == 0 || len(tz) == 0 {
+			continue
+		}
+		odd, oddType := plain, "timestamp"
+		if len(tz) < len(plain) {
+			odd, oddType = tz, "timestamptz"
+		}
+		report(s.Name, "", "schema mixes %d timestamp and %d timestamptz columns, e.g. %s is %s",
+			len(plain), len(tz), odd[0], oddType)
+	}
+}
+
+func ownedBySuperuser(db *inspect.Database, report reportFunc) {
+	db.EachOwned(func(kind, object, owner string) {
+		// The public schema belongs to the bootstrap superuser before
+		// PostgreSQL 15.
+		if object != "public" && db.IsSuperuser(owner) {
+			report(object, "", "%s is owned by superuser %s", kind, owner)
+		}
+	})
+}
+
+func publicGrant(db *inspect.Database, report reportFunc) {
+	for _, s := range db.Schemas {
+		if privs := inspect.PublicPrivileges(s.ACL); strings.IndexByte(privs, 'C') >= 0 {
+			report(s.Name, "", "PUBLIC may create objects in schema %s", s.Name)
+		}
+		for _, t := range s.Tables {
+			if privs := inspect.PublicPrivileges(t.ACL); privs != "" {
+				report(qualified(t), "", "PUBLIC is granted %s", inspect.PrivilegeList(privs))
+			}
+		}
+		for _, f := range s.Functions {
+			// EXECUTE for PUBLIC is the default and harmless for functions
+			// running with the privileges of the caller.
+			if privs := inspect.PublicPrivileges(f.ACL); f.SecurityDefiner && strings.IndexByte(privs, 'X') >= 0 {
+				report(s.Name+"."+f.Signature(), "", "PUBLIC may execute security definer function %s owned by %s",
+					f.Name, f.Owner)
+			}
+		}
+	}
+}
+
+// SearchPathIssue describes why the search_path of a security definer
+// function lets callers substitute objects it uses, or returns an empty
+// string.
+func SearchPathIssue(db *inspect.Database, f *inspect.Function) string {
+	path, ok := f.SearchPath()
+	if !ok {
+		return "search_path is not set, the caller's applies"
+	}
+	var schemas []string
+	for _, s := range strings.Split(path, ",") {
+		schemas = append(schemas, strings.Trim(strings.TrimSpace(s), `"`))
+	}
+	for _, name := range schemas {
+		if name == "$user" {
+			return "search_path includes $user"
+		}
+		if s := db.Schema(name); s != nil && strings.IndexByte(inspect.PublicPrivileges(s.ACL), 'C') >= 0 {
+			return fmt.Sprintf("search_path includes %s, where PUBLIC may create objects", name)
+		}
+	}
+	// pg_temp is searched first for relations unless listed explicitly.
+	if schemas[len(schemas)-1] != "pg_temp" {
+		return "pg_temp is not last in search_path"
+	}
+	return ""
+}
+
+func definerSearchPath(db *inspect.Database, report reportFunc) {
+	for _, s := range db.Schemas {
+		for i := range s.Functions {
+			f := &s.Functions[i]
+			if !f.SecurityDefiner {
+				continue
+			}
+			if issue := SearchPathIssue(db, f); issue != "" {
+				report(s.Name+"."+f.Signature(), "", "security definer function owned by %s: %s", f.Owner, issue)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// cleanDB returns a database without findings of the built-in rules.
+func cleanDB() *inspect.Database {
+	users := &inspect.Table{Schema: "public", Name: "users", Type: "BASE TABLE", Owner: "app", Comment: "Users.",
+		Columns: []inspect.Column{
+			{Name: "id", UDTName: "int8", Position: 1},
+			{Name: "created_at", UDTName: "timestamptz", Position: 2},
+		},
+		PK:      inspect.PrimaryKey{Name: "users_pkey", Columns: []string{"id"}},
+		Indexes: []inspect.Index{{Name: "users_pkey", Method: "btree", Columns: []string{"id"}, Unique: true, Primary: true}},
+	}
+	orders := &inspect.Table{Schema: "public", Name: "orders", Type: "BASE TABLE", Owner: "app", Comment: "Orders.",
+		Columns: []inspect.Column{
+			{Name: "id", UDTName: "int8", Position: 1},
+			{Name: "user_id", UDTName: "int8", Position: 2},
+		},
+		PK: inspect.PrimaryKey{Name: "orders_pkey", Columns: []string{"id"}},
+		Indexes: []inspect.Index{
+			{Name: "orders_pkey", Method: "btree", Columns: []string{"id"}, Unique: true, Primary: true},
+			{Name: "orders_user_id_idx", Method: "btree", Columns: []string{"user_id", "id"}},
+		},
+		FKs: []inspect.ForeignKey{{Name: "orders_user_id_fkey", Columns: []string{"user_id"},
+			RefSchema: "public", RefTable: "users", RefColumns: []string{"id"}}},
+	}
+	return &inspect.Database{Name: "app",
+		Roles:   []inspect.Role{{Name: "app"}, {Name: "postgres", Superuser: true}},
+		Schemas: []*inspect.Schema{{Name: "public", Owner: "app", Tables: []*inspect.Table{users, orders}}},
+	}
+}
+
+func TestBuiltinRules(t *testing.T) {
+	type finding struct{ rule, object, key string }
+	orders := func(db *inspect.Database) *inspect.Table { return db.Schemas[0].Tables[1] }
+	tests := []struct {
+		name   string
+		opts   Options
+		modify func(db *inspect.Database)
+		want   []finding
+	}{
+		{"clean", Options{}, func(db *inspect.Database) {}, nil},
+		{"no primary key", Options{}, func(db *inspect.Database) {
+			orders(db).PK = inspect.PrimaryKey{}
+		}, []finding{{"no-primary-key", "public.orders", ""}}},
+		{"partition without primary key", Options{}, func(db *inspect.Database) {
+			orders(db).PK = inspect.PrimaryKey{}
+			orders(db).PartitionOf = "public.all_orders"
+		}, nil},
+		{"foreign key without index", Options{}, func(db *inspect.Database) {
+			orders(db).Indexes = orders(db).Indexes[:1]
+		}, []finding{{"fk-without-index", "public.orders", "orders_user_id_fkey"}}},
+		{"foreign key behind a leading column", Options{}, func(db *inspect.Database) {
+			orders(db).Indexes[1].Columns = []string{"id", "user_id"}
+		}, []finding{{"fk-without-index", "public.orders", "orders_user_id_fkey"}}},
+		{"foreign key with partial index", Options{}, func(db *inspect.Database) {
+			orders(db).Indexes[1].Predicate = "user_id IS NOT NULL"
+		}, []finding{{"fk-without-index", "public.orders", "orders_user_id_fkey"}}},
+		{"duplicate index", Options{}, func(db *inspect.Database) {
+			orders(db).Indexes = append(orders(db).Indexes, inspect.Index{Name: "orders_user_idx", Method: "btree",
+				Columns: []string{"user_id", "id"}})
+		}, []finding{{"duplicate-index", "public.orders", "orders_user_id_idx,orders_user_idx"}}},
+		{"row security without policies", Options{}, func(db *inspect.Database) {
+			orders(db).RowSecurity = true
+		}, []finding{{"rls-without-policy", "public.orders", ""}}},
+		{"mixed case column", Options{}, func(db *inspect.Database) {
+			orders(db).Columns[1].Name = "userId"
+			orders(db).Indexes[1].Columns[0] = "userId"
+			orders(db).FKs[0].Columns[0] = "userId"
+		}, []finding{{"quoted-identifier", "public.orders.userId", ""}}},
+		{"reserved table name", Options{}, func(db *inspect.Database) {
+			orders(db).Name = "order"
+		}, []finding{{"quoted-identifier", "public.order", ""}}},
+		{"wide table", Options{MaxColumns: 1}, func(db *inspect.Database) {}, []finding{
+			{"wide-table", "public.orders", ""},
+			{"wide-table", "public.users", ""},
+		}},
+		{"wide row", Options{}, func(db *inspect.Database) {
+			orders(db).Stats.RowWidth = 4000
+		}, []finding{{"wide-row", "public.orders", ""}}},
+		{"too many indexes", Options{MaxIndexes: 1}, func(db *inspect.Database) {}, []finding{
+			{"too-many-indexes", "public.orders", ""},
+		}},
+		{"mixed timestamps", Options{}, func(db *inspect.Database) {
+			orders(db).Columns = append(orders(db).Columns, inspect.Column{Name: "paid_at", UDTName: "timestamp", Position: 3})
+		}, []finding{
+			{"mixed-timestamps", "public", ""},
+			{"timestamp-without-time-zone", "public.orders.paid_at", ""},
+		}},
+		{"time with time zone", Options{}, func(db *inspect.Database) {
+			orders(db).Columns = append(orders(db).Columns, inspect.Column{Name: "opens", UDTName: "timetz", Position: 3})
+		}, []finding{{"timetz", "public.orders.opens", ""}}},
+		{"date for an instant", Options{}, func(db *inspect.Database) {
+			orders(db).Columns = append(orders(db).Columns, inspect.Column{Name: "shipped_at", UDTName: "date", Position: 3})
+		}, []finding{{"date-for-instant", "public.orders.shipped_at", ""}}},
+		{"owned by superuser", Options{}, func(db *inspect.Database) {
+			orders(db).Owner = "postgres"
+		}, []finding{{"owned-by-superuser", "public.orders", ""}}},
+		{"public schema owned by superuser", Options{}, func(db *inspect.Database) {
+			db.Schemas[0].Owner = "postgres"
+		}, nil},
+		{"disallowed owner", Options{AllowedOwners: []string{"app", "app_*"}}, func(db *inspect.Database) {
+			orders(db).Owner = "alice"
+		}, []finding{{"disallowed-owner", "public.orders", ""}}},
+		{"security definer without search_path", Options{}, func(db *inspect.Database) {
+			db.Schemas[0].Functions = []inspect.Function{{Name: "touch", Owner: "app", SecurityDefiner: true}}
+		}, []finding{{"definer-search-path", "public.touch()", ""}}},
+		{"security definer with safe search_path", Options{}, func(db *inspect.Database) {
+			db.Schemas[0].Functions = []inspect.Function{{Name: "touch", Owner: "app", SecurityDefiner: true,
+				Config: []string{"search_path=public, pg_temp"}}}
+		}, nil},
+		{"PUBLIC may create in schema", Options{}, func(db *inspect.Database) {
+			db.Schemas[0].ACL = []string{"app=UC/app", "=UC/app"}
+		}, []finding{{"public-grant", "public", ""}}},
+		{"PUBLIC granted a table", Options{}, func(db *inspect.Database) {
+			orders(db).ACL = []string{"app=arwdDxt/app", "=r/app"}
+		}, []finding{{"public-grant", "public.orders", ""}}},
+		{"table without comment", Options{}, func(db *inspect.Database) {
+			orders(db).Comment = ""
+		}, []finding{{"table-without-comment", "public.orders", ""}}},
+		{"rewrite rule", Options{}, func(db *inspect.Database) {
+			orders(db).Rules = []inspect.Rule{{Name: "orders_log", Event: "INSERT"}}
+		}, []finding{{"rewrite-rule", "public.orders", "orders_log"}}},
+		{"materialized view without unique index", Options{}, func(db *inspect.Database) {
+			db.Schemas[0].Tables = append(db.Schemas[0].Tables, &inspect.Table{Schema: "public", Name: "totals",
+				Type: "MATERIALIZED VIEW", Owner: "app", Columns: []inspect.Column{{Name: "user_id", UDTName: "int8", Position: 1}}})
+		}, []finding{{"matview-without-unique-index", "public.totals", ""}}},
+		{"audit columns", Options{AuditColumns: []AuditColumn{
+			{Name: "created_at", Types: []string{"timestamptz"}, NotNull: true},
+			{Name: "deleted_at", Optional: true},
+		}}, func(db *inspect.Database) {
+			db.Schemas[0].Tables[0].Columns[1].Nullable = true
+		}, []finding{
+			{"audit-columns", "public.orders", "created_at"},
+			{"audit-columns", "public.users.created_at", "nullable"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := Builtin(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := cleanDB()
+			tt.modify(db)
+			findings := Run(db, rules)
+			if len(findings) != len(tt.want) {
+				t.Fatalf("got %v, want %v", findings, tt.want)
+			}
+			for i, f := range findings {
+				if got := (finding{f.Rule, f.Object, f.Key}); got != tt.want[i] {
+					t.Errorf("finding %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuiltinOptions(t *testing.T) {
+	if _, err := Builtin(Options{InstantColumns: "("}); err == nil {
+		t.Error("accepted an invalid instant_columns")
+	}
+	if _, err := Builtin(Options{AuditColumns: []AuditColumn{{Types: []string{"timestamptz"}}}}); err == nil {
+		t.Error("accepted an audit column without a name")
+	}
+	if _, err := Builtin(Options{AuditColumns: []AuditColumn{{Name: "created_at", Default: "("}}}); err == nil {
+		t.Error("accepted an invalid audit column default")
+	}
+}
@@ -0,0 +1,71 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// disableDirective matches pg-inspector:disable=rule[,rule...] in a comment.
+// The rule all disables every rule.
+var disableDirective = regexp.MustCompile(`pg-inspector:disable=([a-z0-9,-]+)`)
+
+// disabledRules returns the rules disabled by the directives in comment.
+func disabledRules(comment string) map[string]bool {
+	var rules map[string]bool
+	for _, m := range disableDirective.FindAllStringSubmatch(comment, -1) {
+		if rules == nil {
+			rules = make(map[string]bool)
+		}
+		for _, r := range strings.Split(m[1], ",") {
+			if r != "" {
+				rules[r] = true
+			}
+		}
+	}
+	return rules
+}
+
+// suppressed reports whether the comment of the object of f, or of the
+// table a column belongs to, disables the rule of f. Objects are schemas,
+// tables, columns and functions; they are looked up by their full names, as
+// names may contain dots.
+func suppressed(db *inspect.Database, f Finding) bool {
+	for _, comment := range objectComments(db, f.Object) {
+		if rules := disabledRules(comment); rules[f.Rule] || rules["all"] {
+			return true
+		}
+	}
+	return false
+}
+
+// objectComments returns the comment of the object named object and, for a
+// column, the comment of its table.
+func objectComments(db *inspect.Database, object string) []string {
+	for _, s := range db.Schemas {
+		if object == s.Name {
+			return []string{s.Comment}
+		}
+		if !strings.HasPrefix(object, s.Name+".") {
+			continue
+		}
+		name := strings.TrimPrefix(object, s.Name+".")
+		for _, t := range s.Tables {
+			if name == t.Name {
+				return []string{t.Comment}
+			}
+			if strings.HasPrefix(name, t.Name+".") {
+				if c := t.Column(strings.TrimPrefix(name, t.Name+".")); c != nil {
+					return []string{t.Comment, c.Comment}
+				}
+			}
+		}
+		for i := range s.Functions {
+			if name == s.Functions[i].Signature() {
+				return []string{s.Functions[i].Comment}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+func TestDisabledRules(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    map[string]bool
+	}{
+		{"", nil},
+		{"Orders of customers.", nil},
+		{"Legacy. pg-inspector:disable=no-primary-key", map[string]bool{"no-primary-key": true}},
+		{"pg-inspector:disable=wide-table,wide-row", map[string]bool{"wide-table": true, "wide-row": true}},
+		{"pg-inspector:disable=wide-table pg-inspector:disable=all", map[string]bool{"wide-table": true, "all": true}},
+		{"pg-inspector:disable=timetz,", map[string]bool{"timetz": true}},
+		{"pg-inspector:disable=", nil},
+	}
+	for _, tt := range tests {
+		if got := disabledRules(tt.comment); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("disabledRules(%q) = %v, want %v", tt.comment, got, tt.want)
+		}
+	}
+}
+
+func TestSuppressed(t *testing.T) {
+	tests := []struct {
+		name          string
+		tableComment  string
+		columnComment string
+		want          []string
+	}{
+		{"none", "Orders.", "", []string{"no-primary-key", "timetz"}},
+		{"table", "Orders. pg-inspector:disable=no-primary-key", "", []string{"timetz"}},
+		{"table disables column rules", "Orders. pg-inspector:disable=timetz", "", []string{"no-primary-key"}},
+		{"column", "Orders.", "pg-inspector:disable=timetz", []string{"no-primary-key"}},
+		{"column does not disable table rules", "Orders.", "pg-inspector:disable=no-primary-key", []string{"no-primary-key", "timetz"}},
+		{"all", "pg-inspector:disable=all", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := cleanDB()
+			orders := db.Schemas[0].Tables[1]
+			orders.PK = inspect.PrimaryKey{}
+			orders.Comment = tt.tableComment
+			orders.Columns = append(orders.Columns, inspect.Column{Name: "opens", UDTName: "timetz", Position: 3,
+				Comment: tt.columnComment})
+			var rules []string
+			for _, f := range run(t, db) {
+				rules = append(rules, f.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("findings of %v, want %v", rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuppressedObjects(t *testing.T) {
+	disable := func(rule string) string { return "Kept for now. pg-inspector:disable=" + rule }
+	db := &inspect.Database{Schemas: []*inspect.Schema{
+		{Name: "public", Comment: disable("mixed-timestamps,public-grant"),
+			Tables: []*inspect.Table{
+				{Schema: "public", Name: "events", Comment: "Events."},
+				{Schema: "public", Name: "v1.events", Comment: disable("no-primary-key"),
+					Columns: []inspect.Column{{Name: "at", Comment: disable("timestamp-without-time-zone")}}},
+			},
+			Functions: []inspect.Function{
+				{Name: "login", Arguments: "u public.users", Comment: disable("definer-search-path")},
+				{Name: "logout", Arguments: "", Comment: "Ends a session."},
+			}},
+		{Name: "my.app", Tables: []*inspect.Table{{Schema: "my.app", Name: "t", Comment: disable("all")}}},
+	}}
+	tests := []struct {
+		rule, object string
+		want         bool
+	}{
+		{"mixed-timestamps", "public", true},
+		{"public-grant", "public", true},
+		{"owned-by-superuser", "public", false},
+		{"public-grant", "public.events", false}, // a schema's comment does not cover its objects
+		{"no-primary-key", "public.v1.events", true},
+		{"timestamp-without-time-zone", "public.v1.events.at", true},
+		{"definer-search-path", "public.login(u public.users)", true},
+		{"public-grant", "public.login(u public.users)", false},
+		{"definer-search-path", "public.logout()", false},
+		{"no-primary-key", "my.app.t", true},
+		{"no-primary-key", "my.app.missing", false},
+		{"no-primary-key", "other", false},
+	}
+	for _, tt := range tests {
+		if got := suppressed(db, Finding{Rule: tt.rule, Object: tt.object}); got != tt.want {
+			t.Errorf("suppressed(%s on %s) = %t, want %t", tt.rule, tt.object, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+	"github.com/lib/pq"
+)
+
+// version is the release of the binary, set with
+// -ldflags "-X main.version=v1.2.3". Module builds fall back to the version
+// of the main module.
+var version string
+
+func toolVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// manifest describes a run for -manifest: what ran, against which
+// databases, how long each phase took and what was found, so the artifacts
+// of a CI job can be audited and reproduced. Connection strings are only
+// recorded as hashes. A nil manifest records nothing.
+type manifest struct {
+	Tool      string            `json:"tool"`
+	Version   string            `json:"version"`
+	GoVersion string            `json:"go_version"`
+	Command   string            `json:"command"`
+	Args      []string          `json:"args"`
+	Filters   map[string]string `json:"filters,omitempty"`
+	StartedAt time.Time         `json:"started_at"`
+	// DurationMS is the time of the whole run.
+	DurationMS  float64              `json:"duration_ms"`
+	ExitCode    int                  `json:"exit_code"`
+	Targets     []string             `json:"targets"`
+	Phases      []*manifestPhase     `json:"phases"`
+	Inspections []manifestInspection `json:"inspections"`
+
+	path  string
+	mu    sync.Mutex
+	once  sync.Once
+	conns map[*dbr.Connection]string // target hashes of the open connections
+}
+
+// manifestPhase sums the runs of a phase: connect, migrations or inspect.
+type manifestPhase struct {
+	Name       string  `json:"name"`
+	Count      int     `json:"count"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+type manifestInspection struct {
+	Target        string         `json:"target"`
+	Database      string         `json:"database"`
+	ServerVersion string         `json:"server_version"`
+	InspectedAt   time.Time      `json:"inspected_at"`
+	Objects       map[string]int `json:"objects"`
+}
+
+func newManifest(path, command string, args []string) *manifest {
+	m := &manifest{
+		Tool:        "pg-inspector",
+		Version:     toolVersion(),
+		GoVersion:   runtime.Version(),
+		Command:     command,
+		Filters:     make(map[string]string),
+		StartedAt:   time.Now().UTC(),
+		Targets:     []string{},
+		Phases:      []*manifestPhase{},
+		Inspections: []manifestInspection{},
+		path:        path,
+		conns:       make(map[*dbr.Connection]string),
+	}
+	if m.Command == "" {
+		m.Command = "inspect"
+	}
+	for _, arg := range args {
+		m.Args = append(m.Args, redactArg(arg))
+	}
+	return m
+}
+
+// redactArg replaces a connection string, or the connection string value
+// of a -flag=value argument, with its target hash.
+func redactArg(arg string) string {
+	if strings.HasPrefix(arg, "-") {
+		if i := strings.Index(arg, "="); i > 0 && isConnString(arg[i+1:]) {
+			return arg[:i+1] + "target:" + targetHash(arg[i+1:])
+		}
+		return arg
+	}
+	if isConnString(arg) {
+		return "target:" + targetHash(arg)
+	}
+	return arg
+}
+
+var dsnValue = regexp.MustCompile(`(?:^|\s)([a-z_]+)\s*=\s*('(?:[^'\\]|\\.)*'|\S*)`)
+
+// targetHash identifies the server, port, database and user of dsn, taking
+// those not set from the PG* environment variables like lib/pq, without
+// revealing them or the password.
+func targetHash(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if kv, err := pq.ParseURL(dsn); err == nil {
+			dsn = kv
+		}
+	}
+	values := make(map[string]string)
+	for _, m := range dsnValue.FindAllStringSubmatch(dsn, -1) {
+		v := m[2]
+		if strings.HasPrefix(v, "'") {
+			v = strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(strings.Trim(v, "'"))
+		}
+		values[m[1]] = v
+	}
+	var parts []string
+	for _, key := range [][2]string{{"host", "PGHOST"}, {"port", "PGPORT"}, {"dbname", "PGDATABASE"}, {"user", "PGUSER"}} {
+		v := values[key[0]]
+		if v == "" {
+			v = os.Getenv(key[1])
+		}
+		parts = append(parts, v)
+	}
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(h[:8])
+}
+
+// phase starts timing a phase; the returned function ends it.
+func (m *manifest) phase(name string) func() {
+	if m == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		ms := float64(time.Since(start)) / float64(time.Millisecond)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, p := range m.Phases {
+			if p.Name == name {
+				p.Count++
+				p.DurationMS += ms
+				return
+			}
+		}
+		m.Phases = append(m.Phases, &manifestPhase{Name: name, Count: 1, DurationMS: ms})
+	}
+}
+
+// connected records the target of a connection.
+func (m *manifest) connected(conn *dbr.Connection, dsn string) {
+	if m == nil {
+		return
+	}
+	hash := targetHash(dsn)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conns[conn] = hash
+	for _, t := range m.Targets {
+		if t == hash {
+			return
+		}
+	}
+	m.Targets = append(m.Targets, hash)
+}
+
+// inspected records an inspection through conn.
+func (m *manifest) inspected(conn *dbr.Connection, serverVersion string, db *inspect.Database) {
+	if m == nil {
+		return
+	}
+	objects := make(map[string]int)
+	for _, c := range db.Counts() {
+		objects[c.Kind] = c.N
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Inspections = append(m.Inspections, manifestInspection{
+		Target:        m.conns[conn],
+		Database:      db.Name,
+		ServerVersion: serverVersion,
+		InspectedAt:   db.InspectedAt,
+		Objects:       objects,
+	})
+}
+
+// write writes the manifest with the exit code of the run, once: a run
+// ends either by returning from main or through logrus exit.
+func (m *manifest) write(exitCode int) error {
+	if m == nil {
+		return nil
+	}
+	var err error
+	m.once.Do(func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.ExitCode = exitCode
+		m.DurationMS = float64(time.Since(m.StartedAt)) / float64(time.Millisecond)
+		var b []byte
+		if b, err = json.MarshalIndent(m, "", "  "); err != nil {
+			return
+		}
+		if err = ioutil.WriteFile(m.path, append(b, '\n'), 0644); err != nil {
+			err = fmt.Errorf("write manifest: %v", err)
+		}
+	})
+	return err
+}
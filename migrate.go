@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datainq/pq-inspector/diff"
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/datainq/pq-inspector/migrations"
+	"github.com/datainq/pq-inspector/pgcontainer"
+)
+
+// migrationsPrefix marks a directory of migrations where a snapshot is
+// expected, e.g. drift -against=migrations:db/migrations.
+const migrationsPrefix = "migrations:"
+
+// applyMigrations applies the migrations of dir to a temporary database
+// and returns its connection string and a function removing it. The
+// database is created on the -scratch-db server or, without one, in a
+// PostgreSQL container of the given image.
+func (a *app) applyMigrations(dir, image string) (string, func(), error) {
+	if a.scratch != "" {
+		return a.scratchDatabase(dir)
+	}
+	a.log.Infof("starting %s", image)
+	c, err := pgcontainer.Start(pgcontainer.Options{Image: image})
+	if err != nil {
+		return "", nil, fmt.Errorf("start container: %v", err)
+	}
+	n, err := c.Migrate(dir)
+	if err != nil {
+		c.Stop()
+		return "", nil, err
+	}
+	a.log.Infof("applied %d migrations from %s", n, dir)
+	return c.DSN, func() { c.Stop() }, nil
+}
+
+// scratchDatabase creates a database on the -scratch-db server and applies
+// the migrations of dir to it.
+func (a *app) scratchDatabase(dir string) (string, func(), error) {
+	admin, err := a.open(a.scratch)
+	if err != nil {
+		return "", nil, err
+	}
+	name := fmt.Sprintf("pg_inspector_%x", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + inspect.QuoteIdent(name)); err != nil {
+		admin.Close()
+		return "", nil, fmt.Errorf("create scratch database: %v", err)
+	}
+	drop := func() {
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + inspect.QuoteIdent(name)); err != nil {
+			a.log.WithError(err).Warnf("drop scratch database %s", name)
+		}
+		admin.Close()
+	}
+	dsn, err := setParam(a.scratch, "dbname", name)
+	if err != nil {
+		drop()
+		return "", nil, err
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		drop()
+		return "", nil, err
+	}
+	n, err := migrations.Apply(db, dir)
+	db.Close()
+	if err != nil {
+		drop()
+		return "", nil, err
+	}
+	a.log.Infof("applied %d migrations from %s to %s", n, dir, name)
+	return dsn, drop, nil
+}
+
+// loadMigrations returns the schema the migrations of dir build.
+func (a *app) loadMigrations(dir string) (*inspect.Database, error) {
+	dsn, cleanup, err := a.applyMigrations(dir, a.image)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	conn, err := a.open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return a.inspect(conn.NewSession(nil))
+}
+
+// loadReference reads the snapshot at path or, for migrations:dir, the
+// schema the migrations of dir build.
+func (a *app) loadReference(path string) (*inspect.Database, error) {
+	if strings.HasPrefix(path, migrationsPrefix) {
+		return a.loadMigrations(strings.TrimPrefix(path, migrationsPrefix))
+	}
+	return readSnapshot(path)
+}
+
+// ignoreBookkeeping leaves the bookkeeping tables of migration tools in
+// dbs, and the applied migrations, out of a comparison with the schema
+// built by applying migration files, which has none.
+func ignoreBookkeeping(opts diff.Options, dbs ...*inspect.Database) diff.Options {
+	opts.IgnoreObjects = append([]string(nil), opts.IgnoreObjects...)
+	for _, db := range dbs {
+		for _, m := range db.Migrations {
+			opts.IgnoreObjects = append(opts.IgnoreObjects, m.Table)
+		}
+	}
+	opts.IgnoreAttributes = append(append([]string(nil), opts.IgnoreAttributes...), "migrations")
+	return opts
+}
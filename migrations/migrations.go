@@ -0,0 +1,93 @@
+// Package migrations applies a directory of SQL migration files to a
+// database, so the schema the migrations describe can be inspected.
+//
+// Files are applied in version order: by their leading number, ignoring a
+// V prefix, then by name. Down migrations are skipped: *.down.sql files of
+// golang-migrate, U*.sql undo files of Flyway and the Down sections of
+// goose files.
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// File is a migration file.
+type File struct {
+	Path    string
+	Version int64 // leading number of the name, 0 if none
+}
+
+var versionPrefix = regexp.MustCompile(`^[Vv]?(\d+)`)
+
+// Files returns the up migrations in dir in the order they apply.
+func Files(dir string) ([]File, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".down.sql") ||
+			strings.HasPrefix(name, "U") && versionPrefix.MatchString(name[1:]) {
+			continue
+		}
+		f := File{Path: filepath.Join(dir, name)}
+		if m := versionPrefix.FindStringSubmatch(name); m != nil {
+			f.Version, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		files = append(files, f)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Version != files[j].Version {
+			return files[i].Version < files[j].Version
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// upSection returns the SQL of a migration to apply: the Up section of a
+// goose migration, otherwise all of it.
+func upSection(sql string) string {
+	up := strings.Index(sql, "-- +goose Up")
+	if up < 0 {
+		return sql
+	}
+	sql = sql[up:]
+	if down := strings.Index(sql, "-- +goose Down"); down >= 0 {
+		sql = sql[:down]
+	}
+	return sql
+}
+
+// Apply runs the migrations of dir on db, each in one round trip, and
+// returns the number applied. It stops at the first failing file.
+func Apply(db *sql.DB, dir string) (int, error) {
+	files, err := Files(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, fmt.Errorf("no migrations in %s", dir)
+	}
+	for i, f := range files {
+		b, err := ioutil.ReadFile(f.Path)
+		if err != nil {
+			return i, err
+		}
+		// Without arguments lib/pq sends the file as a simple query,
+		// which may hold several statements.
+		if _, err := db.Exec(upSection(string(b))); err != nil {
+			return i, fmt.Errorf("apply %s: %v", filepath.Base(f.Path), err)
+		}
+	}
+	return len(files), nil
+}
@@ -0,0 +1,161 @@
+package migrations
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// migrationsDir creates a directory with the given files and contents.
+func migrationsDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, sql := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(sql), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{"golang-migrate",
+			[]string{"2_orders.up.sql", "2_orders.down.sql", "10_index.up.sql", "1_users.up.sql", "1_users.down.sql"},
+			[]string{"1_users.up.sql", "2_orders.up.sql", "10_index.up.sql"}},
+		{"flyway",
+			[]string{"V10__index.sql", "V2__orders.sql", "U2__orders.sql", "V1__users.sql"},
+			[]string{"V1__users.sql", "V2__orders.sql", "V10__index.sql"}},
+		{"goose timestamps",
+			[]string{"20240301120000_orders.sql", "20240101090000_users.sql"},
+			[]string{"20240101090000_users.sql", "20240301120000_orders.sql"}},
+		{"same version by name",
+			[]string{"1_b.sql", "1_a.sql"},
+			[]string{"1_a.sql", "1_b.sql"}},
+		{"unversioned first",
+			[]string{"1_users.sql", "schema.sql", "README.md", "Users.sql"},
+			[]string{"Users.sql", "schema.sql", "1_users.sql"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := make(map[string]string)
+			for _, name := range tt.files {
+				files[name] = ""
+			}
+			dir := migrationsDir(t, files)
+			defer os.RemoveAll(dir)
+			if err := os.Mkdir(filepath.Join(dir, "3_seed.sql"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			found, err := Files(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, f := range found {
+				names = append(names, filepath.Base(f.Path))
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("files %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileVersions(t *testing.T) {
+	dir := migrationsDir(t, map[string]string{"V7__a.sql": "", "20240101090000_b.sql": "", "init.sql": ""})
+	defer os.RemoveAll(dir)
+	found, err := Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{0, 7, 20240101090000}
+	for i, f := range found {
+		if f.Version != want[i] {
+			t.Errorf("version of %s = %d, want %d", filepath.Base(f.Path), f.Version, want[i])
+		}
+	}
+}
+
+func TestUpSection(t *testing.T) {
+	tests := []struct {
+		sql, want string
+	}{
+		{"CREATE TABLE a (id int);\n", "CREATE TABLE a (id int);\n"},
+		{"-- +goose Up\nCREATE TABLE a (id int);\n-- +goose Down\nDROP TABLE a;\n",
+			"-- +goose Up\nCREATE TABLE a (id int);\n"},
+		{"-- comment\n-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE a (id int);\n-- +goose StatementEnd\n",
+			"-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE a (id int);\n-- +goose StatementEnd\n"},
+	}
+	for _, tt := range tests {
+		if got := upSection(tt.sql); got != tt.want {
+			t.Errorf("upSection(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := migrationsDir(t, map[string]string{
+		"1_users.up.sql":   "CREATE TABLE users (id integer PRIMARY KEY); CREATE INDEX users_id ON users (id);",
+		"1_users.down.sql": "DROP TABLE users;",
+		"2_orders.sql":     "-- +goose Up\nCREATE TABLE orders (id integer);\n-- +goose Down\nDROP TABLE users;\n",
+	})
+	defer os.RemoveAll(dir)
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	n, err := Apply(db, dir)
+	if err != nil || n != 2 {
+		t.Fatalf("Apply = %d, %v, want 2 migrations", n, err)
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name IN ('users', 'orders')`).
+		Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 2 {
+		t.Errorf("%d tables, want users and orders", tables)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "3_broken.sql"), []byte("CREATE TABLE users (id integer);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "4_later.sql"), []byte("CREATE TABLE later (id integer);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db2, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+	db2.SetMaxOpenConns(1)
+	n, err = Apply(db2, dir)
+	if n != 2 || err == nil || !strings.HasPrefix(err.Error(), "apply 3_broken.sql: ") {
+		t.Errorf("Apply = %d, %v, want 2 migrations and an error of 3_broken.sql", n, err)
+	}
+}
+
+func TestApplyEmpty(t *testing.T) {
+	dir := migrationsDir(t, map[string]string{"README.md": "# Migrations"})
+	defer os.RemoveAll(dir)
+	if _, err := Apply(nil, dir); err == nil || !strings.Contains(err.Error(), "no migrations") {
+		t.Errorf("Apply of a directory without migrations: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// commonInitialisms are written in upper case in Go identifiers.
+var commonInitialisms = map[string]bool{
+	"API": true, "CPU": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "SQL": true, "TCP": true, "TTL": true, "UID": true,
+	"URI": true, "URL": true, "UTF8": true, "UUID": true, "XML": true,
+}
+
+// camelCase turns a snake_case database name into an exported Go name,
+// e.g. user_id -> UserID.
+func camelCase(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if u := strings.ToUpper(part); commonInitialisms[u] {
+			b.WriteString(u)
+			continue
+		}
+		r := []rune(part)
+		b.WriteRune(unicode.ToUpper(r[0]))
+		b.WriteString(string(r[1:]))
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// singular makes a best effort to turn an English plural into singular,
+// e.g. categories -> category, addresses -> address, users -> user.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"),
+		strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "ss"), strings.HasSuffix(name, "us"), strings.HasSuffix(name, "is"):
+		return name
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
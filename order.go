@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// runOrder prints the base tables in the order to load fixtures in or,
+// with -truncate, to empty them in.
+func (a *app) runOrder(args []string) {
+	fs := flag.NewFlagSet("order", flag.ContinueOnError)
+	truncate := fs.Bool("truncate", false, "Print the order to delete rows in, referencing tables first.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	tables, deferred := db.LoadOrder()
+	for _, r := range deferred {
+		a.log.Warnf("defer %s on %s.%s referencing %s.%s, it is part of a cycle",
+			r.FK.Name, r.Schema, r.Table, r.FK.RefSchema, r.FK.RefTable)
+	}
+	if *truncate {
+		for i := len(tables) - 1; i >= 0; i-- {
+			fmt.Println(qualifiedName(tables[i]))
+		}
+		return
+	}
+	for _, t := range tables {
+		fmt.Println(qualifiedName(t))
+	}
+}
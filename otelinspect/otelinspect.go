@@ -0,0 +1,218 @@
+// Package otelinspect reports the cost of inspections to OpenTelemetry: a
+// span per inspection with the number of objects found, a child span per
+// catalog query, and metrics of their durations.
+//
+//	in, err := otelinspect.New(nil, nil) // the global providers
+//	db, err := in.Load(ctx, conn.NewSession(nil), []string{"public"})
+//
+// Queries run outside Load are traced by a session whose event receiver
+// is in.Receiver(ctx), or Receivers of it and another one.
+package otelinspect
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name is the instrumentation scope of the tracer and meter.
+const Name = "github.com/datainq/pq-inspector/otelinspect"
+
+// Instrumentation holds the tracer and instruments.
+type Instrumentation struct {
+	tracer      trace.Tracer
+	queries     metric.Float64Histogram
+	inspections metric.Float64Histogram
+	objects     metric.Int64Gauge
+}
+
+// New creates the instrumentation with the given providers, the global
+// ones if nil.
+func New(tp trace.TracerProvider, mp metric.MeterProvider) (*Instrumentation, error) {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(Name)
+	in := &Instrumentation{tracer: tp.Tracer(Name)}
+	var err error
+	if in.queries, err = meter.Float64Histogram("pg_inspector.query.duration", metric.WithUnit("s"),
+		metric.WithDescription("Duration of catalog queries.")); err != nil {
+		return nil, err
+	}
+	if in.inspections, err = meter.Float64Histogram("pg_inspector.inspection.duration", metric.WithUnit("s"),
+		metric.WithDescription("Duration of inspections.")); err != nil {
+		return nil, err
+	}
+	if in.objects, err = meter.Int64Gauge("pg_inspector.objects", metric.WithUnit("{object}"),
+		metric.WithDescription("Objects found by the last inspection of a database, by kind.")); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// Load runs inspect.Load in a span of ctx. The queries of sess become
+// child spans, sess keeps its own event receiver.
+func (in *Instrumentation) Load(ctx context.Context, sess *dbr.Session, schemas []string) (*inspect.Database, error) {
+	return in.LoadWith(ctx, sess, schemas, func(sess *dbr.Session) (*inspect.Database, error) {
+		return inspect.Load(sess, schemas)
+	})
+}
+
+// LoadWith is Load inspecting with load instead of inspect.Load, e.g. the
+// Load method of an inspect.Inspector. schemas only label the span.
+func (in *Instrumentation) LoadWith(ctx context.Context, sess *dbr.Session, schemas []string,
+	load func(sess *dbr.Session) (*inspect.Database, error)) (*inspect.Database, error) {
+	start := time.Now()
+	ctx, span := in.tracer.Start(ctx, "inspect.Load", trace.WithAttributes(
+		attribute.String("db.system.name", "postgresql"),
+		attribute.StringSlice("pg_inspector.schemas", schemas),
+	))
+	defer span.End()
+
+	traced := &dbr.Session{
+		Connection:    sess.Connection,
+		EventReceiver: Receivers{sess.EventReceiver, in.Receiver(ctx)},
+		Timeout:       sess.Timeout,
+	}
+	db, err := load(traced)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		in.inspections.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attribute.Bool("error", true)))
+		return nil, err
+	}
+
+	database := attribute.String("db.namespace", db.Name)
+	span.SetAttributes(database)
+	in.inspections.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(database, attribute.Bool("error", false)))
+	for _, c := range db.Counts() {
+		span.SetAttributes(attribute.Int("pg_inspector."+c.Kind, c.N))
+		in.objects.Record(ctx, int64(c.N), metric.WithAttributes(database, attribute.String("kind", c.Kind)))
+	}
+	return db, nil
+}
+
+// Receiver returns a dbr event receiver recording a span, a child of ctx,
+// and a duration measurement per query.
+func (in *Instrumentation) Receiver(ctx context.Context) *Receiver {
+	return &Receiver{in: in, ctx: ctx, failed: make(map[string]error)}
+}
+
+// Receiver is a dbr event receiver tracing queries.
+type Receiver struct {
+	dbr.NullEventReceiver
+	in  *Instrumentation
+	ctx context.Context
+
+	mu     sync.Mutex
+	failed map[string]error // by SQL, until the timing of the query
+}
+
+// EventErrKv is called by dbr when a query fails, before TimingKv.
+func (r *Receiver) EventErrKv(event string, err error, kvs map[string]string) error {
+	if sql, ok := kvs["sql"]; ok {
+		r.mu.Lock()
+		r.failed[sql] = err
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// TimingKv is called by dbr after every query with its SQL in kvs. dbr has
+// no hook at the start of a query, so the span is recorded once it ended.
+func (r *Receiver) TimingKv(event string, nanos int64, kvs map[string]string) {
+	end := time.Now()
+	sql := kvs["sql"]
+	r.mu.Lock()
+	err := r.failed[sql]
+	delete(r.failed, sql)
+	r.mu.Unlock()
+
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system.name", "postgresql"),
+		attribute.String("db.operation.name", operation(sql)),
+	}
+	_, span := r.in.tracer.Start(r.ctx, event, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-time.Duration(nanos))),
+		trace.WithAttributes(append(attrs, attribute.String("db.query.text", sql))...))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+	r.in.queries.Record(r.ctx, time.Duration(nanos).Seconds(),
+		metric.WithAttributes(append(attrs, attribute.Bool("error", err != nil))...))
+}
+
+// operation is the first keyword of a statement, e.g. SELECT.
+func operation(sql string) string {
+	if f := strings.Fields(sql); len(f) > 0 {
+		return strings.ToUpper(f[0])
+	}
+	return ""
+}
+
+// Receivers passes the events of dbr to each of its receivers.
+type Receivers []dbr.EventReceiver
+
+func (rs Receivers) Event(event string) {
+	for _, r := range rs {
+		if r != nil {
+			r.Event(event)
+		}
+	}
+}
+
+func (rs Receivers) EventKv(event string, kvs map[string]string) {
+	for _, r := range rs {
+		if r != nil {
+			r.EventKv(event, kvs)
+		}
+	}
+}
+
+func (rs Receivers) EventErr(event string, err error) error {
+	for _, r := range rs {
+		if r != nil {
+			r.EventErr(event, err)
+		}
+	}
+	return err
+}
+
+func (rs Receivers) EventErrKv(event string, err error, kvs map[string]string) error {
+	for _, r := range rs {
+		if r != nil {
+			r.EventErrKv(event, err, kvs)
+		}
+	}
+	return err
+}
+
+func (rs Receivers) Timing(event string, nanos int64) {
+	for _, r := range rs {
+		if r != nil {
+			r.Timing(event, nanos)
+		}
+	}
+}
+
+func (rs Receivers) TimingKv(event string, nanos int64, kvs map[string]string) {
+	for _, r := range rs {
+		if r != nil {
+			r.TimingKv(event, nanos, kvs)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// readPassword prompts for a password on the terminal without echoing it.
+// Without a terminal the password is read from stdin.
+func readPassword(prompt string) (string, error) {
+	var in io.Reader = os.Stdin
+	var out io.Writer = os.Stderr
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer tty.Close()
+		in, out = tty, tty
+		noEcho := exec.Command("stty", "-echo")
+		noEcho.Stdin = tty
+		if noEcho.Run() == nil {
+			defer func() {
+				echo := exec.Command("stty", "echo")
+				echo.Stdin = tty
+				echo.Run()
+			}()
+		}
+	}
+
+	fmt.Fprint(out, prompt)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	fmt.Fprintln(out)
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", fmt.Errorf("read password: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+const redacted = "xxxxx"
+
+var (
+	urlPassword   = regexp.MustCompile(`^(postgres(?:ql)?://[^:/@]*:)([^/?#]*)@`)
+	queryPassword = regexp.MustCompile(`([?&]password=)([^&#]*)`)
+	kvPassword    = regexp.MustCompile(`((?:^|\s)password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+	kvEscape      = regexp.MustCompile(`\\(.)`)
+)
+
+func isURL(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// redactDSN returns dsn with its password replaced, for logs and errors.
+func redactDSN(dsn string) string {
+	if isURL(dsn) {
+		dsn = urlPassword.ReplaceAllString(dsn, "${1}"+redacted+"@")
+		return queryPassword.ReplaceAllString(dsn, "${1}"+redacted)
+	}
+	return kvPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+// dsnPassword returns the password set in dsn, "" if there is none: of
+// the user info or the password parameter of a URL, unescaped, or of a
+// key=value string, unquoted.
+func dsnPassword(dsn string) string {
+	if isURL(dsn) {
+		if m := urlPassword.FindStringSubmatch(dsn); m != nil && m[2] != "" {
+			if p, err := url.PathUnescape(m[2]); err == nil {
+				return p
+			}
+			return m[2]
+		}
+		if m := queryPassword.FindStringSubmatch(dsn); m != nil {
+			if p, err := url.QueryUnescape(m[2]); err == nil {
+				return p
+			}
+			return m[2]
+		}
+		return ""
+	}
+	if m := kvPassword.FindStringSubmatch(dsn); m != nil {
+		if v := m[2]; len(v) >= 2 && strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'") {
+			return kvEscape.ReplaceAllString(v[1:len(v)-1], "$1")
+		}
+		return m[2]
+	}
+	return ""
+}
+
+// redactError removes dsn, its password and the other secrets from the
+// message of err, since parsers include the connection string verbatim.
+func redactError(err error, dsn string, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if dsn != "" {
+		msg = strings.Replace(msg, dsn, redactDSN(dsn), -1)
+	}
+	for _, s := range append(secrets, dsnPassword(dsn)) {
+		if s != "" {
+			msg = strings.Replace(msg, s, redacted, -1)
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
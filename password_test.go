@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDSNPassword(t *testing.T) {
+	tests := []struct {
+		dsn, password, redacted string
+	}{
+		{"postgres://app:s3cret@db:5432/app?sslmode=disable", "s3cret",
+			"postgres://app:xxxxx@db:5432/app?sslmode=disable"},
+		{"postgresql://app:p%40ss%2Fword@db/app", "p@ss/word", "postgresql://app:xxxxx@db/app"},
+		{"postgres://app@db/app", "", "postgres://app@db/app"},
+		{"postgres://h/db?password=s3cret&sslmode=disable", "s3cret",
+			"postgres://h/db?password=xxxxx&sslmode=disable"},
+		{"postgres://h/db?sslmode=disable&password=a%26b", "a&b", "postgres://h/db?sslmode=disable&password=xxxxx"},
+		{"postgres://h/db?sslpassword=key&user=app", "", "postgres://h/db?sslpassword=key&user=app"},
+		{"host=db user=app password=s3cret dbname=app", "s3cret", "host=db user=app password=xxxxx dbname=app"},
+		{"host=db password = s3cret", "s3cret", "host=db password = xxxxx"},
+		{"host=db password='two words' dbname=app", "two words", "host=db password=xxxxx dbname=app"},
+		{`password='it\'s \\ here' host=db`, `it's \ here`, "password=xxxxx host=db"},
+		{"host=db sslpassword=key", "", "host=db sslpassword=key"},
+		{"host=db user=app", "", "host=db user=app"},
+	}
+	for _, tt := range tests {
+		if got := dsnPassword(tt.dsn); got != tt.password {
+			t.Errorf("dsnPassword(%q) = %q, want %q", tt.dsn, got, tt.password)
+		}
+		if got := redactDSN(tt.dsn); got != tt.redacted {
+			t.Errorf("redactDSN(%q) = %q, want %q", tt.dsn, got, tt.redacted)
+		}
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	dsn := "postgres://h/db?password=s3cret&sslmode=disable"
+	err := redactError(errors.New(`parse "`+dsn+`": pq: password "s3cret" rejected, prompt said hunter2`), dsn, "hunter2")
+	want := `parse "postgres://h/db?password=xxxxx&sslmode=disable": pq: password "xxxxx" rejected, prompt said xxxxx`
+	if err.Error() != want {
+		t.Errorf("redactError = %q, want %q", err, want)
+	}
+	orig := errors.New("connection refused")
+	if err := redactError(orig, dsn); err != orig {
+		t.Errorf("redactError changed %q to %q", orig, err)
+	}
+	if redactError(nil, dsn) != nil {
+		t.Error("redactError(nil) is not nil")
+	}
+}
@@ -0,0 +1,155 @@
+// Package pgcontainer runs a disposable PostgreSQL server in a Docker
+// container, applies migrations to it and inspects the result, which
+// turns a directory of migrations into a model without a standing
+// database:
+//
+//	db, err := pgcontainer.Inspect("db/migrations", nil, pgcontainer.Options{})
+//
+// It drives the docker command line, or any compatible one such as
+// podman, so it needs no Docker client library.
+package pgcontainer
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/datainq/pq-inspector/migrations"
+	"github.com/gocraft/dbr"
+	_ "github.com/lib/pq"
+)
+
+// Options configure the container.
+type Options struct {
+	Image   string        // postgres:16-alpine if empty
+	Docker  string        // docker command, docker if empty
+	Timeout time.Duration // to wait for the server, one minute if 0
+}
+
+const (
+	defaultImage = "postgres:16-alpine"
+	password     = "pg-inspector"
+	database     = "inspect"
+)
+
+// Container is a running PostgreSQL container.
+type Container struct {
+	ID  string
+	DSN string // connection string of the database
+	cmd string
+}
+
+func docker(cmd string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	c := exec.Command(cmd, args...)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %v: %s", cmd, args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Start runs a container and waits until its server accepts connections.
+// The caller stops it.
+func Start(opts Options) (*Container, error) {
+	if opts.Image == "" {
+		opts.Image = defaultImage
+	}
+	if opts.Docker == "" {
+		opts.Docker = "docker"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = time.Minute
+	}
+	id, err := docker(opts.Docker, "run", "-d", "--rm", "--label", "pg-inspector",
+		"-e", "POSTGRES_PASSWORD="+password, "-e", "POSTGRES_DB="+database,
+		"-p", "127.0.0.1::5432", opts.Image)
+	if err != nil {
+		return nil, err
+	}
+	c := &Container{ID: id, cmd: opts.Docker}
+	port, err := docker(opts.Docker, "port", id, "5432/tcp")
+	if err != nil {
+		c.Stop()
+		return nil, err
+	}
+	// e.g. 127.0.0.1:49153, one line per address.
+	port = strings.SplitN(port, "\n", 2)[0]
+	port = port[strings.LastIndex(port, ":")+1:]
+	c.DSN = fmt.Sprintf("postgres://postgres:%s@127.0.0.1:%s/%s?sslmode=disable", password, port, database)
+
+	if err := c.wait(opts.Timeout); err != nil {
+		c.Stop()
+		return nil, err
+	}
+	return c, nil
+}
+
+// wait pings the server until it answers. The image restarts the server
+// once after initializing the data directory, without listening on TCP
+// before, so the first successful ping is the final server.
+func (c *Container) wait(timeout time.Duration) error {
+	db, err := sql.Open("postgres", c.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	deadline := time.Now().Add(timeout)
+	for {
+		err := db.Ping()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgres in container %.12s not ready after %s: %v", c.ID, timeout, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// Stop removes the container.
+func (c *Container) Stop() error {
+	_, err := docker(c.cmd, "rm", "-f", c.ID)
+	return err
+}
+
+// Migrate applies the migrations of dir, see the migrations package, and
+// returns the number applied.
+func (c *Container) Migrate(dir string) (int, error) {
+	db, err := sql.Open("postgres", c.DSN)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return migrations.Apply(db, dir)
+}
+
+// Inspect loads the given schemas, all user schemas if none, of the
+// container's database.
+func (c *Container) Inspect(schemas []string) (*inspect.Database, error) {
+	conn, err := dbr.Open("postgres", c.DSN, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return inspect.Load(conn.NewSession(nil), schemas)
+}
+
+// Inspect starts a container, applies the migrations of dir, inspects the
+// given schemas and removes the container.
+func Inspect(dir string, schemas []string, opts Options) (*inspect.Database, error) {
+	c, err := Start(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Stop()
+	if _, err := c.Migrate(dir); err != nil {
+		return nil, err
+	}
+	return c.Inspect(schemas)
+}
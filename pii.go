@@ -0,0 +1,61 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// piiKind is a category of personal data recognized by column name, with
+// the masking suggested for it.
+type piiKind struct {
+	name    string
+	pattern *regexp.Regexp
+	// types are the UDT names the masking expressions apply to.
+	types    map[string]bool
+	strategy string // fake, partial, generalize, hash or redact
+	// sql and anon are masking expressions of the column, {col} stands
+	// for its quoted name. sql uses built-in functions only, anon the functions of
+	// the PostgreSQL Anonymizer extension.
+	sql  string
+	anon string
+}
+
+var textTypes = map[string]bool{"text": true, "varchar": true, "bpchar": true, "citext": true}
+
+// piiKinds are checked in order, the first matching pattern wins.
+var piiKinds = []piiKind{
+	{"email", regexp.MustCompile(`(?i)e_?mail`), textTypes, "fake",
+		"md5({col}) || '@example.com'", "anon.fake_email()"},
+	{"phone", regexp.MustCompile(`(?i)phone|mobile|fax`), textTypes, "partial",
+		"left({col}, 3) || repeat('X', greatest(length({col}) - 3, 0))", "anon.partial({col}, 3, $$XXXXXX$$, 0)"},
+	{"first name", regexp.MustCompile(`(?i)first_?name|given_?name`), textTypes, "fake",
+		"'First ' || left(md5({col}), 6)", "anon.fake_first_name()"},
+	{"last name", regexp.MustCompile(`(?i)last_?name|surname|family_?name`), textTypes, "fake",
+		"'Last ' || left(md5({col}), 6)", "anon.fake_last_name()"},
+	{"full name", regexp.MustCompile(`(?i)full_?name|display_?name`), textTypes, "fake",
+		"'Name ' || left(md5({col}), 6)", "anon.fake_first_name() || ' ' || anon.fake_last_name()"},
+	{"address", regexp.MustCompile(`(?i)address|street|zip|postal|postcode`), textTypes, "fake",
+		"'Address ' || left(md5({col}), 6)", "anon.fake_address()"},
+	{"birth date", regexp.MustCompile(`(?i)birth|dob`), map[string]bool{"date": true, "timestamp": true, "timestamptz": true},
+		"generalize", "date_trunc('year', {col})", "anon.dnoise({col}, '1 year')"},
+	{"national id", regexp.MustCompile(`(?i)ssn|social_?security|national_?id|passport|tax_?id|pesel`), textTypes, "hash",
+		"md5({col})", "anon.hash({col})"},
+	{"payment", regexp.MustCompile(`(?i)card_?number|credit_?card|iban|account_?number`), textTypes, "partial",
+		"repeat('X', greatest(length({col}) - 4, 0)) || right({col}, 4)", "anon.partial({col}, 0, $$XXXXXXXXXXXX$$, 4)"},
+	{"ip address", regexp.MustCompile(`(?i)(^|_)ip(_|$)|ip_?address`), map[string]bool{"inet": true, "text": true, "varchar": true},
+		"redact", "'192.0.2.1'", "'192.0.2.1'"},
+	{"secret", regexp.MustCompile(`(?i)password|passwd|secret|token|api_?key`), textTypes, "redact",
+		"'redacted'", "'redacted'"},
+}
+
+// detectPII returns the kind of personal data column c likely holds or
+// nil.
+func detectPII(c *inspect.Column) *piiKind {
+	for i := range piiKinds {
+		if piiKinds[i].pattern.MatchString(c.Name) {
+			return &piiKinds[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// tableChecksum is the row count and, if requested, an order independent
+// hash of the rows of a table.
+type tableChecksum struct {
+	Rows int64          `db:"rows"`
+	Hash dbr.NullString `db:"hash"`
+}
+
+func checksum(sess *dbr.Session, t *inspect.Table, hash bool) (*tableChecksum, error) {
+	query := "SELECT count(*) AS rows, NULL AS hash FROM " + tableRef(t)
+	if hash {
+		query = "SELECT count(*) AS rows, md5(string_agg(h, '' ORDER BY h)) AS hash FROM (SELECT md5(t::text) AS h FROM " +
+			tableRef(t) + " t) r"
+	}
+	c := &tableChecksum{}
+	if err := sess.SelectBySql(query).LoadOne(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// runReconcile compares row counts and optionally row hashes of the base
+// tables of -db with another database, e.g. after a migration or a
+// replication cutover. It exits with 1 if any table differs.
+func (a *app) runReconcile(args []string) {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	against := fs.String("against", "", "Connection string of the database to compare with.")
+	hash := fs.Bool("hash", false, "Also compare a hash of all rows; reads every table in full.")
+	a.parseFlags(fs, args)
+	if *against == "" {
+		a.log.Fatal("reconcile requires -against")
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	src, err := a.connect()
+	if err != nil {
+		a.log.WithError(err).Fatal("connect")
+	}
+	defer src.Close()
+	dst, err := a.open(*against)
+	if err != nil {
+		a.log.WithError(err).Fatal("connect to -against")
+	}
+	defer dst.Close()
+	srcSess, dstSess := src.NewSession(nil), dst.NewSession(nil)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Table\tRows\tAgainst\tStatus")
+	mismatches := 0
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" {
+				continue
+			}
+			from, err := checksum(srcSess, t, *hash)
+			if err != nil {
+				a.log.WithError(err).Fatalf("checksum %s", qualifiedName(t))
+			}
+			to, err := checksum(dstSess, t, *hash)
+			if err != nil {
+				mismatches++
+				a.log.WithError(err).Warnf("checksum %s in -against", qualifiedName(t))
+				fmt.Fprintf(tw, "%s\t%d\t-\terror\n", qualifiedName(t), from.Rows)
+				continue
+			}
+			status := "ok"
+			switch {
+			case from.Rows != to.Rows:
+				status = "row count differs"
+			case from.Hash.String != to.Hash.String:
+				status = "rows differ"
+			}
+			if status != "ok" {
+				mismatches++
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", qualifiedName(t), from.Rows, to.Rows, status)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if mismatches > 0 {
+		a.log.Errorf("%d tables differ", mismatches)
+		a.log.Exit(exitFindings)
+	}
+}
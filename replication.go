@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// replica is a standby streaming from the server, from pg_stat_replication.
+// Lags are null without the pg_monitor role.
+type replica struct {
+	Name       string          `db:"name" json:"name"` // application_name
+	ClientAddr dbr.NullString  `db:"client_addr" json:"client_addr"`
+	State      string          `db:"state" json:"state"`
+	SyncState  string          `db:"sync_state" json:"sync_state"`
+	LagBytes   dbr.NullInt64   `db:"lag_bytes" json:"lag_bytes"` // WAL not replayed yet
+	ReplayLag  dbr.NullFloat64 `db:"replay_lag" json:"replay_lag_seconds"`
+}
+
+// upstream is the server a standby receives WAL from.
+type upstream struct {
+	Status   string         `db:"status" json:"status"`
+	Host     dbr.NullString `db:"host" json:"host"`
+	Port     dbr.NullInt64  `db:"port" json:"port"`
+	SlotName dbr.NullString `db:"slot_name" json:"slot_name"`
+}
+
+type replicationReport struct {
+	Standby bool `json:"standby"`
+	// ReplayDelay is how far a standby is behind its upstream, 0 when it
+	// replayed all WAL received.
+	ReplayDelay        dbr.NullFloat64   `json:"replay_delay_seconds"`
+	HotStandbyFeedback string            `json:"hot_standby_feedback"`
+	MaxStandbyDelay    string            `json:"max_standby_streaming_delay"`
+	Upstream           *upstream         `json:"upstream,omitempty"`
+	Replicas           []replica         `json:"replicas"`
+	Slots              []replicationSlot `json:"slots"`
+	Warnings           []string          `json:"warnings"`
+	// Safe tells whether heavy inspection queries can run on the server
+	// without reading stale data or being cancelled by recovery.
+	Safe bool `json:"safe"`
+}
+
+func loadReplication(sess *dbr.Session) (*replicationReport, error) {
+	r := &replicationReport{Replicas: []replica{}, Warnings: []string{}}
+	if err := sess.SelectBySql(`SELECT pg_is_in_recovery()`).LoadOne(&r.Standby); err != nil {
+		return nil, fmt.Errorf("select recovery status: %v", err)
+	}
+	if err := sess.SelectBySql(`SELECT current_setting('hot_standby_feedback')`).LoadOne(&r.HotStandbyFeedback); err != nil {
+		return nil, fmt.Errorf("select settings: %v", err)
+	}
+	if err := sess.SelectBySql(`SELECT current_setting('max_standby_streaming_delay')`).LoadOne(&r.MaxStandbyDelay); err != nil {
+		return nil, fmt.Errorf("select settings: %v", err)
+	}
+
+	if r.Standby {
+		if err := sess.SelectBySql(`SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE extract(epoch FROM now() - pg_last_xact_replay_timestamp()) END`).LoadOne(&r.ReplayDelay); err != nil {
+			return nil, fmt.Errorf("select replay delay: %v", err)
+		}
+		var up []upstream
+		if _, err := sess.SelectBySql(`SELECT status, sender_host AS host, sender_port AS port, slot_name
+FROM pg_stat_wal_receiver`).Load(&up); err != nil {
+			return nil, fmt.Errorf("select wal receiver: %v", err)
+		}
+		if len(up) > 0 {
+			r.Upstream = &up[0]
+		}
+	}
+
+	lsn := "pg_current_wal_lsn()"
+	if r.Standby {
+		lsn = "pg_last_wal_replay_lsn()"
+	}
+	if _, err := sess.SelectBySql(`SELECT application_name AS name, client_addr::text AS client_addr, state, sync_state,
+	pg_wal_lsn_diff(` + lsn + `, replay_lsn)::bigint AS lag_bytes, extract(epoch FROM replay_lag) AS replay_lag
+FROM pg_stat_replication ORDER BY application_name`).Load(&r.Replicas); err != nil {
+		return nil, fmt.Errorf("select replicas: %v", err)
+	}
+
+	var err error
+	if r.Slots, err = loadReplicationSlots(sess); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// check fills in the warnings and whether the server is safe for heavy
+// queries.
+func (r *replicationReport) check(maxLag time.Duration, maxRetained int64) {
+	r.Safe = true
+	if r.Standby {
+		if d := r.ReplayDelay; d.Valid && d.Float64 > maxLag.Seconds() {
+			r.Safe = false
+			r.Warnings = append(r.Warnings, fmt.Sprintf("standby replay is %s behind", seconds(d.Float64)))
+		}
+		if r.Upstream == nil || r.Upstream.Status != "streaming" {
+			r.Safe = false
+			r.Warnings = append(r.Warnings, "standby is not streaming from its upstream")
+		}
+		if r.HotStandbyFeedback != "on" {
+			r.Safe = false
+			r.Warnings = append(r.Warnings, fmt.Sprintf(
+				"hot_standby_feedback is off, queries running longer than max_standby_streaming_delay=%s can be cancelled",
+				r.MaxStandbyDelay))
+		}
+	}
+	for _, rep := range r.Replicas {
+		if rep.ReplayLag.Valid && rep.ReplayLag.Float64 > maxLag.Seconds() {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("replica %s is %s behind", rep.Name, seconds(rep.ReplayLag.Float64)))
+		}
+	}
+	for _, s := range r.Slots {
+		if !s.Active && s.RetainedBytes.Valid && s.RetainedBytes.Int64 > maxRetained {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("inactive slot %s retains %s of WAL", s.Name, humanBytes(s.RetainedBytes.Int64)))
+		}
+	}
+}
+
+func seconds(s float64) string {
+	return (time.Duration(s * float64(time.Second))).Round(time.Second).String()
+}
+
+func nullSeconds(s dbr.NullFloat64) string {
+	if !s.Valid {
+		return "-"
+	}
+	return seconds(s.Float64)
+}
+
+func writeReplicationText(w io.Writer, r *replicationReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if r.Standby {
+		fmt.Fprintln(tw, "role\tstandby")
+		if u := r.Upstream; u != nil {
+			fmt.Fprintf(tw, "upstream\t%s:%d (%s)\n", u.Host.String, u.Port.Int64, u.Status)
+		}
+		fmt.Fprintf(tw, "replay delay\t%s\n", nullSeconds(r.ReplayDelay))
+		fmt.Fprintf(tw, "hot_standby_feedback\t%s\n", r.HotStandbyFeedback)
+		fmt.Fprintf(tw, "max_standby_streaming_delay\t%s\n", r.MaxStandbyDelay)
+	} else {
+		fmt.Fprintln(tw, "role\tprimary")
+	}
+
+	fmt.Fprintln(tw, "\n== replicas ==")
+	if len(r.Replicas) == 0 {
+		fmt.Fprintln(tw, "none")
+	} else {
+		fmt.Fprintln(tw, "NAME\tCLIENT\tSTATE\tSYNC\tLAG\tREPLAY LAG")
+	}
+	for _, rep := range r.Replicas {
+		lag := "-"
+		if rep.LagBytes.Valid {
+			lag = humanBytes(rep.LagBytes.Int64)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rep.Name, rep.ClientAddr.String, rep.State, rep.SyncState, lag,
+			nullSeconds(rep.ReplayLag))
+	}
+
+	fmt.Fprintln(tw, "\n== slots ==")
+	if len(r.Slots) == 0 {
+		fmt.Fprintln(tw, "none")
+	} else {
+		fmt.Fprintln(tw, "SLOT\tTYPE\tACTIVE\tRETAINED WAL")
+	}
+	for _, s := range r.Slots {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\n", s.Name, s.Type, s.Active, nullBytes(s.RetainedBytes))
+	}
+
+	fmt.Fprintln(tw)
+	for _, warn := range r.Warnings {
+		fmt.Fprintf(tw, "warning: %s\n", warn)
+	}
+	if r.Safe {
+		fmt.Fprintln(tw, "safe for heavy inspection queries: yes")
+	} else {
+		fmt.Fprintln(tw, "safe for heavy inspection queries: no")
+	}
+	return tw.Flush()
+}
+
+// runReplication reports the replication topology around the server: its
+// role, its upstream or replicas, slots and lag. It exits with 1 when the
+// server is a standby unsafe for heavy queries.
+func (a *app) runReplication(args []string) {
+	fs := flag.NewFlagSet("replication", flag.ContinueOnError)
+	format := fs.String("format", "text", "Output format: text or json.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	maxLag := fs.Duration("max-lag", 30*time.Second, "Replay lag above which a standby is unsafe and a replica is reported.")
+	maxRetained := fs.Int64("max-retained-mb", 1024, "WAL retained by an inactive slot above which it is reported, in MB.")
+	a.parseFlags(fs, args)
+	if *format != "text" && *format != "json" {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		a.log.WithError(err).Fatal("connect")
+	}
+	r, err := loadReplication(conn.NewSession(nil))
+	conn.Close()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect replication")
+	}
+	r.check(*maxLag, *maxRetained<<20)
+
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		err = writeReplicationText(w, r)
+	}
+	if err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if !r.Safe {
+		a.log.Exit(exitFindings)
+	}
+}
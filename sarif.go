@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/datainq/pq-inspector/lint"
+)
+
+// SARIF 2.1.0 types, only what code scanning needs.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	HelpURI              string       `json:"helpUri,omitempty"`
+	DefaultConfiguration struct {
+		Level lint.Severity `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               lint.Severity     `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine int `json:"startLine"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+// writeSARIF writes findings as a SARIF log. Database objects have no file,
+// so every result is attributed to the first line of artifact, e.g. the
+// schema.sql or migrations directory checked into the repository, and
+// names the object as its logical location.
+func writeSARIF(w io.Writer, rules []lint.Rule, findings []lint.Finding, artifact string) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "pg-inspector",
+			InformationURI: "https://github.com/datainq/pq-inspector",
+		}},
+		Results: []sarifResult{},
+	}
+	index := make(map[string]int, len(rules))
+	for i, r := range rules {
+		sr := sarifRule{ID: r.ID(), ShortDescription: sarifMessage{r.Description()}, HelpURI: r.HelpURI()}
+		sr.DefaultConfiguration.Level = r.Severity()
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sr)
+		index[r.ID()] = i
+	}
+	for _, f := range findings {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = artifact
+		loc.PhysicalLocation.Region.StartLine = 1
+		loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: f.Object}}
+		// All results share a location, so the fingerprint which tracks
+		// them across runs is the finding's identity; a count changing in
+		// the message keeps the alert open instead of opening a new one.
+		sum := sha256.Sum256([]byte(f.Identity()))
+		run.Results = append(run.Results, sarifResult{
+			RuleID:              f.Rule,
+			RuleIndex:           index[f.Rule],
+			Level:               f.Severity,
+			Message:             sarifMessage{f.Object + ": " + f.Message},
+			Locations:           []sarifLocation{loc},
+			PartialFingerprints: map[string]string{"objectFingerprint/v1": hex.EncodeToString(sum[:])},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/datainq/pq-inspector/lint"
+)
+
+func TestSARIFFingerprints(t *testing.T) {
+	findings := []lint.Finding{
+		{Rule: "wide-rows", Severity: lint.Warning, Object: "public.events", Message: "rows average 2100 bytes"},
+		{Rule: "wide-rows", Severity: lint.Warning, Object: "public.events", Message: "rows average 2300 bytes"},
+		{Rule: "fk-index", Severity: lint.Warning, Object: "public.orders", Message: "no index", Key: "orders_user_fkey"},
+		{Rule: "fk-index", Severity: lint.Warning, Object: "public.orders", Message: "no index", Key: "orders_shop_fkey"},
+	}
+	var b bytes.Buffer
+	if err := writeSARIF(&b, nil, findings, "schema.sql"); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				PartialFingerprints map[string]string `json:"partialFingerprints"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	var prints []string
+	for _, r := range log.Runs[0].Results {
+		prints = append(prints, r.PartialFingerprints["objectFingerprint/v1"])
+	}
+	if prints[0] != prints[1] {
+		t.Errorf("a changed message changes the fingerprint: %s and %s", prints[0], prints[1])
+	}
+	if prints[2] == prints[3] {
+		t.Errorf("findings with different keys share the fingerprint %s", prints[2])
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// audits are the security reports of `security <name>`. They read the
+// inspected database and may query the live one, and report whether they
+// flagged anything.
+var audits = map[string]func(a *app, db *inspect.Database, sess *dbr.Session, args []string) bool{
+	"defaults": (*app).auditDefaults,
+	"definer":  (*app).auditDefiner,
+	"owners":   (*app).auditOwners,
+	"roles":    (*app).auditRoles,
+	"settings": (*app).auditSettings,
+	"tenancy":  (*app).auditTenancy,
+}
+
+// runSecurity runs one audit, or all of them with `security all`, and
+// exits with 1 if any flagged a problem.
+func (a *app) runSecurity(args []string) {
+	names := make([]string, 0, len(audits))
+	for name := range audits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(args) == 0 || (audits[args[0]] == nil && args[0] != "all") {
+		a.log.Fatalf("security requires an audit: all, %s", strings.Join(names, ", "))
+	}
+
+	db, conn := a.loadLive()
+	defer conn.Close()
+	sess := conn.NewSession(nil)
+
+	flagged := false
+	if args[0] != "all" {
+		flagged = audits[args[0]](a, db, sess, args[1:])
+	} else {
+		for i, name := range names {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("== %s ==\n", name)
+			flagged = audits[name](a, db, sess, nil) || flagged
+		}
+	}
+	if flagged {
+		conn.Close()
+		a.log.Exit(exitFindings)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// auditDefaults explains the privileges future objects will be granted by
+// ALTER DEFAULT PRIVILEGES.
+func (a *app) auditDefaults(db *inspect.Database, sess *dbr.Session, args []string) bool {
+	fs := flag.NewFlagSet("security defaults", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+	if len(db.DefaultPrivileges) == 0 {
+		fmt.Println("No default privileges; new objects are granted only to their owner, and functions and types also to PUBLIC.")
+		return false
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Created by\tIn schema\tObjects\tGrantee\tPrivileges")
+	for _, p := range db.DefaultPrivileges {
+		schema := p.Schema
+		if schema == "" {
+			schema = "any"
+		}
+		for _, s := range p.ACL {
+			item, err := inspect.ParseACLItem(s)
+			if err != nil {
+				a.log.WithError(err).Warn("parse default privileges")
+				continue
+			}
+			grantee := item.Grantee
+			if grantee == "" {
+				grantee = "PUBLIC"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.Role, schema, p.ObjectType, grantee,
+				inspect.PrivilegeList(item.Privileges))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	return false
+}
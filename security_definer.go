@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/datainq/pq-inspector/lint"
+	"github.com/gocraft/dbr"
+)
+
+// auditDefiner lists the security definer functions with their owners and
+// search_path settings and flags those letting callers substitute objects
+// through the search_path.
+func (a *app) auditDefiner(db *inspect.Database, sess *dbr.Session, args []string) bool {
+	fs := flag.NewFlagSet("security definer", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Function\tOwner\tsearch_path\tIssue")
+	flagged := 0
+	for _, s := range db.Schemas {
+		for i := range s.Functions {
+			f := &s.Functions[i]
+			if !f.SecurityDefiner {
+				continue
+			}
+			owner := f.Owner
+			if db.IsSuperuser(owner) {
+				owner += " (superuser)"
+			}
+			path, ok := f.SearchPath()
+			if !ok {
+				path = "-"
+			}
+			issue := lint.SearchPathIssue(db, f)
+			if issue != "" {
+				flagged++
+			} else {
+				issue = "ok"
+			}
+			fmt.Fprintf(tw, "%s.%s\t%s\t%s\t%s\n", s.Name, f.Signature(), owner, path, issue)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	return flagged > 0
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// auditOwners lists the owner of every schema, table and function, with
+// the superuser owners marked.
+func (a *app) auditOwners(db *inspect.Database, sess *dbr.Session, args []string) bool {
+	fs := flag.NewFlagSet("security owners", flag.ContinueOnError)
+	superusers := fs.Bool("superusers", false, "Only list objects owned by superusers.")
+	a.parseFlags(fs, args)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Object\tType\tOwner\tNote")
+	db.EachOwned(func(kind, name, owner string) {
+		super := db.IsSuperuser(owner)
+		if *superusers && !super {
+			return
+		}
+		note := ""
+		if super {
+			note = "superuser"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, kind, owner, note)
+	})
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// roleAttributes lists the notable attributes of r in upper case, as in
+// CREATE ROLE.
+func roleAttributes(r *inspect.Role) []string {
+	var attrs []string
+	for _, a := range []struct {
+		set  bool
+		name string
+	}{
+		{r.Superuser, "SUPERUSER"}, {r.Login, "LOGIN"}, {r.CreateRole, "CREATEROLE"},
+		{r.CreateDB, "CREATEDB"}, {r.Replication, "REPLICATION"}, {r.BypassRLS, "BYPASSRLS"},
+	} {
+		if a.set {
+			attrs = append(attrs, a.name)
+		}
+	}
+	return attrs
+}
+
+// roleNodes returns the roles of db and, for the groups they are members
+// of which are not listed, e.g. predefined pg_* roles, an empty role.
+func roleNodes(db *inspect.Database) []inspect.Role {
+	roles := append([]inspect.Role(nil), db.Roles...)
+	seen := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		seen[r.Name] = true
+	}
+	for _, r := range db.Roles {
+		for _, g := range r.MemberOf {
+			if !seen[g] {
+				seen[g] = true
+				roles = append(roles, inspect.Role{Name: g})
+			}
+		}
+	}
+	return roles
+}
+
+// writeRoleDOT draws roles as nodes, login roles as boxes and groups as
+// ellipses, with an edge from each member to its group.
+func writeRoleDOT(w io.Writer, db *inspect.Database) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph roles {\n\trankdir=LR;\n")
+	for _, r := range roleNodes(db) {
+		shape := "ellipse"
+		if r.Login {
+			shape = "box"
+		}
+		label := r.Name
+		if attrs := roleAttributes(&r); len(attrs) > 0 {
+			label += "\n" + strings.Join(attrs, " ")
+		}
+		style := ""
+		if r.Superuser {
+			style = `, style=filled, fillcolor="#f4cccc"`
+		}
+		fmt.Fprintf(bw, "\t%q [shape=%s, label=%q%s];\n", r.Name, shape, label, style)
+	}
+	for _, r := range db.Roles {
+		for _, g := range r.MemberOf {
+			fmt.Fprintf(bw, "\t%q -> %q;\n", r.Name, g)
+		}
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// writeRoleMermaid draws the same graph as a Mermaid flowchart.
+func writeRoleMermaid(w io.Writer, db *inspect.Database) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("flowchart LR\n")
+	ids := map[string]string{}
+	for i, r := range roleNodes(db) {
+		id := fmt.Sprintf("r%d", i)
+		ids[r.Name] = id
+		label := strings.Replace(r.Name, `"`, "#quot;", -1)
+		if attrs := roleAttributes(&r); len(attrs) > 0 {
+			label += "<br/>" + strings.Join(attrs, " ")
+		}
+		open, close := "([", "])"
+		if r.Login {
+			open, close = "[", "]"
+		}
+		fmt.Fprintf(bw, "  %s%s\"%s\"%s\n", id, open, label, close)
+		if r.Superuser {
+			fmt.Fprintf(bw, "  class %s superuser\n", id)
+		}
+	}
+	for _, r := range db.Roles {
+		for _, g := range r.MemberOf {
+			fmt.Fprintf(bw, "  %s --> %s\n", ids[r.Name], ids[g])
+		}
+	}
+	bw.WriteString("  classDef superuser fill:#f4cccc\n")
+	return bw.Flush()
+}
+
+// auditRoles lists the roles with their attributes and memberships, or
+// renders the membership graph.
+func (a *app) auditRoles(db *inspect.Database, sess *dbr.Session, args []string) bool {
+	fs := flag.NewFlagSet("security roles", flag.ContinueOnError)
+	format := fs.String("format", "text", "Output format: text, dot or mermaid.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	if *format == "text" {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "Role\tAttributes\tMember of")
+		for i := range db.Roles {
+			r := &db.Roles[i]
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Name, strings.Join(roleAttributes(r), " "), strings.Join(r.MemberOf, ", "))
+		}
+		if err := tw.Flush(); err != nil {
+			a.log.WithError(err).Fatal("write report")
+		}
+		return false
+	}
+	render := map[string]func(io.Writer, *inspect.Database) error{"dot": writeRoleDOT, "mermaid": writeRoleMermaid}[*format]
+	if render == nil {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := render(w, db); err != nil {
+		a.log.WithError(err).Fatal("write graph")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write graph")
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// securitySetting is a server setting relevant to security and the values
+// considered safe.
+type securitySetting struct {
+	name      string
+	recommend string
+	ok        func(value string) bool
+}
+
+func oneOf(values ...string) func(string) bool {
+	return func(v string) bool {
+		for _, want := range values {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var securitySettings = []securitySetting{
+	{"ssl", "on", oneOf("on")},
+	{"ssl_min_protocol_version", "TLSv1.2 or later", oneOf("TLSv1.2", "TLSv1.3")},
+	{"password_encryption", "scram-sha-256", oneOf("scram-sha-256")},
+	{"log_connections", "on", oneOf("on")},
+	{"log_disconnections", "on", oneOf("on")},
+	{"log_statement", "ddl or all", oneOf("ddl", "mod", "all")},
+	{"row_security", "on", oneOf("on")},
+}
+
+// auditSettings reports the server settings relevant to security that the
+// user may read, and how the current connection was authenticated.
+func (a *app) auditSettings(db *inspect.Database, sess *dbr.Session, args []string) bool {
+	fs := flag.NewFlagSet("security settings", flag.ContinueOnError)
+	a.parseFlags(fs, args)
+
+	var rows []struct {
+		Name    string `db:"name"`
+		Setting string `db:"setting"`
+	}
+	names := make([]string, len(securitySettings))
+	for i, s := range securitySettings {
+		names[i] = s.name
+	}
+	if _, err := sess.SelectBySql("SELECT name, setting FROM pg_settings WHERE name IN ?", names).Load(&rows); err != nil {
+		a.log.WithError(err).Fatal("read settings")
+	}
+	values := make(map[string]string, len(rows))
+	for _, r := range rows {
+		values[r.Name] = r.Setting
+	}
+
+	flagged := false
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Setting\tValue\tRecommended\tStatus")
+	for _, s := range securitySettings {
+		v, ok := values[s.name]
+		status := "ok"
+		switch {
+		case !ok:
+			v, status = "-", "not visible or not supported"
+		case !s.ok(v):
+			status = "weak"
+			flagged = true
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.name, v, s.recommend, status)
+	}
+
+	var conn struct {
+		SSL     bool   `db:"ssl"`
+		Version string `db:"version"`
+		Cipher  string `db:"cipher"`
+	}
+	err := sess.SelectBySql(`SELECT ssl, COALESCE(version, '') AS version, COALESCE(cipher, '') AS cipher
+FROM pg_stat_ssl WHERE pid = pg_backend_pid()`).LoadOne(&conn)
+	switch {
+	case err != nil:
+		a.log.WithError(err).Warn("read connection encryption")
+	case conn.SSL:
+		fmt.Fprintf(tw, "connection encryption\t%s %s\tTLS\tok\n", conn.Version, conn.Cipher)
+	default:
+		fmt.Fprintf(tw, "connection encryption\tnone\tTLS\tweak\n")
+		flagged = true
+	}
+	// system_user, available since PostgreSQL 16, is method:identity.
+	var auth dbr.NullString
+	if err := sess.SelectBySql("SELECT system_user").LoadOne(&auth); err != nil {
+		fmt.Fprintf(tw, "authentication\t-\tscram-sha-256 or cert\tneeds PostgreSQL 16\n")
+	} else {
+		method := strings.SplitN(auth.String, ":", 2)[0]
+		status := "ok"
+		switch {
+		case !auth.Valid:
+			method, status = "trust", "weak"
+		case method == "password" || method == "md5":
+			status = "weak"
+		}
+		if status == "weak" {
+			flagged = true
+		}
+		fmt.Fprintf(tw, "authentication\t%s\tscram-sha-256 or cert\t%s\n", method, status)
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	return flagged
+}
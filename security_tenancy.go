@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+)
+
+// auditTenancy reviews the isolation of tenants sharing tables by a tenant
+// column: which tables have it, which indexes lead with it, which foreign
+// keys between tenant tables leave it out and so may point at another
+// tenant's rows, and which row level security policies filter on it.
+func (a *app) auditTenancy(db *inspect.Database, sess *dbr.Session, args []string) bool {
+	fs := flag.NewFlagSet("security tenancy", flag.ContinueOnError)
+	def := a.cfg.TenantColumn
+	if def == "" {
+		def = "tenant_id"
+	}
+	column := fs.String("column", def, "Column holding the tenant of a row, e.g. org_id.")
+	a.parseFlags(fs, args)
+	mentions := regexp.MustCompile(`\b` + regexp.QuoteMeta(*column) + `\b`)
+
+	var shared []string
+	flagged := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Table\tIndexes leading with %s\tRLS\tPolicies on %s\tIssue\n", *column, *column)
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			if t.Type != "BASE TABLE" || t.PartitionOf != "" {
+				continue
+			}
+			if t.Column(*column) == nil {
+				shared = append(shared, qualifiedName(t))
+				continue
+			}
+			leading := 0
+			for _, idx := range t.Indexes {
+				if len(idx.Columns) > 0 && idx.Columns[0] == *column {
+					leading++
+				}
+			}
+			var policies []string
+			for _, p := range t.Policies {
+				if mentions.MatchString(p.Using) || mentions.MatchString(p.Check) {
+					policies = append(policies, p.Name)
+				}
+			}
+			rls := "off"
+			if t.RowSecurity {
+				rls = "on"
+				if t.ForceRowSecurity {
+					rls = "forced"
+				}
+			}
+
+			var issues []string
+			if leading == 0 {
+				issues = append(issues, "no index leads with the tenant column")
+			}
+			if t.RowSecurity && len(policies) == 0 {
+				issues = append(issues, "row level security does not filter on the tenant")
+				flagged++
+			}
+			for _, fk := range t.FKs {
+				ps := db.Schema(fk.RefSchema)
+				if ps == nil {
+					continue
+				}
+				parent := ps.Table(fk.RefTable)
+				if parent == nil || parent.Column(*column) == nil || containsString(fk.Columns, *column) {
+					continue
+				}
+				issues = append(issues, fmt.Sprintf("foreign key %s to %s leaves out %s, rows may reference another tenant",
+					fk.Name, qualifiedName(parent), *column))
+				flagged++
+			}
+			if len(issues) == 0 {
+				issues = append(issues, "ok")
+			}
+			fmt.Fprintf(tw, "%s\t%d of %d\t%s\t%s\t%s\n", qualifiedName(t), leading, len(t.Indexes), rls,
+				strings.Join(policies, ", "), strings.Join(issues, "; "))
+		}
+	}
+	if len(shared) > 0 {
+		fmt.Fprintf(tw, "\nTables without %s, shared by all tenants: %s\n", *column, strings.Join(shared, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	return flagged > 0
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// seedTable holds the rows generated for a table, a nil value is NULL.
+type seedTable struct {
+	table *inspect.Table
+	rows  [][]*string
+}
+
+// seeder generates fake rows for tables in load order so that foreign keys
+// can pick values from rows generated before.
+type seeder struct {
+	db       *inspect.Database
+	rnd      *rand.Rand
+	tables   map[*inspect.Table]*seedTable
+	warnings []string
+	warned   map[string]bool
+}
+
+func newSeeder(db *inspect.Database, seed int64) *seeder {
+	return &seeder{db: db, rnd: rand.New(rand.NewSource(seed)), tables: make(map[*inspect.Table]*seedTable),
+		warned: make(map[string]bool)}
+}
+
+// warnf records a problem once, however many rows it affects.
+func (s *seeder) warnf(format string, args ...interface{}) {
+	w := fmt.Sprintf(format, args...)
+	if !s.warned[w] {
+		s.warned[w] = true
+		s.warnings = append(s.warnings, w)
+	}
+}
+
+func (s *seeder) refTable(fk inspect.ForeignKey) *inspect.Table {
+	if sc := s.db.Schema(fk.RefSchema); sc != nil {
+		return sc.Table(fk.RefTable)
+	}
+	return nil
+}
+
+// checkBounds are the limits and allowed values of a column found in its
+// CHECK constraints.
+type checkBounds struct {
+	min, max float64
+	values   []string
+}
+
+var (
+	checkRange  = regexp.MustCompile(`\(?(\w+)\)? (>=|>|<=|<) \(?'?(-?[0-9]+(?:\.[0-9]+)?)'?`)
+	checkAny    = regexp.MustCompile(`(\w+)\)?(?:::\w+)? = ANY \(\(?ARRAY\[(.*?)\]`)
+	checkQuoted = regexp.MustCompile(`'((?:[^']|'')*)'`)
+)
+
+// parseChecks reads simple comparisons with constants and IN lists of the
+// CHECK constraints on column, other expressions are ignored.
+func parseChecks(t *inspect.Table, column string, b *checkBounds) {
+	for _, c := range t.Constraints {
+		if c.Type != "CHECK" {
+			continue
+		}
+		for _, m := range checkRange.FindAllStringSubmatch(c.Definition, -1) {
+			if m[1] != column {
+				continue
+			}
+			v, err := strconv.ParseFloat(m[3], 64)
+			if err != nil {
+				continue
+			}
+			switch m[2] {
+			case ">=":
+				b.min = math.Max(b.min, v)
+			case ">":
+				b.min = math.Max(b.min, v+1)
+			case "<=":
+				b.max = math.Min(b.max, v)
+			case "<":
+				b.max = math.Min(b.max, v-1)
+			}
+		}
+		for _, m := range checkAny.FindAllStringSubmatch(c.Definition, -1) {
+			if m[1] != column {
+				continue
+			}
+			for _, q := range checkQuoted.FindAllStringSubmatch(m[2], -1) {
+				b.values = append(b.values, strings.Replace(q[1], "''", "'", -1))
+			}
+		}
+	}
+}
+
+// uniqueSets are the column sets of the primary key and unique constraints.
+func uniqueSets(t *inspect.Table) [][]string {
+	var sets [][]string
+	if len(t.PK.Columns) > 0 {
+		sets = append(sets, t.PK.Columns)
+	}
+	for _, c := range t.Constraints {
+		if c.Type == "UNIQUE" {
+			sets = append(sets, c.Columns)
+		}
+	}
+	return sets
+}
+
+// generate adds n rows of t. Foreign keys listed in deferred point at
+// tables generated later or at t itself.
+func (s *seeder) generate(t *inspect.Table, n int, deferred map[string]bool) *seedTable {
+	index := make(map[string]int, len(t.Columns))
+	for i, c := range t.Columns {
+		index[c.Name] = i
+	}
+	fkColumn := make(map[string]bool)
+	for _, fk := range t.FKs {
+		for _, c := range fk.Columns {
+			fkColumn[c] = true
+		}
+	}
+
+	// A unique set is kept unique through one sequential column or, when
+	// it consists of foreign keys only, by enumerating parent rows.
+	sequential := make(map[string]bool)
+	combined := make(map[string]bool)
+	for _, set := range uniqueSets(t) {
+		done := false
+		for _, c := range set {
+			if !fkColumn[c] {
+				sequential[c], done = true, true
+				break
+			}
+		}
+		if !done {
+			for _, c := range set {
+				combined[c] = true
+			}
+		}
+	}
+
+	var fks, combine []inspect.ForeignKey
+	for _, fk := range t.FKs {
+		if len(fk.Columns) > 0 && combined[fk.Columns[0]] && !deferred[fk.Name] && s.refTable(fk) != t {
+			combine = append(combine, fk)
+		} else {
+			fks = append(fks, fk)
+		}
+	}
+	if len(combine) > 0 {
+		max := 1
+		for _, fk := range combine {
+			parent := s.tables[s.refTable(fk)]
+			if parent == nil {
+				max = 0
+				break
+			}
+			max *= len(parent.rows)
+		}
+		if n > max {
+			s.warnf("%s: only %d unique combinations of foreign keys, generating %d rows", qualifiedName(t), max, max)
+			n = max
+		}
+	}
+
+	st := &seedTable{table: t}
+	s.tables[t] = st
+	for i := 0; i < n; i++ {
+		row := make([]*string, len(t.Columns))
+		for j := range t.Columns {
+			c := &t.Columns[j]
+			if fkColumn[c.Name] || c.Generated != "" {
+				continue
+			}
+			row[j] = s.value(t, c, i, sequential[c.Name])
+		}
+		// Row i takes its combined parents by the digits of i in the mixed
+		// radix of the parents' row counts.
+		k := i
+		for _, fk := range combine {
+			parent := s.tables[s.refTable(fk)]
+			s.copyKey(row, index, fk, parent, k%len(parent.rows))
+			k /= len(parent.rows)
+		}
+		for _, fk := range fks {
+			s.fillFK(t, row, index, fk, i, deferred[fk.Name])
+		}
+		st.rows = append(st.rows, row)
+	}
+	return st
+}
+
+// fillFK picks a parent row for fk of row i.
+func (s *seeder) fillFK(t *inspect.Table, row []*string, index map[string]int, fk inspect.ForeignKey, i int, deferred bool) {
+	nullable := true
+	for _, c := range fk.Columns {
+		nullable = nullable && t.Column(c).Nullable
+	}
+	ref := s.refTable(fk)
+	switch {
+	case ref == t:
+		// Rows reference themselves or an earlier row.
+		if nullable && i == 0 {
+			return
+		}
+		target := i
+		if nullable {
+			target = s.rnd.Intn(i)
+		}
+		for k, c := range fk.Columns {
+			src := t.Column(fk.RefColumns[k])
+			if src == nil {
+				return
+			}
+			if target == i {
+				row[index[c]] = row[index[fk.RefColumns[k]]]
+			} else {
+				row[index[c]] = s.tables[t].rows[target][index[fk.RefColumns[k]]]
+			}
+		}
+		return
+	case deferred || s.tables[ref] == nil || len(s.tables[ref].rows) == 0:
+		if !nullable {
+			s.warnf("%s: no rows to reference for %s, left NULL", qualifiedName(t), fk.Name)
+		}
+		return
+	case nullable && s.rnd.Intn(10) == 0:
+		return
+	}
+	parent := s.tables[ref]
+	s.copyKey(row, index, fk, parent, s.rnd.Intn(len(parent.rows)))
+}
+
+func (s *seeder) copyKey(row []*string, index map[string]int, fk inspect.ForeignKey, parent *seedTable, i int) {
+	for k, c := range fk.Columns {
+		for j, pc := range parent.table.Columns {
+			if pc.Name == fk.RefColumns[k] {
+				row[index[c]] = parent.rows[i][j]
+			}
+		}
+	}
+}
+
+var (
+	seedFirstNames = []string{"Alice", "Bob", "Carol", "Dave", "Eve", "Frank", "Grace", "Heidi", "Ivan", "Judy"}
+	seedLastNames  = []string{"Smith", "Jones", "Brown", "Taylor", "Wilson", "Evans", "Walker", "Wright", "Clark", "Hall"}
+	seedCities     = []string{"Berlin", "Lisbon", "Oslo", "Warsaw", "Madrid", "Dublin", "Vienna", "Prague"}
+	seedCountries  = []string{"DE", "PT", "NO", "PL", "ES", "IE", "AT", "CZ"}
+	seedWords      = []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do"}
+	seedEpoch      = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func (s *seeder) pick(words []string) string {
+	return words[s.rnd.Intn(len(words))]
+}
+
+// text makes up a string fitting the column name.
+func (s *seeder) text(name string, i int, unique bool) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "email"):
+		return fmt.Sprintf("user%d@example.com", i+1)
+	case strings.Contains(name, "url") || strings.Contains(name, "website"):
+		return fmt.Sprintf("https://example.com/%d", i+1)
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("+1555%07d", i+1)
+	}
+	var v string
+	switch {
+	case strings.Contains(name, "first_name"):
+		v = s.pick(seedFirstNames)
+	case strings.Contains(name, "last_name") || strings.Contains(name, "surname"):
+		v = s.pick(seedLastNames)
+	case name == "name" || strings.HasSuffix(name, "_name"):
+		v = s.pick(seedFirstNames) + " " + s.pick(seedLastNames)
+	case strings.Contains(name, "city"):
+		v = s.pick(seedCities)
+	case strings.Contains(name, "country"):
+		v = s.pick(seedCountries)
+	default:
+		words := make([]string, 1+s.rnd.Intn(4))
+		for k := range words {
+			words[k] = s.pick(seedWords)
+		}
+		v = strings.Join(words, " ")
+	}
+	if unique {
+		v += " " + strconv.Itoa(i+1)
+	}
+	return v
+}
+
+// value generates the value of column c in row i of t, nil for NULL.
+func (s *seeder) value(t *inspect.Table, c *inspect.Column, i int, unique bool) *string {
+	if c.Nullable && !unique && s.rnd.Intn(10) == 0 {
+		return nil
+	}
+	b := checkBounds{min: math.Inf(-1), max: math.Inf(1)}
+	parseChecks(t, c.Name, &b)
+	if len(b.values) > 0 {
+		v := b.values[s.rnd.Intn(len(b.values))]
+		if unique {
+			v = b.values[i%len(b.values)]
+		}
+		return &v
+	}
+	if c.DataType == "USER-DEFINED" {
+		if sc := s.db.Schema(c.UDTSchema); sc != nil {
+			for _, e := range sc.Enums {
+				if e.Name == c.UDTName && len(e.Labels) > 0 {
+					v := e.Labels[s.rnd.Intn(len(e.Labels))]
+					return &v
+				}
+			}
+		}
+	}
+
+	var v string
+	switch c.UDTName {
+	case "int2", "int4", "int8", "numeric", "float4", "float8":
+		lo, hi := 1.0, 1000.0
+		if !math.IsInf(b.min, 0) {
+			lo = b.min
+			if hi < lo {
+				hi = lo + 1000
+			}
+		}
+		if !math.IsInf(b.max, 0) {
+			hi = b.max
+			if lo > hi {
+				lo = hi - 1000
+			}
+		}
+		if c.UDTName == "int2" {
+			hi = math.Min(hi, math.MaxInt16)
+		}
+		if c.UDTName == "numeric" && c.Precision > 0 {
+			hi = math.Min(hi, math.Pow10(c.Precision-c.Scale)-1)
+		}
+		x := lo + s.rnd.Float64()*(hi-lo)
+		if unique {
+			x = lo + float64(i)
+		}
+		switch {
+		case strings.HasPrefix(c.UDTName, "int"):
+			v = strconv.FormatInt(int64(x), 10)
+		case c.UDTName == "numeric" && c.Precision > 0:
+			v = strconv.FormatFloat(x, 'f', c.Scale, 64)
+		default:
+			v = strconv.FormatFloat(x, 'f', 2, 64)
+		}
+	case "bool":
+		v = strconv.FormatBool(s.rnd.Intn(2) == 0)
+	case "text", "varchar", "bpchar", "citext", "name":
+		v = s.text(c.Name, i, unique)
+		if c.MaxLength > 0 && len(v) > c.MaxLength {
+			v = v[len(v)-c.MaxLength:]
+		}
+	case "uuid":
+		u := make([]byte, 16)
+		s.rnd.Read(u)
+		u[6], u[8] = u[6]&0x0f|0x40, u[8]&0x3f|0x80
+		v = fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+	case "date":
+		v = seedEpoch.AddDate(0, 0, s.rnd.Intn(730)).Format("2006-01-02")
+	case "timestamp", "timestamptz":
+		v = seedEpoch.Add(time.Duration(s.rnd.Int63n(730*24*3600)) * time.Second).Format(time.RFC3339)
+	case "time", "timetz":
+		v = time.Time{}.Add(time.Duration(s.rnd.Intn(24*3600)) * time.Second).Format("15:04:05")
+	case "interval":
+		v = fmt.Sprintf("%d days", s.rnd.Intn(365))
+	case "json", "jsonb":
+		v = "{}"
+		if unique {
+			v = fmt.Sprintf(`{"n": %d}`, i+1)
+		}
+	case "bytea":
+		v = fmt.Sprintf(`\x%08x`, i)
+	case "inet", "cidr":
+		v = fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)
+	case "geometry", "geography":
+		sp := c.Spatial
+		if sp != nil && (sp.Dimensions > 2 || sp.Type != "POINT" && sp.Type != "GEOMETRY") {
+			if !c.Nullable {
+				s.warnf("%s.%s: cannot generate %s values, left NULL", qualifiedName(t), c.Name, c.TypeName())
+			}
+			return nil
+		}
+		v = fmt.Sprintf("POINT(%.6f %.6f)", s.rnd.Float64()*360-180, s.rnd.Float64()*180-90)
+		if sp != nil && sp.SRID != 0 {
+			v = fmt.Sprintf("SRID=%d;%s", sp.SRID, v)
+		}
+	default:
+		if c.DataType == "ARRAY" {
+			v = "{}"
+			break
+		}
+		if !c.Nullable {
+			s.warnf("%s.%s: cannot generate %s values, left NULL", qualifiedName(t), c.Name, c.TypeName())
+		}
+		return nil
+	}
+	return &v
+}
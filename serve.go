@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/gocraft/dbr"
+	"github.com/sirupsen/logrus"
+)
+
+// target is a named database which is connected to and inspected on the
+// first request that needs it, and re-inspected incrementally once the
+// inspection is older than refresh, unless refresh is 0.
+type target struct {
+	DatabaseConfig
+	refresh time.Duration
+	open    func(dsn string) (*dbr.Connection, error)
+	inspect inspectFunc
+
+	mu        sync.Mutex
+	conn      *dbr.Connection
+	inspector *inspect.Inspector
+	db        *inspect.Database
+}
+
+func (t *target) load() (*inspect.Database, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.db != nil && (t.refresh == 0 || time.Since(t.db.InspectedAt) < t.refresh) {
+		return t.db, nil
+	}
+	if t.conn == nil {
+		conn, err := t.open(t.DSN)
+		if err != nil {
+			return nil, err
+		}
+		t.conn = conn
+		t.inspector = &inspect.Inspector{Schemas: t.Schemas}
+	}
+	db, err := t.inspect(t.conn.NewSession(nil), t.Schemas, t.inspector.Load)
+	if err != nil {
+		return nil, err
+	}
+	t.db = db
+	return db, nil
+}
+
+type server struct {
+	log     *logrus.Logger
+	targets map[string]*target
+}
+
+// inspectFunc inspects schemas of a session with load, see app.inspectWith.
+type inspectFunc func(sess *dbr.Session, schemas []string,
+	load func(sess *dbr.Session) (*inspect.Database, error)) (*inspect.Database, error)
+
+func newServer(log *logrus.Logger, dbs []DatabaseConfig, refresh time.Duration, open func(dsn string) (*dbr.Connection, error),
+	inspectWith inspectFunc) *server {
+	s := &server{log: log, targets: make(map[string]*target, len(dbs))}
+	for _, d := range dbs {
+		s.targets[d.Name] = &target{DatabaseConfig: d, refresh: refresh, open: open, inspect: inspectWith}
+	}
+	return s
+}
+
+// ServeHTTP handles:
+//
+//	/dbs
+//	/dbs/:name
+//	/dbs/:name/:schema
+//	/dbs/:name/:schema/:table
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "dbs" || len(parts) > 4 {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 1 {
+		names := make([]string, 0, len(s.targets))
+		for name := range s.targets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		s.writeJSON(w, names)
+		return
+	}
+
+	t, ok := s.targets[parts[1]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	db, err := t.load()
+	if err != nil {
+		s.log.WithError(err).Errorf("inspect database %s", t.Name)
+		http.Error(w, "cannot inspect database", http.StatusBadGateway)
+		return
+	}
+	if len(parts) == 2 {
+		s.writeJSON(w, db)
+		return
+	}
+
+	schema := db.Schema(parts[2])
+	if schema == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 3 {
+		s.writeJSON(w, schema)
+		return
+	}
+
+	table := schema.Table(parts[3])
+	if table == nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.writeJSON(w, table)
+}
+
+func (s *server) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.log.WithError(err).Warn("write response")
+	}
+}
+
+func (a *app) runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on.")
+	refresh := fs.Duration("refresh", 0, "Re-inspect changed schemas of a database when its inspection is older, never if 0.")
+	a.parseFlags(fs, args)
+
+	dbs := a.cfg.Databases
+	if len(dbs) == 0 && a.connStr != "" {
+		dbs = []DatabaseConfig{{Name: "default", DSN: a.connStr, Schemas: a.schemas}}
+	}
+	if len(dbs) == 0 {
+		a.log.Fatal("no databases configured, use -db or -config")
+	}
+
+	a.log.Infof("serving %d databases on %s", len(dbs), *addr)
+	if err := http.ListenAndServe(*addr, newServer(a.log, dbs, *refresh, a.open, a.inspectWith)); err != nil {
+		a.log.WithError(err).Fatal("serve")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serviceFiles returns the connection service files in lookup order: the
+// user's, then the system-wide one, as libpq reads them.
+func serviceFiles() []string {
+	var files []string
+	if f := os.Getenv("PGSERVICEFILE"); f != "" {
+		files = append(files, f)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".pg_service.conf"))
+	}
+	if dir := os.Getenv("PGSYSCONFDIR"); dir != "" {
+		files = append(files, filepath.Join(dir, "pg_service.conf"))
+	} else {
+		files = append(files, "/etc/postgresql-common/pg_service.conf", "/etc/pg_service.conf")
+	}
+	return files
+}
+
+// readService returns the settings of the first [name] section of a
+// service file, nil if the file has no such section.
+func readService(path, name string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var params map[string]string
+	in := false
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || line[0] == '#':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			if in {
+				return params, nil
+			}
+			if in = line[1:len(line)-1] == name; in {
+				params = make(map[string]string)
+			}
+		case in:
+			i := strings.IndexByte(line, '=')
+			if i < 0 {
+				return nil, fmt.Errorf("%s:%d: syntax error", path, n)
+			}
+			// The first setting of a key wins, as in libpq.
+			if key := strings.TrimSpace(line[:i]); params[key] == "" {
+				params[key] = strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+	return params, sc.Err()
+}
+
+// lookupService returns the connection settings of the named service from
+// the first service file defining it.
+func lookupService(name string) (map[string]string, error) {
+	for _, path := range serviceFiles() {
+		params, err := readService(path, name)
+		if err != nil {
+			return nil, err
+		}
+		if params != nil {
+			return params, nil
+		}
+	}
+	return nil, fmt.Errorf("service %q not found", name)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const serviceConf = `# Connection services
+[prod]
+host=db.example.com
+port = 5432
+# the read-only user
+user=reader
+user=admin
+options=-c search_path=app
+
+[staging]
+host=staging.example.com
+not a setting
+
+[prod]
+host=other.example.com
+`
+
+func writeServiceFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadService(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeServiceFile(t, dir, "pg_service.conf", serviceConf)
+
+	params, err := readService(path, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"host": "db.example.com", "port": "5432", "user": "reader", "options": "-c search_path=app"}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("prod = %v, want %v", params, want)
+	}
+
+	if _, err := readService(path, "staging"); err == nil || !strings.HasSuffix(err.Error(), "pg_service.conf:12: syntax error") {
+		t.Errorf("staging: got error %v, want a syntax error in line 12", err)
+	}
+	if params, err := readService(path, "dev"); params != nil || err != nil {
+		t.Errorf("dev = %v, %v, want no service", params, err)
+	}
+	if params, err := readService(filepath.Join(dir, "missing.conf"), "prod"); params != nil || err != nil {
+		t.Errorf("missing file = %v, %v, want no service", params, err)
+	}
+}
+
+func TestLookupService(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	user := writeServiceFile(t, dir, "user.conf", "[dev]\nhost=localhost\n")
+	writeServiceFile(t, dir, "pg_service.conf", "[dev]\nhost=shared\n[prod]\nhost=db.example.com\n")
+	for k, v := range map[string]string{"PGSERVICEFILE": user, "PGSYSCONFDIR": dir} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	if params, err := lookupService("dev"); err != nil || params["host"] != "localhost" {
+		t.Errorf("dev = %v, %v, want the user's service file first", params, err)
+	}
+	if params, err := lookupService("prod"); err != nil || params["host"] != "db.example.com" {
+		t.Errorf("prod = %v, %v, want the system service file", params, err)
+	}
+	if _, err := lookupService("staging"); err == nil || err.Error() != `service "staging" not found` {
+		t.Errorf("staging: got error %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// readSnapshot reads the snapshot at path, a local file or the URL of a
+// snapshot store.
+func readSnapshot(path string) (*inspect.Database, error) {
+	b, err := readSnapshotFile(path)
+	if err != nil {
+		return nil, err
+	}
+	db := &inspect.Database{}
+	if err := json.Unmarshal(b, db); err != nil {
+		return nil, fmt.Errorf("parse snapshot %s: %v", path, err)
+	}
+	db.Sort()
+	db.LinkReferences()
+	return db, nil
+}
+
+// writeSnapshot writes db to path, a local file or the URL of a snapshot
+// store, or to stdout if path is empty or -.
+func writeSnapshot(path string, db *inspect.Database) error {
+	b, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return writeSnapshotFile(path, b)
+}
+
+func (a *app) runSnapshot(args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	a.recordHistory(db)
+	if err := writeSnapshot(*out, db); err != nil {
+		a.log.WithError(err).Fatal("write snapshot")
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/oauth2/google"
+)
+
+// snapshotStore reads and writes snapshots at a location given by URL.
+// Local paths are read and written directly.
+type snapshotStore interface {
+	get(ctx context.Context, u *url.URL) ([]byte, error)
+	put(ctx context.Context, u *url.URL, b []byte) error
+}
+
+// snapshotStores are the stores by URL scheme. Credentials come from the
+// standard chain of each provider: environment, shared config files and
+// the instance metadata.
+var snapshotStores = map[string]snapshotStore{
+	"s3":    s3Store{},
+	"gs":    gcsStore{},
+	"az":    azStore{},
+	"http":  httpStore{},
+	"https": httpStore{},
+}
+
+// snapshotLocation parses path as a URL of a snapshot store, returning nil
+// for local paths.
+func snapshotLocation(path string) (*url.URL, snapshotStore, error) {
+	i := strings.Index(path, "://")
+	if i < 0 {
+		return nil, nil, nil
+	}
+	store := snapshotStores[path[:i]]
+	if store == nil {
+		return nil, nil, fmt.Errorf("unsupported snapshot location %s", path)
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, store, nil
+}
+
+// displayURL returns u without credentials for messages; the query of a
+// presigned URL holds its signature.
+func displayURL(u *url.URL) string {
+	c := *u
+	c.User, c.RawQuery = nil, ""
+	return c.String()
+}
+
+// readSnapshotFile returns the content of the snapshot at path, a local
+// file or a store URL.
+func readSnapshotFile(path string) ([]byte, error) {
+	u, store, err := snapshotLocation(path)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return ioutil.ReadFile(path)
+	}
+	b, err := store.get(context.Background(), u)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", displayURL(u), err)
+	}
+	return b, nil
+}
+
+// writeSnapshotFile writes b to path, a local file or a store URL.
+func writeSnapshotFile(path string, b []byte) error {
+	u, store, err := snapshotLocation(path)
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return ioutil.WriteFile(path, b, 0644)
+	}
+	if err := store.put(context.Background(), u, b); err != nil {
+		return fmt.Errorf("write %s: %v", displayURL(u), err)
+	}
+	return nil
+}
+
+// s3Store keeps snapshots in S3 at s3://bucket/key. AWS_ENDPOINT_URL_S3
+// points it at S3 compatible storage.
+type s3Store struct{}
+
+func (s3Store) client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := os.Getenv("AWS_ENDPOINT_URL_S3"); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
+
+func (s s3Store) get(ctx context.Context, u *url.URL) ([]byte, error) {
+	c, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(u.Host), Key: aws.String(strings.TrimPrefix(u.Path, "/"))})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return ioutil.ReadAll(out.Body)
+}
+
+func (s s3Store) put(ctx context.Context, u *url.URL, b []byte) error {
+	c, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(u.Host),
+		Key:         aws.String(strings.TrimPrefix(u.Path, "/")),
+		Body:        bytes.NewReader(b),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// gcsStore keeps snapshots in Google Cloud Storage at gs://bucket/object,
+// with the application default credentials.
+type gcsStore struct{}
+
+func (gcsStore) do(ctx context.Context, method, endpoint string, body io.Reader) ([]byte, error) {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.read_write")
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doHTTP(client, req.WithContext(ctx))
+}
+
+func (g gcsStore) get(ctx context.Context, u *url.URL) ([]byte, error) {
+	return g.do(ctx, "GET", fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s?alt=media",
+		url.PathEscape(u.Host), url.PathEscape(strings.TrimPrefix(u.Path, "/"))), nil)
+}
+
+func (g gcsStore) put(ctx context.Context, u *url.URL, b []byte) error {
+	_, err := g.do(ctx, "POST", fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		url.PathEscape(u.Host), url.QueryEscape(strings.TrimPrefix(u.Path, "/"))), bytes.NewReader(b))
+	return err
+}
+
+// azStore keeps snapshots in Azure Blob Storage at
+// az://account/container/blob, with the default Azure credential chain:
+// environment, workload or managed identity and the Azure CLI.
+type azStore struct{}
+
+func (azStore) do(ctx context.Context, method string, u *url.URL, body io.Reader) ([]byte, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://storage.azure.com/.default"}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("https://%s.blob.core.windows.net%s", u.Host, u.EscapedPath()), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	// Bearer tokens need version 2017-11-09 or later of the Blob API.
+	req.Header.Set("x-ms-version", "2021-08-06")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-ms-blob-type", "BlockBlob")
+	}
+	return doHTTP(http.DefaultClient, req.WithContext(ctx))
+}
+
+func (a azStore) get(ctx context.Context, u *url.URL) ([]byte, error) {
+	return a.do(ctx, "GET", u, nil)
+}
+
+func (a azStore) put(ctx context.Context, u *url.URL, b []byte) error {
+	_, err := a.do(ctx, "PUT", u, bytes.NewReader(b))
+	return err
+}
+
+// httpStore reads snapshots with GET and writes them with PUT, e.g. to
+// presigned S3 or GCS URLs and Azure Blob Storage SAS URLs.
+// PG_INSPECTOR_HTTP_TOKEN is sent as a bearer token.
+type httpStore struct{}
+
+func (httpStore) request(ctx context.Context, method string, u *url.URL, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("PG_INSPECTOR_HTTP_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		if strings.HasSuffix(u.Hostname(), ".blob.core.windows.net") {
+			req.Header.Set("x-ms-blob-type", "BlockBlob")
+		}
+	}
+	return doHTTP(http.DefaultClient, req.WithContext(ctx))
+}
+
+func (h httpStore) get(ctx context.Context, u *url.URL) ([]byte, error) {
+	return h.request(ctx, "GET", u, nil)
+}
+
+func (h httpStore) put(ctx context.Context, u *url.URL, b []byte) error {
+	_, err := h.request(ctx, "PUT", u, bytes.NewReader(b))
+	return err
+}
+
+// doHTTP sends req and returns the response body, or an error for a
+// status other than 2xx. Errors show the URL without its query, which may
+// hold a signature.
+func doHTTP(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if uerr, ok := err.(*url.Error); ok {
+		return nil, fmt.Errorf("%s %s: %v", req.Method, displayURL(req.URL), uerr.Err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, displayURL(req.URL), resp.Status, bytes.TrimSpace(b))
+	}
+	return b, nil
+}
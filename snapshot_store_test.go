@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotErrorsHideSignature(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	for _, base := range []string{srv.URL, closed.URL} {
+		path := base + "/schemas/prod.json?sv=2022-11-02&sig=secret-signature"
+		_, err := readSnapshotFile(path)
+		if err == nil {
+			t.Fatalf("read %s: no error", base)
+		}
+		if strings.Contains(err.Error(), "secret-signature") {
+			t.Errorf("error %q contains the signature", err)
+		}
+		if !strings.Contains(err.Error(), "/schemas/prod.json") {
+			t.Errorf("error %q does not name the snapshot", err)
+		}
+	}
+}
+
+func TestSnapshotLocation(t *testing.T) {
+	tests := []struct {
+		path  string
+		store snapshotStore
+		err   bool
+	}{
+		{"prod.json", nil, false},
+		{"s3://schemas/prod.json", s3Store{}, false},
+		{"gs://schemas/prod.json", gcsStore{}, false},
+		{"az://acct/schemas/prod.json", azStore{}, false},
+		{"https://acct.blob.core.windows.net/schemas/prod.json?sig=x", httpStore{}, false},
+		{"ftp://host/prod.json", nil, true},
+	}
+	for _, tt := range tests {
+		_, store, err := snapshotLocation(tt.path)
+		if (err != nil) != tt.err || store != tt.store {
+			t.Errorf("snapshotLocation(%q) = %T, %v", tt.path, store, err)
+		}
+	}
+}
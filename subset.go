@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// subsetName is the temporary table collecting the rows of t.
+func subsetName(t *inspect.Table) string {
+	return inspect.QuoteIdent("subset_" + t.Schema + "." + t.Name)
+}
+
+func tableRef(t *inspect.Table) string {
+	return inspect.QualifiedName(t.Schema, t.Name)
+}
+
+// joinOn is the condition matching columns of alias a to refColumns of
+// alias b.
+func joinOn(a string, columns []string, b string, refColumns []string) string {
+	conds := make([]string, len(columns))
+	for i := range columns {
+		conds[i] = fmt.Sprintf("%s.%s = %s.%s", a, inspect.QuoteIdent(columns[i]), b, inspect.QuoteIdent(refColumns[i]))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// notCollected excludes rows of alias p already collected for t, by
+// primary key. Rows of tables without one may be collected twice.
+func notCollected(t *inspect.Table, p string) string {
+	if len(t.PK.Columns) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM %s s WHERE %s)", subsetName(t),
+		joinOn("s", t.PK.Columns, p, t.PK.Columns))
+}
+
+// writeSubset writes a psql script extracting the rows of root matching
+// where, the rows referencing them when children is set, and every row
+// referenced by those, into one CSV file per table in dir.
+func writeSubset(w io.Writer, db *inspect.Database, root *inspect.Table, where string, children bool, dir string) error {
+	lookup := func(schema, table string) *inspect.Table {
+		if s := db.Schema(schema); s != nil {
+			return s.Table(table)
+		}
+		return nil
+	}
+
+	// Tables reached downwards from the root, in breadth first order.
+	down := []*inspect.Table{root}
+	inSet := map[*inspect.Table]bool{root: true}
+	if children {
+		for i := 0; i < len(down); i++ {
+			for _, r := range down[i].ReferencedBy {
+				if c := lookup(r.Schema, r.Table); c != nil && !inSet[c] {
+					inSet[c] = true
+					down = append(down, c)
+				}
+			}
+		}
+	}
+	// Every table referenced from the set, transitively.
+	for changed := true; changed; {
+		changed = false
+		for t := range inSet {
+			for _, fk := range t.FKs {
+				if p := lookup(fk.RefSchema, fk.RefTable); p != nil && !inSet[p] {
+					inSet[p], changed = true, true
+				}
+			}
+		}
+	}
+	order, deferred := db.LoadOrder()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "-- Subset of %s WHERE %s, run with psql.\nBEGIN;\n\n", qualifiedName(root), where)
+	for _, t := range order {
+		if !inSet[t] {
+			continue
+		}
+		if t == root {
+			fmt.Fprintf(bw, "CREATE TEMP TABLE %s AS SELECT * FROM %s WHERE %s;\n", subsetName(t), tableRef(t), where)
+		} else {
+			fmt.Fprintf(bw, "CREATE TEMP TABLE %s AS SELECT * FROM %s WITH NO DATA;\n", subsetName(t), tableRef(t))
+		}
+	}
+
+	if len(down) > 1 {
+		bw.WriteString("\n-- Rows referencing collected rows.\n")
+	}
+	for _, p := range down {
+		for _, r := range p.ReferencedBy {
+			c := lookup(r.Schema, r.Table)
+			if c == nil || c == root || !inSet[c] || !children {
+				continue
+			}
+			fmt.Fprintf(bw, "INSERT INTO %s SELECT * FROM %s c WHERE EXISTS (SELECT 1 FROM %s p WHERE %s)%s;\n",
+				subsetName(c), tableRef(c), subsetName(p), joinOn("c", r.FK.Columns, "p", r.FK.RefColumns),
+				notCollected(c, "c"))
+		}
+	}
+
+	// Parents are completed after all their children, which are visited
+	// before them in reverse load order.
+	bw.WriteString("\n-- Rows referenced by collected rows.\n")
+	for i := len(order) - 1; i >= 0; i-- {
+		t := order[i]
+		if !inSet[t] {
+			continue
+		}
+		for _, fk := range t.FKs {
+			p := lookup(fk.RefSchema, fk.RefTable)
+			if p == nil || !inSet[p] {
+				continue
+			}
+			fmt.Fprintf(bw, "INSERT INTO %s SELECT * FROM %s p WHERE EXISTS (SELECT 1 FROM %s c WHERE %s)%s;\n",
+				subsetName(p), tableRef(p), subsetName(t), joinOn("c", fk.Columns, "p", fk.RefColumns),
+				notCollected(p, "p"))
+		}
+	}
+	for _, r := range deferred {
+		if t := lookup(r.Schema, r.Table); inSet[t] {
+			fmt.Fprintf(bw, "-- %s of %s.%s is part of a cycle, rows it references may be missing.\n",
+				r.FK.Name, r.Schema, r.Table)
+		}
+	}
+
+	bw.WriteString("\n")
+	for _, t := range order {
+		if inSet[t] {
+			path := filepath.Join(dir, qualifiedName(t)+".csv")
+			fmt.Fprintf(bw, "\\copy %s TO %s WITH (FORMAT csv, HEADER)\n", subsetName(t), sqlLiteral(path))
+		}
+	}
+	bw.WriteString("\nROLLBACK;\n")
+	return bw.Flush()
+}
+
+// runSubset writes a script extracting a referentially consistent slice of
+// the database.
+func (a *app) runSubset(args []string) {
+	fs := flag.NewFlagSet("subset", flag.ContinueOnError)
+	where := fs.String("where", "true", "Predicate selecting the rows of the root table.")
+	children := fs.Bool("children", true, "Also collect rows referencing the collected rows, transitively.")
+	dir := fs.String("dir", ".", "Directory the script writes CSV files to.")
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		a.log.Fatal("usage: subset [flags] schema.table")
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	root := findTable(db, fs.Arg(0))
+	if root == nil {
+		a.log.Fatalf("table %s not found", fs.Arg(0))
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeSubset(w, db, root, *where, *children, *dir); err != nil {
+		a.log.WithError(err).Fatal("write subset")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write subset")
+	}
+}
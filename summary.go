@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// schemaSummary are the rollups of one schema, or of the whole database.
+type schemaSummary struct {
+	name                                    string
+	tables, views, matviews, foreign, other int
+	functions, sequences, enums             int
+	columns, fks, indexes                   int
+	size                                    int64
+	rows                                    int64
+	types                                   map[string]int
+	largest                                 []*inspect.Table
+}
+
+// add counts t into the summary.
+func (s *schemaSummary) add(t *inspect.Table) {
+	switch t.Type {
+	case "BASE TABLE":
+		s.tables++
+		s.largest = append(s.largest, t)
+	case "VIEW":
+		s.views++
+	case "MATERIALIZED VIEW":
+		s.matviews++
+		s.largest = append(s.largest, t)
+	case "FOREIGN TABLE":
+		s.foreign++
+	default:
+		s.other++
+	}
+	s.columns += len(t.Columns)
+	s.fks += len(t.FKs)
+	s.indexes += len(t.Indexes)
+	s.size += t.Stats.SizeBytes
+	s.rows += t.Stats.RowEstimate
+	for i := range t.Columns {
+		s.types[t.Columns[i].UDTName]++
+	}
+}
+
+// summarize rolls up the schemas of db, returning one summary per schema
+// followed by the total.
+func summarize(db *inspect.Database) []*schemaSummary {
+	total := &schemaSummary{name: "total", types: make(map[string]int)}
+	var out []*schemaSummary
+	for _, s := range db.Schemas {
+		sum := &schemaSummary{name: s.Name, types: make(map[string]int),
+			functions: len(s.Functions), sequences: len(s.Sequences), enums: len(s.Enums)}
+		total.functions += sum.functions
+		total.sequences += sum.sequences
+		total.enums += sum.enums
+		for _, t := range s.Tables {
+			sum.add(t)
+			total.add(t)
+		}
+		out = append(out, sum)
+	}
+	for _, s := range append(out, total) {
+		sort.SliceStable(s.largest, func(i, j int) bool {
+			return s.largest[i].Stats.SizeBytes > s.largest[j].Stats.SizeBytes
+		})
+	}
+	return append(out, total)
+}
+
+// topTypes returns the n most used column types with their counts, e.g.
+// "text 41".
+func topTypes(types map[string]int, n int) []string {
+	names := make([]string, 0, len(types))
+	for t := range types {
+		names = append(names, t)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if types[names[i]] != types[names[j]] {
+			return types[names[i]] > types[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	for i, t := range names {
+		names[i] = fmt.Sprintf("%s %d", t, types[t])
+	}
+	return names
+}
+
+// writeSummary writes a one screen overview of db: the objects, size,
+// keys and indexes of every schema, their largest tables and most used
+// column types.
+func writeSummary(w io.Writer, db *inspect.Database, top int) error {
+	sums := summarize(db)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Database %s, inspected %s\n\n", db.Name, db.InspectedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintln(tw, "SCHEMA\tTABLES\tVIEWS\tMATVIEWS\tFOREIGN\tFUNCTIONS\tSEQUENCES\tENUMS\tCOLUMNS\tFKS\tINDEXES\tROWS\tSIZE")
+	for i, s := range sums {
+		if i == len(sums)-1 && len(sums) == 2 {
+			continue // the total of a single schema
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", s.name, s.tables, s.views,
+			s.matviews, s.foreign, s.functions, s.sequences, s.enums, s.columns, s.fks, s.indexes, s.rows,
+			humanBytes(s.size))
+	}
+
+	for _, s := range sums[:len(sums)-1] {
+		if len(s.largest) == 0 && len(s.types) == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "\n== %s ==\n", s.name)
+		if len(s.largest) > 0 {
+			fmt.Fprintln(tw, "LARGEST\tROWS\tSIZE\tSHARE")
+		}
+		for i, t := range s.largest {
+			if i == top {
+				break
+			}
+			share := 0.0
+			if s.size > 0 {
+				share = 100 * float64(t.Stats.SizeBytes) / float64(s.size)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%.0f%%\n", t.Name, t.Stats.RowEstimate, humanBytes(t.Stats.SizeBytes), share)
+		}
+		if len(s.types) > 0 {
+			fmt.Fprintf(tw, "column types\t%s\n", strings.Join(topTypes(s.types, top), ", "))
+		}
+	}
+	return tw.Flush()
+}
+
+// runSummary prints per schema rollups of the database, an overview for a
+// database seen for the first time.
+func (a *app) runSummary(args []string) {
+	fs := flag.NewFlagSet("summary", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	top := fs.Int("top", 5, "Number of largest tables and most used column types to list per schema.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeSummary(w, db, *top); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
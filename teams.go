@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+	"github.com/datainq/pq-inspector/lint"
+)
+
+// unowned is the team of tables no annotation or pattern assigns.
+const unowned = "(unowned)"
+
+// teamOf returns the team owning t: the @owner or @team annotation of its
+// comment, or else the first team with a path.Match pattern matching
+// schema.table.
+func teamOf(t *inspect.Table, teams []TeamConfig) string {
+	for _, key := range []string{"owner", "team"} {
+		if v := t.Annotations[key]; v != "" && v != "true" {
+			return v
+		}
+	}
+	name := t.Schema + "." + t.Name
+	for _, tc := range teams {
+		for _, p := range tc.Match {
+			if ok, _ := path.Match(p, name); ok {
+				return tc.Team
+			}
+		}
+	}
+	return unowned
+}
+
+// teamInventory are the tables of a team with their size and lint findings.
+type teamInventory struct {
+	team     string
+	tables   []*inspect.Table
+	size     int64
+	rows     int64
+	findings map[lint.Severity]int
+}
+
+// teamInventories groups the tables of db by team and counts the findings
+// on each table, or on its columns, for its team.
+func teamInventories(db *inspect.Database, teams []TeamConfig, findings []lint.Finding) []*teamInventory {
+	byTeam := make(map[string]*teamInventory)
+	byTable := make(map[string]*teamInventory)
+	var out []*teamInventory
+	for _, s := range db.Schemas {
+		for _, t := range s.Tables {
+			name := teamOf(t, teams)
+			inv := byTeam[name]
+			if inv == nil {
+				inv = &teamInventory{team: name, findings: make(map[lint.Severity]int)}
+				byTeam[name] = inv
+				out = append(out, inv)
+			}
+			inv.tables = append(inv.tables, t)
+			inv.size += t.Stats.SizeBytes
+			inv.rows += t.Stats.RowEstimate
+			byTable[t.Schema+"."+t.Name] = inv
+		}
+	}
+	for _, f := range findings {
+		parts := strings.SplitN(f.Object, ".", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		if inv := byTable[parts[0]+"."+parts[1]]; inv != nil {
+			inv.findings[f.Severity]++
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].size != out[j].size {
+			return out[i].size > out[j].size
+		}
+		return out[i].team < out[j].team
+	})
+	return out
+}
+
+// writeTeams writes the inventory of every team and, with tables, the
+// tables of each team.
+func writeTeams(w io.Writer, inventories []*teamInventory, tables bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEAM\tTABLES\tROWS\tSIZE\tERRORS\tWARNINGS\tNOTES")
+	for _, inv := range inventories {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%d\t%d\t%d\n", inv.team, len(inv.tables), inv.rows, humanBytes(inv.size),
+			inv.findings[lint.Error], inv.findings[lint.Warning], inv.findings[lint.Note])
+	}
+	if tables {
+		for _, inv := range inventories {
+			fmt.Fprintf(tw, "\n== %s ==\n", inv.team)
+			sort.SliceStable(inv.tables, func(i, j int) bool {
+				return inv.tables[i].Stats.SizeBytes > inv.tables[j].Stats.SizeBytes
+			})
+			for _, t := range inv.tables {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", qualifiedName(t), strings.ToLower(t.Type), t.Stats.RowEstimate,
+					humanBytes(t.Stats.SizeBytes))
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// runTeams reports the tables, sizes and lint findings per owning team,
+// from @owner annotations in table comments and the teams of the config
+// file.
+func (a *app) runTeams(args []string) {
+	fs := flag.NewFlagSet("teams", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	tables := fs.Bool("tables", false, "List the tables of every team.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	findings := lint.Run(db, a.lintRules())
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeTeams(w, teamInventories(db, a.cfg.Teams, findings), *tables); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/datainq/pq-inspector/otelinspect"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// telemetryExporters build the span and metric exporters of -telemetry.
+var telemetryExporters = map[string]func(ctx context.Context, c TelemetryConfig) (sdktrace.SpanExporter, sdkmetric.Exporter, error){
+	"otlp": func(ctx context.Context, c TelemetryConfig) (sdktrace.SpanExporter, sdkmetric.Exporter, error) {
+		// Without an endpoint the exporters follow the OTEL_EXPORTER_OTLP_*
+		// environment variables.
+		var traceOpts []otlptracehttp.Option
+		var metricOpts []otlpmetrichttp.Option
+		if c.Endpoint != "" {
+			traceOpts = append(traceOpts, otlptracehttp.WithEndpointURL(strings.TrimSuffix(c.Endpoint, "/")+"/v1/traces"))
+			metricOpts = append(metricOpts, otlpmetrichttp.WithEndpointURL(strings.TrimSuffix(c.Endpoint, "/")+"/v1/metrics"))
+		}
+		spans, err := otlptracehttp.New(ctx, traceOpts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		metrics, err := otlpmetrichttp.New(ctx, metricOpts...)
+		return spans, metrics, err
+	},
+	"stdout": func(ctx context.Context, c TelemetryConfig) (sdktrace.SpanExporter, sdkmetric.Exporter, error) {
+		spans, err := stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, err
+		}
+		metrics, err := stdoutmetric.New(stdoutmetric.WithWriter(os.Stderr), stdoutmetric.WithPrettyPrint())
+		return spans, metrics, err
+	},
+}
+
+// startTelemetry sets up the exporter of c and a span of the command,
+// which inspections and their queries are children of. The returned
+// function ends the span and flushes the exporters.
+func (a *app) startTelemetry(c TelemetryConfig, command string) (func(), error) {
+	newExporters := telemetryExporters[c.Exporter]
+	if newExporters == nil {
+		return nil, fmt.Errorf("unknown telemetry exporter %q, want otlp or stdout", c.Exporter)
+	}
+	ctx := context.Background()
+	spans, metrics, err := newExporters(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "pg-inspector")),
+		resource.WithFromEnv(), resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spans), sdktrace.WithResource(res))
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metrics)), sdkmetric.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+
+	if a.otel, err = otelinspect.New(tp, mp); err != nil {
+		return nil, err
+	}
+	if command == "" {
+		command = "inspect"
+	}
+	var span trace.Span
+	a.ctx, span = tp.Tracer(otelinspect.Name).Start(ctx, "pg-inspector "+command)
+	// Run by the exit handlers and on return, whichever comes first.
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			span.End()
+			for _, shutdown := range []func(context.Context) error{tp.Shutdown, mp.Shutdown} {
+				if err := shutdown(ctx); err != nil {
+					a.log.WithError(err).Warn("flush telemetry")
+				}
+			}
+		})
+	}, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/datainq/pq-inspector/diff"
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// compareTenant returns the changes turning template into tenant. Table
+// and column names in the changes are relative to the tenant schema.
+func compareTenant(template, tenant *inspect.Schema, opts diff.Options) []diff.Change {
+	s := *tenant
+	s.Tables = make([]*inspect.Table, len(tenant.Tables))
+	for i, t := range tenant.Tables {
+		s.Tables[i] = requalify(t, template.Name)
+	}
+	changes := diff.CompareSchemas(template, &s, opts)
+	for i := range changes {
+		changes[i].Name = strings.TrimPrefix(changes[i].Name, tenant.Name+".")
+		changes[i].From = strings.TrimPrefix(changes[i].From, tenant.Name+".")
+	}
+	return changes
+}
+
+// runTenants compares every tenant schema with a template schema and
+// summarizes which tenants deviate and how. It exits with 1 if any does.
+func (a *app) runTenants(args []string) {
+	fs := flag.NewFlagSet("tenants", flag.ContinueOnError)
+	template := fs.String("template", "", "Schema the tenant schemas should match.")
+	match := fs.String("match", "*", "Pattern of tenant schema names, e.g. tenant_*.")
+	owners := fs.Bool("owners", false, "Also compare table owners, which often differ per tenant.")
+	a.parseFlags(fs, args)
+	if *template == "" {
+		a.log.Fatal("tenants requires -template")
+	}
+	if _, err := path.Match(*match, ""); err != nil {
+		a.log.WithError(err).Fatal("invalid -match")
+	}
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	tmpl := db.Schema(*template)
+	if tmpl == nil {
+		a.log.Fatalf("no schema %s", *template)
+	}
+	opts := a.diffOptions(false)
+	if !*owners {
+		opts.IgnoreAttributes = append(opts.IgnoreAttributes, "owner")
+	}
+
+	var tenants, deviating int
+	// byChange counts the tenants with each change, to show the common ones.
+	byChange := map[string][]string{}
+	for _, s := range db.Schemas {
+		if s == tmpl {
+			continue
+		}
+		if ok, _ := path.Match(*match, s.Name); !ok {
+			continue
+		}
+		tenants++
+		changes := compareTenant(tmpl, s, opts)
+		if len(changes) == 0 {
+			continue
+		}
+		deviating++
+		fmt.Printf("%s: %s\n", s.Name, diff.Summary(changes))
+		for _, c := range changes {
+			fmt.Printf("  %s\n", c)
+			byChange[c.String()] = append(byChange[c.String()], s.Name)
+		}
+	}
+	if tenants == 0 {
+		a.log.Fatalf("no schema matches %q", *match)
+	}
+	fmt.Printf("%d of %d tenants deviate from %s\n", deviating, tenants, tmpl.Name)
+	if deviating == 0 {
+		return
+	}
+
+	changes := make([]string, 0, len(byChange))
+	for c := range byChange {
+		changes = append(changes, c)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if len(byChange[changes[i]]) != len(byChange[changes[j]]) {
+			return len(byChange[changes[i]]) > len(byChange[changes[j]])
+		}
+		return changes[i] < changes[j]
+	})
+	fmt.Println("\nDeviations by number of tenants:")
+	for _, c := range changes {
+		fmt.Printf("  %d  %s\n", len(byChange[c]), c)
+	}
+	a.log.Exit(exitFindings)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/datainq/pq-inspector/inspect"
+)
+
+// writeTextSearch lists the text search configurations and dictionaries of
+// db, its tsvector columns and what maintains them.
+func writeTextSearch(w io.Writer, db *inspect.Database) error {
+	columns := inspect.SearchColumns(db)
+	used := map[string]int{}
+	for _, c := range columns {
+		if c.Config != "" {
+			used[c.Config]++
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "== configurations ==")
+	fmt.Fprintln(tw, "NAME\tPARSER\tDICTIONARIES\tCOLUMNS\tOWNER")
+	for _, s := range db.Schemas {
+		for _, c := range s.TextSearchConfigs {
+			name := s.Name + "." + c.Name
+			n := used[name]
+			if s.Name == "public" {
+				n += used[c.Name]
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", name, c.Parser, strings.Join(c.Dictionaries, ", "), n, c.Owner)
+		}
+	}
+
+	fmt.Fprintln(tw, "\n== dictionaries ==")
+	fmt.Fprintln(tw, "NAME\tTEMPLATE\tOPTIONS\tOWNER")
+	for _, s := range db.Schemas {
+		for _, d := range s.TextSearchDictionaries {
+			fmt.Fprintf(tw, "%s.%s\t%s\t%s\t%s\n", s.Name, d.Name, d.Template, d.Options, d.Owner)
+		}
+	}
+
+	fmt.Fprintln(tw, "\n== tsvector columns ==")
+	fmt.Fprintln(tw, "COLUMN\tSOURCE\tCONFIG\tGIN/GIST\tDEFINITION")
+	for _, c := range columns {
+		source, config, index := c.Source, c.Config, "no"
+		if source == "" {
+			source = "none"
+		}
+		if config == "" {
+			config = "-"
+		}
+		if indexedWith(c.Table, c.Column.Name, "gin", "gist") {
+			index = "yes"
+		}
+		fmt.Fprintf(tw, "%s.%s\t%s\t%s\t%s\t%s\n", qualifiedName(c.Table), c.Column.Name, source, config, index,
+			c.Definition)
+	}
+	return tw.Flush()
+}
+
+// runTextSearch reports the full text search setup of the database.
+func (a *app) runTextSearch(args []string) {
+	fs := flag.NewFlagSet("textsearch", flag.ContinueOnError)
+	out := fs.String("o", "", "Output file, stdout if empty.")
+	a.parseFlags(fs, args)
+
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	w, err := createOutput(*out)
+	if err != nil {
+		a.log.WithError(err).Fatal("create output")
+	}
+	if err := writeTextSearch(w, db); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+	if err := w.Close(); err != nil {
+		a.log.WithError(err).Fatal("write report")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/datainq/pq-inspector/descriptor"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// readDescriptor reads a YAML or JSON descriptor, a local file or the URL
+// of a snapshot store.
+func readDescriptor(path string) (*descriptor.Descriptor, error) {
+	b, err := readSnapshotFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &descriptor.Descriptor{}
+	// JSON is YAML too.
+	if err := yaml.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("parse descriptor %s: %v", path, err)
+	}
+	if d.Version > descriptor.Version {
+		return nil, fmt.Errorf("descriptor %s has version %d, newer than %d", path, d.Version, descriptor.Version)
+	}
+	return d, nil
+}
+
+// runVerify checks that the database conforms to a desired state given as
+// a descriptor and exits with 1 listing every violation.
+func (a *app) runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	desired := fs.String("desired", "", "Descriptor of the desired state, YAML or JSON, see gen descriptor.")
+	extra := fs.String("allow-extra", "", "Comma separated objects the database may have beyond the descriptor: schemas, tables, columns, relations, indexes.")
+	format := fs.String("format", "text", "Output format: text or json.")
+	a.parseFlags(fs, args)
+	if *desired == "" {
+		a.log.Fatal("verify requires -desired")
+	}
+	if *format != "text" && *format != "json" {
+		a.log.Fatalf("unknown format %q", *format)
+	}
+	var opts descriptor.VerifyOptions
+	for _, e := range strings.Split(*extra, ",") {
+		switch strings.TrimSpace(e) {
+		case "":
+		case "schemas":
+			opts.ExtraSchemas = true
+		case "tables":
+			opts.ExtraTables = true
+		case "columns":
+			opts.ExtraColumns = true
+		case "relations":
+			opts.ExtraRelations = true
+		case "indexes":
+			opts.ExtraIndexes = true
+		default:
+			a.log.Fatalf("invalid -allow-extra %q", e)
+		}
+	}
+
+	want, err := readDescriptor(*desired)
+	if err != nil {
+		a.log.WithError(err).Fatal("read descriptor")
+	}
+	db, err := a.load()
+	if err != nil {
+		a.log.WithError(err).Fatal("inspect database")
+	}
+	violations := descriptor.Verify(want, descriptor.FromDatabase(db), opts)
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if violations == nil {
+			violations = []descriptor.Violation{}
+		}
+		if err := enc.Encode(violations); err != nil {
+			a.log.WithError(err).Fatal("write report")
+		}
+	} else {
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+	}
+	if len(violations) > 0 {
+		a.log.Errorf("%s does not conform to %s: %d violations", db.Name, *desired, len(violations))
+		a.log.Exit(exitFindings)
+	}
+}
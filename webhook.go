@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/datainq/pq-inspector/diff"
+)
+
+// maxSlackChanges limits the number of changes listed in a Slack message.
+const maxSlackChanges = 20
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+type webhookPayload struct {
+	Database string        `json:"database"`
+	Summary  string        `json:"summary"`
+	Changes  []diff.Change `json:"changes"`
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func slackText(dbName string, changes []diff.Change) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Schema drift in *%s*: %s", dbName, diff.Summary(changes))
+	for i, c := range changes {
+		if i == maxSlackChanges {
+			fmt.Fprintf(&buf, "\n… and %d more", len(changes)-i)
+			break
+		}
+		fmt.Fprintf(&buf, "\n• `%s`", c)
+	}
+	return buf.String()
+}
+
+// notify posts changes to all configured webhooks. Failures are logged only,
+// naming the webhook by its position and host: the URL of a Slack webhook
+// is its secret.
+func (a *app) notify(dbName string, changes []diff.Change) {
+	for i, h := range a.cfg.Webhooks {
+		var payload interface{}
+		switch h.Format {
+		case "slack":
+			payload = slackPayload{Text: slackText(dbName, changes)}
+		case "", "json":
+			payload = webhookPayload{Database: dbName, Summary: diff.Summary(changes), Changes: changes}
+		default:
+			a.log.Warnf("webhook %s: unknown format %q", webhookName(i, h.URL), h.Format)
+			continue
+		}
+		if err := postJSON(h.URL, payload); err != nil {
+			a.log.WithError(err).Warnf("webhook %s", webhookName(i, h.URL))
+		}
+	}
+}
+
+// webhookName identifies the i-th webhook in logs, e.g. #1 (hooks.slack.com).
+func webhookName(i int, rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return fmt.Sprintf("#%d (%s)", i+1, u.Hostname())
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// postJSON posts v to target. Errors leave out the URL.
+func postJSON(target string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(target, "application/json", bytes.NewReader(b))
+	if uerr, ok := err.(*url.Error); ok {
+		return fmt.Errorf("%s: %v", strings.ToLower(uerr.Op), uerr.Err)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
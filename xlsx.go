@@ -0,0 +1,164 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// workbook is a minimal XLSX writer: sheets of strings and numbers with a
+// bold header row, no other formatting.
+type workbook struct {
+	sheets []*sheet
+}
+
+type sheet struct {
+	name string
+	rows [][]interface{} // string, int, int64, float64 or bool cells
+}
+
+// addSheet adds a sheet named after name, made valid and unique.
+func (wb *workbook) addSheet(name string, header ...interface{}) *sheet {
+	name = strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "-", "/", "-", `\`, "-").Replace(name)
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	base := name
+	for i := 2; wb.sheet(name) != nil; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		if len(base)+len(suffix) > 31 {
+			base = base[:31-len(suffix)]
+		}
+		name = base + suffix
+	}
+	s := &sheet{name: name, rows: [][]interface{}{header}}
+	wb.sheets = append(wb.sheets, s)
+	return s
+}
+
+func (wb *workbook) sheet(name string) *sheet {
+	for _, s := range wb.sheets {
+		if strings.EqualFold(s.name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (s *sheet) add(cells ...interface{}) {
+	s.rows = append(s.rows, cells)
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+%s</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+// xlsxStyles has two cell formats: 0 is the default and 1 is bold.
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="1"><fill><patternFill patternType="none"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+</styleSheet>`
+
+func (wb *workbook) write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	var overrides, sheets, rels strings.Builder
+	for i, s := range wb.sheets {
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", i+1)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(s.name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", i+1, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`+"\n", len(wb.sheets)+1)
+
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, overrides.String())},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+` + rels.String() + `</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+	for i, s := range wb.sheets {
+		fw, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := s.write(fw); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (s *sheet) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range s.rows {
+		fmt.Fprintf(bw, `<row r="%d">`, r+1)
+		style := ""
+		if r == 0 {
+			style = ` s="1"`
+		}
+		for c, v := range row {
+			ref := fmt.Sprintf("%s%d", columnRef(c), r+1)
+			switch v := v.(type) {
+			case int, int64, float64:
+				fmt.Fprintf(bw, `<c r="%s"%s><v>%v</v></c>`, ref, style, v)
+			case bool:
+				b := 0
+				if v {
+					b = 1
+				}
+				fmt.Fprintf(bw, `<c r="%s"%s t="b"><v>%d</v></c>`, ref, style, b)
+			default:
+				fmt.Fprintf(bw, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+					ref, style, xmlEscape(fmt.Sprint(v)))
+			}
+		}
+		bw.WriteString("</row>")
+	}
+	bw.WriteString("</sheetData></worksheet>")
+	return bw.Flush()
+}
+
+// columnRef returns the letters of the zero based column c, e.g. 27 -> AB.
+func columnRef(c int) string {
+	ref := ""
+	for c++; c > 0; c = (c - 1) / 26 {
+		ref = string(rune('A'+(c-1)%26)) + ref
+	}
+	return ref
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}